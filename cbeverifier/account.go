@@ -0,0 +1,91 @@
+package cbeverifier
+
+import "strings"
+
+// maskChar is the character NormalizeAccount uses for masked digits
+const maskChar = '*'
+
+// accountMask maps the characters receipts and statements use to mask account
+// digits to maskChar, and drops the separators they group digits with
+var accountMask = strings.NewReplacer(
+	"X", "*",
+	"x", "*",
+	"#", "*",
+	"•", "*",
+	"●", "*",
+	" ", "",
+	"\t", "",
+	"-", "",
+	" ", "",
+)
+
+// NormalizeAccount returns the canonical form of a full or masked account number:
+// separators and whitespace are removed and every masking character ('X', '#',
+// '•') is replaced with '*'. The number of masked digits is preserved.
+//
+// Example:
+//
+//	cbeverifier.NormalizeAccount("1*** ***1234") // "1******1234"
+//	cbeverifier.NormalizeAccount("1000-XXXX-1234") // "1000****1234"
+func NormalizeAccount(account string) string {
+	return strings.ToUpper(accountMask.Replace(strings.TrimSpace(account)))
+}
+
+// IsMaskedAccount reports whether an account number has masked digits
+func IsMaskedAccount(account string) bool {
+	return strings.ContainsRune(NormalizeAccount(account), maskChar)
+}
+
+// AccountMatches reports whether a full account number is consistent with a
+// masked one, such as the account printed on a receipt
+//
+// When both have the same length, every visible digit of masked must match the
+// digit at the same position of full. Receipts do not always print one mask
+// character per hidden digit, so otherwise each run of masked digits matches
+// one or more digits. At least one digit must be visible. Two unmasked numbers
+// match when they are equal.
+//
+// Example:
+//
+//	cbeverifier.AccountMatches("1000123451234", "1000*****1234") // true
+//	cbeverifier.AccountMatches("1000123451234", "1****1234")     // true
+//	cbeverifier.AccountMatches("1000123451234", "1****5678")     // false
+func AccountMatches(full, masked string) bool {
+	full, masked = NormalizeAccount(full), NormalizeAccount(masked)
+	if full == "" || masked == "" || strings.ContainsRune(full, maskChar) {
+		return false
+	}
+	if strings.Trim(masked, string(maskChar)) == "" {
+		return false
+	}
+
+	if len(full) == len(masked) {
+		for i := 0; i < len(masked); i++ {
+			if masked[i] != maskChar && masked[i] != full[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	return matchMasked(full, masked)
+}
+
+// matchMasked matches full against masked, where each run of mask characters
+// stands for one or more characters
+func matchMasked(full, masked string) bool {
+	if masked == "" {
+		return full == ""
+	}
+	if masked[0] != maskChar {
+		return full != "" && full[0] == masked[0] && matchMasked(full[1:], masked[1:])
+	}
+
+	rest := strings.TrimLeft(masked, string(maskChar))
+	for i := 1; i <= len(full); i++ {
+		if matchMasked(full[i:], rest) {
+			return true
+		}
+	}
+	return false
+}
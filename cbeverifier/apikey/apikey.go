@@ -0,0 +1,194 @@
+// Package apikey authenticates the clients of the verification servers with API
+// keys and limits each key to its own request rate, so that one client cannot
+// starve the others or exhaust CBE's rate limit.
+//
+// Keys are listed in code or in a YAML file:
+//
+//	keys:
+//	  - name: billing
+//	    key: 8c1f0e7d4b2a49f6a3d5
+//	    rps: 2
+//	    burst: 10
+//	  - name: support-dashboard
+//	    key: 52b7c9e1f04d4a8e9b6c
+//
+// Example:
+//
+//	keys, err := apikey.Load("api-keys.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	srv := server.New(cbeverifier.New(), server.WithAPIKeys(keys))
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Errors returned by Keys.Allow
+var (
+	ErrMissingKey    = errors.New("missing API key")
+	ErrUnknownKey    = errors.New("unknown API key")
+	ErrQuotaExceeded = errors.New("API key request quota exceeded")
+)
+
+// Key is an API key and the client it identifies
+type Key struct {
+	// Name identifies the client in logs
+	Name string `yaml:"name"`
+	// Key is the secret sent by the client
+	Key string `yaml:"key"`
+	// RPS is the number of requests per second allowed to the key, or 0 for no
+	// limit
+	RPS float64 `yaml:"rps"`
+	// Burst is the number of requests the key may send at once (default: 1)
+	Burst int `yaml:"burst"`
+}
+
+// Keys is a set of API keys, each with its own request quota. It is safe for
+// concurrent use.
+type Keys struct {
+	// keys are indexed by the hash of their secret, so that looking a key up
+	// does not compare secrets byte by byte
+	keys map[[sha256.Size]byte]*entry
+}
+
+// entry is a key with the state of its quota
+type entry struct {
+	Key
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New creates a set of keys. Each key needs a name and a secret, both unique.
+func New(keys ...Key) (*Keys, error) {
+	k := &Keys{keys: make(map[[sha256.Size]byte]*entry, len(keys))}
+	names := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		switch {
+		case key.Name == "":
+			return nil, fmt.Errorf("key %d has no name", i+1)
+		case key.Key == "":
+			return nil, fmt.Errorf("key %q has no secret", key.Name)
+		case names[key.Name]:
+			return nil, fmt.Errorf("key name %q is used twice", key.Name)
+		case key.RPS < 0:
+			return nil, fmt.Errorf("key %q has a negative rate", key.Name)
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := k.keys[hash]; ok {
+			return nil, fmt.Errorf("key %q has the secret of another key", key.Name)
+		}
+		names[key.Name] = true
+
+		key.Burst = max(key.Burst, 1)
+		k.keys[hash] = &entry{Key: key, tokens: float64(key.Burst), last: time.Now()}
+	}
+	return k, nil
+}
+
+// Load reads the keys listed in a YAML file
+func Load(path string) (*Keys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Keys []Key `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	keys, err := New(file.Keys...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// Allow authenticates a request made with secret and takes one request from
+// the key's quota. It returns the key, or fails with ErrMissingKey,
+// ErrUnknownKey or ErrQuotaExceeded; when the quota is exceeded, retryAfter is
+// the time until the key may send its next request.
+func (k *Keys) Allow(secret string) (key Key, retryAfter time.Duration, err error) {
+	if secret == "" {
+		return Key{}, 0, ErrMissingKey
+	}
+	e, ok := k.keys[sha256.Sum256([]byte(secret))]
+	if !ok {
+		return Key{}, 0, ErrUnknownKey
+	}
+	if delay := e.take(); delay > 0 {
+		return e.Key, delay, ErrQuotaExceeded
+	}
+	return e.Key, 0, nil
+}
+
+// Names returns the names of the keys, sorted
+func (k *Keys) Names() []string {
+	names := make([]string, 0, len(k.keys))
+	for _, e := range k.keys {
+		names = append(names, e.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// take takes a request from the key's token bucket if one is left, and
+// otherwise returns how long until the next one
+func (e *entry) take() time.Duration {
+	if e.RPS == 0 {
+		return 0
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.tokens = min(e.tokens+now.Sub(e.last).Seconds()*e.RPS, float64(e.Burst))
+	e.last = now
+	if e.tokens >= 1 {
+		e.tokens--
+		return 0
+	}
+	return time.Duration((1 - e.tokens) / e.RPS * float64(time.Second))
+}
+
+// FromRequest returns the API key of a request, sent as "Authorization: Bearer
+// KEY" or "X-API-Key: KEY"
+func FromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// contextKey is the context key of the authenticated key
+type contextKey struct{}
+
+// NewContext returns ctx carrying the key that authenticated a request
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns the key that authenticated the request of ctx, if any
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
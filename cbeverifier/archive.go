@@ -0,0 +1,76 @@
+package cbeverifier
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// archiveTimeout bounds the time spent archiving a receipt
+const archiveTimeout = 30 * time.Second
+
+// reUnsafeKey matches the characters of references that are not kept in
+// archive keys, which name files and objects
+var reUnsafeKey = regexp.MustCompile(`[^A-Z0-9_-]+`)
+
+// Archiver keeps the receipts verified against, as evidence for disputes
+// raised long after the payment. Receipts are stored under ArchiveKey, so
+// archiving the same receipt twice stores it once. Implementations must be
+// safe for concurrent use.
+type Archiver interface {
+	// Archive stores receipt under key
+	Archive(ctx context.Context, key string, receipt []byte) error
+	// Load returns the receipt stored under key
+	Load(ctx context.Context, key string) ([]byte, error)
+}
+
+// WithArchiver makes the Verifier store every receipt it verifies against in
+// archiver, fetched or supplied, before recording the attempt with its key in
+// Attempt.ReceiptKey. Failures are logged at warn level and do not change the
+// result.
+func WithArchiver(archiver Archiver) VerifierOption {
+	return func(v *Verifier) {
+		v.archiver = archiver
+	}
+}
+
+// ArchiveKey returns the key a receipt is archived under: the reference, with
+// other characters than letters, digits, - and _ replaced, followed by the
+// receipt's SHA-256 (e.g.,
+// "FT24123ABCDE/9ca3c8ac735f996e94e65d674efa2e96a04154526a474e7d31462147a6e7bfc2")
+func ArchiveKey(reference string, receipt []byte) string {
+	return reUnsafeKey.ReplaceAllString(replayKey(reference), "_") + "/" + ReceiptFingerprint(receipt)
+}
+
+// archiveReceipt archives a receipt, returning its key or an empty string if
+// it was not archived
+func (v *Verifier) archiveReceipt(ctx context.Context, transaction Transaction, result *VerificationResult, receipt []byte) string {
+	if v.archiver == nil || len(receipt) == 0 {
+		return ""
+	}
+
+	reference := archiveReference(transaction, result)
+	key := ArchiveKey(reference, receipt)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), archiveTimeout)
+	defer cancel()
+	if err := v.archiver.Archive(ctx, key, receipt); err != nil {
+		v.logger.WarnContext(ctx, "failed to archive receipt", "reference", redact(reference), "error", err)
+		return ""
+	}
+	return key
+}
+
+// archiveReference returns the reference a receipt is archived under: the one
+// printed on the receipt, or else the one submitted without its suffix
+func archiveReference(transaction Transaction, result *VerificationResult) string {
+	if result.Details != nil && result.Details.TransactionID != "" {
+		return result.Details.TransactionID
+	}
+	if normalized, err := normalizeTransaction(transaction); err == nil && normalized.ID != "" {
+		return normalized.ID
+	}
+	if transaction.ID != "" {
+		return transaction.ID
+	}
+	return "unknown"
+}
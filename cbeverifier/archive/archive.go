@@ -0,0 +1,107 @@
+// Package archive implements cbeverifier.Archiver on a local directory and on
+// S3-compatible object storage (Amazon S3, MinIO, Cloudflare R2, DigitalOcean
+// Spaces...), keeping the receipts verified against for as long as disputes
+// can be raised.
+//
+// Receipts are stored under cbeverifier.ArchiveKey, which the verification
+// records of the store package keep as Record.ReceiptKey, so the evidence of a
+// disputed payment is found from its record.
+//
+// Example:
+//
+//	archiver, err := archive.NewS3(archive.S3Config{
+//		Endpoint:        "https://s3.eu-west-1.amazonaws.com",
+//		Region:          "eu-west-1",
+//		Bucket:          "receipts",
+//		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+//		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	verifier := cbeverifier.New(
+//		cbeverifier.WithArchiver(archiver),
+//		cbeverifier.WithRecorder(history),
+//	)
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Load for keys without a receipt
+var ErrNotFound = errors.New("archive: receipt not found")
+
+// Dir is an Archiver storing each receipt in a file named by its key, under a
+// root directory
+type Dir struct {
+	root string
+}
+
+// NewDir creates an archiver storing receipts under root, which is created if
+// needed
+func NewDir(root string) (*Dir, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &Dir{root: root}, nil
+}
+
+// Archive stores receipt under key. Receipts already archived are left as
+// they are, since their key names their content.
+func (d *Dir) Archive(_ context.Context, key string, receipt []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Readers never see a partly written receipt
+	f, err := os.CreateTemp(filepath.Dir(path), ".receipt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(receipt); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Load returns the receipt stored under key
+func (d *Dir) Load(_ context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return receipt, err
+}
+
+// path returns the file of key, refusing keys outside the root
+func (d *Dir) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("archive: invalid key %q", key)
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
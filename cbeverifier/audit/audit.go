@@ -0,0 +1,282 @@
+// Package audit writes a tamper-evident log of every verification: who
+// requested it, what was submitted, the outcome and the fingerprint of the
+// receipt. Regulated merchants use it to show what was checked, and when.
+//
+// The log is a file of JSON lines, each holding the hash of the line before
+// it and its own SHA-256, so that editing, removing or reordering lines breaks
+// the chain. Verify checks the chain of a log. Removing the last lines leaves a
+// valid chain, so keep a copy of the last hash elsewhere, e.g. in a daily
+// report, to detect that too.
+//
+//	{"seq":1,"time":"2025-01-02T10:04:05Z","actor":"checkout","transaction":{...},"outcome":"verified","valid":true,"receipt_sha256":"9ca3...","prev_hash":"","hash":"5d1e..."}
+//
+// Example:
+//
+//	auditLog, err := audit.Open("audit.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer auditLog.Close()
+//	verifier := cbeverifier.New(cbeverifier.WithRecorder(auditLog))
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+)
+
+// Errors of entries that break the chain, wrapped in a LineError
+var (
+	ErrMalformedEntry = errors.New("audit: malformed entry")
+	ErrHashMismatch   = errors.New("audit: entry does not match its hash")
+	ErrBrokenChain    = errors.New("audit: entry does not follow the previous one")
+)
+
+// maxLineBytes bounds the length of an entry when reading a log
+const maxLineBytes = 1 << 20
+
+// reHash matches the hash that ends every line
+var reHash = regexp.MustCompile(`,"hash":"([0-9a-f]{64})"}$`)
+
+// Entry is a line of the audit log
+type Entry struct {
+	// Seq numbers the entries from 1
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	// Actor is who requested the verification (see ContextWithActor)
+	Actor       string                  `json:"actor,omitempty"`
+	MerchantID  string                  `json:"merchant_id,omitempty"`
+	Transaction cbeverifier.Transaction `json:"transaction"`
+	Outcome     string                  `json:"outcome"`
+	Valid       bool                    `json:"valid"`
+	Error       string                  `json:"error,omitempty"`
+	// MismatchFields names the fields that did not match; their values are
+	// left out, since they hold names and account numbers from the receipt
+	MismatchFields []string `json:"mismatch_fields,omitempty"`
+	ReceiptSHA256  string   `json:"receipt_sha256,omitempty"`
+	// ReceiptKey is the key the receipt was archived under, if it was
+	ReceiptKey string `json:"receipt_key,omitempty"`
+	// PrevHash is the hash of the previous entry, empty for the first one
+	PrevHash string `json:"prev_hash"`
+	// Hash is the hex SHA-256 of the entry's line up to the hash itself
+	Hash string `json:"hash,omitempty"`
+}
+
+// LineError is an entry breaking the chain of a log
+type LineError struct {
+	// Line is the line of the entry, from 1
+	Line int
+	Err  error
+}
+
+// Error implements error
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the reason the entry breaks the chain
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// actorKey is the context key of the actor
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx naming who requests the
+// verifications made with it
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of ctx: the one set with
+// ContextWithActor, or else the name of the API key that authenticated the
+// request
+func ActorFromContext(ctx context.Context) (string, bool) {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		return actor, true
+	}
+	if key, ok := apikey.FromContext(ctx); ok {
+		return key.Name, true
+	}
+	return "", false
+}
+
+// Log appends entries to an audit log file. It is a cbeverifier.Recorder.
+//
+// A log must be written by a single process at a time.
+type Log struct {
+	mu    sync.Mutex
+	file  *os.File
+	seq   int64
+	last  string
+	actor string
+}
+
+// Option configures a Log
+type Option func(*Log)
+
+// WithActor sets the actor of verifications whose context names none, e.g.
+// the user running a batch
+func WithActor(actor string) Option {
+	return func(l *Log) {
+		l.actor = actor
+	}
+}
+
+// Open opens the log at path for appending, creating it if needed. The chain
+// of an existing log is verified first, so that a log is never extended past
+// a broken entry.
+func Open(path string, options ...Option) (*Log, error) {
+	l := &Log{}
+	for _, option := range options {
+		option(l)
+	}
+
+	if f, err := os.Open(path); err == nil {
+		last, err := Verify(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		l.seq, l.last = last.Seq, last.Hash
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+	return l, nil
+}
+
+// Record implements cbeverifier.Recorder, appending an entry to the log and
+// syncing it to disk
+func (l *Log) Record(ctx context.Context, attempt *cbeverifier.Attempt) error {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		actor = l.actor
+	}
+	entry := Entry{
+		Time:          attempt.FinishedAt.UTC(),
+		Actor:         actor,
+		MerchantID:    attempt.MerchantID,
+		Transaction:   attempt.Transaction,
+		Outcome:       attempt.Outcome,
+		ReceiptSHA256: attempt.ReceiptSHA256,
+		ReceiptKey:    attempt.ReceiptKey,
+	}
+	if result := attempt.Result; result != nil {
+		entry.Valid = result.IsValid
+		entry.Error = result.Error
+		for field := range result.Mismatches {
+			entry.MismatchFields = append(entry.MismatchFields, field)
+		}
+		sort.Strings(entry.MismatchFields)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Seq, entry.PrevHash = l.seq+1, l.last
+	line, hash, err := encode(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.seq, l.last = entry.Seq, hash
+	return nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// encode returns the line of an entry and its hash
+func encode(entry Entry) (line []byte, hash string, err error) {
+	entry.Hash = ""
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	hash = hashBody(body)
+	line = append(body[:len(body)-1], fmt.Sprintf(`,"hash":"%s"}`+"\n", hash)...)
+	return line, hash, nil
+}
+
+// hashBody returns the hex SHA-256 of an entry without its hash
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks the chain of the log read from r: every entry must match its
+// hash and follow the previous one. It returns the last entry, or a
+// *LineError for the first entry breaking the chain. An empty log is valid,
+// with a zero last entry.
+//
+// Example:
+//
+//	f, err := os.Open("audit.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	last, err := audit.Verify(f)
+//	if err != nil {
+//		log.Fatalf("audit log tampered with: %v", err)
+//	}
+//	fmt.Printf("%d entries, last hash %s\n", last.Seq, last.Hash)
+func Verify(r io.Reader) (Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+
+	var last Entry
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		match := reHash.FindSubmatchIndex(line)
+		if match == nil {
+			return last, &LineError{Line: n, Err: ErrMalformedEntry}
+		}
+		hash := string(line[match[2]:match[3]])
+		body := append(bytes.Clone(line[:match[0]]), '}')
+		if hashBody(body) != hash {
+			return last, &LineError{Line: n, Err: ErrHashMismatch}
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(body, &entry); err != nil || entry.Hash != "" {
+			return last, &LineError{Line: n, Err: ErrMalformedEntry}
+		}
+		if entry.Seq != last.Seq+1 || entry.PrevHash != last.Hash {
+			return last, &LineError{Line: n, Err: ErrBrokenChain}
+		}
+		entry.Hash = hash
+		last = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return last, err
+	}
+	return last, nil
+}
@@ -0,0 +1,207 @@
+// Package awash verifies Awash Bank transfers by fetching and parsing the
+// transaction confirmation Awash publishes for every transaction.
+//
+// Confirmations are identified by the receipt ID shown in the Awash mobile app
+// and SMS, two groups of letters and digits separated by a hyphen (e.g.,
+// "2H1NV4F5K0-39X8NG"). Results have the same shape as CBE verifications: the
+// parsed confirmation is returned as a cbeverifier.TransactionDetails and
+// compared with cbeverifier.CompareDetails.
+//
+// Example:
+//
+//	result, err := awash.Verify(cbeverifier.Transaction{
+//		ID:     "2H1NV4F5K0-39X8NG",
+//		Amount: 1500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "awash", so
+// cbeverifier.Verify handles transactions whose Provider is awash.Name.
+package awash
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "awash"
+
+// DefaultBaseURL is the Awash confirmation endpoint; the receipt ID is appended to it
+const DefaultBaseURL = "https://awashpay.awashbank.com:8225/-"
+
+// Errors returned by the awash package
+var (
+	ErrInvalidReference = errors.New("invalid Awash receipt ID")
+	ErrNetworkError     = errors.New("network error while requesting Awash confirmation")
+	ErrInvalidResponse  = errors.New("invalid response from Awash")
+	ErrReceiptTooLarge  = errors.New("Awash confirmation exceeds maximum size")
+	ErrReceiptNotFound  = errors.New("Awash confirmation not found")
+)
+
+// reReference matches an Awash receipt ID (e.g., "2H1NV4F5K0-39X8NG")
+var reReference = regexp.MustCompile(`^[A-Z0-9]{10}-[A-Z0-9]{6}$`)
+
+// Client fetches and verifies Awash confirmations. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient makes the Client send requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBaseURL makes the Client fetch confirmations from baseURL instead of
+// DefaultBaseURL, e.g. a caching proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// ValidateReference returns the normalized receipt ID, accepting the ID itself
+// (with or without the leading hyphen Awash prints) or the confirmation URL
+//
+// Example:
+//
+//	id, err := awash.ValidateReference("https://awashpay.awashbank.com:8225/-2h1nv4f5k0-39x8ng")
+//	// id = "2H1NV4F5K0-39X8NG"
+func ValidateReference(reference string) (string, error) {
+	ref := strings.TrimSpace(reference)
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", ErrInvalidReference
+		}
+		ref = u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+
+	ref = normalizeReference(ref)
+	if !reReference.MatchString(ref) {
+		return "", ErrInvalidReference
+	}
+	return ref, nil
+}
+
+// normalizeReference uppercases a receipt ID and removes spacing and the leading hyphen
+func normalizeReference(ref string) string {
+	return strings.TrimLeft(strings.ToUpper(strings.Join(strings.Fields(ref), "")), "-")
+}
+
+// Verify fetches the Awash confirmation for transaction.ID (or FullReference)
+// and verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the receipt ID; no suffix is needed
+// 2. Fetches the confirmation from Awash
+// 3. Parses the confirmation into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the Awash confirmation and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized receipt ID from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	id, err := ValidateReference(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = id
+	return transaction, nil
+}
+
+// Fetch fetches and parses the Awash confirmation for a receipt ID without
+// comparing it, returning the parsed details and the raw confirmation
+func Fetch(ctx context.Context, reference string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, reference, opts)
+}
+
+// Fetch fetches and parses the Awash confirmation for a receipt ID
+func (c *Client) Fetch(ctx context.Context, reference string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	id, err := ValidateReference(reference)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confirmation, err := c.fetchConfirmation(ctx, id, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(confirmation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, confirmation, nil
+}
+
+// fetchConfirmation downloads the confirmation for a validated receipt ID
+func (c *Client) fetchConfirmation(ctx context.Context, id string, opts cbeverifier.Options) ([]byte, error) {
+	confirmation, _, err := webreceipt.Fetch(ctx, c.httpClient, c.baseURL+id, "text/html, application/pdf", opts, webreceipt.Errors{
+		Network:         ErrNetworkError,
+		InvalidResponse: ErrInvalidResponse,
+		NotFound:        ErrReceiptNotFound,
+		TooLarge:        ErrReceiptTooLarge,
+	})
+	return confirmation, err
+}
@@ -0,0 +1,87 @@
+package awash
+
+import (
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Confirmation layouts reported in TransactionDetails.FormatVersion
+const (
+	// FormatWeb is the HTML confirmation served by the Awash confirmation page
+	FormatWeb = "awash-web"
+	// FormatPDF is the PDF confirmation shared from the Awash mobile app
+	FormatPDF = "awash-pdf"
+)
+
+// confirmationLabels are the labels printed on confirmations for each field, in
+// priority order
+var confirmationLabels = webreceipt.Labels{
+	{"payer", `sender\s*name|payer\s*name|debit\s*account\s*name`},
+	{"payer_account", `sender\s*account(?:\s*(?:number|no\.?))?|debit\s*account`},
+	{"receiver", `beneficiary\s*name|receiver\s*name|credit\s*account\s*name`},
+	{"receiver_bank", `beneficiary\s*bank(?:\s*name)?|receiver\s*bank|destination\s*bank`},
+	{"receiver_account", `beneficiary\s*account(?:\s*(?:number|no\.?))?|receiver\s*account|credit\s*account`},
+	{"service_charge", `(?:service\s*)?charge|commission`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?(?:\s*debited)?`},
+	{"amount", `(?:transaction\s*|transferred\s*)?amount`},
+	{"date", `transaction\s*(?:time|date)|date(?:\s*(?:&|and)\s*time)?`},
+	{"transaction_id", `transaction\s*id|receipt\s*(?:id|no\.?)|reference(?:\s*no\.?)?`},
+	{"reason", `reason|narration|remark|description`},
+	{"transaction_type", `transaction\s*type`},
+}
+
+var (
+	// confirmationFields match the label cells of HTML confirmations
+	confirmationFields = confirmationLabels.Fields()
+	// confirmationTemplate matches the text rows of PDF confirmations
+	confirmationTemplate = confirmationLabels.Template(FormatPDF)
+)
+
+// dateLayouts are the transaction time formats seen on confirmations
+var dateLayouts = []string{
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"Jan 2, 2006 3:04:05 PM",
+	"Jan 2, 2006, 3:04:05 PM",
+	"02-01-2006 15:04:05",
+	"02/01/2006",
+}
+
+// validID reports whether a printed transaction ID is a receipt ID
+func validID(id string) bool {
+	return reReference.MatchString(normalizeReference(id))
+}
+
+// Parse extracts transaction information from an Awash confirmation, either the
+// HTML confirmation page or a PDF confirmation
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(confirmation []byte) (*cbeverifier.TransactionDetails, error) {
+	var (
+		details *cbeverifier.TransactionDetails
+		err     error
+	)
+	if webreceipt.IsPDF(confirmation) {
+		details, err = webreceipt.ParsePDF(confirmation, confirmationTemplate, dateLayouts, validID)
+		if details == nil {
+			return nil, err
+		}
+	} else {
+		values, verr := webreceipt.Values(confirmation, confirmationFields)
+		if verr != nil {
+			return nil, verr
+		}
+		if len(values) == 0 {
+			return nil, ErrReceiptNotFound
+		}
+		details, err = webreceipt.Finish(webreceipt.Details(values, FormatWeb), dateLayouts)
+	}
+
+	// Confirmations print the receipt ID with a leading hyphen
+	details.TransactionID = normalizeReference(details.TransactionID)
+	return details, err
+}
@@ -0,0 +1,62 @@
+package awash
+
+import (
+	"context"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that fetches confirmations with
+// client, for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch downloads the confirmation for the transaction's receipt ID
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.fetchConfirmation(ctx, transaction.ID, opts)
+}
+
+// Parse parses an HTML or PDF confirmation
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is an Awash receipt ID or
+// confirmation URL
+func (p provider) DetectReference(reference string) bool {
+	_, err := ValidateReference(reference)
+	return err == nil
+}
+
+// Capabilities reports online lookup and uploads of PDF and HTML confirmations
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf", "text/html"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the confirmation details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
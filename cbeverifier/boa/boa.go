@@ -0,0 +1,243 @@
+// Package boa verifies Bank of Abyssinia (BOA) transfers by fetching and parsing
+// the online slip BOA publishes for every transaction.
+//
+// Slips are identified by the transaction reference (e.g., "FT23062669JJ")
+// followed by the last five digits of the payer's account, much like CBE
+// receipts. Results have the same shape as CBE verifications: the parsed slip is
+// returned as a cbeverifier.TransactionDetails and compared with
+// cbeverifier.CompareDetails.
+//
+// Example:
+//
+//	result, err := boa.Verify(cbeverifier.Transaction{
+//		ID:     "FT23062669JJ",
+//		Suffix: "90960",
+//		Amount: 1500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "boa", so
+// cbeverifier.Verify handles transactions whose Provider is boa.Name.
+package boa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "boa"
+
+// DefaultBaseURL is the BOA slip endpoint; the reference and suffix are appended to it
+const DefaultBaseURL = "https://cs.bankofabyssinia.com/slip/?trx="
+
+// Errors returned by the boa package
+var (
+	ErrInvalidReference = errors.New("invalid BOA reference")
+	ErrInvalidSuffix    = errors.New("invalid BOA account suffix")
+	ErrNetworkError     = errors.New("network error while requesting BOA slip")
+	ErrInvalidResponse  = errors.New("invalid response from BOA")
+	ErrReceiptTooLarge  = errors.New("BOA slip exceeds maximum size")
+	ErrReceiptNotFound  = errors.New("BOA slip not found")
+)
+
+var (
+	// reReference matches a BOA transaction reference (e.g., "FT23062669JJ")
+	reReference = regexp.MustCompile(`^FT[A-Z0-9]{10}$`)
+	// reSuffix matches the last five digits of the payer's account
+	reSuffix = regexp.MustCompile(`^\d{5}$`)
+	// reFullReference splits a reference with the suffix appended
+	reFullReference = regexp.MustCompile(`^(FT[A-Z0-9]{10})(\d{5})$`)
+)
+
+// Client fetches and verifies BOA slips. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient makes the Client send requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBaseURL makes the Client fetch slips from baseURL instead of DefaultBaseURL,
+// e.g. a caching proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// SplitReference splits a reference with the account suffix appended, or the
+// slip URL, into the reference and suffix
+//
+// Example:
+//
+//	id, suffix, err := boa.SplitReference("https://cs.bankofabyssinia.com/slip/?trx=FT23062669JJ90960")
+//	// id = "FT23062669JJ", suffix = "90960"
+func SplitReference(full string) (id, suffix string, err error) {
+	ref := strings.TrimSpace(full)
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", "", ErrInvalidReference
+		}
+		ref = u.Query().Get("trx")
+	}
+
+	ref = strings.ToUpper(strings.Join(strings.Fields(ref), ""))
+	m := reFullReference.FindStringSubmatch(ref)
+	if m == nil {
+		return "", "", ErrInvalidReference
+	}
+	return m[1], m[2], nil
+}
+
+// Verify fetches the BOA slip for transaction.ID and Suffix (or FullReference)
+// and verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the reference and the payer's account suffix
+// 2. Fetches the slip from BOA
+// 3. Parses the slip into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the BOA slip and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID, transaction.Suffix, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// normalizeTransaction fills ID and Suffix from FullReference, or splits an ID
+// that already has the suffix appended
+func normalizeTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	switch {
+	case strings.TrimSpace(transaction.FullReference) != "":
+		id, suffix, err := SplitReference(transaction.FullReference)
+		if err != nil {
+			return transaction, err
+		}
+		transaction.ID, transaction.Suffix = id, suffix
+	case strings.TrimSpace(transaction.Suffix) == "":
+		// The reference may have been pasted with the suffix appended
+		if id, suffix, err := SplitReference(transaction.ID); err == nil {
+			transaction.ID, transaction.Suffix = id, suffix
+		}
+	}
+	return transaction, nil
+}
+
+// validateTransaction checks the amount and fills the normalized reference and
+// suffix from ID and Suffix or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	transaction, err := normalizeTransaction(transaction)
+	if err != nil {
+		return transaction, err
+	}
+
+	id, suffix, err := validateReference(transaction.ID, transaction.Suffix)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID, transaction.Suffix = id, suffix
+	return transaction, nil
+}
+
+// validateReference returns the normalized reference and suffix
+func validateReference(reference, suffix string) (string, string, error) {
+	reference = strings.ToUpper(strings.TrimSpace(reference))
+	suffix = strings.TrimSpace(suffix)
+	if !reReference.MatchString(reference) {
+		return "", "", ErrInvalidReference
+	}
+	if !reSuffix.MatchString(suffix) {
+		return "", "", ErrInvalidSuffix
+	}
+	return reference, suffix, nil
+}
+
+// Fetch fetches and parses the BOA slip for a reference and account suffix
+// without comparing it, returning the parsed details and the raw slip
+func Fetch(ctx context.Context, reference, suffix string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, reference, suffix, opts)
+}
+
+// Fetch fetches and parses the BOA slip for a reference and account suffix
+func (c *Client) Fetch(ctx context.Context, reference, suffix string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	reference, suffix, err := validateReference(reference, suffix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slip, err := c.fetchSlip(ctx, reference+suffix, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(slip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, slip, nil
+}
+
+// fetchSlip downloads the slip for a validated reference with the suffix appended
+func (c *Client) fetchSlip(ctx context.Context, fullReference string, opts cbeverifier.Options) ([]byte, error) {
+	slip, _, err := webreceipt.Fetch(ctx, c.httpClient, c.baseURL+fullReference, "text/html, application/pdf", opts, webreceipt.Errors{
+		Network:         ErrNetworkError,
+		InvalidResponse: ErrInvalidResponse,
+		NotFound:        ErrReceiptNotFound,
+		TooLarge:        ErrReceiptTooLarge,
+	})
+	return slip, err
+}
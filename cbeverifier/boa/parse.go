@@ -0,0 +1,71 @@
+package boa
+
+import (
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Slip layouts reported in TransactionDetails.FormatVersion
+const (
+	// FormatWeb is the HTML slip served by the BOA slip portal
+	FormatWeb = "boa-web"
+	// FormatPDF is the PDF slip downloaded from BOA mobile and internet banking
+	FormatPDF = "boa-pdf"
+)
+
+// slipLabels are the labels printed on slips for each field, in priority order
+var slipLabels = webreceipt.Labels{
+	{"payer", `source\s*account\s*name|payer'?s?\s*name|sender\s*name`},
+	{"payer_account", `source\s*account|payer'?s?\s*account|debited\s*account`},
+	{"receiver", `receiver'?s?\s*name|beneficiary\s*name|credited\s*account\s*name`},
+	{"receiver_account", `receiver'?s?\s*account|beneficiary\s*account|credited\s*account`},
+	{"amount", `(?:transferred|transfer|transaction)\s*amount`},
+	{"service_charge", `service\s*charge`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total\s*(?:amount|debited)`},
+	{"date", `(?:transaction\s*)?date(?:\s*(?:&|and)\s*time)?`},
+	{"transaction_id", `transaction\s*ref(?:erence|\.)?(?:\s*no\.?)?`},
+	{"reason", `narrative|remark|reason|description`},
+	{"transaction_type", `transaction\s*type`},
+}
+
+var (
+	// slipFields match the label cells of HTML slips
+	slipFields = slipLabels.Fields()
+	// slipTemplate matches the text rows of PDF slips
+	slipTemplate = slipLabels.Template(FormatPDF)
+)
+
+// dateLayouts are the transaction date formats seen on slips, which put the day first
+var dateLayouts = []string{
+	"02/01/06 15:04",
+	"02/01/06 15:04:05",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2/1/2006 15:04:05",
+	"2006-01-02 15:04:05",
+	"02-Jan-2006 15:04:05",
+	"02-Jan-2006 15:04",
+	"02/01/2006",
+	"02/01/06",
+}
+
+// Parse extracts transaction information from a BOA slip, either the HTML page
+// served by the slip portal or a PDF slip
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(slip []byte) (*cbeverifier.TransactionDetails, error) {
+	if webreceipt.IsPDF(slip) {
+		return webreceipt.ParsePDF(slip, slipTemplate, dateLayouts, reReference.MatchString)
+	}
+
+	values, err := webreceipt.Values(slip, slipFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrReceiptNotFound
+	}
+	return webreceipt.Finish(webreceipt.Details(values, FormatWeb), dateLayouts)
+}
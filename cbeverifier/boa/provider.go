@@ -0,0 +1,66 @@
+package boa
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that fetches slips with client,
+// for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch downloads the slip for the transaction's reference and suffix
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.fetchSlip(ctx, transaction.ID+transaction.Suffix, opts)
+}
+
+// Parse parses an HTML or PDF slip
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is a BOA reference with the
+// account suffix appended or a slip URL. Bare FT references are shared with
+// other banks and are not detected.
+func (p provider) DetectReference(reference string) bool {
+	_, _, err := SplitReference(reference)
+	return err == nil
+}
+
+// Capabilities reports online lookup and uploads of PDF and HTML slips
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf", "text/html"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the slip details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	// The suffix is not needed to compare, e.g. for slips passed to VerifyPDF
+	transaction, err := normalizeTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	transaction.ID = strings.ToUpper(strings.TrimSpace(transaction.ID))
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
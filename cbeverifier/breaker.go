@@ -0,0 +1,166 @@
+package cbeverifier
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState represents the state of the circuit breaker guarding CBE requests
+type BreakerState int
+
+const (
+	// BreakerClosed allows requests through normally
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests immediately with ErrUpstreamUnavailable
+	BreakerOpen
+	// BreakerHalfOpen allows a single trial request after the cooldown has elapsed
+	BreakerHalfOpen
+)
+
+// String returns the name of the breaker state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker trips after a number of consecutive upstream failures
+type circuitBreaker struct {
+	mu            sync.Mutex
+	threshold     int
+	cooldown      time.Duration
+	onStateChange func(from, to BreakerState)
+
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trialing bool
+	pending  [][2]BreakerState
+}
+
+// newCircuitBreaker creates a breaker that opens after threshold consecutive failures
+func newCircuitBreaker(threshold int, cooldown time.Duration, onStateChange func(from, to BreakerState)) *circuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &circuitBreaker{
+		threshold:     threshold,
+		cooldown:      cooldown,
+		onStateChange: onStateChange,
+	}
+}
+
+// Allow reports whether a request may be sent upstream
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.trialing = true
+		return true
+	case BreakerHalfOpen:
+		// Only one trial request is allowed while half-open
+		if b.trialing {
+			return false
+		}
+		b.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a request that reached CBE and got a non-5xx response
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.failures = 0
+	b.trialing = false
+	b.setState(BreakerClosed)
+}
+
+// Failure records a network error or 5xx response
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.failures++
+	b.trialing = false
+	// Late failures of requests sent before the breaker opened do not extend
+	// its cooldown
+	if b.state != BreakerOpen && (b.state == BreakerHalfOpen || b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// Abandon records a request its caller gave up on, which tells nothing of
+// CBE's health; while half-open, another trial request is allowed
+func (b *circuitBreaker) Abandon() {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.trialing = false
+}
+
+// State returns the current breaker state. An open breaker whose cooldown has
+// elapsed is reported half-open, as it lets the next request through.
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Recover closes an open or half-open breaker, once CBE is known to answer
+// again
+func (b *circuitBreaker) Recover() {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if b.state == BreakerClosed {
+		return
+	}
+	b.failures = 0
+	b.trialing = false
+	b.setState(BreakerClosed)
+}
+
+// setState transitions the breaker and queues a callback notification; callers must hold mu
+func (b *circuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.pending = append(b.pending, [2]BreakerState{b.state, state})
+	b.state = state
+}
+
+// unlock releases mu and then delivers queued state changes, so the callback
+// may safely call back into the Verifier
+func (b *circuitBreaker) unlock() {
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if b.onStateChange == nil {
+		return
+	}
+	for _, change := range pending {
+		b.onStateChange(change[0], change[1])
+	}
+}
@@ -0,0 +1,144 @@
+package cbeverifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var changes []string
+	b := newCircuitBreaker(2, 20*time.Millisecond, func(from, to BreakerState) {
+		changes = append(changes, from.String()+" -> "+to.String())
+	})
+
+	b.Failure()
+	if !b.Allow() || b.State() != BreakerClosed {
+		t.Fatalf("one failure of two opened the breaker: %s", b.State())
+	}
+	b.Failure()
+	if b.Allow() || b.State() != BreakerOpen {
+		t.Fatalf("state = %s, want open and requests refused", b.State())
+	}
+
+	// Late failures of requests sent before the breaker opened do not extend
+	// the cooldown
+	time.Sleep(15 * time.Millisecond)
+	b.Failure()
+	time.Sleep(10 * time.Millisecond)
+
+	// Once the cooldown has elapsed, the breaker is reported half-open before a
+	// request is even tried, and lets a single trial through
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("state after the cooldown = %s, want half-open", state)
+	}
+	if !b.Allow() {
+		t.Fatal("trial request refused")
+	}
+	if b.Allow() {
+		t.Error("second trial request allowed")
+	}
+
+	// A failed trial opens the breaker again, and a successful one closes it
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("state after a failed trial = %s, want open", b.State())
+	}
+	time.Sleep(25 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("trial request refused")
+	}
+	b.Success()
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Errorf("state after a successful trial = %s, want closed", b.State())
+	}
+
+	want := []string{"closed -> open", "open -> half-open", "half-open -> open", "open -> half-open", "half-open -> closed"}
+	if !slices.Equal(changes, want) {
+		t.Errorf("state changes = %q, want %q", changes, want)
+	}
+}
+
+func TestCircuitBreakerRecover(t *testing.T) {
+	b := newCircuitBreaker(1, time.Hour, nil)
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s, want open", b.State())
+	}
+	b.Recover()
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Errorf("state after Recover = %s, want closed", b.State())
+	}
+	// Recovered failures are forgotten
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Errorf("state = %s, want open after a new failure", b.State())
+	}
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	listener := stalledServer(t)
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", listener.Addr().String())
+	}
+	opts := Options{
+		FetchTimeout:          5 * time.Second,
+		DialTimeout:           time.Second,
+		ResponseHeaderTimeout: time.Second,
+	}
+
+	tests := []struct {
+		name  string
+		ctx   func() (context.Context, context.CancelFunc)
+		opts  Options
+		state BreakerState
+	}{
+		{"caller cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return ctx, cancel
+		}, opts, BreakerClosed},
+		{"caller deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}, opts, BreakerClosed},
+		// CBE not answering within the verifier's own budget still counts
+		{"dial timeout", func() (context.Context, context.CancelFunc) {
+			return context.WithCancel(context.Background())
+		}, Options{FetchTimeout: 5 * time.Second, DialTimeout: 50 * time.Millisecond}, BreakerOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(
+				WithHTTPClient(&http.Client{Transport: &http.Transport{DialContext: dial}}),
+				WithCircuitBreaker(1, time.Hour, nil),
+			)
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, _, err := v.FetchReceipt(ctx, "FT24123ABCDE", "12345678", tt.opts)
+			if !errors.Is(err, ErrNetworkError) {
+				t.Fatalf("err = %v, want %v", err, ErrNetworkError)
+			}
+			if state := v.BreakerState(); state != tt.state {
+				t.Errorf("breaker state = %s, want %s", state, tt.state)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerAbandonedTrial(t *testing.T) {
+	b := newCircuitBreaker(1, 10*time.Millisecond, nil)
+	b.Failure()
+	time.Sleep(15 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("trial request refused")
+	}
+	// A trial the caller gave up on lets another one through
+	b.Abandon()
+	if !b.Allow() {
+		t.Error("trial request refused after an abandoned trial")
+	}
+}
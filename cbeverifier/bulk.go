@@ -0,0 +1,141 @@
+package cbeverifier
+
+// transferFields are the fields whose recurrence with a different value marks the
+// start of another transfer in a bulk receipt. Accounts are excluded since both
+// parties' accounts appear within a single transfer.
+var transferFields = map[string]bool{
+	"payer":          true,
+	"receiver":       true,
+	"amount":         true,
+	"transaction_id": true,
+	"date":           true,
+	"reason":         true,
+}
+
+// extractTransfers extracts every transfer listed on a receipt
+//
+// Receipts for batch transfers (e.g., salaries) list several transfers, usually
+// after a header naming the payer. The rows are split wherever a field that was
+// already extracted for the current transfer recurs with a different value, and
+// fields that appear only once, such as the payer, are shared by all transfers.
+// A single Transfers entry is never set: it is only populated for bulk receipts,
+// whose embedded details are those of the first transfer.
+func (t *ReceiptTemplate) extractTransfers(rows []receiptRow) *ParsedReceipt {
+	segments := t.segment(rows)
+	if len(segments) == 1 {
+		return t.extract(rows)
+	}
+
+	receipt := t.extract(segments[0])
+	first := receipt.TransactionDetails
+
+	transfers := []TransactionDetails{first}
+	for _, segment := range segments[1:] {
+		transfer := t.extract(segment).TransactionDetails
+		inheritShared(&transfer, &first)
+		transfers = append(transfers, transfer)
+	}
+
+	receipt.Transfers = transfers
+	return receipt
+}
+
+// segment splits rows into one slice per transfer
+func (t *ReceiptTemplate) segment(rows []receiptRow) [][]receiptRow {
+	var (
+		segments [][]receiptRow
+		start    int
+		seen     = make(map[string]string)
+	)
+
+	t.extractWith(rows, func(row int, rule *FieldRule, value string) {
+		if !transferFields[rule.Field] {
+			return
+		}
+		if prev, ok := seen[rule.Field]; ok && prev != value && row > start {
+			segments = append(segments, rows[start:row])
+			start = row
+			seen = make(map[string]string)
+		}
+		seen[rule.Field] = value
+	})
+
+	return append(segments, rows[start:])
+}
+
+// inheritShared copies the fields a transfer lacks from the first transfer, except
+// amounts, which are never shared between transfers
+func inheritShared(transfer, first *TransactionDetails) {
+	inherit := func(dst *string, src, field string) {
+		if *dst == "" && src != "" {
+			*dst = src
+			if score, ok := first.Confidence[field]; ok {
+				transfer.Confidence[field] = score
+			}
+		}
+	}
+
+	if transfer.Confidence == nil {
+		transfer.Confidence = make(map[string]float64)
+	}
+
+	inherit(&transfer.Payer, first.Payer, "payer")
+	inherit(&transfer.PayerAccount, first.PayerAccount, "payer_account")
+	inherit(&transfer.Receiver, first.Receiver, "receiver")
+	inherit(&transfer.ReceiverAccount, first.ReceiverAccount, "receiver_account")
+	if len(transfer.PayerAccounts) == 0 {
+		transfer.PayerAccounts = first.PayerAccounts
+	}
+	if len(transfer.ReceiverAccounts) == 0 {
+		transfer.ReceiverAccounts = first.ReceiverAccounts
+	}
+	inherit(&transfer.Currency, first.Currency, "currency")
+	inherit(&transfer.TransactionID, first.TransactionID, "transaction_id")
+	inherit(&transfer.Reason, first.Reason, "reason")
+	inherit(&transfer.Branch, first.Branch, "branch")
+	inherit(&transfer.Channel, first.Channel, "channel")
+	inherit(&transfer.TransactionType, first.TransactionType, "transaction_type")
+	if transfer.DateRaw == "" && first.DateRaw != "" {
+		transfer.Date, transfer.DateRaw, transfer.DateEC = first.Date, first.DateRaw, first.DateEC
+		if score, ok := first.Confidence["date"]; ok {
+			transfer.Confidence["date"] = score
+		}
+	}
+}
+
+// selectTransfer returns the transfer of a bulk receipt to verify the provided
+// transaction against: the first that passes every required check, or else the
+// one with the fewest mismatches
+func selectTransfer(provided Transaction, details *TransactionDetails, opts Options) *TransactionDetails {
+	if len(details.Transfers) < 2 {
+		return details
+	}
+
+	var (
+		best       *TransactionDetails
+		mismatches int
+	)
+	for i := range details.Transfers {
+		transfer := details.Transfers[i]
+		inheritReceipt(&transfer, details)
+
+		valid, failed, _ := compareTransaction(provided, &transfer, opts)
+		if valid {
+			return &transfer
+		}
+		if best == nil || len(failed) < mismatches {
+			best, mismatches = &transfer, len(failed)
+		}
+	}
+	return best
+}
+
+// inheritReceipt copies the fields that describe the receipt as a whole, rather
+// than one of its transfers, so that the signature and QR checks of
+// compareTransaction apply to whichever transfer is selected
+func inheritReceipt(transfer, details *TransactionDetails) {
+	transfer.FormatVersion = details.FormatVersion
+	transfer.QRPayload = details.QRPayload
+	transfer.SignatureValid = details.SignatureValid
+	transfer.Transfers = details.Transfers
+}
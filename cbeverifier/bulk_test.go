@@ -0,0 +1,81 @@
+package cbeverifier
+
+import "testing"
+
+// bulkReceipt returns the details of a signed salary batch with two transfers
+func bulkReceipt(signatureValid bool) *TransactionDetails {
+	first := TransactionDetails{
+		Payer:         "ACME PLC",
+		Receiver:      "ABEBE KEBEDE",
+		Amount:        1500,
+		Currency:      "ETB",
+		TransactionID: "FT24123ABCDE",
+	}
+	second := first
+	second.Receiver, second.Amount, second.TransactionID = "ALMAZ TADESSE", 2750, "FT24123FGHIJ"
+
+	details := first
+	details.SignatureValid = &signatureValid
+	details.Transfers = []TransactionDetails{first, second}
+	return &details
+}
+
+func TestBulkReceiptSignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		valid      bool
+		indicators []string
+		policy     *Policy
+		want       bool
+		field      string
+	}{
+		{name: "valid signature", valid: true, want: true},
+		{name: "invalid signature", valid: false, field: "signature"},
+		{name: "invalid signature advisory", valid: false, policy: LenientPolicy(), want: true},
+		{name: "tamper indicators", valid: true, indicators: []string{TamperIncrementalUpdate}, field: "tamper"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Policy = tt.policy
+			// The second transfer is the one matching the transaction
+			transaction := Transaction{ID: "FT24123FGHIJ", Amount: 2750}
+
+			result, _ := buildResult(transaction, bulkReceipt(tt.valid), tt.indicators, opts)
+			if result.IsValid != tt.want {
+				t.Fatalf("IsValid = %v, want %v (mismatches %v)", result.IsValid, tt.want, result.Mismatches)
+			}
+			if tt.field != "" {
+				if _, ok := result.Mismatches[tt.field]; !ok {
+					t.Errorf("Mismatches = %v, want a %q mismatch", result.Mismatches, tt.field)
+				}
+				if len(result.Mismatches) != 1 {
+					t.Errorf("Mismatches = %v, want only %q, as the transfer matches", result.Mismatches, tt.field)
+				}
+			}
+			if tt.policy != nil {
+				if _, ok := result.Warnings["signature"]; !ok {
+					t.Errorf("Warnings = %v, want a signature warning", result.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectTransferKeepsReceiptFields(t *testing.T) {
+	details := bulkReceipt(false)
+	details.QRPayload = "https://apps.cbe.com.et:100/?id=FT24123ABCDE12345678"
+	details.FormatVersion = FormatPortalV2
+
+	transfer := selectTransfer(Transaction{ID: "FT24123FGHIJ", Amount: 2750}, details, DefaultOptions())
+	if transfer.TransactionID != "FT24123FGHIJ" {
+		t.Fatalf("selected %s, want FT24123FGHIJ", transfer.TransactionID)
+	}
+	if transfer.SignatureValid == nil || *transfer.SignatureValid {
+		t.Errorf("SignatureValid = %v, want the receipt's false", transfer.SignatureValid)
+	}
+	if transfer.QRPayload != details.QRPayload || transfer.FormatVersion != details.FormatVersion {
+		t.Errorf("transfer lost the receipt's QR payload or format: %+v", transfer)
+	}
+}
@@ -0,0 +1,120 @@
+package cbeverifier
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a receipt stored in a Cache
+type CacheEntry struct {
+	// Details contains the parsed transaction information
+	Details *TransactionDetails `json:"details"`
+	// PDF contains the raw receipt PDF as returned by CBE
+	PDF []byte `json:"pdf,omitempty"`
+}
+
+// Cache stores fetched receipts by full reference (ID + suffix).
+//
+// Receipts are immutable once issued, so a Verifier consults its cache before
+// fetching from CBE. Implementations must be safe for concurrent use and should
+// treat backend errors as cache misses.
+type Cache interface {
+	// Get returns the cached receipt for reference, if present
+	Get(ctx context.Context, reference string) (*CacheEntry, bool)
+	// Set stores the receipt for reference
+	Set(ctx context.Context, reference string, entry *CacheEntry)
+}
+
+// MemoryCache is an in-memory LRU Cache with per-entry expiry
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+// memoryCacheItem is the value stored in the LRU list
+type memoryCacheItem struct {
+	reference string
+	entry     *CacheEntry
+	expires   time.Time
+}
+
+// NewMemoryCache creates an LRU cache holding up to capacity receipts, each kept
+// for at most ttl. A non-positive ttl keeps entries until they are evicted.
+func NewMemoryCache(capacity int, ttl time.Duration) *MemoryCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemoryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached receipt for reference, if present and not expired
+func (c *MemoryCache) Get(_ context.Context, reference string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[reference]
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*memoryCacheItem)
+	if !item.expires.IsZero() && time.Now().After(item.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+// Set stores the receipt for reference, evicting the least recently used entry if full
+func (c *MemoryCache) Set(_ context.Context, reference string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+
+	if elem, ok := c.items[reference]; ok {
+		item := elem.Value.(*memoryCacheItem)
+		item.entry = entry
+		item.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[reference] = c.order.PushFront(&memoryCacheItem{
+		reference: reference,
+		entry:     entry,
+		expires:   expires,
+	})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of cached receipts
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove deletes an element from the cache; callers must hold mu
+func (c *MemoryCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*memoryCacheItem).reference)
+}
@@ -0,0 +1,205 @@
+// Package cbebirr verifies CBE Birr mobile money transfers by fetching and
+// parsing the receipt CBE publishes for every CBE Birr transaction ID.
+//
+// CBE Birr transactions are identified by a 10-character transaction ID sent in
+// the confirmation SMS (e.g., "CKA3B7XY2Z") rather than by an FT reference and
+// account suffix, and their receipts are served by a separate endpoint. Receipt
+// PDFs are parsed by the CBE parser's FormatCBEBirr layout, so results have the
+// same shape as CBE verifications.
+//
+// Example:
+//
+//	result, err := cbebirr.Verify(cbeverifier.Transaction{
+//		ID:     "CKA3B7XY2Z",
+//		Amount: 500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "cbebirr", so
+// cbeverifier.Verify handles transactions whose Provider is cbebirr.Name.
+package cbebirr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "cbebirr"
+
+// DefaultBaseURL is the CBE Birr receipt endpoint; the transaction ID is appended to it
+const DefaultBaseURL = "https://cbebirr.cbe.com.et/receipt/"
+
+// Errors returned by the cbebirr package
+var (
+	ErrInvalidTransactionID = errors.New("invalid CBE Birr transaction ID")
+	ErrNetworkError         = errors.New("network error while requesting CBE Birr receipt")
+	ErrInvalidResponse      = errors.New("invalid response from CBE Birr")
+	ErrReceiptTooLarge      = errors.New("CBE Birr receipt exceeds maximum size")
+	ErrReceiptNotFound      = errors.New("CBE Birr receipt not found")
+)
+
+// reTransactionID matches a well-formed CBE Birr transaction ID (e.g., "CKA3B7XY2Z")
+var reTransactionID = regexp.MustCompile(`^[A-Z0-9]{10}$`)
+
+// Client fetches and verifies CBE Birr receipts. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient makes the Client send requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBaseURL makes the Client fetch receipts from baseURL instead of
+// DefaultBaseURL, e.g. a caching proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// ValidateTransactionID returns the normalized transaction ID, accepting the ID
+// itself or the receipt URL sent in the CBE Birr SMS
+//
+// Example:
+//
+//	id, err := cbebirr.ValidateTransactionID("https://cbebirr.cbe.com.et/receipt/cka3b7xy2z")
+//	// id = "CKA3B7XY2Z"
+func ValidateTransactionID(reference string) (string, error) {
+	ref := strings.TrimSpace(reference)
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", ErrInvalidTransactionID
+		}
+		ref = u.Query().Get("id")
+		if ref == "" {
+			ref = u.Path[strings.LastIndex(u.Path, "/")+1:]
+		}
+	}
+
+	ref = strings.ToUpper(strings.Join(strings.Fields(ref), ""))
+	if !reTransactionID.MatchString(ref) {
+		return "", ErrInvalidTransactionID
+	}
+	return ref, nil
+}
+
+// Verify fetches the CBE Birr receipt for transaction.ID (or FullReference) and
+// verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the transaction ID; no suffix is needed
+// 2. Fetches the receipt from CBE Birr
+// 3. Parses the receipt into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the CBE Birr receipt and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized transaction ID from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	id, err := ValidateTransactionID(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = id
+	return transaction, nil
+}
+
+// Fetch fetches and parses the CBE Birr receipt for a transaction ID without
+// comparing it, returning the parsed details and the raw receipt
+func Fetch(ctx context.Context, transactionID string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, transactionID, opts)
+}
+
+// Fetch fetches and parses the CBE Birr receipt for a transaction ID
+func (c *Client) Fetch(ctx context.Context, transactionID string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	id, err := ValidateTransactionID(transactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receipt, err := c.fetchReceipt(ctx, id, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(receipt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, receipt, nil
+}
+
+// fetchReceipt downloads the receipt for a validated transaction ID
+func (c *Client) fetchReceipt(ctx context.Context, id string, opts cbeverifier.Options) ([]byte, error) {
+	receipt, _, err := webreceipt.Fetch(ctx, c.httpClient, c.baseURL+id, "application/pdf, text/html", opts, webreceipt.Errors{
+		Network:         ErrNetworkError,
+		InvalidResponse: ErrInvalidResponse,
+		NotFound:        ErrReceiptNotFound,
+		TooLarge:        ErrReceiptTooLarge,
+	})
+	return receipt, err
+}
@@ -0,0 +1,81 @@
+package cbebirr
+
+import (
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// FormatWeb is the HTML receipt page served by the CBE Birr receipt endpoint,
+// reported in TransactionDetails.FormatVersion. PDF receipts are reported as
+// cbeverifier.FormatCBEBirr.
+const FormatWeb = "cbebirr-web"
+
+// receiptFields match the label cells of HTML receipts. The parties' phone
+// numbers are their wallet accounts.
+var receiptFields = webreceipt.Labels{
+	{"payer_account", `(?:sender|from)\s*(?:phone|mobile|wallet|account)(?:\s*(?:no\.?|number))?`},
+	{"receiver_account", `(?:receiver|beneficiary|merchant|to)\s*(?:phone|mobile|wallet|account|id)(?:\s*(?:no\.?|number))?`},
+	{"payer", `(?:sender|from)(?:\s*name)?`},
+	{"receiver", `(?:receiver|beneficiary|merchant|to)(?:\s*name)?`},
+	{"service_charge", `service\s*(?:charge|fee)|fee`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?(?:\s*debited)?`},
+	{"amount", `(?:transaction\s*|transferred\s*)?amount`},
+	{"date", `(?:transaction\s*)?date(?:\s*(?:&|and)\s*time)?`},
+	{"transaction_id", `transaction\s*(?:id|number|no\.?)|txn\s*id|receipt\s*(?:no\.?|number)`},
+	{"transaction_type", `transaction\s*type|service`},
+	{"reason", `reason|remarks?|note|purpose`},
+}.Fields()
+
+// dateLayouts are the transaction date formats seen on receipt pages
+var dateLayouts = []string{
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"Jan 2, 2006 3:04:05 PM",
+	"Jan 2, 2006, 3:04:05 PM",
+	"02-01-2006 15:04:05",
+	"02/01/2006",
+}
+
+// Parse extracts transaction information from a CBE Birr receipt, either a PDF
+// receipt, which is parsed like cbeverifier.ParseCBEReceipt, or the HTML receipt
+// page
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(receipt []byte) (*cbeverifier.TransactionDetails, error) {
+	var (
+		details *cbeverifier.TransactionDetails
+		err     error
+	)
+	if webreceipt.IsPDF(receipt) {
+		parsed, perr := cbeverifier.ParseCBEReceipt(receipt)
+		if parsed == nil {
+			return nil, perr
+		}
+		details, err = &parsed.TransactionDetails, perr
+
+		// The CBE parser halves the confidence of references that are not FT
+		// references; restore it for CBE Birr transaction IDs
+		if score, ok := details.Confidence["transaction_id"]; ok && score < 0.5 && reTransactionID.MatchString(details.TransactionID) {
+			details.Confidence["transaction_id"] = min(1, score*2)
+		}
+	} else {
+		values, verr := webreceipt.Values(receipt, receiptFields)
+		if verr != nil {
+			return nil, verr
+		}
+		if len(values) == 0 {
+			return nil, ErrReceiptNotFound
+		}
+		details, err = webreceipt.Finish(webreceipt.Details(values, FormatWeb), dateLayouts)
+	}
+
+	// Every CBE Birr transfer is made from the wallet
+	if details.Channel == "" {
+		details.Channel = cbeverifier.ChannelMobile
+	}
+	return details, err
+}
@@ -0,0 +1,77 @@
+package cbebirr
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that fetches receipts with client,
+// for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch downloads the receipt for the transaction ID
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.fetchReceipt(ctx, transaction.ID, opts)
+}
+
+// Parse parses a PDF or HTML receipt
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is a receipt URL of the client's
+// endpoint. Bare transaction IDs have the same shape as Telebirr transaction
+// numbers and are not detected.
+func (p provider) DetectReference(reference string) bool {
+	ref := strings.TrimSpace(reference)
+	if !strings.Contains(ref, "://") {
+		return false
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(p.client.baseURL)
+	if err != nil || !strings.EqualFold(u.Host, base.Host) {
+		return false
+	}
+	_, err = ValidateTransactionID(ref)
+	return err == nil
+}
+
+// Capabilities reports online lookup and uploads of PDF and HTML receipts
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf", "text/html"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the receipt details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
@@ -0,0 +1,307 @@
+package cbeverifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Verifier verifies CBE transactions using a shared HTTP client and request limits.
+//
+// A Verifier is safe for concurrent use and should be reused across verifications
+// so that connections and rate limits are shared.
+//
+// Example:
+//
+//	verifier := cbeverifier.New(cbeverifier.WithRateLimit(2, 5))
+//
+//	result, err := verifier.Verify(ctx, transaction, cbeverifier.DefaultOptions())
+type Verifier struct {
+	client         *http.Client
+	limiter        *rateLimiter
+	breaker        *circuitBreaker
+	cache          Cache
+	logger         *slog.Logger
+	metrics        Metrics
+	tracer         trace.Tracer
+	replay         ReplayStore
+	recorder       Recorder
+	archiver       Archiver
+	ocr            OCREngine
+	qr             QRDecoder
+	templates      []*ReceiptTemplate
+	signatureRoots *x509.CertPool
+	webhookClient  *http.Client
+	// webhooks counts the webhooks being sent
+	webhooks sync.WaitGroup
+}
+
+// VerifierOption configures a Verifier
+type VerifierOption func(*Verifier)
+
+// New creates a Verifier configured with the given options
+func New(options ...VerifierOption) *Verifier {
+	v := &Verifier{
+		logger:        slog.New(slog.DiscardHandler),
+		metrics:       nopMetrics{},
+		tracer:        otel.GetTracerProvider().Tracer(tracerName),
+		webhookClient: &http.Client{},
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true, // Note: This is required for CBE's server
+				},
+			},
+		},
+	}
+
+	for _, option := range options {
+		option(v)
+	}
+
+	return v
+}
+
+// WithRateLimit limits requests to CBE's receipt endpoint to rps requests per second,
+// allowing bursts of up to burst requests. The limit is shared by all calls made
+// through the Verifier. A non-positive rps disables rate limiting.
+func WithRateLimit(rps float64, burst int) VerifierOption {
+	return func(v *Verifier) {
+		if rps <= 0 {
+			v.limiter = nil
+			return
+		}
+		v.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// WithCircuitBreaker stops sending requests to CBE after threshold consecutive network
+// errors or 5xx responses. While the breaker is open, verifications fail immediately
+// with ErrUpstreamUnavailable until cooldown has elapsed, after which a single trial
+// request decides whether to close the breaker again. onStateChange, if not nil, is
+// called on every state transition.
+func WithCircuitBreaker(threshold int, cooldown time.Duration, onStateChange func(from, to BreakerState)) VerifierOption {
+	return func(v *Verifier) {
+		v.breaker = newCircuitBreaker(threshold, cooldown, onStateChange)
+	}
+}
+
+// WithCache makes the Verifier consult cache before fetching a receipt from CBE
+// and store every successfully parsed receipt in it
+func WithCache(cache Cache) VerifierOption {
+	return func(v *Verifier) {
+		v.cache = cache
+	}
+}
+
+// WithLogger makes the Verifier emit structured events: fetches, response sizes,
+// parse durations and cache hits at debug level, verification outcomes (with
+// mismatched field names) at info level and upstream errors at warn level.
+// References and account numbers are redacted and names are never logged.
+func WithLogger(logger *slog.Logger) VerifierOption {
+	return func(v *Verifier) {
+		if logger != nil {
+			v.logger = logger
+		}
+	}
+}
+
+// WithMetrics makes the Verifier report verification outcomes, field mismatches,
+// upstream errors and fetch/parse latencies to metrics
+func WithMetrics(metrics Metrics) VerifierOption {
+	return func(v *Verifier) {
+		if metrics != nil {
+			v.metrics = metrics
+		}
+	}
+}
+
+// WithTracerProvider makes the Verifier create cbe.verify, cbe.fetch, cbe.parse and
+// cbe.compare spans using provider instead of the global OpenTelemetry provider
+func WithTracerProvider(provider trace.TracerProvider) VerifierOption {
+	return func(v *Verifier) {
+		if provider != nil {
+			v.tracer = provider.Tracer(tracerName)
+		}
+	}
+}
+
+// WithReplayStore makes the Verifier claim every successfully verified receipt in
+// store, so the same reference can only verify once per Options.MerchantID.
+// Later verifications of a claimed reference fail with ErrReceiptAlreadyUsed.
+func WithReplayStore(store ReplayStore) VerifierOption {
+	return func(v *Verifier) {
+		v.replay = store
+	}
+}
+
+// WithOCR makes the Verifier fall back to engine for receipt PDFs that have no
+// extractable text layer, such as scanned or image-only receipts
+func WithOCR(engine OCREngine) VerifierOption {
+	return func(v *Verifier) {
+		v.ocr = engine
+	}
+}
+
+// WithQRDecoder makes the Verifier decode the QR code embedded in each receipt,
+// expose it as TransactionDetails.QRPayload and require the reference it encodes
+// to match the reference printed on the receipt
+func WithQRDecoder(decoder QRDecoder) VerifierOption {
+	return func(v *Verifier) {
+		v.qr = decoder
+	}
+}
+
+// WithReceiptTemplates makes the Verifier try templates, in order, before the
+// built-in templates when parsing receipts. The first template that extracts
+// every required field is used. Templates must be compiled, e.g. by loading
+// them with LoadReceiptTemplate.
+func WithReceiptTemplates(templates ...*ReceiptTemplate) VerifierOption {
+	return func(v *Verifier) {
+		v.templates = append(v.templates, templates...)
+	}
+}
+
+// WithSignatureRoots makes the Verifier require the certificate of every signed
+// receipt to chain to one of roots. Without it, TransactionDetails.SignatureValid
+// only reports whether the signature matches the receipt's content. Unsigned
+// receipts are not rejected: their SignatureValid stays nil either way.
+func WithSignatureRoots(roots *x509.CertPool) VerifierOption {
+	return func(v *Verifier) {
+		v.signatureRoots = roots
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used to fetch receipts
+func WithHTTPClient(client *http.Client) VerifierOption {
+	return func(v *Verifier) {
+		if client != nil {
+			v.client = client
+		}
+	}
+}
+
+// WithProxy sends requests to CBE through the proxy at proxyURL (e.g.,
+// "http://proxy.internal:3128"), or through the proxy configured by the
+// HTTPS_PROXY and NO_PROXY environment variables when proxyURL is nil. It has no
+// effect on clients set with WithHTTPClient whose transport is not an
+// *http.Transport, and must follow WithHTTPClient if both are used.
+func WithProxy(proxyURL *url.URL) VerifierOption {
+	return func(v *Verifier) {
+		transport, ok := v.client.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyFromEnvironment
+		if proxyURL != nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		client := *v.client
+		client.Transport = transport
+		v.client = &client
+	}
+}
+
+// defaultVerifier backs the package-level Verify function
+var defaultVerifier = New()
+
+// BreakerState returns the current circuit breaker state, or BreakerClosed if the
+// Verifier has no circuit breaker configured. An open breaker is reported
+// half-open once its cooldown has elapsed.
+func (v *Verifier) BreakerState() BreakerState {
+	if v.breaker == nil {
+		return BreakerClosed
+	}
+	return v.breaker.State()
+}
+
+// Probe checks that CBE's receipt service is reachable with a HEAD request,
+// e.g. for readiness checks. Answers other than 5xx count as reachable; other
+// failures wrap ErrNetworkError or ErrUpstreamUnavailable. Probes are not rate
+// limited, and failed probes are not counted by the circuit breaker; a
+// successful probe closes it, as CBE answers again.
+func (v *Verifier) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://apps.cbe.com.et:100/", nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (CBE-Verifier-Go/1.0)")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: status %d", ErrUpstreamUnavailable, resp.StatusCode)
+	}
+	if v.breaker != nil {
+		v.breaker.Recover()
+	}
+	return nil
+}
+
+// wait blocks until the rate limiter allows another request
+func (v *Verifier) wait(ctx context.Context) error {
+	if v.limiter == nil {
+		return nil
+	}
+	return v.limiter.Wait(ctx)
+}
+
+// allowUpstream reports whether the circuit breaker lets a request through
+func (v *Verifier) allowUpstream() bool {
+	return v.breaker == nil || v.breaker.Allow()
+}
+
+// recordUpstream reports the outcome of an upstream request to the circuit breaker
+func (v *Verifier) recordUpstream(failed bool) {
+	if v.breaker == nil {
+		return
+	}
+	if failed {
+		v.breaker.Failure()
+	} else {
+		v.breaker.Success()
+	}
+}
+
+// abandonUpstream reports an upstream request cancelled by its caller to the
+// circuit breaker, which does not count it
+func (v *Verifier) abandonUpstream() {
+	if v.breaker != nil {
+		v.breaker.Abandon()
+	}
+}
+
+// complete records metrics, logs, trace attributes, the receipt and the
+// attempt, calls the OnResult hook and sends the webhook for a finished
+// verification
+func (v *Verifier) complete(ctx context.Context, span trace.Span, start time.Time, transaction Transaction, result *VerificationResult, receipt []byte, opts Options) {
+	if result == nil {
+		return
+	}
+
+	span.SetAttributes(attribute.String("cbe.result", resultOutcome(result)))
+	v.recordResult(result, time.Since(start))
+	v.logResult(ctx, transaction, result)
+	v.record(ctx, start, transaction, result, receipt, v.archiveReceipt(ctx, transaction, result, receipt), opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	if opts.WebhookURL != "" {
+		v.SendWebhook(ctx, WebhookEventVerified, transaction, result, opts)
+	}
+}
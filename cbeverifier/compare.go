@@ -0,0 +1,181 @@
+package cbeverifier
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/names"
+)
+
+// compareTransaction compares provided transaction data with official details,
+// returning whether all required checks passed along with required mismatches
+// and advisory warnings
+func compareTransaction(provided Transaction, official *TransactionDetails, opts Options) (bool, map[string]interface{}, map[string]interface{}) {
+	policy := opts.policy()
+	c := newComparison()
+
+	// Compare transaction ID
+	if policy.Reference != Ignored {
+		providedID := strings.TrimSpace(provided.ID)
+		officialID := strings.TrimSpace(official.TransactionID)
+		if providedID != officialID {
+			c.fail(policy.Reference, "transaction_id", providedID, officialID)
+		}
+	}
+
+	// The QR code must encode the same reference as the printed text
+	if policy.Reference != Ignored && official.QRPayload != "" {
+		qrRef := QRReference(official.QRPayload)
+		if !strings.EqualFold(qrRef, strings.TrimSpace(official.TransactionID)) {
+			c.fail(policy.Reference, "qr_reference", official.TransactionID, qrRef)
+		}
+	}
+
+	// A signed receipt whose signature does not verify was edited after signing
+	if policy.Tamper != Ignored && official.SignatureValid != nil && !*official.SignatureValid {
+		c.fail(policy.Tamper, "signature", true, false)
+	}
+
+	// Compare amount (with rounding to handle floating point precision)
+	officialAmount, amountField := official.Amount, "amount"
+	if opts.CompareTotalDebited {
+		officialAmount, amountField = official.TotalDebited, "total_debited"
+	}
+	if policy.Amount != Ignored && !amountMatches(provided.Amount, officialAmount, opts) {
+		c.fail(policy.Amount, amountField, provided.Amount, officialAmount)
+	}
+
+	// An amount only matches in the expected currency
+	if policy.Amount != Ignored {
+		expected := normalizeCurrency(provided.Currency)
+		if actual := normalizeCurrency(official.Currency); actual != expected {
+			c.fail(policy.Amount, "currency", expected, actual)
+		}
+	}
+
+	if policy.Receiver != Ignored {
+		// Compare receiver accounts against the merchant's own account suffix
+		if expected := strings.TrimSpace(opts.ExpectedReceiverSuffix); expected != "" {
+			if !anyAccountMatchesSuffix(receiverAccounts(official), expected) {
+				c.fail(policy.Receiver, "receiver_account", expected, official.ReceiverAccount)
+			}
+		}
+
+		if expected := strings.TrimSpace(provided.ReceiverName); expected != "" {
+			if !names.Match(expected, official.Receiver) {
+				c.fail(policy.Receiver, "receiver_name", expected, official.Receiver)
+			}
+		}
+	}
+
+	if policy.Payer != Ignored {
+		if expected := strings.TrimSpace(provided.PayerName); expected != "" {
+			if !names.Match(expected, official.Payer) {
+				c.fail(policy.Payer, "payer_name", expected, official.Payer)
+			}
+		}
+	}
+
+	// Reject stale receipts reused for new orders
+	if policy.Date != Ignored {
+		if violation := checkDateWindow(official.Date, opts, time.Now()); violation != "" {
+			c.fail(policy.Date, "date", violation, official.DateRaw)
+		}
+	}
+
+	// Check the payment reason for required keywords (e.g., an order number)
+	if keywords := opts.reasonKeywords(); policy.Reason != Ignored && len(keywords) > 0 {
+		if missing := missingKeywords(official.Reason, keywords); len(missing) > 0 {
+			c.fail(policy.Reason, "reason", missing, official.Reason)
+		}
+	}
+
+	return len(c.mismatches) == 0, c.mismatches, c.warnings
+}
+
+// missingKeywords returns the keywords that do not appear in text, ignoring case,
+// spacing and punctuation, so "ORDER-1234" matches a reason of "order 1234"
+func missingKeywords(text string, keywords []string) []string {
+	normalized := normalizeKeyword(text)
+
+	var missing []string
+	for _, keyword := range keywords {
+		k := normalizeKeyword(keyword)
+		if k != "" && !strings.Contains(normalized, k) {
+			missing = append(missing, keyword)
+		}
+	}
+	return missing
+}
+
+// normalizeKeyword lowercases s and keeps only its letters and digits
+func normalizeKeyword(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// amountMatches compares amounts using the tolerance and mode configured in opts
+func amountMatches(provided, official float64, opts Options) bool {
+	tolerance := opts.AmountTolerance
+	if pct := provided * opts.AmountTolerancePercent / 100; pct > tolerance {
+		tolerance = pct
+	}
+	tolerance = round2(tolerance)
+
+	diff := round2(official) - round2(provided)
+	if opts.AmountAtLeast {
+		return diff >= -tolerance-0.001
+	}
+	return math.Abs(diff) <= tolerance+0.001
+}
+
+// receiverAccounts returns every receiver account on the receipt
+func receiverAccounts(official *TransactionDetails) []string {
+	if len(official.ReceiverAccounts) > 0 {
+		return official.ReceiverAccounts
+	}
+	return []string{official.ReceiverAccount}
+}
+
+// anyAccountMatchesSuffix reports whether any of accounts matches the expected suffix
+func anyAccountMatchesSuffix(accounts []string, suffix string) bool {
+	for _, account := range accounts {
+		if accountMatchesSuffix(account, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// accountMatchesSuffix reports whether a (possibly masked) receipt account number
+// is consistent with the expected account suffix. Masked digits match any digit,
+// but at least one visible digit must match. A suffix longer than the account
+// is not its tail.
+func accountMatchesSuffix(account, suffix string) bool {
+	account = NormalizeAccount(account)
+	suffix = NormalizeAccount(suffix)
+	if account == "" || suffix == "" || len(suffix) > len(account) {
+		return false
+	}
+
+	// Compare from the end, since the suffix is the tail of the account number
+	matched := 0
+	for i, j := len(account)-1, len(suffix)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		a := account[i]
+		if a == maskChar {
+			continue
+		}
+		if a != suffix[j] {
+			return false
+		}
+		matched++
+	}
+
+	return matched > 0
+}
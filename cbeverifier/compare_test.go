@@ -0,0 +1,31 @@
+package cbeverifier
+
+import "testing"
+
+func TestAccountMatchesSuffix(t *testing.T) {
+	tests := []struct {
+		account string
+		suffix  string
+		want    bool
+	}{
+		{"1000123412345678", "12345678", true},
+		{"1000123412345678", "12345679", false},
+		{"12345678", "12345678", true},
+		// Masked digits match anything, but a visible one must match
+		{"1****5678", "12345678", true},
+		{"1xxxx5678", "12345678", true},
+		{"1****5679", "12345678", false},
+		{"********", "12345678", false},
+		{"1000 1234 1234 5678", "12345678", true},
+		// The suffix must fit within the account
+		{"5678", "12345678", false},
+		{"****5678", "012345678", false},
+		{"", "12345678", false},
+		{"1000123412345678", "", false},
+	}
+	for _, tt := range tests {
+		if got := accountMatchesSuffix(tt.account, tt.suffix); got != tt.want {
+			t.Errorf("accountMatchesSuffix(%q, %q) = %v, want %v", tt.account, tt.suffix, got, tt.want)
+		}
+	}
+}
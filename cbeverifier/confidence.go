@@ -0,0 +1,136 @@
+package cbeverifier
+
+import "math"
+
+const (
+	// layoutConfidence is the confidence of a value read from the value column
+	// next to a recognized label
+	layoutConfidence = 1.0
+	// tableConfidence is the confidence of a pattern match on a table row
+	tableConfidence = 0.9
+	// lineConfidence is the confidence of a pattern match on untabulated text
+	lineConfidence = 0.8
+	// inferredPenalty scales the confidence of accounts whose party is inferred
+	// from the preceding rows
+	inferredPenalty = 0.9
+	// conflictPenalty scales the confidence of a field extracted with different
+	// values from several rows
+	conflictPenalty = 0.7
+	// malformedPenalty scales the confidence of a value that does not look like
+	// what the field should hold
+	malformedPenalty = 0.5
+)
+
+// fieldScores tracks the confidence of each extracted field
+type fieldScores struct {
+	confidence map[string]float64
+	values     map[string]string
+}
+
+// newFieldScores creates an empty score set
+func newFieldScores() *fieldScores {
+	return &fieldScores{
+		confidence: make(map[string]float64),
+		values:     make(map[string]string),
+	}
+}
+
+// record stores the confidence of a field, lowering it when a different value
+// was already extracted for the same field
+func (s *fieldScores) record(field, value string, score float64) {
+	if prev, ok := s.values[field]; ok && prev != value {
+		score = math.Min(score, s.confidence[field]) * conflictPenalty
+	}
+	s.values[field] = value
+	s.confidence[field] = score
+}
+
+// recordFirst stores the confidence of a field whose first value is kept
+func (s *fieldScores) recordFirst(field string, score float64) {
+	if _, ok := s.confidence[field]; !ok {
+		s.confidence[field] = score
+	}
+}
+
+// penalize lowers the confidence of a malformed field
+func (s *fieldScores) penalize(field string) {
+	if score, ok := s.confidence[field]; ok {
+		s.confidence[field] = score * malformedPenalty
+	}
+}
+
+// result returns the field confidences rounded to two decimal places
+func (s *fieldScores) result() map[string]float64 {
+	rounded := make(map[string]float64, len(s.confidence))
+	for field, score := range s.confidence {
+		rounded[field] = round2(score)
+	}
+	return rounded
+}
+
+// matchConfidence returns the confidence of a rule match on row
+func matchConfidence(rule *FieldRule, row receiptRow) float64 {
+	score := lineConfidence
+	switch {
+	case rule.label != nil:
+		score = layoutConfidence
+	case row.Value != "":
+		score = tableConfidence
+	}
+
+	// OCR rows carry the engine's own confidence
+	if row.Confidence > 0 {
+		score *= math.Min(row.Confidence, 1)
+	}
+	return score
+}
+
+// lowConfidenceFields returns the required checks' fields parsed with less than
+// Options.MinConfidence, or nil if there are none or the receipt has no scores
+func lowConfidenceFields(provided Transaction, details *TransactionDetails, opts Options) map[string]float64 {
+	if opts.MinConfidence <= 0 || details == nil || details.Confidence == nil {
+		return nil
+	}
+	policy := opts.policy()
+
+	var fields []string
+	if policy.Reference == Required {
+		fields = append(fields, "transaction_id")
+	}
+	if policy.Amount == Required {
+		if opts.CompareTotalDebited {
+			fields = append(fields, "total_debited")
+		} else {
+			fields = append(fields, "amount")
+		}
+		fields = append(fields, "currency")
+	}
+	if policy.Receiver == Required {
+		if opts.ExpectedReceiverSuffix != "" {
+			fields = append(fields, "receiver_account")
+		}
+		if provided.ReceiverName != "" {
+			fields = append(fields, "receiver")
+		}
+	}
+	if policy.Payer == Required && provided.PayerName != "" {
+		fields = append(fields, "payer")
+	}
+	if policy.Date == Required && (opts.MaxAge > 0 || !opts.MinDate.IsZero() || !opts.MaxDate.IsZero()) {
+		fields = append(fields, "date")
+	}
+	if policy.Reason == Required && len(opts.reasonKeywords()) > 0 {
+		fields = append(fields, "reason")
+	}
+
+	var low map[string]float64
+	for _, field := range fields {
+		if score := details.Confidence[field]; score < opts.MinConfidence {
+			if low == nil {
+				low = make(map[string]float64)
+			}
+			low[field] = score
+		}
+	}
+	return low
+}
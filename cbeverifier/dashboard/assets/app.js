@@ -0,0 +1,228 @@
+// The dashboard of the verification history. It loads api/summary every
+// refreshInterval, and posts the "verify now" form to api/verify.
+"use strict";
+
+const refreshInterval = 15000;
+
+// credentials are sent with every request, for servers that require an API key
+const credentials = {
+  get key() { return sessionStorage.getItem("cbe-verify-key") || ""; },
+  get merchant() { return sessionStorage.getItem("cbe-verify-merchant") || ""; },
+  save(key, merchant) {
+    sessionStorage.setItem("cbe-verify-key", key);
+    sessionStorage.setItem("cbe-verify-merchant", merchant);
+  },
+  clear() {
+    sessionStorage.removeItem("cbe-verify-key");
+    sessionStorage.removeItem("cbe-verify-merchant");
+  },
+};
+
+class UnauthorizedError extends Error {}
+
+// api calls an endpoint of the dashboard, returning its JSON response
+async function api(path, body) {
+  const headers = { "Accept": "application/json" };
+  if (credentials.key) headers["X-API-Key"] = credentials.key;
+  if (credentials.merchant) headers["X-Merchant-ID"] = credentials.merchant;
+  const init = { headers };
+  if (body !== undefined) {
+    init.method = "POST";
+    init.body = JSON.stringify(body);
+    headers["Content-Type"] = "application/json";
+  }
+  const response = await fetch(path, init);
+  const data = await response.json().catch(() => ({}));
+  if (response.status === 401) throw new UnauthorizedError(data.error || "unauthorized");
+  if (!response.ok) throw new Error(data.error || response.statusText);
+  return data;
+}
+
+const $ = (id) => document.getElementById(id);
+
+// element creates an element, whose text is set with textContent so that
+// values from the history are never parsed as HTML
+function element(tag, text, className) {
+  const el = document.createElement(tag);
+  if (text !== undefined) el.textContent = text;
+  if (className) el.className = className;
+  return el;
+}
+
+const percent = (rate) => (rate * 100).toFixed(rate > 0 && rate < 0.1 ? 1 : 0) + "%";
+const amount = (value, currency) => value ? value.toLocaleString(undefined, { minimumFractionDigits: 2, maximumFractionDigits: 2 }) + " " + (currency || "ETB") : "";
+const time = (iso) => new Date(iso).toLocaleString();
+
+function counts(c) {
+  return `${c.total} verifications: ${c.verified} verified, ${c.mismatch} mismatched, ${c.review} to review, ${c.error} errors`;
+}
+
+function renderUpstream(upstream) {
+  const status = $("upstream-status");
+  let detail;
+  if (upstream.breaker === "open") {
+    status.textContent = "Unavailable";
+    status.className = "value down";
+    detail = "The circuit breaker is open: verifications fail at once until CBE answers again.";
+  } else if (upstream.errors > 0 || upstream.breaker === "half-open") {
+    status.textContent = "Degraded";
+    status.className = "value degraded";
+    detail = `${upstream.errors} verifications failed upstream in the last hour.`;
+  } else {
+    status.textContent = "Available";
+    status.className = "value ok";
+    detail = "No upstream errors in the last hour.";
+  }
+  if (upstream.last_error) {
+    detail += ` Last error, ${time(upstream.last_error_at)}: ${upstream.last_error}`;
+  }
+  $("upstream-detail").textContent = detail;
+}
+
+function renderRates(summary) {
+  for (const [prefix, c] of [["hour", summary.last_hour], ["day", summary.last_day]]) {
+    const rate = $(prefix + "-rate");
+    rate.textContent = c.total ? percent(c.failure_rate) + " failed" : "No verifications";
+    rate.className = "value" + (c.failure_rate >= 0.2 ? " down" : c.failure_rate > 0 ? " degraded" : "");
+    $(prefix + "-counts").textContent = counts(c);
+  }
+  $("truncated").hidden = !summary.truncated;
+}
+
+function renderHourly(hourly) {
+  const chart = $("hourly");
+  const max = Math.max(1, ...hourly.map((bucket) => bucket.total));
+  chart.replaceChildren(...hourly.map((bucket) => {
+    const failed = bucket.mismatch + bucket.error;
+    const bar = element("div", undefined, "bar");
+    bar.style.height = (100 * bucket.total / max) + "%";
+    bar.title = `${new Date(bucket.start).toLocaleTimeString([], { hour: "2-digit", minute: "2-digit" })}: ${counts(bucket)}`;
+    const ok = element("div", undefined, "ok-part");
+    ok.style.flex = String(bucket.total - failed);
+    const bad = element("div", undefined, "failed-part");
+    bad.style.flex = String(failed);
+    bar.append(ok, bad);
+    return bar;
+  }));
+}
+
+function outcome(name) {
+  return element("span", name, "outcome outcome-" + name);
+}
+
+function renderRecent(recent) {
+  $("recent").replaceChildren(...recent.map((v) => {
+    const row = element("tr");
+    const cell = (text, className) => row.append(element("td", text, className));
+    cell(time(v.started_at));
+    cell(v.reference);
+    cell(v.merchant_id || "");
+    cell(amount(v.amount, v.currency), "number");
+    const outcomeCell = element("td");
+    outcomeCell.append(outcome(v.outcome));
+    row.append(outcomeCell);
+    cell(v.mismatches ? "Mismatched: " + v.mismatches.join(", ") : v.error || "");
+    cell(v.duration_ms + " ms", "number");
+    return row;
+  }));
+}
+
+async function refresh() {
+  try {
+    const summary = await api("api/summary");
+    showDashboard();
+    $("error").textContent = "";
+    renderUpstream(summary.upstream);
+    renderRates(summary);
+    renderHourly(summary.hourly);
+    renderRecent(summary.recent);
+    $("verify-panel").hidden = !summary.can_verify;
+    $("updated").textContent = "Updated " + new Date(summary.generated_at).toLocaleTimeString();
+  } catch (err) {
+    if (err instanceof UnauthorizedError) {
+      showLogin(credentials.key ? err.message : "");
+      return;
+    }
+    $("error").textContent = "Could not load the dashboard: " + err.message;
+  }
+}
+
+function showDashboard() {
+  $("login").hidden = true;
+  $("dashboard").hidden = false;
+  $("sign-out").hidden = !credentials.key;
+}
+
+function showLogin(message) {
+  $("dashboard").hidden = true;
+  $("sign-out").hidden = true;
+  $("login").hidden = false;
+  $("login-error").textContent = message;
+}
+
+$("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const form = event.target;
+  credentials.save(form.key.value.trim(), form.merchant.value.trim());
+  form.key.value = "";
+  refresh();
+});
+
+$("sign-out").addEventListener("click", () => {
+  credentials.clear();
+  showLogin("");
+});
+
+$("verify").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const form = event.target;
+  const provider = form.provider.value.trim();
+  const transaction = { amount: Number(form.amount.value) };
+  // Only CBE references carry the account suffix
+  if (provider && provider !== "cbe") {
+    transaction.id = form.reference.value.trim();
+    transaction.provider = provider;
+  } else {
+    transaction.full_reference = form.reference.value.trim();
+  }
+
+  const button = form.querySelector("button");
+  button.disabled = true;
+  const details = $("verify-details");
+  const result = $("verify-outcome");
+  $("verify-result").hidden = false;
+  result.textContent = "Verifying…";
+  result.className = "value";
+  details.replaceChildren();
+  try {
+    const verification = await api("api/verify", transaction);
+    const name = verification.is_valid ? "verified" : verification.needs_review ? "review" : verification.mismatches ? "mismatch" : "error";
+    result.replaceChildren(outcome(name));
+    const rows = [];
+    if (verification.error) rows.push(["Error", verification.error]);
+    for (const [field, mismatch] of Object.entries(verification.mismatches || {})) {
+      rows.push(["Mismatch: " + field, typeof mismatch === "object" ? JSON.stringify(mismatch) : String(mismatch)]);
+    }
+    for (const field of Object.keys(verification.warnings || {})) rows.push(["Warning", field]);
+    const d = verification.details;
+    if (d) {
+      rows.push(["Payer", d.payer || ""], ["Receiver", d.receiver || ""], ["Amount", amount(d.amount, d.currency)], ["Date", d.date ? time(d.date) : d.date_raw || ""]);
+    }
+    details.replaceChildren(...rows.flatMap(([term, value]) => [element("dt", term), element("dd", value)]));
+    refresh();
+  } catch (err) {
+    if (err instanceof UnauthorizedError) {
+      showLogin(err.message);
+      return;
+    }
+    result.textContent = "Could not verify: " + err.message;
+    result.className = "value down";
+  } finally {
+    button.disabled = false;
+  }
+});
+
+refresh();
+setInterval(() => {
+  if (!$("dashboard").hidden && !document.hidden) refresh();
+}, refreshInterval);
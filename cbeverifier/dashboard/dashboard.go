@@ -0,0 +1,384 @@
+// Package dashboard serves a web dashboard of the verification history, for
+// operations staff who would rather not use the CLI: the recent
+// verifications, the failure rates of the last hour and day, the status of
+// CBE's receipt service, and a form to verify a transaction at once.
+//
+// The page and its assets are embedded in the binary. They load the data
+// from the JSON endpoints of the Handler, relative to where it is mounted:
+//
+//	GET  api/summary  Summary of the history
+//	POST api/verify   JSON Transaction in, JSON VerificationResult out
+//
+// The upstream status comes from the Verifier's circuit breaker and from the
+// upstream errors of recent verifications; the dashboard makes no requests to
+// CBE of its own.
+//
+// A Handler does not authenticate requests. Mount it behind the
+// authentication of your admin tools, or serve it from a server.Server with
+// server.WithDashboard, where the page asks for an API key and sends it with
+// every call to the endpoints.
+//
+// Example:
+//
+//	history, err := store.NewPostgres(db, "cbe_verifications")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	verifier := cbeverifier.New(cbeverifier.WithRecorder(history))
+//	http.Handle("/dashboard/", requireAdmin(http.StripPrefix("/dashboard", dashboard.New(history, verifier))))
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// maxTransactionBytes limits the size of api/verify request bodies
+const maxTransactionBytes = 64 << 10
+
+// maxScanned is the number of records of the last day the failure rates are
+// computed from at most
+const maxScanned = 10000
+
+// defaultRecent is the number of recent verifications listed by default
+const defaultRecent = 50
+
+//go:embed assets
+var assets embed.FS
+
+// upstreamErrors are the errors of verifications that mean CBE was
+// unreachable
+var upstreamErrors = []error{cbeverifier.ErrNetworkError, cbeverifier.ErrUpstreamUnavailable}
+
+// Summary is the response of GET api/summary
+type Summary struct {
+	// Recent are the most recent verifications, most recent first
+	Recent []Verification `json:"recent"`
+	// LastHour and LastDay count the verifications of the last hour and of
+	// the last 24 hours
+	LastHour Counts `json:"last_hour"`
+	LastDay  Counts `json:"last_day"`
+	// Hourly counts the verifications of each of the last 24 hours, oldest
+	// first
+	Hourly []Bucket `json:"hourly"`
+	// Truncated reports that the last day had more verifications than were
+	// counted
+	Truncated bool     `json:"truncated,omitempty"`
+	Upstream  Upstream `json:"upstream"`
+	// CanVerify reports whether api/verify is available
+	CanVerify   bool      `json:"can_verify"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Verification is a verification of the history, without the names and
+// accounts of its transaction
+type Verification struct {
+	ID         int64   `json:"id"`
+	Reference  string  `json:"reference"`
+	MerchantID string  `json:"merchant_id,omitempty"`
+	Amount     float64 `json:"amount,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	Provider   string  `json:"provider,omitempty"`
+	Outcome    string  `json:"outcome"`
+	// Mismatches are the names of the checks that failed
+	Mismatches []string  `json:"mismatches,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	// DurationMS is how long the verification took, in milliseconds
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// Counts counts verifications by outcome
+type Counts struct {
+	Total    int `json:"total"`
+	Verified int `json:"verified"`
+	Mismatch int `json:"mismatch"`
+	Review   int `json:"review"`
+	Error    int `json:"error"`
+	// FailureRate is the share of mismatches and errors, from 0 to 1
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// Bucket counts the verifications started in the hour from Start
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Counts
+}
+
+// Upstream is the status of CBE's receipt service, as seen by the verifier
+type Upstream struct {
+	// Breaker is the state of the circuit breaker (see
+	// cbeverifier.WithCircuitBreaker): closed, open or half-open
+	Breaker string `json:"breaker"`
+	// Errors is the number of verifications of the last hour that failed
+	// because CBE was unreachable
+	Errors int `json:"errors"`
+	// LastError is the most recent of those errors, of the last day
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+}
+
+// Handler is an http.Handler serving the dashboard
+type Handler struct {
+	history  store.Store
+	verifier *cbeverifier.Verifier
+	opts     cbeverifier.Options
+	recent   int
+	mux      *http.ServeMux
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithOptions sets the options of the verifications of the form (default:
+// cbeverifier.DefaultOptions()). An Options.MerchantID also restricts the
+// dashboard to the records of the merchant.
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(h *Handler) {
+		h.opts = opts
+	}
+}
+
+// WithRecent sets the number of recent verifications listed (default: 50)
+func WithRecent(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.recent = n
+		}
+	}
+}
+
+// New creates a Handler showing history. The form verifies transactions with
+// verifier, which should record them to history (see
+// cbeverifier.WithRecorder); with a nil verifier, the form is hidden.
+func New(history store.Store, verifier *cbeverifier.Verifier, options ...Option) *Handler {
+	h := &Handler{
+		history:  history,
+		verifier: verifier,
+		opts:     cbeverifier.DefaultOptions(),
+		recent:   defaultRecent,
+		mux:      http.NewServeMux(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+
+	static, _ := fs.Sub(assets, "assets")
+	h.mux.HandleFunc("GET /{$}", h.serveIndex)
+	h.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	h.mux.HandleFunc("GET /api/summary", h.serveSummary)
+	h.mux.HandleFunc("POST /api/verify", h.serveVerify)
+	return h
+}
+
+// optionsKey is the context key of the options of a request
+type optionsKey struct{}
+
+// NewContext returns a copy of ctx whose requests run with opts instead of
+// the options of the Handler, e.g. those of the request's merchant
+func NewContext(ctx context.Context, opts cbeverifier.Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// options returns the options of a request
+func (h *Handler) options(ctx context.Context) cbeverifier.Options {
+	if opts, ok := ctx.Value(optionsKey{}).(cbeverifier.Options); ok {
+		return opts
+	}
+	return h.opts
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The page only loads its own assets
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	h.mux.ServeHTTP(w, r)
+}
+
+// serveIndex serves the page of the dashboard
+func (h *Handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	page, err := assets.ReadFile("assets/index.html")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+}
+
+// serveSummary serves the summary of the history
+func (h *Handler) serveSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.Summary(r.Context(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// Summary summarizes the history at now, with the records of the merchant of
+// the options of ctx (see NewContext), if any
+func (h *Handler) Summary(ctx context.Context, now time.Time) (*Summary, error) {
+	merchant := h.options(ctx).MerchantID
+	recent, err := h.history.Query(ctx, store.Filter{MerchantID: merchant, Limit: h.recent})
+	if err != nil {
+		return nil, err
+	}
+	day, err := h.history.Query(ctx, store.Filter{MerchantID: merchant, From: now.Add(-24 * time.Hour), To: now, Limit: maxScanned})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		Recent:      make([]Verification, 0, len(recent)),
+		Hourly:      make([]Bucket, 24),
+		Truncated:   len(day) == maxScanned,
+		CanVerify:   h.verifier != nil,
+		GeneratedAt: now,
+	}
+	// The last bucket is the current hour
+	from := now.Truncate(time.Hour).Add(-23 * time.Hour)
+	for i := range summary.Hourly {
+		summary.Hourly[i].Start = from.Add(time.Duration(i) * time.Hour)
+	}
+	breaker := cbeverifier.BreakerClosed
+	if h.verifier != nil {
+		breaker = h.verifier.BreakerState()
+	}
+	summary.Upstream.Breaker = breaker.String()
+
+	for _, record := range recent {
+		summary.Recent = append(summary.Recent, verification(record))
+	}
+	hourAgo := now.Add(-time.Hour)
+	// Records are queried most recent first
+	for _, record := range day {
+		summary.LastDay.add(record.Outcome)
+		if i := int(record.StartedAt.Sub(from) / time.Hour); i >= 0 && i < len(summary.Hourly) {
+			summary.Hourly[i].add(record.Outcome)
+		}
+		if !record.StartedAt.Before(hourAgo) {
+			summary.LastHour.add(record.Outcome)
+		}
+		if message := upstreamError(record.Result); message != "" {
+			if !record.StartedAt.Before(hourAgo) {
+				summary.Upstream.Errors++
+			}
+			if summary.Upstream.LastError == "" {
+				summary.Upstream.LastError, summary.Upstream.LastErrorAt = message, record.StartedAt
+			}
+		}
+	}
+	summary.LastHour.rate()
+	summary.LastDay.rate()
+	for i := range summary.Hourly {
+		summary.Hourly[i].rate()
+	}
+	return summary, nil
+}
+
+// add counts a verification of an outcome
+func (c *Counts) add(outcome string) {
+	c.Total++
+	switch outcome {
+	case cbeverifier.OutcomeVerified:
+		c.Verified++
+	case cbeverifier.OutcomeMismatch:
+		c.Mismatch++
+	case cbeverifier.OutcomeReview:
+		c.Review++
+	default:
+		c.Error++
+	}
+}
+
+// rate sets the failure rate of the counts
+func (c *Counts) rate() {
+	if c.Total > 0 {
+		c.FailureRate = float64(c.Mismatch+c.Error) / float64(c.Total)
+	}
+}
+
+// verification returns the row of a record
+func verification(record store.Record) Verification {
+	v := Verification{
+		ID:         record.ID,
+		Reference:  record.Reference,
+		MerchantID: record.MerchantID,
+		Amount:     record.Transaction.Amount,
+		Currency:   record.Transaction.Currency,
+		Provider:   record.Transaction.Provider,
+		Outcome:    record.Outcome,
+		StartedAt:  record.StartedAt,
+		DurationMS: record.FinishedAt.Sub(record.StartedAt).Milliseconds(),
+	}
+	if result := record.Result; result != nil {
+		v.Error = result.Error
+		for field := range result.Mismatches {
+			v.Mismatches = append(v.Mismatches, field)
+		}
+		sort.Strings(v.Mismatches)
+	}
+	return v
+}
+
+// upstreamError returns the error of a verification that failed because CBE
+// was unreachable, or empty
+func upstreamError(result *cbeverifier.VerificationResult) string {
+	if result == nil || result.IsValid {
+		return ""
+	}
+	for _, err := range upstreamErrors {
+		if strings.HasPrefix(result.Error, err.Error()) {
+			return result.Error
+		}
+	}
+	return ""
+}
+
+// serveVerify verifies the transaction of the form
+func (h *Handler) serveVerify(w http.ResponseWriter, r *http.Request) {
+	if h.verifier == nil {
+		writeError(w, http.StatusNotFound, errors.New("verification is not enabled"))
+		return
+	}
+	var transaction cbeverifier.Transaction
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTransactionBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&transaction); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid transaction: %w", err))
+		return
+	}
+	result, err := h.verifier.Verify(r.Context(), transaction, h.options(r.Context()))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeJSON responds with v as JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with err as a JSON error
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,83 @@
+package dashen
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// amoleLabels are the labels of the confirmation page shown after an Amole
+// transfer, in priority order
+var amoleLabels = webreceipt.Labels{
+	{"payer_account", `sender\s*(?:phone|account)|from\s*(?:phone|account)`},
+	{"payer", `sender(?:\s*name)?|from`},
+	{"receiver_account", `receiver\s*(?:phone|account)|beneficiary\s*(?:phone|account)|to\s*(?:phone|account)`},
+	{"receiver", `receiver(?:\s*name)?|beneficiary(?:\s*name)?|to`},
+	{"service_charge", `(?:service\s*)?(?:charge|fee)`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?`},
+	{"amount", `(?:transaction\s*|transferred\s*)?amount`},
+	{"date", `(?:transaction\s*)?(?:date(?:\s*(?:&|and)\s*time)?|time)`},
+	{"transaction_id", `transaction\s*(?:id|no\.?|number)|txn\s*id`},
+	{"reason", `reason|remarks?|note|purpose`},
+}
+
+// amoleFields match the label cells of Amole confirmation pages
+var amoleFields = amoleLabels.Fields()
+
+// Patterns for the Amole confirmation SMS, e.g. "Dear Abebe, you have sent
+// ETB 500.00 to ACME TRADING (0911****78) on 12/05/2025 10:22:11. Transaction
+// ID: 123456789012. Reason: INV-77."
+var (
+	reSMSPayer    = regexp.MustCompile(`(?i)\bdear\s+([^,]+),`)
+	reSMSAmount   = regexp.MustCompile(`(?i)(?:ETB|birr)\s*([\d,]+(?:\.\d{1,2})?)|([\d,]+(?:\.\d{1,2})?)\s*(?:ETB|birr)`)
+	reSMSReceiver = regexp.MustCompile(`(?i)\bto\s+([^(.,]+?)\s*(?:\(([+\d*]{6,})\))?\s+(?:on|at)\b`)
+	reSMSID       = regexp.MustCompile(`(?i)(?:transaction|txn|trx)\s*(?:id|no\.?|number|ref(?:erence)?)\s*(?:is|:|#)?\s*([A-Z0-9]{6,})`)
+	reSMSDate     = regexp.MustCompile(`(\d{1,2}/\d{1,2}/\d{4}\s+\d{1,2}:\d{2}(?::\d{2})?|\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(?::\d{2})?)`)
+	reSMSReason   = regexp.MustCompile(`(?i)\b(?:reason|remark|note)\s*[:\-]\s*([^.;]+)`)
+)
+
+// ParseAmole extracts transaction information from an Amole wallet transfer
+// confirmation: the confirmation page (HTML) or the confirmation SMS (plain
+// text). Amole phone numbers are reported as the payer and receiver accounts.
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func ParseAmole(confirmation []byte) (*cbeverifier.TransactionDetails, error) {
+	values, err := webreceipt.Values(confirmation, amoleFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fill what the page does not label from the confirmation text
+	text, err := webreceipt.Text(confirmation)
+	if err != nil {
+		return nil, err
+	}
+	fill := func(field string, re *regexp.Regexp, groups ...int) {
+		if values[field] != "" {
+			return
+		}
+		if m := re.FindStringSubmatch(text); m != nil {
+			for _, g := range groups {
+				if v := strings.TrimSpace(m[g]); v != "" {
+					values[field] = v
+					return
+				}
+			}
+		}
+	}
+	fill("payer", reSMSPayer, 1)
+	fill("amount", reSMSAmount, 1, 2)
+	fill("receiver", reSMSReceiver, 1)
+	fill("receiver_account", reSMSReceiver, 2)
+	fill("transaction_id", reSMSID, 1)
+	fill("date", reSMSDate, 1)
+	fill("reason", reSMSReason, 1)
+
+	details := webreceipt.Details(values, FormatAmole)
+	details.Channel = cbeverifier.ChannelMobile
+	return webreceipt.Finish(details, dateLayouts)
+}
@@ -0,0 +1,219 @@
+// Package dashen verifies Dashen Bank transfers, both internet and mobile
+// banking transfers, whose receipts Dashen publishes by transaction reference,
+// and Amole wallet transfers, whose confirmations are shared by the customer.
+//
+// Bank transfers are identified by their reference (e.g., "FT25123ABCDE") and
+// Amole transfers by their numeric transaction ID. Results have the same shape
+// as CBE verifications: receipts and confirmations are parsed into a
+// cbeverifier.TransactionDetails and compared with cbeverifier.CompareDetails.
+//
+// Example:
+//
+//	result, err := dashen.Verify(cbeverifier.Transaction{
+//		ID:     "FT25123ABCDE",
+//		Amount: 1500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Amole confirmations cannot be fetched; verify the confirmation page or SMS the
+// customer shares with cbeverifier.VerifyPDF and Provider set to dashen.Name.
+//
+// Importing the package also registers a provider named "dashen", so
+// cbeverifier.Verify handles transactions whose Provider is dashen.Name.
+package dashen
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "dashen"
+
+// DefaultBaseURL is the Dashen receipt endpoint; the reference is appended to it
+const DefaultBaseURL = "https://receipt.dashensuperapp.com/receipt/"
+
+// Errors returned by the dashen package
+var (
+	ErrInvalidReference  = errors.New("invalid Dashen reference")
+	ErrAmoleNotFetchable = errors.New("Amole confirmations cannot be fetched; verify the shared confirmation")
+	ErrNetworkError      = errors.New("network error while requesting Dashen receipt")
+	ErrInvalidResponse   = errors.New("invalid response from Dashen")
+	ErrReceiptTooLarge   = errors.New("Dashen receipt exceeds maximum size")
+	ErrReceiptNotFound   = errors.New("Dashen receipt not found")
+)
+
+var (
+	// reReference matches a Dashen bank transfer reference (e.g., "FT25123ABCDE")
+	reReference = regexp.MustCompile(`^FT[A-Z0-9]{10}$`)
+	// reAmoleID matches an Amole wallet transaction ID
+	reAmoleID = regexp.MustCompile(`^\d{12,16}$`)
+)
+
+// Client fetches and verifies Dashen receipts. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient makes the Client send requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBaseURL makes the Client fetch receipts from baseURL instead of
+// DefaultBaseURL, e.g. a caching proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// ValidateReference returns the normalized bank reference or Amole transaction
+// ID, accepting the receipt URL as well
+//
+// Example:
+//
+//	ref, err := dashen.ValidateReference("https://receipt.dashensuperapp.com/receipt/ft25123abcde")
+//	// ref = "FT25123ABCDE"
+func ValidateReference(reference string) (string, error) {
+	ref := strings.TrimSpace(reference)
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", ErrInvalidReference
+		}
+		ref = u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+
+	ref = strings.ToUpper(strings.Join(strings.Fields(ref), ""))
+	if !reReference.MatchString(ref) && !reAmoleID.MatchString(ref) {
+		return "", ErrInvalidReference
+	}
+	return ref, nil
+}
+
+// IsAmoleID reports whether a validated reference is an Amole transaction ID
+func IsAmoleID(reference string) bool {
+	return reAmoleID.MatchString(reference)
+}
+
+// Verify fetches the Dashen receipt for transaction.ID (or FullReference) and
+// verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the reference; Amole transaction IDs fail with ErrAmoleNotFetchable
+// 2. Fetches the receipt from Dashen
+// 3. Parses the receipt into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the Dashen receipt and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized reference from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	ref, err := ValidateReference(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = ref
+	return transaction, nil
+}
+
+// Fetch fetches and parses the Dashen receipt for a bank reference without
+// comparing it, returning the parsed details and the raw receipt
+func Fetch(ctx context.Context, reference string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, reference, opts)
+}
+
+// Fetch fetches and parses the Dashen receipt for a bank reference
+func (c *Client) Fetch(ctx context.Context, reference string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	ref, err := ValidateReference(reference)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	receipt, err := c.fetchReceipt(ctx, ref, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(receipt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, receipt, nil
+}
+
+// fetchReceipt downloads the receipt for a validated reference
+func (c *Client) fetchReceipt(ctx context.Context, ref string, opts cbeverifier.Options) ([]byte, error) {
+	if IsAmoleID(ref) {
+		return nil, ErrAmoleNotFetchable
+	}
+
+	receipt, _, err := webreceipt.Fetch(ctx, c.httpClient, c.baseURL+ref, "application/pdf, text/html", opts, webreceipt.Errors{
+		Network:         ErrNetworkError,
+		InvalidResponse: ErrInvalidResponse,
+		NotFound:        ErrReceiptNotFound,
+		TooLarge:        ErrReceiptTooLarge,
+	})
+	return receipt, err
+}
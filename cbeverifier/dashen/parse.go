@@ -0,0 +1,86 @@
+package dashen
+
+import (
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Receipt layouts reported in TransactionDetails.FormatVersion
+const (
+	// FormatPDF is the PDF receipt of Dashen internet and mobile banking
+	FormatPDF = "dashen-pdf"
+	// FormatWeb is the HTML receipt page served by the Dashen receipt service
+	FormatWeb = "dashen-web"
+	// FormatAmole is an Amole wallet confirmation page or SMS
+	FormatAmole = "amole"
+)
+
+// receiptLabels are the labels printed on bank receipts for each field, in
+// priority order
+var receiptLabels = webreceipt.Labels{
+	{"payer", `sender\s*name|payer\s*name|debited\s*(?:account\s*)?name`},
+	{"payer_account", `sender\s*account(?:\s*(?:number|no\.?))?|debited\s*account`},
+	{"receiver", `receiver\s*name|beneficiary\s*name|credited\s*(?:account\s*)?name`},
+	{"receiver_account", `receiver\s*account(?:\s*(?:number|no\.?))?|beneficiary\s*account|credited\s*account`},
+	{"service_charge", `service\s*charge|commission`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?(?:\s*debited)?`},
+	{"amount", `(?:transaction\s*|transferred\s*)?amount`},
+	{"date", `transaction\s*date(?:\s*(?:&|and)\s*time)?|date`},
+	{"transaction_id", `transaction\s*ref(?:erence|\.)?(?:\s*no\.?)?|reference(?:\s*no\.?)?`},
+	{"reason", `narrative|remarks?|reason|description`},
+	{"transaction_type", `transaction\s*type`},
+}
+
+var (
+	// receiptFields match the label cells of HTML receipts
+	receiptFields = receiptLabels.Fields()
+	// receiptTemplate matches the text rows of PDF receipts
+	receiptTemplate = receiptLabels.Template(FormatPDF)
+)
+
+// dateLayouts are the transaction date formats seen on receipts and confirmations
+var dateLayouts = []string{
+	"Jan 2, 2006, 3:04:05 PM",
+	"Jan 2, 2006 3:04:05 PM",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2/1/2006 15:04:05",
+	"2/1/2006 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"02/01/2006",
+}
+
+// Parse extracts transaction information from a Dashen bank receipt, either a
+// PDF receipt or the HTML receipt page, or from an Amole confirmation (see
+// ParseAmole), which is recognized by its mention of Amole or by the absence of
+// receipt labels
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(receipt []byte) (*cbeverifier.TransactionDetails, error) {
+	if webreceipt.IsPDF(receipt) {
+		return webreceipt.ParsePDF(receipt, receiptTemplate, dateLayouts, reReference.MatchString)
+	}
+
+	text, err := webreceipt.Text(receipt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(strings.ToLower(text), "amole") {
+		return ParseAmole(receipt)
+	}
+
+	values, err := webreceipt.Values(receipt, receiptFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return ParseAmole(receipt)
+	}
+	return webreceipt.Finish(webreceipt.Details(values, FormatWeb), dateLayouts)
+}
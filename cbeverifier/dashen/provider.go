@@ -0,0 +1,78 @@
+package dashen
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that fetches receipts with client,
+// for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch downloads the receipt for the bank reference; Amole transaction IDs fail
+// with ErrAmoleNotFetchable
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.fetchReceipt(ctx, transaction.ID, opts)
+}
+
+// Parse parses a bank receipt or an Amole confirmation
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is an Amole transaction ID or a
+// receipt URL of the client's endpoint. Bare FT references have the shape of
+// CBE references and are not detected.
+func (p provider) DetectReference(reference string) bool {
+	ref, err := ValidateReference(reference)
+	if err != nil {
+		return false
+	}
+	if !strings.Contains(reference, "://") {
+		return IsAmoleID(ref)
+	}
+	u, err := url.Parse(strings.TrimSpace(reference))
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(p.client.baseURL)
+	return err == nil && strings.EqualFold(u.Host, base.Host)
+}
+
+// Capabilities reports online lookup of bank receipts and uploads of receipts
+// and Amole confirmations, which cannot be looked up
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf", "text/html", "text/plain"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the receipt details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
@@ -0,0 +1,32 @@
+package dashen
+
+import (
+	"testing"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func TestDetectReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      bool
+	}{
+		{"123456789012", true},
+		{DefaultBaseURL + "ft25123abcde", true},
+		{DefaultBaseURL + "123456789012", true},
+		// Bare FT references are CBE's
+		{"FT25123ABCDE", false},
+		{"https://apps.cbe.com.et:100/?id=FT25123ABCDE", false},
+		{"12345", false},
+	}
+	p := provider{defaultClient}
+	for _, tt := range tests {
+		if got := p.DetectReference(tt.reference); got != tt.want {
+			t.Errorf("DetectReference(%q) = %v, want %v", tt.reference, got, tt.want)
+		}
+	}
+
+	if names := cbeverifier.DetectProviders("FT25123ABCDE"); len(names) != 1 || names[0] != cbeverifier.ProviderCBE {
+		t.Errorf("DetectProviders(FT25123ABCDE) = %v, want only %s", names, cbeverifier.ProviderCBE)
+	}
+}
@@ -0,0 +1,79 @@
+package cbeverifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// addisAbaba is the time zone used on CBE receipts. It falls back to a fixed
+// UTC+3 zone (Ethiopia has no DST) when the tz database is unavailable.
+var addisAbaba = loadAddisAbaba()
+
+// loadAddisAbaba loads Africa/Addis_Ababa from the tz database
+func loadAddisAbaba() *time.Location {
+	if loc, err := time.LoadLocation("Africa/Addis_Ababa"); err == nil {
+		return loc
+	}
+	return time.FixedZone("EAT", 3*60*60)
+}
+
+// receiptDateLayouts are the date formats seen on CBE receipts
+var receiptDateLayouts = []string{
+	"1/2/2006, 3:04:05 PM",
+	"1/2/2006, 3:04:05PM",
+	"1/2/2006, 3:04 PM",
+	"1/2/2006, 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"1/2/2006 3:04 PM",
+	"1/2/2006 15:04:05",
+	"1/2/2006 15:04",
+	"1/2/2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2-Jan-2006 15:04:05",
+	"2-Jan-2006 3:04:05 PM",
+	"2-Jan-2006 15:04",
+	"2-Jan-2006",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006, 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006 15:04:05",
+	"2 January 2006",
+}
+
+// parseReceiptDate parses a receipt payment date in Addis Ababa time
+func parseReceiptDate(value string) (time.Time, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range receiptDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, addisAbaba); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized receipt date %q", value)
+}
+
+// checkDateWindow returns a description of the violated constraint, or "" if the
+// receipt date satisfies MaxAge, MinDate and MaxDate
+func checkDateWindow(paid time.Time, opts Options, now time.Time) string {
+	if opts.MaxAge <= 0 && opts.MinDate.IsZero() && opts.MaxDate.IsZero() {
+		return ""
+	}
+
+	if paid.IsZero() {
+		return "unparseable receipt date"
+	}
+
+	if opts.MaxAge > 0 && now.Sub(paid) > opts.MaxAge {
+		return fmt.Sprintf("not older than %s", opts.MaxAge)
+	}
+	if !opts.MinDate.IsZero() && paid.Before(opts.MinDate) {
+		return fmt.Sprintf("on or after %s", opts.MinDate.Format(time.RFC3339))
+	}
+	if !opts.MaxDate.IsZero() && paid.After(opts.MaxDate) {
+		return fmt.Sprintf("on or before %s", opts.MaxDate.Format(time.RFC3339))
+	}
+	return ""
+}
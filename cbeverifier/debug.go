@@ -0,0 +1,157 @@
+package cbeverifier
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseDebug describes how a receipt was parsed, for diagnosing receipts that
+// fail with missing fields
+type ParseDebug struct {
+	// Receipt is the parsed receipt, which may be partial
+	Receipt *ParsedReceipt `json:"receipt,omitempty"`
+	// Pages contains the text extracted from each page
+	Pages []DebugPage `json:"pages"`
+	// Format is the detected receipt layout, or "" if it was not recognized
+	Format string `json:"format,omitempty"`
+	// Templates describes what each template tried extracted, in order
+	Templates []DebugTemplate `json:"templates"`
+}
+
+// DebugPage is the text extracted from one page of a receipt
+type DebugPage struct {
+	// Number is the 1-based page number
+	Number int `json:"number"`
+	// Lines are the rows of text on the page
+	Lines []DebugLine `json:"lines"`
+}
+
+// DebugLine is one row of extracted text
+type DebugLine struct {
+	// Text is the full text of the row
+	Text string `json:"text"`
+	// Label and Value are the table columns, when the layout was recognized
+	Label string `json:"label,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// DebugTemplate records the rule matches of one template
+type DebugTemplate struct {
+	// Name is the template name
+	Name string `json:"name"`
+	// Matches lists each row consumed by a rule
+	Matches []DebugMatch `json:"matches"`
+	// Missing lists the required fields the template did not extract
+	Missing []string `json:"missing,omitempty"`
+}
+
+// DebugMatch is a row matched by a template rule
+type DebugMatch struct {
+	// Page and Line locate the row in Pages (both 1-based)
+	Page int `json:"page"`
+	Line int `json:"line"`
+	// Field is the field the rule extracts
+	Field string `json:"field"`
+	// Rule is the rule's label or pattern
+	Rule string `json:"rule"`
+	// Value is the extracted value
+	Value string `json:"value"`
+}
+
+// ParseCBEReceiptDebug parses a receipt like ParseCBEReceipt, additionally returning
+// the text extracted from every page and which rule matched which line
+//
+// The debug information is returned even when parsing fails, so it can be used
+// to see what text was actually extracted when the parser reports missing fields.
+//
+// Example:
+//
+//	debug, err := cbeverifier.ParseCBEReceiptDebug(pdfBytes)
+//	if err != nil {
+//		debug.Dump(os.Stderr)
+//	}
+func ParseCBEReceiptDebug(pdfBytes []byte, templates ...*ReceiptTemplate) (*ParseDebug, error) {
+	debug := &ParseDebug{}
+
+	rows, err := readPDFRows(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), "")
+	if err != nil {
+		return debug, err
+	}
+
+	// Group rows by page, remembering where each row ended up
+	type position struct{ page, line int }
+	positions := make([]position, len(rows))
+	for i, row := range rows {
+		if n := len(debug.Pages); n == 0 || debug.Pages[n-1].Number != row.Page {
+			debug.Pages = append(debug.Pages, DebugPage{Number: row.Page})
+		}
+		page := &debug.Pages[len(debug.Pages)-1]
+		page.Lines = append(page.Lines, DebugLine{Text: row.text(), Label: row.labelColumn(), Value: row.Value})
+		positions[i] = position{row.Page, len(page.Lines)}
+	}
+
+	format := detectFormat(rows)
+	debug.Format = formatVersion(format)
+
+	for _, template := range append(templates, formatTemplates(format)...) {
+		if template == nil {
+			continue
+		}
+
+		entry := DebugTemplate{Name: template.Name}
+		receipt := template.extractWith(rows, func(row int, rule *FieldRule, value string) {
+			pattern := rule.Pattern
+			if pattern == "" {
+				pattern = rule.Label
+			}
+			entry.Matches = append(entry.Matches, DebugMatch{
+				Page:  positions[row].page,
+				Line:  positions[row].line,
+				Field: rule.Field,
+				Rule:  pattern,
+				Value: value,
+			})
+		})
+		entry.Missing = missingFields(receipt)
+		debug.Templates = append(debug.Templates, entry)
+	}
+
+	debug.Receipt, err = parseRows(rows, templates...)
+	return debug, err
+}
+
+// labelColumn returns the label column of a table row, or "" for untabulated rows
+func (r receiptRow) labelColumn() string {
+	if r.Value == "" {
+		return ""
+	}
+	return r.Label
+}
+
+// Dump writes a human-readable report of the extracted text and rule matches to w
+func (d *ParseDebug) Dump(w io.Writer) {
+	for _, page := range d.Pages {
+		fmt.Fprintf(w, "--- page %d ---\n", page.Number)
+		for i, line := range page.Lines {
+			if line.Label != "" {
+				fmt.Fprintf(w, "%3d  %s | %s\n", i+1, line.Label, line.Value)
+			} else {
+				fmt.Fprintf(w, "%3d  %s\n", i+1, line.Text)
+			}
+		}
+	}
+
+	if d.Format != "" {
+		fmt.Fprintf(w, "--- format %s ---\n", d.Format)
+	}
+	for _, template := range d.Templates {
+		fmt.Fprintf(w, "--- template %s ---\n", template.Name)
+		for _, m := range template.Matches {
+			fmt.Fprintf(w, "p%d:%-3d %-16s %q\n", m.Page, m.Line, m.Field, m.Value)
+		}
+		if len(template.Missing) > 0 {
+			fmt.Fprintf(w, "missing: %s\n", strings.Join(template.Missing, ", "))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package echoadapter serves the verification endpoints of the server package
+// from Echo routers, and binds transactions and receipts for Echo handlers of
+// their own.
+//
+// It is a module of its own, so that the verifier does not depend on Echo.
+//
+// Example:
+//
+//	e := echo.New()
+//	api := e.Group("/payments", authMiddleware)
+//	echoadapter.Register(api, server.New(cbeverifier.New()))
+//	e.Logger.Fatal(e.Start(":8080"))
+package echoadapter
+
+import (
+	"fmt"
+	"io"
+	"mime"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+)
+
+// Router is where Register adds the routes: an *echo.Echo or an *echo.Group
+type Router interface {
+	POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Register serves POST /v1/verify and POST /v1/parse of srv from router. The
+// router's own middleware authenticates requests; the API keys of srv are not
+// checked.
+func Register(router Router, srv *server.Server) {
+	router.POST("/v1/verify", Verify(srv))
+	router.POST("/v1/parse", Parse(srv))
+}
+
+// Verify returns a handler verifying the JSON transaction in the request body,
+// as POST /v1/verify of srv does
+func Verify(srv *server.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		srv.ServeVerify(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// Parse returns a handler parsing an uploaded receipt, as POST /v1/parse of
+// srv does
+func Parse(srv *server.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		srv.ServeParse(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// BindTransaction reads the transaction of a JSON or form request, whose
+// fields are named as in the JSON of cbeverifier.Transaction
+//
+// Example:
+//
+//	e.POST("/orders/:id/pay", func(c echo.Context) error {
+//		transaction, err := echoadapter.BindTransaction(c)
+//		if err != nil {
+//			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+//		}
+//		result, err := verifier.Verify(c.Request().Context(), transaction, opts)
+//		...
+//	})
+func BindTransaction(c echo.Context) (cbeverifier.Transaction, error) {
+	var transaction cbeverifier.Transaction
+	if err := c.Bind(&transaction); err != nil {
+		return cbeverifier.Transaction{}, fmt.Errorf("invalid transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// BindReceipt reads an uploaded receipt: the "receipt" file of a multipart
+// form, or else the request body. Receipts larger than limit bytes fail with
+// cbeverifier.ErrReceiptTooLarge.
+func BindReceipt(c echo.Context, limit int64) ([]byte, error) {
+	var receipt io.Reader = c.Request().Body
+	if mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType)); mediaType == echo.MIMEMultipartForm {
+		header, err := c.FormFile("receipt")
+		if err != nil {
+			return nil, fmt.Errorf("missing receipt file: %w", err)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		receipt = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(receipt, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, cbeverifier.ErrReceiptTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,127 @@
+// Package ethiocal converts dates between the Gregorian and Ethiopian calendars.
+//
+// The Ethiopian calendar has twelve months of 30 days followed by Pagume, a
+// thirteenth month of 5 days (6 in leap years). Years are counted from the
+// Amete Mihret epoch, roughly seven to eight years behind the Gregorian calendar.
+//
+// Example:
+//
+//	d := ethiocal.FromTime(time.Date(2025, time.September, 11, 0, 0, 0, 0, time.UTC))
+//	// d.String() = "01/01/2018", d.MonthName() = "Meskerem"
+package ethiocal
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// jdnEpoch is the Julian Day Number offset of the Amete Mihret era
+	jdnEpoch = 1723856
+	// jdnUnix is the Julian Day Number of 1 January 1970
+	jdnUnix = 2440588
+)
+
+// monthNames are the transliterated Ethiopian month names
+var monthNames = [13]string{
+	"Meskerem", "Tikimt", "Hidar", "Tahsas", "Tir", "Yekatit", "Megabit",
+	"Miyazya", "Ginbot", "Sene", "Hamle", "Nehase", "Pagume",
+}
+
+// amharicMonthNames are the Ethiopian month names in Ge'ez script
+var amharicMonthNames = [13]string{
+	"መስከረም", "ጥቅምት", "ኅዳር", "ታኅሣሥ", "ጥር", "የካቲት", "መጋቢት",
+	"ሚያዝያ", "ግንቦት", "ሰኔ", "ሐምሌ", "ነሐሴ", "ጳጉሜ",
+}
+
+// Date is a day in the Ethiopian calendar
+type Date struct {
+	// Year is the Amete Mihret year
+	Year int `json:"year"`
+	// Month is the month, from 1 (Meskerem) to 13 (Pagume)
+	Month int `json:"month"`
+	// Day is the day of the month, from 1 to 30
+	Day int `json:"day"`
+}
+
+// IsLeapYear reports whether year has a 6-day Pagume
+func IsLeapYear(year int) bool {
+	return year%4 == 3
+}
+
+// FromTime returns the Ethiopian date of t's calendar day in t's location
+func FromTime(t time.Time) Date {
+	y, m, d := t.Date()
+	days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+	return fromJDN(int(days) + jdnUnix)
+}
+
+// ToTime returns midnight of the Gregorian day d falls on, in loc
+func (d Date) ToTime(loc *time.Location) time.Time {
+	days := d.jdn() - jdnUnix
+	t := time.Unix(int64(days)*86400, 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Valid reports whether d is a day that exists in the Ethiopian calendar
+func (d Date) Valid() bool {
+	if d.Year < 1 || d.Month < 1 || d.Month > 13 || d.Day < 1 {
+		return false
+	}
+	if d.Month == 13 {
+		if IsLeapYear(d.Year) {
+			return d.Day <= 6
+		}
+		return d.Day <= 5
+	}
+	return d.Day <= 30
+}
+
+// MonthName returns the transliterated month name (e.g., "Meskerem")
+func (d Date) MonthName() string {
+	if d.Month < 1 || d.Month > 13 {
+		return ""
+	}
+	return monthNames[d.Month-1]
+}
+
+// AmharicMonthName returns the month name in Ge'ez script (e.g., "መስከረም")
+func (d Date) AmharicMonthName() string {
+	if d.Month < 1 || d.Month > 13 {
+		return ""
+	}
+	return amharicMonthNames[d.Month-1]
+}
+
+// String formats d as DD/MM/YYYY, the usual way EC dates are written
+func (d Date) String() string {
+	return fmt.Sprintf("%02d/%02d/%04d", d.Day, d.Month, d.Year)
+}
+
+// Parse parses an Ethiopian date written as DD/MM/YYYY
+func Parse(value string) (Date, error) {
+	var d Date
+	if _, err := fmt.Sscanf(value, "%d/%d/%d", &d.Day, &d.Month, &d.Year); err != nil {
+		return Date{}, fmt.Errorf("invalid Ethiopian date %q: %w", value, err)
+	}
+	if !d.Valid() {
+		return Date{}, fmt.Errorf("invalid Ethiopian date %q", value)
+	}
+	return d, nil
+}
+
+// jdn returns the Julian Day Number of d
+func (d Date) jdn() int {
+	return jdnEpoch + 365*d.Year + d.Year/4 + 30*d.Month + d.Day - 31
+}
+
+// fromJDN converts a Julian Day Number to an Ethiopian date
+func fromJDN(jdn int) Date {
+	r := (jdn - jdnEpoch) % 1461
+	n := r%365 + 365*(r/1460)
+	return Date{
+		Year:  4*((jdn-jdnEpoch)/1461) + r/365 - r/1460,
+		Month: n/30 + 1,
+		Day:   n%30 + 1,
+	}
+}
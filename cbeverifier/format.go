@@ -0,0 +1,151 @@
+package cbeverifier
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Receipt layouts reported in TransactionDetails.FormatVersion
+const (
+	// FormatPortalV1 is the original online receipt layout, which labels the
+	// parties "Customer Name" / "Beneficiary Name" and their accounts
+	// "Debited Account" / "Credited Account"
+	FormatPortalV1 = "portal-v1"
+	// FormatPortalV2 is the current layout, with payer and receiver sections and
+	// the reference printed as the VAT invoice number
+	FormatPortalV2 = "portal-v2"
+	// FormatCBEBirr is the CBE Birr mobile money receipt, which identifies the
+	// parties by phone number
+	FormatCBEBirr = "cbe-birr"
+)
+
+// receiptFormat is a receipt layout, recognized by its markers and parsed with its template
+type receiptFormat struct {
+	version string
+	// markers are patterns matching rows found only on receipts of this layout
+	markers  []*regexp.Regexp
+	template *ReceiptTemplate
+}
+
+// receiptFormats are the known layouts. When no markers match, the templates are
+// tried in this order, so the current layout comes first.
+var receiptFormats = []*receiptFormat{
+	{
+		version: FormatPortalV2,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^(?:payer|ከፋይ)\b`),
+			regexp.MustCompile(`(?i)vat\s*invoice\s*no`),
+			regexp.MustCompile(`(?i)transferred amount|የተላለፈው`),
+			regexp.MustCompile(`(?i)payment date|የክፍያ\s*ቀን`),
+			regexp.MustCompile(`(?i)type of service`),
+		},
+		template: defaultTemplate,
+	},
+	{
+		version: FormatPortalV1,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)^debit(?:ed)?\s*account`),
+			regexp.MustCompile(`(?i)^credit(?:ed)?\s*account`),
+			regexp.MustCompile(`(?i)^(?:customer|beneficiary)\s*name`),
+			regexp.MustCompile(`(?i)^narrative`),
+			regexp.MustCompile(`(?i)^(?:transaction|value)\s*date`),
+		},
+		template: portalV1Template,
+	},
+	{
+		version: FormatCBEBirr,
+		markers: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)cbe\s*birr`),
+			regexp.MustCompile(`(?i)^(?:sender|receiver)\s*(?:phone|mobile|wallet)`),
+			regexp.MustCompile(`(?i)^(?:sender|receiver)(?:\s*name)?\b`),
+			regexp.MustCompile(`(?i)^transaction\s*(?:id|number)`),
+		},
+		template: cbeBirrTemplate,
+	},
+}
+
+// portalV1Template extracts fields from receipts in the FormatPortalV1 layout
+var portalV1Template = mustCompileTemplate(&ReceiptTemplate{
+	Name: FormatPortalV1,
+	Rules: []FieldRule{
+		{Field: "total_debited", Pattern: reTotalDebited.String()},
+		{Field: "payer_account", Pattern: `(?i)^debit(?:ed)?\s*account(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*(\S+)`},
+		{Field: "receiver_account", Pattern: `(?i)^(?:credit(?:ed)?|beneficiary)\s*account(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*(\S+)`},
+		{Field: "payer", Pattern: `(?i)^(?:customer|debited\s*party)\s*name\s*[:፥፦]?\s*([\p{L}\p{M}\p{N}_\s&\.-]+)$`},
+		{Field: "receiver_bank", Pattern: reReceiverBank.String()},
+		{Field: "receiver", Pattern: `(?i)^(?:beneficiary|credited\s*party)\s*name\s*[:፥፦]?\s*([\p{L}\p{M}\p{N}_\s&\.-]+)$`},
+		{Field: "service_charge", Pattern: `(?i)^(?:service charge|commission)\s*[:፥፦]?\s*(?:[A-Z]{3}\s*)?([\d,]+\.\d{2})`},
+		{Field: "vat", Pattern: reVAT.String()},
+		{Field: "amount", Pattern: `(?i)^(?:transaction\s*)?amount\s*[:፥፦]?\s*(?:([A-Z]{3})\s*)?([\d,]+\.\d{2})\s*([A-Z]{3}|ብር)?`},
+		{Field: "branch", Pattern: reBranch.String()},
+		{Field: "reason", Pattern: `(?i)^(?:narrative|remarks?|description)\s*[:፥፦]?\s*(.+)`},
+		{Field: "transaction_id", Pattern: `(?i)^(?:transaction\s*)?reference(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*([A-Z0-9]+)`},
+		{Field: "date", Pattern: `(?i)^(?:transaction|value|posting)\s*date.*?(` + datePattern + `)`},
+	},
+})
+
+// cbeBirrTemplate extracts fields from receipts in the FormatCBEBirr layout. The
+// parties' phone numbers are their wallet accounts.
+var cbeBirrTemplate = mustCompileTemplate(&ReceiptTemplate{
+	Name: FormatCBEBirr,
+	Rules: []FieldRule{
+		{Field: "payer_account", Pattern: `(?i)^(?:sender|from)\s*(?:phone|mobile|wallet|account)(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*(\+?[\d*X]+)`},
+		{Field: "receiver_account", Pattern: `(?i)^(?:receiver|beneficiary|merchant|to)\s*(?:phone|mobile|wallet|account|id)(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*(\+?[\d*X]+)`},
+		{Field: "payer", Pattern: `(?i)^(?:sender|from)(?:\s*name)?\s*[:፥፦]?\s*([\p{L}\p{M}][\p{L}\p{M}&\.\s-]*)$`},
+		{Field: "receiver", Pattern: `(?i)^(?:receiver|beneficiary|merchant|to)(?:\s*name)?\s*[:፥፦]?\s*([\p{L}\p{M}][\p{L}\p{M}&\.\s-]*)$`},
+		{Field: "service_charge", Pattern: `(?i)^(?:service\s*(?:charge|fee)|fee)\s*[:፥፦]?\s*(?:[A-Z]{3,4}\s*)?([\d,]+\.\d{2})`},
+		{Field: "vat", Pattern: reVAT.String()},
+		{Field: "amount", Pattern: `(?i)^(?:transaction\s*|transferred\s*)?amount\s*[:፥፦]?\s*(?:(Birr|[A-Z]{3})\s*)?([\d,]+\.\d{2})\s*(Birr|[A-Z]{3}|ብር)?`},
+		{Field: "transaction_id", Pattern: `(?i)^(?:transaction\s*(?:id|number|no\.?)|txn\s*id|receipt\s*(?:no\.?|number))\s*[:፥፦]?\s*([A-Z0-9]+)`},
+		{Field: "transaction_type", Pattern: `(?i)^(?:transaction\s*type|service)\s*[:፥፦]?\s*(.+)`},
+		{Field: "reason", Pattern: `(?i)^(?:reason|remarks?|note|purpose)\s*[:፥፦]?\s*(.+)`},
+		{Field: "date", Pattern: `(?i)^(?:transaction\s*)?date(?:\s*(?:&|and)\s*time)?.*?(` + datePattern + `)`},
+	},
+})
+
+// detectFormat returns the layout whose markers match the most distinct rows,
+// or nil if none match
+func detectFormat(rows []receiptRow) *receiptFormat {
+	var (
+		best      *receiptFormat
+		bestScore int
+	)
+	for _, format := range receiptFormats {
+		score := 0
+		for _, marker := range format.markers {
+			for _, row := range rows {
+				if marker.MatchString(strings.TrimSpace(normalizeEthiopic(row.text()))) {
+					score++
+					break
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = format, score
+		}
+	}
+	return best
+}
+
+// formatTemplates returns the built-in templates to try for a receipt detected as
+// format: its own template first, then the others in case detection was wrong
+func formatTemplates(format *receiptFormat) []*ReceiptTemplate {
+	templates := make([]*ReceiptTemplate, 0, len(receiptFormats))
+	if format != nil {
+		templates = append(templates, format.template)
+	}
+	for _, f := range receiptFormats {
+		if f != format {
+			templates = append(templates, f.template)
+		}
+	}
+	return templates
+}
+
+// formatVersion returns the version of format, or "" if it is nil
+func formatVersion(format *receiptFormat) string {
+	if format == nil {
+		return ""
+	}
+	return format.version
+}
@@ -0,0 +1,97 @@
+// Package ginadapter serves the verification endpoints of the server package
+// from Gin routers, and binds transactions and receipts for Gin handlers of
+// their own.
+//
+// It is a module of its own, so that the verifier does not depend on Gin.
+//
+// Example:
+//
+//	router := gin.Default()
+//	api := router.Group("/payments", authMiddleware)
+//	ginadapter.Register(api, server.New(cbeverifier.New()))
+//	router.Run(":8080")
+package ginadapter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+)
+
+// Register serves POST /v1/verify and POST /v1/parse of srv from routes. The
+// routes' own middleware authenticates requests; the API keys of srv are not
+// checked.
+func Register(routes gin.IRoutes, srv *server.Server) {
+	routes.POST("/v1/verify", Verify(srv))
+	routes.POST("/v1/parse", Parse(srv))
+}
+
+// Verify returns a handler verifying the JSON transaction in the request body,
+// as POST /v1/verify of srv does
+func Verify(srv *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		srv.ServeVerify(c.Writer, c.Request)
+	}
+}
+
+// Parse returns a handler parsing an uploaded receipt, as POST /v1/parse of
+// srv does
+func Parse(srv *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		srv.ServeParse(c.Writer, c.Request)
+	}
+}
+
+// BindTransaction reads the transaction of a JSON or form request, whose
+// fields are named as in the JSON of cbeverifier.Transaction
+//
+// Example:
+//
+//	router.POST("/orders/:id/pay", func(c *gin.Context) {
+//		transaction, err := ginadapter.BindTransaction(c)
+//		if err != nil {
+//			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+//			return
+//		}
+//		result, err := verifier.Verify(c, transaction, opts)
+//		...
+//	})
+func BindTransaction(c *gin.Context) (cbeverifier.Transaction, error) {
+	var transaction cbeverifier.Transaction
+	if err := c.ShouldBind(&transaction); err != nil {
+		return cbeverifier.Transaction{}, fmt.Errorf("invalid transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// BindReceipt reads an uploaded receipt: the "receipt" file of a multipart
+// form, or else the request body. Receipts larger than limit bytes fail with
+// cbeverifier.ErrReceiptTooLarge.
+func BindReceipt(c *gin.Context, limit int64) ([]byte, error) {
+	var receipt io.Reader = c.Request.Body
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		header, err := c.FormFile("receipt")
+		if err != nil {
+			return nil, fmt.Errorf("missing receipt file: %w", err)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		receipt = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(receipt, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, cbeverifier.ErrReceiptTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,306 @@
+// Package graphql serves the verification history of a store over GraphQL,
+// for admin tools that query their backends with it rather than REST.
+//
+// The schema (see Schema) has one query and one mutation:
+//
+//	verifications(reference, merchantId, outcome, from, to, limit, offset): [Verification!]!
+//	verify(transaction: TransactionInput!): VerificationResult
+//
+// Its types are those of store.Record (as Verification) and of the verifier,
+// with their JSON keys in camelCase: receipt_sha256 is receiptSha256, and
+// is_valid isValid. Pointers, slices, maps and zero times are null when
+// empty. Mismatches and warnings are JSON values.
+//
+// The executor is generated by gqlgen from schema.graphqls into generated.go
+// (go generate ./...). The package is a module of its own, so that programs
+// using the verifier without GraphQL do not depend on gqlgen. Requests are
+// served with introspection, and operations nested too deeply or resolving
+// too many fields are rejected before they run (see WithMaxDepth and
+// WithMaxComplexity).
+//
+// A Handler does not authenticate requests. Mount it behind the
+// authentication of the admin tools, or serve it from a server.Server with
+// server.WithGraphQL, which checks API keys and scopes each request to its
+// merchant.
+//
+// Example:
+//
+//	history, err := store.NewPostgres(db, "cbe_verifications")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	verifier := cbeverifier.New(cbeverifier.WithRecorder(history))
+//	http.Handle("/graphql", requireAdmin(graphql.New(history, verifier)))
+//
+// A query for the receipts to check manually:
+//
+//	query Review($from: Time!) {
+//	  verifications(outcome: "review", from: $from, limit: 20) {
+//	    id
+//	    reference
+//	    startedAt
+//	    transaction { amount payerName }
+//	    result { lowConfidence }
+//	  }
+//	}
+package graphql
+
+//go:generate go tool gqlgen generate
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// maxRequestBytes limits the size of request bodies
+const maxRequestBytes = 1 << 20
+
+// defaultMaxLimit is the number of records a query returns at most by default
+const defaultMaxLimit = 100
+
+// Default limits of the operations a Handler executes
+const (
+	defaultMaxDepth      = 10
+	defaultMaxComplexity = 10000
+)
+
+// Error codes of the operations over the limits of a Handler, the second that
+// of gqlgen's own complexity limit
+const (
+	errDepthLimit      = "DEPTH_LIMIT_EXCEEDED"
+	errComplexityLimit = "COMPLEXITY_LIMIT_EXCEEDED"
+)
+
+//go:embed schema.graphqls
+var schema string
+
+// Handler is an http.Handler serving GraphQL requests at any path, POSTed as
+// JSON or as application/graphql, or as the query parameters of GET requests,
+// which cannot run mutations
+type Handler struct {
+	history       store.Store
+	verifier      *cbeverifier.Verifier
+	opts          cbeverifier.Options
+	maxLimit      int
+	maxDepth      int
+	maxComplexity int
+	server        *handler.Server
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithOptions sets the options of the verify mutation (default:
+// cbeverifier.DefaultOptions()). An Options.MerchantID also restricts queries
+// to the records of the merchant.
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(h *Handler) {
+		h.opts = opts
+	}
+}
+
+// WithMaxLimit sets the number of records a query returns at most, whatever
+// its limit argument (default: 100)
+func WithMaxLimit(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxLimit = n
+		}
+	}
+}
+
+// WithMaxDepth sets how deeply the fields of an operation may be nested
+// (default: 10); verifications { result { details { payer } } } is 4 deep.
+// Deeper operations are rejected before they are executed.
+func WithMaxDepth(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxDepth = n
+		}
+	}
+}
+
+// WithMaxComplexity sets the complexity an operation may have at most
+// (default: 10000). The complexity is the number of fields it resolves, with
+// the subfields of verifications counted once per record its limit (or
+// WithMaxLimit) allows: verifications(limit: 20) { id reference } is 41. More
+// complex operations are rejected before they are executed.
+func WithMaxComplexity(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxComplexity = n
+		}
+	}
+}
+
+// New creates a Handler querying history. The verify mutation verifies
+// transactions with verifier, which should record them to history (see
+// cbeverifier.WithRecorder); with a nil verifier, the handler only serves
+// queries.
+func New(history store.Store, verifier *cbeverifier.Verifier, options ...Option) *Handler {
+	h := &Handler{
+		history:       history,
+		verifier:      verifier,
+		opts:          cbeverifier.DefaultOptions(),
+		maxLimit:      defaultMaxLimit,
+		maxDepth:      defaultMaxDepth,
+		maxComplexity: defaultMaxComplexity,
+	}
+	for _, option := range options {
+		option(h)
+	}
+
+	h.server = handler.New(NewExecutableSchema(Config{Resolvers: &resolver{h}}))
+	h.server.AddTransport(transport.GET{})
+	h.server.AddTransport(transport.POST{})
+	h.server.AddTransport(transport.GRAPHQL{})
+	h.server.Use(extension.Introspection{})
+	h.server.Use(limits{h})
+	return h
+}
+
+// Schema returns the schema of the API in the GraphQL schema definition
+// language
+func Schema() string {
+	return schema
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method != http.MethodGet && r.Method != http.MethodPost:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, gqlerror.Errorf("method %s not allowed", r.Method))
+		return
+	case r.ContentLength > maxRequestBytes:
+		writeError(w, http.StatusRequestEntityTooLarge, gqlerror.Errorf("the request is larger than %d bytes", maxRequestBytes))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBytes)
+	h.server.ServeHTTP(w, r)
+}
+
+// writeError responds with a GraphQL response of err alone
+func writeError(w http.ResponseWriter, status int, err *gqlerror.Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&graphql.Response{Errors: gqlerror.List{err}})
+}
+
+// limits rejects the operations nested more deeply, or more complex, than
+// the limits of a Handler. It measures the complexity itself rather than
+// with gqlgen's extension.ComplexityLimit, which expands every fragment spread
+// and so takes exponential time over fragments spreading each other many
+// times.
+type limits struct {
+	*Handler
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = limits{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (limits) ExtensionName() string {
+	return "Limits"
+}
+
+// Validate implements graphql.HandlerExtension
+func (limits) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext implements graphql.OperationContextMutator. It runs
+// once the operation is validated, so its fragments do not spread each other
+// in cycles.
+func (l limits) MutateOperationContext(_ context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	m := &meter{limits: l, variables: opCtx.Variables, fragments: make(map[string]measure)}
+	size := m.measure(op.SelectionSet)
+	switch {
+	case size.depth > l.maxDepth:
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", size.depth, l.maxDepth)
+		errcode.Set(err, errDepthLimit)
+		return err
+	case size.complexity > l.maxComplexity:
+		err := gqlerror.Errorf("operation has a complexity of more than %d", l.maxComplexity)
+		errcode.Set(err, errComplexityLimit)
+		return err
+	}
+	return nil
+}
+
+// measure is the depth and complexity of a selection set
+type measure struct {
+	depth      int
+	complexity int
+}
+
+// meter measures selection sets, each fragment once
+type meter struct {
+	limits
+	variables map[string]any
+	fragments map[string]measure
+}
+
+// measure returns the size of selections. The complexity is capped at one
+// more than the limit, so that it cannot overflow.
+func (m *meter) measure(selections ast.SelectionSet) measure {
+	var total measure
+	add := func(size measure) {
+		total.depth = max(total.depth, size.depth)
+		total.complexity = min(total.complexity+size.complexity, m.maxComplexity+1)
+	}
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			sub := m.measure(sel.SelectionSet)
+			add(measure{depth: sub.depth + 1, complexity: 1 + min(m.records(sel)*sub.complexity, m.maxComplexity+1)})
+		case *ast.InlineFragment:
+			add(m.measure(sel.SelectionSet))
+		case *ast.FragmentSpread:
+			size, ok := m.fragments[sel.Name]
+			if !ok {
+				size = m.measure(sel.Definition.SelectionSet)
+				m.fragments[sel.Name] = size
+			}
+			add(size)
+		}
+	}
+	return total
+}
+
+// records returns the number of records a field returns at most: the limit of
+// fields with a limit argument, or else 1
+func (m *meter) records(field *ast.Field) int {
+	if field.Definition == nil || field.Definition.Arguments.ForName("limit") == nil {
+		return 1
+	}
+	limit := m.maxLimit
+	var n int64
+	switch v := field.ArgumentMap(m.variables)["limit"].(type) {
+	case int64:
+		n = v
+	case int:
+		n = int64(v)
+	case json.Number:
+		n, _ = v.Int64()
+	default:
+		return limit
+	}
+	return int(min(max(n, 1), int64(limit)))
+}
@@ -0,0 +1,324 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// memoryStore keeps records in memory, most recent first
+type memoryStore struct {
+	records []store.Record
+}
+
+func (m *memoryStore) Record(ctx context.Context, attempt *cbeverifier.Attempt) error {
+	return m.Save(ctx, store.NewRecord(attempt))
+}
+
+func (m *memoryStore) Save(_ context.Context, record *store.Record) error {
+	record.ID = int64(len(m.records) + 1)
+	m.records = append([]store.Record{*record}, m.records...)
+	return nil
+}
+
+func (m *memoryStore) Query(_ context.Context, filter store.Filter) ([]store.Record, error) {
+	var records []store.Record
+	for _, r := range m.records {
+		switch {
+		case filter.Reference != "" && r.Reference != store.NormalizeReference(filter.Reference),
+			filter.MerchantID != "" && r.MerchantID != filter.MerchantID,
+			filter.Outcome != "" && r.Outcome != filter.Outcome,
+			!filter.From.IsZero() && r.StartedAt.Before(filter.From),
+			!filter.To.IsZero() && !r.StartedAt.Before(filter.To):
+			continue
+		}
+		records = append(records, r)
+	}
+	records = records[min(filter.Offset, len(records)):]
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// testHistory returns a store of two verifications of two merchants
+func testHistory(t *testing.T) *memoryStore {
+	t.Helper()
+	history := &memoryStore{}
+	records := []store.Record{
+		{
+			Reference:     "FT24123ABCDE",
+			MerchantID:    "merkato-books",
+			Transaction:   cbeverifier.Transaction{ID: "FT24123ABCDE", Suffix: "12345678", Amount: 1500, PayerName: "ABEBE KEBEDE"},
+			Outcome:       cbeverifier.OutcomeVerified,
+			Result:        &cbeverifier.VerificationResult{IsValid: true},
+			ReceiptSHA256: "ab12",
+			StartedAt:     time.Date(2025, 9, 4, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			Reference:   "FT24123FGHIJ",
+			MerchantID:  "habesha-crafts",
+			Transaction: cbeverifier.Transaction{ID: "FT24123FGHIJ", Suffix: "12345678", Amount: 2750},
+			Outcome:     cbeverifier.OutcomeMismatch,
+			Result:      &cbeverifier.VerificationResult{Mismatches: map[string]any{"amount": "2500"}},
+			StartedAt:   time.Date(2025, 9, 5, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	for i := range records {
+		if err := history.Save(context.Background(), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return history
+}
+
+// fragmentBomb returns a query whose fragments each spread the next one
+// twice, selecting 2^n fields once expanded
+func fragmentBomb(n int) string {
+	var b strings.Builder
+	b.WriteString("{ verifications { ...f0 } }")
+	for i := range n {
+		fmt.Fprintf(&b, " fragment f%d on Verification { ...f%d a%d: id ...f%d }", i, i+1, i, i+1)
+	}
+	fmt.Fprintf(&b, " fragment f%d on Verification { id }", n)
+	return b.String()
+}
+
+// post POSTs a query to h, returning the status and body of the response
+func post(t *testing.T, h http.Handler, ctx context.Context, query string, variables map[string]any) (int, string) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestExecute(t *testing.T) {
+	merchant := cbeverifier.DefaultOptions()
+	merchant.MerchantID = "merkato-books"
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		options   []Option
+		// opts are the options of the request's context, if any
+		opts   *cbeverifier.Options
+		status int
+		want   string
+	}{
+		{
+			name:   "records",
+			query:  `{ verifications { id reference outcome } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"verifications":[{"id":"2","reference":"FT24123FGHIJ","outcome":"mismatch"},{"id":"1","reference":"FT24123ABCDE","outcome":"verified"}]}}`,
+		},
+		{
+			name:   "aliases, nested objects and empty fields",
+			query:  `{ latest: verifications(limit: 1) { __typename transaction { amount payerName } result { isValid mismatches lowConfidence details { payer } } startedAt } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"latest":[{"__typename":"Verification","transaction":{"amount":2750,"payerName":""},"result":{"isValid":false,"mismatches":{"amount":"2500"},"lowConfidence":null,"details":null},"startedAt":"2025-09-05T10:00:00Z"}]}}`,
+		},
+		{
+			name:      "variables",
+			query:     `query ($outcome: String, $from: Time = "2025-09-01") { verifications(outcome: $outcome, from: $from) { id } }`,
+			variables: map[string]any{"outcome": "verified"},
+			status:    http.StatusOK,
+			want:      `{"data":{"verifications":[{"id":"1"}]}}`,
+		},
+		{
+			name:      "fragments and directives",
+			query:     `query ($brief: Boolean!) { verifications(offset: 1) { ...ids reference @skip(if: $brief) ... on Verification @include(if: $brief) { outcome } } } fragment ids on Verification { id }`,
+			variables: map[string]any{"brief": true},
+			status:    http.StatusOK,
+			want:      `{"data":{"verifications":[{"id":"1","outcome":"verified"}]}}`,
+		},
+		{
+			name:   "merchant of the request",
+			query:  `{ verifications { id merchantId } }`,
+			opts:   &merchant,
+			status: http.StatusOK,
+			want:   `{"data":{"verifications":[{"id":"1","merchantId":"merkato-books"}]}}`,
+		},
+		{
+			name:   "another merchant",
+			query:  `{ verifications(merchantId: "habesha-crafts") { id } }`,
+			opts:   &merchant,
+			status: http.StatusOK,
+			want:   `{"errors":[{"message":"verifications of another merchant","path":["verifications"]}],"data":null}`,
+		},
+		{
+			name:   "unknown outcome",
+			query:  `{ verifications(outcome: "lost") { id } }`,
+			status: http.StatusOK,
+			want:   `{"errors":[{"message":"unknown outcome \"lost\", expected one of [\"verified\" \"mismatch\" \"review\" \"error\"]","path":["verifications"]}],"data":null}`,
+		},
+		{
+			name:   "mutation without a verifier",
+			query:  `mutation { verify(transaction: {id: "FT24123ABCDE", suffix: "12345678", amount: 1500}) { isValid } }`,
+			status: http.StatusOK,
+			want:   `{"errors":[{"message":"mutations are disabled: the handler has no verifier","path":["verify"]}],"data":{"verify":null}}`,
+		},
+		{
+			name:   "introspection",
+			query:  `{ __type(name: "Verification") { fields(includeDeprecated: false) { name } } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"__type":{"fields":[{"name":"id"},{"name":"reference"},{"name":"merchantId"},{"name":"transaction"},{"name":"outcome"},{"name":"result"},{"name":"receiptSha256"},{"name":"receiptKey"},{"name":"startedAt"},{"name":"finishedAt"}]}}}`,
+		},
+		{
+			name:   "unknown field",
+			query:  `{ verifications { id nope } }`,
+			status: http.StatusUnprocessableEntity,
+			want:   `{"errors":[{"message":"Cannot query field \"nope\" on type \"Verification\".","locations":[{"line":1,"column":22}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`,
+		},
+		{
+			name:   "invalid argument",
+			query:  `{ verifications(limit: "ten") { id } }`,
+			status: http.StatusUnprocessableEntity,
+			want:   `{"errors":[{"message":"Int cannot represent non-integer value: \"ten\"","locations":[{"line":1,"column":25}],"extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}],"data":null}`,
+		},
+		{
+			name:   "invalid time",
+			query:  `{ verifications(from: "yesterday") { id } }`,
+			status: http.StatusOK,
+			want:   `{"errors":[{"message":"expected Time, found \"yesterday\"","path":["verifications","from"]}],"data":null}`,
+		},
+		{
+			name:    "depth within the limit",
+			query:   `{ verifications(limit: 1) { result { isValid } } }`,
+			options: []Option{WithMaxDepth(3)},
+			status:  http.StatusOK,
+			want:    `{"data":{"verifications":[{"result":{"isValid":false}}]}}`,
+		},
+		{
+			name:    "depth over the limit",
+			query:   `{ verifications { result { details { payer } } } }`,
+			options: []Option{WithMaxDepth(3)},
+			status:  http.StatusOK,
+			want:    `{"errors":[{"message":"operation has depth 4, which exceeds the limit of 3","extensions":{"code":"DEPTH_LIMIT_EXCEEDED"}}],"data":null}`,
+		},
+		{
+			name:    "depth over the limit through fragments",
+			query:   `{ verifications { ...r } } fragment r on Verification { result { details { payer } } }`,
+			options: []Option{WithMaxDepth(3)},
+			status:  http.StatusOK,
+			want:    `{"errors":[{"message":"operation has depth 4, which exceeds the limit of 3","extensions":{"code":"DEPTH_LIMIT_EXCEEDED"}}],"data":null}`,
+		},
+		{
+			name:    "complexity within the limit",
+			query:   `{ verifications(limit: 19) { id reference } }`,
+			options: []Option{WithMaxComplexity(40)},
+			status:  http.StatusOK,
+			want:    `{"data":{"verifications":[{"id":"2","reference":"FT24123FGHIJ"},{"id":"1","reference":"FT24123ABCDE"}]}}`,
+		},
+		{
+			name:    "complexity over the limit",
+			query:   `{ verifications(limit: 20) { id reference } }`,
+			options: []Option{WithMaxComplexity(40)},
+			status:  http.StatusOK,
+			want:    `{"errors":[{"message":"operation has a complexity of more than 40","extensions":{"code":"COMPLEXITY_LIMIT_EXCEEDED"}}],"data":null}`,
+		},
+		{
+			name:      "complexity of a limit variable",
+			query:     `query ($n: Int) { verifications(limit: $n) { id reference } }`,
+			variables: map[string]any{"n": 20},
+			options:   []Option{WithMaxComplexity(40)},
+			status:    http.StatusOK,
+			want:      `{"errors":[{"message":"operation has a complexity of more than 40","extensions":{"code":"COMPLEXITY_LIMIT_EXCEEDED"}}],"data":null}`,
+		},
+		{
+			name:    "complexity of the maximum limit",
+			query:   `{ verifications { id reference } }`,
+			options: []Option{WithMaxComplexity(40), WithMaxLimit(19)},
+			status:  http.StatusOK,
+			want:    `{"data":{"verifications":[{"id":"2","reference":"FT24123FGHIJ"},{"id":"1","reference":"FT24123ABCDE"}]}}`,
+		},
+		{
+			name:   "fragment bomb",
+			query:  fragmentBomb(40),
+			status: http.StatusOK,
+			want:   `{"errors":[{"message":"operation has a complexity of more than 10000","extensions":{"code":"COMPLEXITY_LIMIT_EXCEEDED"}}],"data":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(testHistory(t), nil, tt.options...)
+			ctx := context.Background()
+			if tt.opts != nil {
+				ctx = server.NewContext(ctx, *tt.opts)
+			}
+			status, got := post(t, h, ctx, tt.query, tt.variables)
+			if status != tt.status || got != tt.want {
+				t.Errorf("status %d: %s\nwant %d: %s", status, got, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	h := New(testHistory(t), nil)
+	query := `{ verifications(limit: 1) { id } }`
+	request := func(method, contentType, body string) *http.Request {
+		r := httptest.NewRequest(method, "/", strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		status  int
+	}{
+		{"GET query", httptest.NewRequest(http.MethodGet, "/?query="+url.QueryEscape(query), nil), http.StatusOK},
+		{"GET mutation", httptest.NewRequest(http.MethodGet, "/?query="+url.QueryEscape(`mutation { verify(transaction: {}) { isValid } }`), nil), http.StatusNotAcceptable},
+		{"POST JSON", request(http.MethodPost, "application/json", `{"query":"`+query+`"}`), http.StatusOK},
+		{"POST application/graphql", request(http.MethodPost, "application/graphql", query), http.StatusOK},
+		{"missing query", request(http.MethodPost, "application/json", `{}`), http.StatusUnprocessableEntity},
+		{"syntax error", request(http.MethodPost, "application/json", `{"query":"{"}`), http.StatusUnprocessableEntity},
+		{"too large", request(http.MethodPost, "application/json", `{"query":"`+strings.Repeat(" ", maxRequestBytes)+query+`"}`), http.StatusRequestEntityTooLarge},
+		{"PUT", request(http.MethodPut, "application/json", `{"query":"`+query+`"}`), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.request)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var response struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Errorf("invalid response %s: %v", w.Body, err)
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	for _, definition := range []string{"type Query {", "type Mutation {", "type Verification {", "input TransactionInput {", "scalar Time"} {
+		if !strings.Contains(Schema(), definition) {
+			t.Errorf("the schema has no %q", definition)
+		}
+	}
+}
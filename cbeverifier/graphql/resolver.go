@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// Errors of field resolution
+var (
+	errMutationsDisabled = errors.New("mutations are disabled: the handler has no verifier")
+	errForeignMerchant   = errors.New("verifications of another merchant")
+)
+
+// outcomes are the outcomes records are stored with
+var outcomes = []string{cbeverifier.OutcomeVerified, cbeverifier.OutcomeMismatch, cbeverifier.OutcomeReview, cbeverifier.OutcomeError}
+
+// resolver resolves the root fields of the schema for the generated
+// executor
+type resolver struct {
+	*Handler
+}
+
+func (r *resolver) Query() QueryResolver       { return r }
+func (r *resolver) Mutation() MutationResolver { return r }
+
+// options returns the options of a request: those the server put in its
+// context, e.g. its merchant's, or else the Handler's
+func (r *resolver) options(ctx context.Context) cbeverifier.Options {
+	if opts, ok := server.OptionsFromContext(ctx); ok {
+		return opts
+	}
+	return r.opts
+}
+
+// Verifications resolves Query.verifications, scoped to the merchant of the
+// request, if any
+func (r *resolver) Verifications(ctx context.Context, reference *string, merchantID *string, outcome *string, from *time.Time, to *time.Time, limit *int, offset *int) ([]store.Record, error) {
+	filter := store.Filter{Limit: r.maxLimit}
+	if reference != nil {
+		filter.Reference = *reference
+	}
+	if merchantID != nil {
+		filter.MerchantID = *merchantID
+	}
+	if outcome != nil {
+		filter.Outcome = *outcome
+	}
+	if from != nil {
+		filter.From = *from
+	}
+	if to != nil {
+		filter.To = *to
+	}
+
+	if merchant := r.options(ctx).MerchantID; merchant != "" {
+		if filter.MerchantID != "" && filter.MerchantID != merchant {
+			return nil, errForeignMerchant
+		}
+		filter.MerchantID = merchant
+	}
+	if filter.Outcome != "" && !slices.Contains(outcomes, filter.Outcome) {
+		return nil, fmt.Errorf("unknown outcome %q, expected one of %q", filter.Outcome, outcomes)
+	}
+	if limit != nil {
+		if *limit < 1 {
+			return nil, fmt.Errorf("limit %d is not positive", *limit)
+		}
+		filter.Limit = min(*limit, r.maxLimit)
+	}
+	if offset != nil {
+		if *offset < 0 {
+			return nil, fmt.Errorf("offset %d is negative", *offset)
+		}
+		filter.Offset = *offset
+	}
+
+	records, err := r.history.Query(ctx, filter)
+	if records == nil && err == nil {
+		records = []store.Record{}
+	}
+	return records, err
+}
+
+// Verify resolves Mutation.verify with the options of the request
+func (r *resolver) Verify(ctx context.Context, transaction cbeverifier.Transaction) (*cbeverifier.VerificationResult, error) {
+	if r.verifier == nil {
+		return nil, errMutationsDisabled
+	}
+	return r.verifier.Verify(ctx, transaction, r.options(ctx))
+}
+
+func (r *resolver) VerificationResult() VerificationResultResolver { return r }
+func (r *resolver) TransactionDetails() TransactionDetailsResolver { return r }
+
+// The resolvers below convert the maps of numbers or strings of the JSON
+// fields to the map[string]any JSON is bound to
+
+// LowConfidence resolves VerificationResult.lowConfidence
+func (r *resolver) LowConfidence(_ context.Context, result *cbeverifier.VerificationResult) (map[string]any, error) {
+	return jsonObject(result.LowConfidence), nil
+}
+
+// Extra resolves TransactionDetails.extra
+func (r *resolver) Extra(_ context.Context, details *cbeverifier.TransactionDetails) (map[string]any, error) {
+	return jsonObject(details.Extra), nil
+}
+
+// Confidence resolves TransactionDetails.confidence
+func (r *resolver) Confidence(_ context.Context, details *cbeverifier.TransactionDetails) (map[string]any, error) {
+	return jsonObject(details.Confidence), nil
+}
+
+// jsonObject converts m, or returns nil for null when it is empty
+func jsonObject[V any](m map[string]V) map[string]any {
+	if len(m) == 0 {
+		return nil
+	}
+	object := make(map[string]any, len(m))
+	for key, value := range m {
+		object[key] = value
+	}
+	return object
+}
@@ -0,0 +1,56 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// The functions below marshal the scalars of the schema, as bound in
+// gqlgen.yml
+
+// MarshalRecordID marshals the ID of a store.Record as a string
+func MarshalRecordID(id int64) graphql.Marshaler {
+	return graphql.MarshalString(strconv.FormatInt(id, 10))
+}
+
+// UnmarshalRecordID unmarshals an ID argument
+func UnmarshalRecordID(v any) (int64, error) {
+	switch id := v.(type) {
+	case string:
+		return strconv.ParseInt(id, 10, 64)
+	case json.Number:
+		return id.Int64()
+	case int64:
+		return id, nil
+	case int:
+		return int64(id), nil
+	}
+	return 0, fmt.Errorf("expected ID, found %v", v)
+}
+
+// MarshalTime marshals a Time as RFC 3339, or null when zero
+func MarshalTime(t time.Time) graphql.Marshaler {
+	if t.IsZero() {
+		return graphql.Null
+	}
+	return graphql.MarshalTime(t)
+}
+
+// UnmarshalTime unmarshals a Time argument: an RFC 3339 timestamp or a date
+func UnmarshalTime(v any) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected Time, found %v", v)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("expected Time, found %q", s)
+}
@@ -0,0 +1,835 @@
+// The gRPC interface of the verifier, served by the cbeverifier/grpc module
+// and by its cbe-verify-grpc command. The Go code generated from this file is
+// in cbeverifier/grpc/cbeverifierpb; run `go generate` in cbeverifier/grpc
+// after changing it.
+//
+// Messages mirror the JSON shapes of the Go types of the same names. Compared
+// values of mismatches and warnings are carried as text, and dates as RFC 3339
+// strings.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: cbeverifier.proto
+
+package cbeverifierpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Suffix        string                 `protobuf:"bytes,2,opt,name=suffix,proto3" json:"suffix,omitempty"`
+	Amount        float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	FullReference string                 `protobuf:"bytes,4,opt,name=full_reference,json=fullReference,proto3" json:"full_reference,omitempty"`
+	Currency      string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	ReceiverName  string                 `protobuf:"bytes,6,opt,name=receiver_name,json=receiverName,proto3" json:"receiver_name,omitempty"`
+	PayerName     string                 `protobuf:"bytes,7,opt,name=payer_name,json=payerName,proto3" json:"payer_name,omitempty"`
+	Provider      string                 `protobuf:"bytes,8,opt,name=provider,proto3" json:"provider,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_cbeverifier_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Transaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Transaction) GetSuffix() string {
+	if x != nil {
+		return x.Suffix
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Transaction) GetFullReference() string {
+	if x != nil {
+		return x.FullReference
+	}
+	return ""
+}
+
+func (x *Transaction) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Transaction) GetReceiverName() string {
+	if x != nil {
+		return x.ReceiverName
+	}
+	return ""
+}
+
+func (x *Transaction) GetPayerName() string {
+	if x != nil {
+		return x.PayerName
+	}
+	return ""
+}
+
+func (x *Transaction) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   *Transaction           `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_cbeverifier_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VerifyRequest) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+type ParseReceiptRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Receipt []byte                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	// provider names the issuer of the receipt (default: "cbe")
+	Provider      string `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ParseReceiptRequest) Reset() {
+	*x = ParseReceiptRequest{}
+	mi := &file_cbeverifier_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ParseReceiptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParseReceiptRequest) ProtoMessage() {}
+
+func (x *ParseReceiptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParseReceiptRequest.ProtoReflect.Descriptor instead.
+func (*ParseReceiptRequest) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ParseReceiptRequest) GetReceipt() []byte {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (x *ParseReceiptRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+type VerifyBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyBatchRequest) Reset() {
+	*x = VerifyBatchRequest{}
+	mi := &file_cbeverifier_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyBatchRequest) ProtoMessage() {}
+
+func (x *VerifyBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyBatchRequest.ProtoReflect.Descriptor instead.
+func (*VerifyBatchRequest) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *VerifyBatchRequest) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type VerifyBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*VerificationResult  `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyBatchResponse) Reset() {
+	*x = VerifyBatchResponse{}
+	mi := &file_cbeverifier_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyBatchResponse) ProtoMessage() {}
+
+func (x *VerifyBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyBatchResponse.ProtoReflect.Descriptor instead.
+func (*VerifyBatchResponse) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *VerifyBatchResponse) GetResults() []*VerificationResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// Comparison is a value provided by the caller and the one on the receipt
+type Comparison struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provided      string                 `protobuf:"bytes,1,opt,name=provided,proto3" json:"provided,omitempty"`
+	Official      string                 `protobuf:"bytes,2,opt,name=official,proto3" json:"official,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Comparison) Reset() {
+	*x = Comparison{}
+	mi := &file_cbeverifier_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Comparison) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Comparison) ProtoMessage() {}
+
+func (x *Comparison) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Comparison.ProtoReflect.Descriptor instead.
+func (*Comparison) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Comparison) GetProvided() string {
+	if x != nil {
+		return x.Provided
+	}
+	return ""
+}
+
+func (x *Comparison) GetOfficial() string {
+	if x != nil {
+		return x.Official
+	}
+	return ""
+}
+
+type VerificationResult struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	IsValid          bool                   `protobuf:"varint,1,opt,name=is_valid,json=isValid,proto3" json:"is_valid,omitempty"`
+	Error            string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Mismatches       map[string]*Comparison `protobuf:"bytes,3,rep,name=mismatches,proto3" json:"mismatches,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Warnings         map[string]*Comparison `protobuf:"bytes,4,rep,name=warnings,proto3" json:"warnings,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Details          *TransactionDetails    `protobuf:"bytes,5,opt,name=details,proto3" json:"details,omitempty"`
+	NeedsReview      bool                   `protobuf:"varint,6,opt,name=needs_review,json=needsReview,proto3" json:"needs_review,omitempty"`
+	TamperIndicators []string               `protobuf:"bytes,7,rep,name=tamper_indicators,json=tamperIndicators,proto3" json:"tamper_indicators,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *VerificationResult) Reset() {
+	*x = VerificationResult{}
+	mi := &file_cbeverifier_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerificationResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerificationResult) ProtoMessage() {}
+
+func (x *VerificationResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerificationResult.ProtoReflect.Descriptor instead.
+func (*VerificationResult) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VerificationResult) GetIsValid() bool {
+	if x != nil {
+		return x.IsValid
+	}
+	return false
+}
+
+func (x *VerificationResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *VerificationResult) GetMismatches() map[string]*Comparison {
+	if x != nil {
+		return x.Mismatches
+	}
+	return nil
+}
+
+func (x *VerificationResult) GetWarnings() map[string]*Comparison {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *VerificationResult) GetDetails() *TransactionDetails {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *VerificationResult) GetNeedsReview() bool {
+	if x != nil {
+		return x.NeedsReview
+	}
+	return false
+}
+
+func (x *VerificationResult) GetTamperIndicators() []string {
+	if x != nil {
+		return x.TamperIndicators
+	}
+	return nil
+}
+
+type TransactionDetails struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Payer           string                 `protobuf:"bytes,1,opt,name=payer,proto3" json:"payer,omitempty"`
+	PayerAccount    string                 `protobuf:"bytes,2,opt,name=payer_account,json=payerAccount,proto3" json:"payer_account,omitempty"`
+	Receiver        string                 `protobuf:"bytes,3,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	ReceiverAccount string                 `protobuf:"bytes,4,opt,name=receiver_account,json=receiverAccount,proto3" json:"receiver_account,omitempty"`
+	ReceiverBank    string                 `protobuf:"bytes,5,opt,name=receiver_bank,json=receiverBank,proto3" json:"receiver_bank,omitempty"`
+	Amount          float64                `protobuf:"fixed64,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency        string                 `protobuf:"bytes,7,opt,name=currency,proto3" json:"currency,omitempty"`
+	ServiceCharge   float64                `protobuf:"fixed64,8,opt,name=service_charge,json=serviceCharge,proto3" json:"service_charge,omitempty"`
+	Vat             float64                `protobuf:"fixed64,9,opt,name=vat,proto3" json:"vat,omitempty"`
+	TotalDebited    float64                `protobuf:"fixed64,10,opt,name=total_debited,json=totalDebited,proto3" json:"total_debited,omitempty"`
+	// date is the payment date in RFC 3339, or empty if it could not be parsed
+	Date            string                `protobuf:"bytes,11,opt,name=date,proto3" json:"date,omitempty"`
+	DateRaw         string                `protobuf:"bytes,12,opt,name=date_raw,json=dateRaw,proto3" json:"date_raw,omitempty"`
+	TransactionId   string                `protobuf:"bytes,13,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	Reason          string                `protobuf:"bytes,14,opt,name=reason,proto3" json:"reason,omitempty"`
+	Branch          string                `protobuf:"bytes,15,opt,name=branch,proto3" json:"branch,omitempty"`
+	Channel         string                `protobuf:"bytes,16,opt,name=channel,proto3" json:"channel,omitempty"`
+	TransactionType string                `protobuf:"bytes,17,opt,name=transaction_type,json=transactionType,proto3" json:"transaction_type,omitempty"`
+	FormatVersion   string                `protobuf:"bytes,18,opt,name=format_version,json=formatVersion,proto3" json:"format_version,omitempty"`
+	Extra           map[string]string     `protobuf:"bytes,19,rep,name=extra,proto3" json:"extra,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Transfers       []*TransactionDetails `protobuf:"bytes,20,rep,name=transfers,proto3" json:"transfers,omitempty"`
+	// date_ec is the payment date in the Ethiopian calendar, as DD/MM/YYYY
+	DateEc           string   `protobuf:"bytes,21,opt,name=date_ec,json=dateEc,proto3" json:"date_ec,omitempty"`
+	PayerAccounts    []string `protobuf:"bytes,22,rep,name=payer_accounts,json=payerAccounts,proto3" json:"payer_accounts,omitempty"`
+	ReceiverAccounts []string `protobuf:"bytes,23,rep,name=receiver_accounts,json=receiverAccounts,proto3" json:"receiver_accounts,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TransactionDetails) Reset() {
+	*x = TransactionDetails{}
+	mi := &file_cbeverifier_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionDetails) ProtoMessage() {}
+
+func (x *TransactionDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_cbeverifier_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionDetails.ProtoReflect.Descriptor instead.
+func (*TransactionDetails) Descriptor() ([]byte, []int) {
+	return file_cbeverifier_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TransactionDetails) GetPayer() string {
+	if x != nil {
+		return x.Payer
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetPayerAccount() string {
+	if x != nil {
+		return x.PayerAccount
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetReceiverAccount() string {
+	if x != nil {
+		return x.ReceiverAccount
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetReceiverBank() string {
+	if x != nil {
+		return x.ReceiverBank
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *TransactionDetails) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetServiceCharge() float64 {
+	if x != nil {
+		return x.ServiceCharge
+	}
+	return 0
+}
+
+func (x *TransactionDetails) GetVat() float64 {
+	if x != nil {
+		return x.Vat
+	}
+	return 0
+}
+
+func (x *TransactionDetails) GetTotalDebited() float64 {
+	if x != nil {
+		return x.TotalDebited
+	}
+	return 0
+}
+
+func (x *TransactionDetails) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetDateRaw() string {
+	if x != nil {
+		return x.DateRaw
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetTransactionType() string {
+	if x != nil {
+		return x.TransactionType
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetFormatVersion() string {
+	if x != nil {
+		return x.FormatVersion
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetExtra() map[string]string {
+	if x != nil {
+		return x.Extra
+	}
+	return nil
+}
+
+func (x *TransactionDetails) GetTransfers() []*TransactionDetails {
+	if x != nil {
+		return x.Transfers
+	}
+	return nil
+}
+
+func (x *TransactionDetails) GetDateEc() string {
+	if x != nil {
+		return x.DateEc
+	}
+	return ""
+}
+
+func (x *TransactionDetails) GetPayerAccounts() []string {
+	if x != nil {
+		return x.PayerAccounts
+	}
+	return nil
+}
+
+func (x *TransactionDetails) GetReceiverAccounts() []string {
+	if x != nil {
+		return x.ReceiverAccounts
+	}
+	return nil
+}
+
+var File_cbeverifier_proto protoreflect.FileDescriptor
+
+const file_cbeverifier_proto_rawDesc = "" +
+	"\n" +
+	"\x11cbeverifier.proto\x12\x0ecbeverifier.v1\"\xf0\x01\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06suffix\x18\x02 \x01(\tR\x06suffix\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12%\n" +
+	"\x0efull_reference\x18\x04 \x01(\tR\rfullReference\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12#\n" +
+	"\rreceiver_name\x18\x06 \x01(\tR\freceiverName\x12\x1d\n" +
+	"\n" +
+	"payer_name\x18\a \x01(\tR\tpayerName\x12\x1a\n" +
+	"\bprovider\x18\b \x01(\tR\bprovider\"N\n" +
+	"\rVerifyRequest\x12=\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x1b.cbeverifier.v1.TransactionR\vtransaction\"K\n" +
+	"\x13ParseReceiptRequest\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\fR\areceipt\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\"U\n" +
+	"\x12VerifyBatchRequest\x12?\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x1b.cbeverifier.v1.TransactionR\ftransactions\"S\n" +
+	"\x13VerifyBatchResponse\x12<\n" +
+	"\aresults\x18\x01 \x03(\v2\".cbeverifier.v1.VerificationResultR\aresults\"D\n" +
+	"\n" +
+	"Comparison\x12\x1a\n" +
+	"\bprovided\x18\x01 \x01(\tR\bprovided\x12\x1a\n" +
+	"\bofficial\x18\x02 \x01(\tR\bofficial\"\xa9\x04\n" +
+	"\x12VerificationResult\x12\x19\n" +
+	"\bis_valid\x18\x01 \x01(\bR\aisValid\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12R\n" +
+	"\n" +
+	"mismatches\x18\x03 \x03(\v22.cbeverifier.v1.VerificationResult.MismatchesEntryR\n" +
+	"mismatches\x12L\n" +
+	"\bwarnings\x18\x04 \x03(\v20.cbeverifier.v1.VerificationResult.WarningsEntryR\bwarnings\x12<\n" +
+	"\adetails\x18\x05 \x01(\v2\".cbeverifier.v1.TransactionDetailsR\adetails\x12!\n" +
+	"\fneeds_review\x18\x06 \x01(\bR\vneedsReview\x12+\n" +
+	"\x11tamper_indicators\x18\a \x03(\tR\x10tamperIndicators\x1aY\n" +
+	"\x0fMismatchesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.cbeverifier.v1.ComparisonR\x05value:\x028\x01\x1aW\n" +
+	"\rWarningsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.cbeverifier.v1.ComparisonR\x05value:\x028\x01\"\xed\x06\n" +
+	"\x12TransactionDetails\x12\x14\n" +
+	"\x05payer\x18\x01 \x01(\tR\x05payer\x12#\n" +
+	"\rpayer_account\x18\x02 \x01(\tR\fpayerAccount\x12\x1a\n" +
+	"\breceiver\x18\x03 \x01(\tR\breceiver\x12)\n" +
+	"\x10receiver_account\x18\x04 \x01(\tR\x0freceiverAccount\x12#\n" +
+	"\rreceiver_bank\x18\x05 \x01(\tR\freceiverBank\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\x01R\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\a \x01(\tR\bcurrency\x12%\n" +
+	"\x0eservice_charge\x18\b \x01(\x01R\rserviceCharge\x12\x10\n" +
+	"\x03vat\x18\t \x01(\x01R\x03vat\x12#\n" +
+	"\rtotal_debited\x18\n" +
+	" \x01(\x01R\ftotalDebited\x12\x12\n" +
+	"\x04date\x18\v \x01(\tR\x04date\x12\x19\n" +
+	"\bdate_raw\x18\f \x01(\tR\adateRaw\x12%\n" +
+	"\x0etransaction_id\x18\r \x01(\tR\rtransactionId\x12\x16\n" +
+	"\x06reason\x18\x0e \x01(\tR\x06reason\x12\x16\n" +
+	"\x06branch\x18\x0f \x01(\tR\x06branch\x12\x18\n" +
+	"\achannel\x18\x10 \x01(\tR\achannel\x12)\n" +
+	"\x10transaction_type\x18\x11 \x01(\tR\x0ftransactionType\x12%\n" +
+	"\x0eformat_version\x18\x12 \x01(\tR\rformatVersion\x12C\n" +
+	"\x05extra\x18\x13 \x03(\v2-.cbeverifier.v1.TransactionDetails.ExtraEntryR\x05extra\x12@\n" +
+	"\ttransfers\x18\x14 \x03(\v2\".cbeverifier.v1.TransactionDetailsR\ttransfers\x12\x17\n" +
+	"\adate_ec\x18\x15 \x01(\tR\x06dateEc\x12%\n" +
+	"\x0epayer_accounts\x18\x16 \x03(\tR\rpayerAccounts\x12+\n" +
+	"\x11receiver_accounts\x18\x17 \x03(\tR\x10receiverAccounts\x1a8\n" +
+	"\n" +
+	"ExtraEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\x88\x02\n" +
+	"\bVerifier\x12K\n" +
+	"\x06Verify\x12\x1d.cbeverifier.v1.VerifyRequest\x1a\".cbeverifier.v1.VerificationResult\x12W\n" +
+	"\fParseReceipt\x12#.cbeverifier.v1.ParseReceiptRequest\x1a\".cbeverifier.v1.TransactionDetails\x12V\n" +
+	"\vVerifyBatch\x12\".cbeverifier.v1.VerifyBatchRequest\x1a#.cbeverifier.v1.VerifyBatchResponseBCZAgithub.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc/cbeverifierpbb\x06proto3"
+
+var (
+	file_cbeverifier_proto_rawDescOnce sync.Once
+	file_cbeverifier_proto_rawDescData []byte
+)
+
+func file_cbeverifier_proto_rawDescGZIP() []byte {
+	file_cbeverifier_proto_rawDescOnce.Do(func() {
+		file_cbeverifier_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_cbeverifier_proto_rawDesc), len(file_cbeverifier_proto_rawDesc)))
+	})
+	return file_cbeverifier_proto_rawDescData
+}
+
+var file_cbeverifier_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_cbeverifier_proto_goTypes = []any{
+	(*Transaction)(nil),         // 0: cbeverifier.v1.Transaction
+	(*VerifyRequest)(nil),       // 1: cbeverifier.v1.VerifyRequest
+	(*ParseReceiptRequest)(nil), // 2: cbeverifier.v1.ParseReceiptRequest
+	(*VerifyBatchRequest)(nil),  // 3: cbeverifier.v1.VerifyBatchRequest
+	(*VerifyBatchResponse)(nil), // 4: cbeverifier.v1.VerifyBatchResponse
+	(*Comparison)(nil),          // 5: cbeverifier.v1.Comparison
+	(*VerificationResult)(nil),  // 6: cbeverifier.v1.VerificationResult
+	(*TransactionDetails)(nil),  // 7: cbeverifier.v1.TransactionDetails
+	nil,                         // 8: cbeverifier.v1.VerificationResult.MismatchesEntry
+	nil,                         // 9: cbeverifier.v1.VerificationResult.WarningsEntry
+	nil,                         // 10: cbeverifier.v1.TransactionDetails.ExtraEntry
+}
+var file_cbeverifier_proto_depIdxs = []int32{
+	0,  // 0: cbeverifier.v1.VerifyRequest.transaction:type_name -> cbeverifier.v1.Transaction
+	0,  // 1: cbeverifier.v1.VerifyBatchRequest.transactions:type_name -> cbeverifier.v1.Transaction
+	6,  // 2: cbeverifier.v1.VerifyBatchResponse.results:type_name -> cbeverifier.v1.VerificationResult
+	8,  // 3: cbeverifier.v1.VerificationResult.mismatches:type_name -> cbeverifier.v1.VerificationResult.MismatchesEntry
+	9,  // 4: cbeverifier.v1.VerificationResult.warnings:type_name -> cbeverifier.v1.VerificationResult.WarningsEntry
+	7,  // 5: cbeverifier.v1.VerificationResult.details:type_name -> cbeverifier.v1.TransactionDetails
+	10, // 6: cbeverifier.v1.TransactionDetails.extra:type_name -> cbeverifier.v1.TransactionDetails.ExtraEntry
+	7,  // 7: cbeverifier.v1.TransactionDetails.transfers:type_name -> cbeverifier.v1.TransactionDetails
+	5,  // 8: cbeverifier.v1.VerificationResult.MismatchesEntry.value:type_name -> cbeverifier.v1.Comparison
+	5,  // 9: cbeverifier.v1.VerificationResult.WarningsEntry.value:type_name -> cbeverifier.v1.Comparison
+	1,  // 10: cbeverifier.v1.Verifier.Verify:input_type -> cbeverifier.v1.VerifyRequest
+	2,  // 11: cbeverifier.v1.Verifier.ParseReceipt:input_type -> cbeverifier.v1.ParseReceiptRequest
+	3,  // 12: cbeverifier.v1.Verifier.VerifyBatch:input_type -> cbeverifier.v1.VerifyBatchRequest
+	6,  // 13: cbeverifier.v1.Verifier.Verify:output_type -> cbeverifier.v1.VerificationResult
+	7,  // 14: cbeverifier.v1.Verifier.ParseReceipt:output_type -> cbeverifier.v1.TransactionDetails
+	4,  // 15: cbeverifier.v1.Verifier.VerifyBatch:output_type -> cbeverifier.v1.VerifyBatchResponse
+	13, // [13:16] is the sub-list for method output_type
+	10, // [10:13] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_cbeverifier_proto_init() }
+func file_cbeverifier_proto_init() {
+	if File_cbeverifier_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cbeverifier_proto_rawDesc), len(file_cbeverifier_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cbeverifier_proto_goTypes,
+		DependencyIndexes: file_cbeverifier_proto_depIdxs,
+		MessageInfos:      file_cbeverifier_proto_msgTypes,
+	}.Build()
+	File_cbeverifier_proto = out.File
+	file_cbeverifier_proto_goTypes = nil
+	file_cbeverifier_proto_depIdxs = nil
+}
@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc/cbeverifierpb"
+)
+
+// Client calls a Verifier service. Calls that fail return an error with the
+// gRPC status of the call, read with status.Code and status.Convert.
+//
+// Example:
+//
+//	client, err := grpc.NewClient("verifier.internal:9090")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer client.Close()
+//	result, err := client.Verify(ctx, cbeverifier.Transaction{
+//		ID:     "FT24123ABCDE",
+//		Suffix: "12345678",
+//		Amount: 1500,
+//	})
+type Client struct {
+	conn   *gogrpc.ClientConn
+	client cbeverifierpb.VerifierClient
+
+	credentials credentials.TransportCredentials
+	apiKey      string
+	dialOptions []gogrpc.DialOption
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithTLS calls the service over TLS with config instead of plaintext HTTP/2
+func WithTLS(config *tls.Config) ClientOption {
+	return func(c *Client) {
+		c.credentials = credentials.NewTLS(config)
+	}
+}
+
+// WithAPIKey authenticates the calls with key, for servers with API keys
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithDialOptions adds options to the connection of the client, such as
+// interceptors or a custom dialer
+func WithDialOptions(options ...gogrpc.DialOption) ClientOption {
+	return func(c *Client) {
+		c.dialOptions = append(c.dialOptions, options...)
+	}
+}
+
+// NewClient creates a Client calling the service at addr ("host:port") over
+// plaintext HTTP/2. The connection is made by the first call.
+func NewClient(addr string, options ...ClientOption) (*Client, error) {
+	c := &Client{credentials: insecure.NewCredentials()}
+	for _, option := range options {
+		option(c)
+	}
+
+	dialOptions := append([]gogrpc.DialOption{gogrpc.WithTransportCredentials(c.credentials)}, c.dialOptions...)
+	if c.apiKey != "" {
+		dialOptions = append(dialOptions, gogrpc.WithUnaryInterceptor(c.authenticate))
+	}
+	conn, err := gogrpc.NewClient(addr, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.client = cbeverifierpb.NewVerifierClient(conn)
+	return c, nil
+}
+
+// Close closes the connection of the client
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// authenticate sends the client's API key with a call
+func (c *Client) authenticate(ctx context.Context, method string, req, reply any, cc *gogrpc.ClientConn, invoker gogrpc.UnaryInvoker, opts ...gogrpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.apiKey)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// Verify verifies a transaction against its official receipt. Mismatched
+// values are returned as text.
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction) (*cbeverifier.VerificationResult, error) {
+	result, err := c.client.Verify(ctx, &cbeverifierpb.VerifyRequest{Transaction: fromTransaction(transaction)})
+	if err != nil {
+		return nil, err
+	}
+	return toResult(result)
+}
+
+// ParseReceipt extracts the details of a receipt issued by provider (default:
+// CBE)
+func (c *Client) ParseReceipt(ctx context.Context, receipt []byte, provider string) (*cbeverifier.TransactionDetails, error) {
+	details, err := c.client.ParseReceipt(ctx, &cbeverifierpb.ParseReceiptRequest{Receipt: receipt, Provider: provider})
+	if err != nil {
+		return nil, err
+	}
+	return toDetails(details)
+}
+
+// VerifyBatch verifies several transactions, returning their results in order
+func (c *Client) VerifyBatch(ctx context.Context, transactions []cbeverifier.Transaction) ([]*cbeverifier.VerificationResult, error) {
+	req := &cbeverifierpb.VerifyBatchRequest{}
+	for _, transaction := range transactions {
+		req.Transactions = append(req.Transactions, fromTransaction(transaction))
+	}
+	response, err := c.client.VerifyBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*cbeverifier.VerificationResult, len(response.GetResults()))
+	for i, message := range response.GetResults() {
+		result, err := toResult(message)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,72 @@
+// Command cbe-verify-grpc serves the gRPC service of proto/cbeverifier.proto,
+// over plaintext HTTP/2, with the verifier's default options.
+//
+// Usage:
+//
+//	cbe-verify-grpc [-addr :9090] [-api-keys api-keys.yaml]
+//
+// With -api-keys, every call must carry one of the keys of the YAML file read
+// by apikey.Load, as for cbe-verify serve.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc"
+
+	// Register the providers of the other supported banks
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/awash"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/boa"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/cbebirr"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/dashen"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/hibret"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/mpesa"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/telebirr"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/zemen"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	keysFile := flag.String("api-keys", "", "YAML file of the API keys clients must send, with their request rates")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	options := []grpc.ServerOption{grpc.WithLogger(logger)}
+	if *keysFile != "" {
+		keys, err := apikey.Load(*keysFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cbe-verify-grpc: %v\n", err)
+			os.Exit(5)
+		}
+		options = append(options, grpc.WithAPIKeys(keys))
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cbe-verify-grpc: %v\n", err)
+		os.Exit(3)
+	}
+	srv := grpc.NewServer(cbeverifier.New(cbeverifier.WithProxy(nil)), options...)
+
+	// Serve until interrupted, letting the calls in progress finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	fmt.Fprintf(os.Stderr, "cbe-verify-grpc: serving on %s\n", listener.Addr())
+	if err := srv.Serve(listener); err != nil {
+		fmt.Fprintf(os.Stderr, "cbe-verify-grpc: %v\n", err)
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,206 @@
+package grpc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc/cbeverifierpb"
+)
+
+// The functions below convert between the library's types and the messages
+// generated from proto/cbeverifier.proto
+
+// toTransaction converts a Transaction message
+func toTransaction(t *cbeverifierpb.Transaction) cbeverifier.Transaction {
+	return cbeverifier.Transaction{
+		ID:            t.GetId(),
+		Suffix:        t.GetSuffix(),
+		Amount:        t.GetAmount(),
+		FullReference: t.GetFullReference(),
+		Currency:      t.GetCurrency(),
+		ReceiverName:  t.GetReceiverName(),
+		PayerName:     t.GetPayerName(),
+		Provider:      t.GetProvider(),
+	}
+}
+
+// fromTransaction returns the Transaction message of t
+func fromTransaction(t cbeverifier.Transaction) *cbeverifierpb.Transaction {
+	return &cbeverifierpb.Transaction{
+		Id:            t.ID,
+		Suffix:        t.Suffix,
+		Amount:        t.Amount,
+		FullReference: t.FullReference,
+		Currency:      t.Currency,
+		ReceiverName:  t.ReceiverName,
+		PayerName:     t.PayerName,
+		Provider:      t.Provider,
+	}
+}
+
+// toTransactions converts the transactions of a VerifyBatchRequest
+func toTransactions(transactions []*cbeverifierpb.Transaction) []cbeverifier.Transaction {
+	converted := make([]cbeverifier.Transaction, len(transactions))
+	for i, t := range transactions {
+		converted[i] = toTransaction(t)
+	}
+	return converted
+}
+
+// fromResult returns the VerificationResult message of result
+func fromResult(result *cbeverifier.VerificationResult) *cbeverifierpb.VerificationResult {
+	message := &cbeverifierpb.VerificationResult{
+		IsValid:          result.IsValid,
+		Error:            result.Error,
+		Mismatches:       fromComparisons(result.Mismatches),
+		Warnings:         fromComparisons(result.Warnings),
+		NeedsReview:      result.NeedsReview,
+		TamperIndicators: result.TamperIndicators,
+	}
+	if result.Details != nil {
+		message.Details = fromDetails(result.Details)
+	}
+	return message
+}
+
+// toResult converts a VerificationResult message
+func toResult(message *cbeverifierpb.VerificationResult) (*cbeverifier.VerificationResult, error) {
+	result := &cbeverifier.VerificationResult{
+		IsValid:          message.GetIsValid(),
+		Error:            message.GetError(),
+		Mismatches:       toComparisons(message.GetMismatches()),
+		Warnings:         toComparisons(message.GetWarnings()),
+		NeedsReview:      message.GetNeedsReview(),
+		TamperIndicators: message.GetTamperIndicators(),
+	}
+	if message.GetDetails() != nil {
+		details, err := toDetails(message.GetDetails())
+		if err != nil {
+			return nil, err
+		}
+		result.Details = details
+	}
+	return result, nil
+}
+
+// fromComparisons converts the mismatches or warnings of a result, whose
+// compared values are sent as text
+func fromComparisons(fields map[string]interface{}) map[string]*cbeverifierpb.Comparison {
+	if len(fields) == 0 {
+		return nil
+	}
+	comparisons := make(map[string]*cbeverifierpb.Comparison, len(fields))
+	for name, value := range fields {
+		if values, ok := value.(map[string]interface{}); ok {
+			comparisons[name] = &cbeverifierpb.Comparison{Provided: formatValue(values["provided"]), Official: formatValue(values["official"])}
+		} else {
+			comparisons[name] = &cbeverifierpb.Comparison{Provided: formatValue(value)}
+		}
+	}
+	return comparisons
+}
+
+// toComparisons converts comparisons to the shape of the library's mismatches
+func toComparisons(comparisons map[string]*cbeverifierpb.Comparison) map[string]interface{} {
+	if len(comparisons) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(comparisons))
+	for name, comparison := range comparisons {
+		fields[name] = map[string]interface{}{"provided": comparison.GetProvided(), "official": comparison.GetOfficial()}
+	}
+	return fields
+}
+
+// formatValue formats a compared value as text
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// fromDetails returns the TransactionDetails message of d
+func fromDetails(d *cbeverifier.TransactionDetails) *cbeverifierpb.TransactionDetails {
+	message := &cbeverifierpb.TransactionDetails{
+		Payer:            d.Payer,
+		PayerAccount:     d.PayerAccount,
+		Receiver:         d.Receiver,
+		ReceiverAccount:  d.ReceiverAccount,
+		ReceiverBank:     d.ReceiverBank,
+		Amount:           d.Amount,
+		Currency:         d.Currency,
+		ServiceCharge:    d.ServiceCharge,
+		Vat:              d.VAT,
+		TotalDebited:     d.TotalDebited,
+		DateRaw:          d.DateRaw,
+		TransactionId:    d.TransactionID,
+		Reason:           d.Reason,
+		Branch:           d.Branch,
+		Channel:          d.Channel,
+		TransactionType:  d.TransactionType,
+		FormatVersion:    d.FormatVersion,
+		Extra:            d.Extra,
+		DateEc:           d.DateEC,
+		PayerAccounts:    d.PayerAccounts,
+		ReceiverAccounts: d.ReceiverAccounts,
+	}
+	if !d.Date.IsZero() {
+		message.Date = d.Date.Format(time.RFC3339)
+	}
+	for i := range d.Transfers {
+		message.Transfers = append(message.Transfers, fromDetails(&d.Transfers[i]))
+	}
+	return message
+}
+
+// toDetails converts a TransactionDetails message
+func toDetails(message *cbeverifierpb.TransactionDetails) (*cbeverifier.TransactionDetails, error) {
+	d := &cbeverifier.TransactionDetails{
+		Payer:            message.GetPayer(),
+		PayerAccount:     message.GetPayerAccount(),
+		Receiver:         message.GetReceiver(),
+		ReceiverAccount:  message.GetReceiverAccount(),
+		ReceiverBank:     message.GetReceiverBank(),
+		Amount:           message.GetAmount(),
+		Currency:         message.GetCurrency(),
+		ServiceCharge:    message.GetServiceCharge(),
+		VAT:              message.GetVat(),
+		TotalDebited:     message.GetTotalDebited(),
+		DateRaw:          message.GetDateRaw(),
+		TransactionID:    message.GetTransactionId(),
+		Reason:           message.GetReason(),
+		Branch:           message.GetBranch(),
+		Channel:          message.GetChannel(),
+		TransactionType:  message.GetTransactionType(),
+		FormatVersion:    message.GetFormatVersion(),
+		Extra:            message.GetExtra(),
+		DateEC:           message.GetDateEc(),
+		PayerAccounts:    message.GetPayerAccounts(),
+		ReceiverAccounts: message.GetReceiverAccounts(),
+	}
+	if message.GetDate() != "" {
+		date, err := time.Parse(time.RFC3339, message.GetDate())
+		if err != nil {
+			return nil, fmt.Errorf("date: %w", err)
+		}
+		d.Date = date
+	}
+	for _, transfer := range message.GetTransfers() {
+		converted, err := toDetails(transfer)
+		if err != nil {
+			return nil, err
+		}
+		d.Transfers = append(d.Transfers, *converted)
+	}
+	return d, nil
+}
@@ -0,0 +1,221 @@
+package grpc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc/cbeverifierpb"
+)
+
+// testTransaction sets every field of a Transaction
+var testTransaction = cbeverifier.Transaction{
+	ID:            "FT24123ABCDE",
+	Suffix:        "12345678",
+	Amount:        1500.25,
+	FullReference: "FT24123ABCDE12345678",
+	Currency:      "ETB",
+	ReceiverName:  "Abebe Kebede",
+	PayerName:     "Almaz Tesfaye",
+	Provider:      "cbe",
+}
+
+const testTransactionJSON = `{"id": "FT24123ABCDE", "suffix": "12345678", "amount": 1500.25,
+	"fullReference": "FT24123ABCDE12345678", "currency": "ETB", "receiverName": "Abebe Kebede",
+	"payerName": "Almaz Tesfaye", "provider": "cbe"}`
+
+// testDetails sets every field of a TransactionDetails, and some of a transfer
+var testDetails = &cbeverifier.TransactionDetails{
+	Payer:            "Almaz Tesfaye",
+	PayerAccount:     "1****5678",
+	Receiver:         "Abebe Kebede",
+	ReceiverAccount:  "1****4321",
+	ReceiverBank:     "Commercial Bank of Ethiopia",
+	Amount:           1500.25,
+	Currency:         "ETB",
+	ServiceCharge:    2,
+	VAT:              0.3,
+	TotalDebited:     1502.55,
+	Date:             time.Date(2025, 9, 5, 10, 30, 0, 0, time.FixedZone("EAT", 3*60*60)),
+	DateRaw:          "9/5/2025, 10:30:00 AM",
+	TransactionID:    "FT24123ABCDE",
+	Reason:           "Order 1042",
+	Branch:           "Bole",
+	Channel:          "Mobile",
+	TransactionType:  "Account to Account",
+	FormatVersion:    "cbe-2024",
+	Extra:            map[string]string{"terminal": "T01", "agent": "A7"},
+	Transfers:        []cbeverifier.TransactionDetails{{Receiver: "Second Receiver", Amount: 500}},
+	DateEC:           "30/12/2017",
+	PayerAccounts:    []string{"1****5678", "1****9999"},
+	ReceiverAccounts: []string{"1****4321"},
+}
+
+const testDetailsJSON = `{"payer": "Almaz Tesfaye", "payerAccount": "1****5678", "receiver": "Abebe Kebede",
+	"receiverAccount": "1****4321", "receiverBank": "Commercial Bank of Ethiopia", "amount": 1500.25,
+	"currency": "ETB", "serviceCharge": 2, "vat": 0.3, "totalDebited": 1502.55,
+	"date": "2025-09-05T10:30:00+03:00", "dateRaw": "9/5/2025, 10:30:00 AM", "transactionId": "FT24123ABCDE",
+	"reason": "Order 1042", "branch": "Bole", "channel": "Mobile", "transactionType": "Account to Account",
+	"formatVersion": "cbe-2024", "extra": {"terminal": "T01", "agent": "A7"},
+	"transfers": [{"receiver": "Second Receiver", "amount": 500}], "dateEc": "30/12/2017",
+	"payerAccounts": ["1****5678", "1****9999"], "receiverAccounts": ["1****4321"]}`
+
+// testResult sets every field of a VerificationResult. Compared values are
+// sent as text, so they are decoded as testDecodedResult.
+var testResult = &cbeverifier.VerificationResult{
+	Error: "transaction verification failed",
+	Mismatches: map[string]interface{}{
+		"amount":   map[string]interface{}{"provided": 1000.0, "official": 1500.25},
+		"receiver": map[string]interface{}{"provided": "Abebe", "official": "Abebe Kebede"},
+	},
+	Warnings:         map[string]interface{}{"date": time.Date(2025, 9, 5, 7, 30, 0, 0, time.UTC)},
+	Details:          testDetails,
+	NeedsReview:      true,
+	TamperIndicators: []string{"incremental update", "font mismatch"},
+}
+
+var testDecodedResult = &cbeverifier.VerificationResult{
+	Error: "transaction verification failed",
+	Mismatches: map[string]interface{}{
+		"amount":   map[string]interface{}{"provided": "1000", "official": "1500.25"},
+		"receiver": map[string]interface{}{"provided": "Abebe", "official": "Abebe Kebede"},
+	},
+	Warnings:         map[string]interface{}{"date": map[string]interface{}{"provided": "2025-09-05T07:30:00Z", "official": ""}},
+	Details:          testDetails,
+	NeedsReview:      true,
+	TamperIndicators: []string{"incremental update", "font mismatch"},
+}
+
+const testResultJSON = `{"error": "transaction verification failed",
+	"mismatches": {"amount": {"provided": "1000", "official": "1500.25"}, "receiver": {"provided": "Abebe", "official": "Abebe Kebede"}},
+	"warnings": {"date": {"provided": "2025-09-05T07:30:00Z"}},
+	"details": ` + testDetailsJSON + `, "needsReview": true, "tamperIndicators": ["incremental update", "font mismatch"]}`
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		message string
+		// json is the message in the protobuf JSON mapping
+		json string
+		// empty returns an empty message of the type
+		empty func() proto.Message
+		// from converts the Go value to its message
+		from func() proto.Message
+		// to converts a message to its Go value
+		to func(proto.Message) (any, error)
+		// want is the Go value converted from the message
+		want any
+	}{
+		{
+			message: "Transaction",
+			json:    testTransactionJSON,
+			empty:   func() proto.Message { return &cbeverifierpb.Transaction{} },
+			from:    func() proto.Message { return fromTransaction(testTransaction) },
+			to: func(m proto.Message) (any, error) {
+				return toTransaction(m.(*cbeverifierpb.Transaction)), nil
+			},
+			want: testTransaction,
+		},
+		{
+			message: "TransactionDetails",
+			json:    testDetailsJSON,
+			empty:   func() proto.Message { return &cbeverifierpb.TransactionDetails{} },
+			from:    func() proto.Message { return fromDetails(testDetails) },
+			to: func(m proto.Message) (any, error) {
+				return toDetails(m.(*cbeverifierpb.TransactionDetails))
+			},
+			want: testDetails,
+		},
+		{
+			message: "VerificationResult",
+			json:    testResultJSON,
+			empty:   func() proto.Message { return &cbeverifierpb.VerificationResult{} },
+			from:    func() proto.Message { return fromResult(testResult) },
+			to: func(m proto.Message) (any, error) {
+				return toResult(m.(*cbeverifierpb.VerificationResult))
+			},
+			want: testDecodedResult,
+		},
+		{
+			message: "empty VerificationResult",
+			json:    `{"isValid": true}`,
+			empty:   func() proto.Message { return &cbeverifierpb.VerificationResult{} },
+			from:    func() proto.Message { return fromResult(&cbeverifier.VerificationResult{IsValid: true}) },
+			to: func(m proto.Message) (any, error) {
+				return toResult(m.(*cbeverifierpb.VerificationResult))
+			},
+			want: &cbeverifier.VerificationResult{IsValid: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			want := tt.empty()
+			if err := protojson.Unmarshal([]byte(tt.json), want); err != nil {
+				t.Fatal(err)
+			}
+
+			// Each field of the Go value lands in the field of the .proto file
+			// of the same name
+			if got := tt.from(); !proto.Equal(got, want) {
+				gotJSON, _ := protojson.Marshal(got)
+				t.Errorf("converted to %s, want %s", gotJSON, tt.json)
+			}
+
+			value, err := tt.to(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalValues(value, tt.want) {
+				t.Errorf("converted %+v, want %+v", value, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertInvalidDate(t *testing.T) {
+	if _, err := toDetails(&cbeverifierpb.TransactionDetails{Date: "5 September"}); err == nil {
+		t.Error("invalid date accepted")
+	}
+}
+
+// equalValues reports whether decoded values are equal, comparing dates as
+// instants since the name of their zone is not sent
+func equalValues(got, want any) bool {
+	return reflect.DeepEqual(normalize(reflect.ValueOf(got)), normalize(reflect.ValueOf(want)))
+}
+
+// normalize returns a copy of v, its dates in UTC
+func normalize(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return normalize(v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = normalize(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		if date, ok := v.Interface().(time.Time); ok {
+			return date.UTC()
+		}
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			// Unexported fields, such as the error of a result, are not sent
+			if v.Type().Field(i).IsExported() {
+				fields[v.Type().Field(i).Name] = normalize(v.Field(i))
+			}
+		}
+		return fields
+	}
+	return v.Interface()
+}
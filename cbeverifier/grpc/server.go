@@ -0,0 +1,247 @@
+// Package grpc serves the verifier as the gRPC service defined in
+// proto/cbeverifier.proto, and calls it with a Client, for microservice meshes
+// that standardize on gRPC.
+//
+// The messages and stubs of the service are generated from the .proto file
+// into package cbeverifierpb, with protoc-gen-go and protoc-gen-go-grpc. The
+// package is a module of its own, so that programs using the verifier without
+// gRPC do not depend on the gRPC runtime.
+//
+// Example:
+//
+//	listener, err := net.Listen("tcp", ":9090")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	srv := grpc.NewServer(cbeverifier.New())
+//	log.Fatal(srv.Serve(listener))
+package grpc
+
+//go:generate protoc -I ../../proto --go_out=cbeverifierpb --go_opt=paths=source_relative --go-grpc_out=cbeverifierpb --go-grpc_opt=paths=source_relative cbeverifier.proto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/grpc/cbeverifierpb"
+)
+
+// defaultMaxMessageBytes limits the size of request messages, which carry
+// receipts of up to 5 MiB
+const defaultMaxMessageBytes = 8 << 20
+
+// Server serves the Verifier service
+type Server struct {
+	cbeverifierpb.UnimplementedVerifierServer
+
+	verifier    *cbeverifier.Verifier
+	opts        cbeverifier.Options
+	logger      *slog.Logger
+	keys        *apikey.Keys
+	concurrency int
+	grpcServer  *gogrpc.Server
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithOptions sets the options of every verification and parse (default:
+// cbeverifier.DefaultOptions())
+func WithOptions(opts cbeverifier.Options) ServerOption {
+	return func(s *Server) {
+		s.opts = opts
+	}
+}
+
+// WithLogger logs each call, and why failed calls failed, to logger (default:
+// discarded)
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithAPIKeys requires every call to carry one of keys in its authorization
+// ("Bearer KEY") or x-api-key metadata, and limits each key to its own request
+// rate. Calls without a valid key fail with Unauthenticated, and calls over
+// their key's quota with ResourceExhausted.
+func WithAPIKeys(keys *apikey.Keys) ServerOption {
+	return func(s *Server) {
+		s.keys = keys
+	}
+}
+
+// WithBatchConcurrency sets how many transactions of a VerifyBatch call are
+// verified at once (default: 4)
+func WithBatchConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// NewServer creates a Server verifying transactions with verifier
+func NewServer(verifier *cbeverifier.Verifier, options ...ServerOption) *Server {
+	s := &Server{
+		verifier:    verifier,
+		opts:        cbeverifier.DefaultOptions(),
+		logger:      slog.New(slog.DiscardHandler),
+		concurrency: 4,
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	limit := defaultMaxMessageBytes
+	if s.opts.MaxPDFBytes > 0 {
+		limit = int(s.opts.MaxPDFBytes) + 64<<10
+	}
+	s.grpcServer = gogrpc.NewServer(gogrpc.UnaryInterceptor(s.intercept), gogrpc.MaxRecvMsgSize(limit))
+	cbeverifierpb.RegisterVerifierServer(s.grpcServer, s)
+	return s
+}
+
+// Serve accepts connections on listener and serves the calls they carry,
+// until Stop or GracefulStop is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpcServer.Serve(listener)
+}
+
+// GracefulStop stops accepting connections and waits for the calls in
+// progress to finish
+func (s *Server) GracefulStop() {
+	s.grpcServer.GracefulStop()
+}
+
+// Stop closes every connection at once, cancelling the calls in progress
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+}
+
+// intercept authenticates and logs each call, and gives the errors of the
+// verifier their status code
+func (s *Server) intercept(ctx context.Context, req any, info *gogrpc.UnaryServerInfo, handler gogrpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	key, err := s.authenticate(ctx)
+	var resp any
+	if err == nil {
+		resp, err = handler(apikey.NewContext(ctx, key), req)
+	}
+	err = toStatus(err)
+
+	code := status.Code(err)
+	if code != codes.OK {
+		s.logger.WarnContext(ctx, "gRPC call failed", "client", key.Name, "method", info.FullMethod, "code", code.String(), "error", status.Convert(err).Message())
+	}
+	s.logger.InfoContext(ctx, "gRPC call", "client", key.Name, "method", info.FullMethod, "code", code.String(), "elapsed", time.Since(start))
+	return resp, err
+}
+
+// authenticate returns the API key of a call when the server has keys
+func (s *Server) authenticate(ctx context.Context) (apikey.Key, error) {
+	if s.keys == nil {
+		return apikey.Key{}, nil
+	}
+	key, retryAfter, err := s.keys.Allow(callKey(ctx))
+	switch {
+	case errors.Is(err, apikey.ErrQuotaExceeded):
+		return key, status.Errorf(codes.ResourceExhausted, "%v; retry in %s", err, retryAfter.Round(time.Millisecond))
+	case err != nil:
+		return key, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return key, nil
+}
+
+// callKey returns the API key of a call, sent as "authorization: Bearer KEY"
+// or "x-api-key: KEY" metadata
+func callKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// Verify implements the Verify method
+func (s *Server) Verify(ctx context.Context, req *cbeverifierpb.VerifyRequest) (*cbeverifierpb.VerificationResult, error) {
+	result, err := s.verifier.Verify(ctx, toTransaction(req.GetTransaction()), s.opts)
+	if err != nil {
+		return nil, err
+	}
+	return fromResult(result), nil
+}
+
+// ParseReceipt implements the ParseReceipt method
+func (s *Server) ParseReceipt(ctx context.Context, req *cbeverifierpb.ParseReceiptRequest) (*cbeverifierpb.TransactionDetails, error) {
+	if len(req.GetReceipt()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "empty receipt")
+	}
+	name := req.GetProvider()
+	if name == "" {
+		name = cbeverifier.ProviderCBE
+	}
+	if _, err := s.verifier.ProviderCapabilities(name); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The verifier parses CBE receipts with its own OCR engine, templates, QR
+	// decoder and signature roots
+	details, _, err := s.verifier.Lookup(ctx, cbeverifier.Transaction{Provider: name}, req.GetReceipt(), s.opts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return fromDetails(details), nil
+}
+
+// VerifyBatch implements the VerifyBatch method. A transaction that cannot be
+// verified gets a result with its error rather than failing the call.
+func (s *Server) VerifyBatch(ctx context.Context, req *cbeverifierpb.VerifyBatchRequest) (*cbeverifierpb.VerifyBatchResponse, error) {
+	transactions := toTransactions(req.GetTransactions())
+	results := make([]*cbeverifierpb.VerificationResult, len(transactions))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.concurrency, len(transactions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result, err := s.verifier.Verify(ctx, transactions[i], s.opts)
+				if err != nil {
+					result = cbeverifier.NewErrorResult(err)
+				}
+				results[i] = fromResult(result)
+			}
+		}()
+	}
+	for i := range transactions {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &cbeverifierpb.VerifyBatchResponse{Results: results}, nil
+}
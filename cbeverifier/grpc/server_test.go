@@ -0,0 +1,260 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+)
+
+// testProviderName is the provider of the transactions the tests verify
+const testProviderName = "grpctest"
+
+// testProvider issues receipts of 1500 ETB, naming their reference
+type testProvider struct{}
+
+func init() {
+	cbeverifier.Register(testProviderName, testProvider{})
+}
+
+func (testProvider) Fetch(_ context.Context, transaction cbeverifier.Transaction, _ cbeverifier.Options) ([]byte, error) {
+	return []byte("receipt " + transaction.ID), nil
+}
+
+func (testProvider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	id, ok := strings.CutPrefix(string(receipt), "receipt ")
+	if !ok {
+		return nil, cbeverifier.ErrReceiptParseError
+	}
+	return &cbeverifier.TransactionDetails{TransactionID: id, Amount: 1500, Currency: "ETB"}, nil
+}
+
+func (testProvider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
+
+// startServer serves srv on a loopback port and returns a Client calling it
+func startServer(t *testing.T, srv *Server, options ...ClientOption) *Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	client, err := NewClient(listener.Addr().String(), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient(t *testing.T) {
+	opts := cbeverifier.DefaultOptions()
+	opts.IncludeDetails = true
+	client := startServer(t, NewServer(cbeverifier.New(), WithOptions(opts)))
+	ctx := context.Background()
+
+	result, err := client.Verify(ctx, cbeverifier.Transaction{ID: "T1", Amount: 1500, Provider: testProviderName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsValid || result.Details == nil || result.Details.TransactionID != "T1" {
+		t.Errorf("result = %+v, want a valid result with the details of T1", result)
+	}
+
+	result, err = client.Verify(ctx, cbeverifier.Transaction{ID: "T2", Amount: 1000, Provider: testProviderName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"provided": "1000", "official": "1500"}
+	if result.IsValid || fmt.Sprint(result.Mismatches["amount"]) != fmt.Sprint(want) {
+		t.Errorf("mismatches = %v, want amount %v", result.Mismatches, want)
+	}
+
+	results, err := client.VerifyBatch(ctx, []cbeverifier.Transaction{
+		{ID: "T3", Amount: 1500, Provider: testProviderName},
+		{ID: "T4", Amount: 1500, Provider: "unknown"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].IsValid || !strings.Contains(results[1].Error, cbeverifier.ErrUnknownProvider.Error()) {
+		t.Errorf("results = %+v, want T3 valid and T4 failed with its provider", results)
+	}
+
+	details, err := client.ParseReceipt(ctx, []byte("receipt T5"), testProviderName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.TransactionID != "T5" || details.Amount != 1500 {
+		t.Errorf("details = %+v", details)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	keys, err := apikey.New(apikey.Key{Name: "shop", Key: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := startServer(t, NewServer(cbeverifier.New()))
+
+	tests := []struct {
+		name    string
+		client  *Client
+		call    func(*Client) error
+		code    codes.Code
+		message string
+	}{
+		{
+			name:    "unknown provider",
+			client:  client,
+			call:    parse([]byte("receipt T1"), "nobank"),
+			code:    codes.InvalidArgument,
+			message: cbeverifier.ErrUnknownProvider.Error(),
+		},
+		{
+			name:    "empty receipt",
+			client:  client,
+			call:    parse(nil, testProviderName),
+			code:    codes.InvalidArgument,
+			message: "empty receipt",
+		},
+		{
+			name:    "unparsable receipt",
+			client:  client,
+			call:    parse([]byte("%PDF-1.4"), testProviderName),
+			code:    codes.InvalidArgument,
+			message: cbeverifier.ErrReceiptParseError.Error(),
+		},
+		{
+			name:    "missing API key",
+			client:  startServer(t, NewServer(cbeverifier.New(), WithAPIKeys(keys))),
+			call:    parse([]byte("receipt T1"), testProviderName),
+			code:    codes.Unauthenticated,
+			message: apikey.ErrMissingKey.Error(),
+		},
+		{
+			name:   "wrong API key",
+			client: startServer(t, NewServer(cbeverifier.New(), WithAPIKeys(keys)), WithAPIKey("guess")),
+			call:   parse([]byte("receipt T1"), testProviderName),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "message too large",
+			client: startServer(t, NewServer(cbeverifier.New(), WithOptions(cbeverifier.Options{MaxPDFBytes: 1}))),
+			call:   parse(bytes.Repeat([]byte("x"), 65<<10), testProviderName),
+			code:   codes.ResourceExhausted,
+		},
+		{
+			// The verification ends with the deadline the client sends
+			name:   "expired deadline",
+			client: client,
+			call: func(c *Client) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				_, err := c.VerifyBatch(ctx, []cbeverifier.Transaction{{ID: "T1", Amount: 1, Provider: testProviderName + "-slow"}})
+				return err
+			},
+			code: codes.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(tt.client)
+			s, ok := status.FromError(err)
+			if err == nil || !ok {
+				t.Fatalf("err = %v, want a status error", err)
+			}
+			if s.Code() != tt.code || !strings.Contains(s.Message(), tt.message) {
+				t.Errorf("status %s %q, want %s containing %q", s.Code(), s.Message(), tt.code, tt.message)
+			}
+		})
+	}
+
+	// An authenticated call succeeds
+	authenticated := startServer(t, NewServer(cbeverifier.New(), WithAPIKeys(keys)), WithAPIKey("secret"))
+	if err := parse([]byte("receipt T1"), testProviderName)(authenticated); err != nil {
+		t.Errorf("authenticated call: %v", err)
+	}
+}
+
+// parse returns a call parsing receipt
+func parse(receipt []byte, provider string) func(*Client) error {
+	return func(c *Client) error {
+		_, err := c.ParseReceipt(context.Background(), receipt, provider)
+		return err
+	}
+}
+
+// slowProvider fetches receipts once its context is done
+type slowProvider struct {
+	testProvider
+}
+
+func init() {
+	cbeverifier.Register(testProviderName+"-slow", slowProvider{})
+}
+
+func (slowProvider) Fetch(ctx context.Context, _ cbeverifier.Transaction, _ cbeverifier.Options) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCallKey(t *testing.T) {
+	tests := []struct {
+		md   metadata.MD
+		want string
+	}{
+		{metadata.Pairs("authorization", "Bearer secret"), "secret"},
+		{metadata.Pairs("authorization", "bearer  secret "), "secret"},
+		{metadata.Pairs("x-api-key", "secret", "authorization", "Bearer other"), "secret"},
+		{metadata.Pairs("authorization", "Basic c2VjcmV0"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+		if got := callKey(ctx); got != tt.want {
+			t.Errorf("callKey(%v) = %q, want %q", tt.md, got, tt.want)
+		}
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{nil, codes.OK},
+		{status.Error(codes.ResourceExhausted, "quota"), codes.ResourceExhausted},
+		{fmt.Errorf("verify: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{fmt.Errorf("fetch: %w", cbeverifier.ErrNetworkError), codes.Unavailable},
+		{cbeverifier.ErrUpstreamUnavailable, codes.Unavailable},
+		{fmt.Errorf("%w: FT1", cbeverifier.ErrInvalidTransactionID), codes.InvalidArgument},
+		{cbeverifier.ErrInvalidSuffix, codes.InvalidArgument},
+		{cbeverifier.ErrInvalidAmount, codes.InvalidArgument},
+		{cbeverifier.ErrInvalidReference, codes.InvalidArgument},
+		{cbeverifier.ErrUnknownProvider, codes.InvalidArgument},
+		{cbeverifier.ErrAmbiguousReference, codes.InvalidArgument},
+		{errors.New("disk full"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if code := status.Code(toStatus(tt.err)); code != tt.code {
+			t.Errorf("toStatus(%v) = %s, want %s", tt.err, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// toStatus returns the status error of a call that failed with err, or nil
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(errorCode(err), err.Error())
+}
+
+// errorCode returns the status code of an error of the verifier
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, cbeverifier.ErrNetworkError), errors.Is(err, cbeverifier.ErrUpstreamUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, cbeverifier.ErrInvalidTransactionID), errors.Is(err, cbeverifier.ErrInvalidSuffix),
+		errors.Is(err, cbeverifier.ErrInvalidAmount), errors.Is(err, cbeverifier.ErrInvalidReference),
+		errors.Is(err, cbeverifier.ErrUnknownProvider), errors.Is(err, cbeverifier.ErrAmbiguousReference):
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
@@ -0,0 +1,48 @@
+package hibret
+
+import (
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// FormatPDF is the Hibret transfer advice PDF, reported in
+// TransactionDetails.FormatVersion
+const FormatPDF = "hibret-pdf"
+
+// adviceTemplate matches the text rows of transfer advices, in priority order
+var adviceTemplate = webreceipt.Labels{
+	{"payer", `debit\s*account\s*name|customer\s*name|ordering\s*customer|sender\s*name`},
+	{"receiver", `credit\s*account\s*name|beneficiary\s*name|receiver\s*name`},
+	{"payer_account", `debit\s*account(?:\s*(?:number|no\.?))?|sender\s*account(?:\s*(?:number|no\.?))?`},
+	{"receiver_account", `credit\s*account(?:\s*(?:number|no\.?))?|beneficiary\s*account(?:\s*(?:number|no\.?))?`},
+	{"receiver_bank", `beneficiary\s*bank(?:\s*name)?|receiver\s*bank`},
+	{"receiver", `beneficiary`},
+	{"service_charge", `(?:service\s*)?charges?|commission`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?(?:\s*debited)?`},
+	{"amount", `(?:transfer(?:red)?\s*|transaction\s*|debit\s*)?amount`},
+	{"date", `(?:transaction|value|posting)\s*date|date`},
+	{"transaction_id", `(?:ft\s*|transaction\s*)?reference(?:\s*no\.?)?|ref\.?\s*no\.?`},
+	{"reason", `payment\s*details|narrative|remarks?|reason|purpose`},
+	{"branch", `branch(?:\s*name)?`},
+}.Template(FormatPDF)
+
+// dateLayouts are the date formats seen on transfer advices
+var dateLayouts = []string{
+	"02 Jan 2006 15:04:05",
+	"02 Jan 2006",
+	"02-Jan-2006 15:04",
+	"02-Jan-2006",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parse extracts transaction information from a Hibret transfer advice PDF
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(pdf []byte) (*cbeverifier.TransactionDetails, error) {
+	return webreceipt.ParsePDF(pdf, adviceTemplate, dateLayouts, reReference.MatchString)
+}
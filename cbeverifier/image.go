@@ -0,0 +1,83 @@
+package cbeverifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// screenshotTemplate extracts fields from the CBE mobile app's transfer
+// confirmation screen, which labels fields differently from the PDF receipt
+var screenshotTemplate = mustCompileTemplate(&ReceiptTemplate{
+	Name: "cbe-mobile",
+	Rules: []FieldRule{
+		{Field: "total_debited", Pattern: reTotalDebited.String()},
+		{Field: "service_charge", Pattern: reServiceCharge.String()},
+		{Field: "vat", Pattern: reVAT.String()},
+		{Field: "payer_account", Pattern: `(?i)^(?:from|debit(?:ed)?|sender)\s*account(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*([\d*X]+)`},
+		{Field: "receiver_account", Pattern: `(?i)^(?:(?:to|credit(?:ed)?|receiver|beneficiary)\s*)?account(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*([\d*X]+)`},
+		{Field: "payer", Pattern: `(?i)^(?:from|sender|payer)(?:\s*name)?\s*[:፥፦]?\s*([\p{L}\p{M}][\p{L}\p{M}&\.\s-]*)$`},
+		{Field: "receiver", Pattern: `(?i)^(?:transferred to|sent to|to|receiver|beneficiary)(?:\s*name)?\s*[:፥፦]?\s*([\p{L}\p{M}][\p{L}\p{M}&\.\s-]*)$`},
+		{Field: "amount", Pattern: `(?i)^(?:(?:transferred\s+)?amount\s*[:፥፦]?\s*)?(?P<currency>[A-Z]{3}|ብር)?\s*(?P<value>[\d,]+\.\d{2})\s*(?P<currency>[A-Z]{3}|ብር)?$`},
+		{Field: "transaction_id", Pattern: `\b(FT[A-Z0-9]{10})\b`},
+		{Field: "reason", Pattern: `(?i)^(?:reason|remark|narrative|description)\s*[:፥፦]?\s*(.+)`},
+		{Field: "date", Pattern: `(?i)(` + datePattern + `)`},
+	},
+})
+
+// ParseReceiptImage extracts transaction information from a PNG or JPEG
+// screenshot of the CBE mobile app's confirmation screen, or a photo of a
+// printed receipt, using engine
+//
+// Screenshots usually omit some fields shown on the PDF receipt, such as the
+// payer's account, so only the transaction ID and amount are required: a
+// *MissingFieldsError is returned only when either of those is missing. Since a
+// screenshot is easily edited, use the extracted reference to verify against
+// the official receipt rather than trusting the image.
+//
+// Example:
+//
+//	receipt, err := cbeverifier.ParseReceiptImage(ctx, screenshot, tesseract.New())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	result, err := cbeverifier.Verify(cbeverifier.Transaction{
+//		ID:     receipt.TransactionID,
+//		Suffix: suffix,
+//		Amount: receipt.Amount,
+//	}, cbeverifier.DefaultOptions())
+func ParseReceiptImage(ctx context.Context, image []byte, engine OCREngine) (*ParsedReceipt, error) {
+	if engine == nil {
+		return nil, fmt.Errorf("%w: no OCR engine configured", ErrReceiptParseError)
+	}
+
+	contentType := http.DetectContentType(image)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("%w: unsupported image type %s", ErrReceiptParseError, contentType)
+	}
+
+	ocrLines, err := engine.Recognize(ctx, image, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: OCR failed: %v", ErrReceiptParseError, err)
+	}
+
+	receipt, err := bestReceipt(ocrRows(ocrLines), []*ReceiptTemplate{screenshotTemplate, defaultTemplate}, missingImageFields)
+	if receipt != nil {
+		receipt.OCR = true
+	}
+	return receipt, err
+}
+
+// missingImageFields returns the fields a screenshot must show that were not extracted
+func missingImageFields(r *ParsedReceipt) []string {
+	var missing []string
+	if r.TransactionID == "" {
+		missing = append(missing, "transaction_id")
+	}
+	if r.Amount <= 0 {
+		missing = append(missing, "amount")
+	}
+	return missing
+}
@@ -0,0 +1,93 @@
+// Package webreceipt holds the fetching and HTML parsing shared by the providers
+// whose receipts are published as web pages (Telebirr, BOA and others).
+package webreceipt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+const (
+	// defaultTimeout is the total time budget for fetching a receipt
+	defaultTimeout = 30 * time.Second
+	// defaultMaxBytes is the largest receipt that will be downloaded
+	defaultMaxBytes = 5 << 20
+)
+
+// Errors are the provider's sentinel errors that fetch failures are reported as
+type Errors struct {
+	// Network wraps request and transport errors
+	Network error
+	// InvalidResponse wraps unexpected status codes
+	InvalidResponse error
+	// NotFound is returned for 404 responses
+	NotFound error
+	// TooLarge is returned for receipts over Options.MaxPDFBytes
+	TooLarge error
+}
+
+// Fetch downloads the receipt at url, applying the timeout, size limit and
+// request hooks in opts, and returns the body and its content type
+func Fetch(ctx context.Context, client *http.Client, url, accept string, opts cbeverifier.Options, errs Errors) ([]byte, string, error) {
+	timeout := opts.FetchTimeout
+	if timeout <= 0 && opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	maxBytes := opts.MaxPDFBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errs.Network, err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (CBE-Verifier-Go/1.0)")
+	req.Header.Set("Accept", accept)
+
+	// Let integrators attach headers or log the outgoing fetch
+	if opts.OnRequest != nil {
+		opts.OnRequest(req)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if opts.OnResponse != nil {
+		opts.OnResponse(resp, err, time.Since(start))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errs.Network, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, "", errs.NotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("%w: status %d", errs.InvalidResponse, resp.StatusCode)
+	}
+
+	// Reject oversized responses before buffering them
+	if resp.ContentLength > maxBytes {
+		return nil, "", errs.TooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errs.Network, err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, "", errs.TooLarge
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,141 @@
+package webreceipt
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/ethiocal"
+)
+
+// Labels pairs fields with the labels printed for them, as unanchored regular
+// expression alternatives, in priority order. One set of labels describes a
+// receipt that is published both as an HTML page and as a PDF. Fields named
+// "extra.<name>" are kept in TransactionDetails.Extra.
+type Labels []struct {
+	Field string
+	Label string
+}
+
+// Fields returns the Fields matching HTML label cells
+func (l Labels) Fields() []Field {
+	fields := make([]Field, 0, len(l))
+	for _, label := range l {
+		fields = append(fields, Label(label.Field, `(?i)^(?:`+label.Label+`)\s*:?$`))
+	}
+	return fields
+}
+
+// Template returns a compiled receipt template matching PDF text rows, where a
+// label is followed by its value
+func (l Labels) Template(name string) *cbeverifier.ReceiptTemplate {
+	t := &cbeverifier.ReceiptTemplate{Name: name}
+	for _, label := range l {
+		value := `(?P<value>.+)`
+		if label.Field == "amount" {
+			value = `(?:(?P<currency>[A-Z]{3})\s*)?(?P<value>[\d,]+(?:\.\d{2})?)\s*(?P<currency>[A-Z]{3})?`
+		}
+		rule := cbeverifier.FieldRule{Field: label.Field, Pattern: `(?i)^(?:` + label.Label + `)\s*:?\s*` + value + `$`}
+		if label.Field == "transaction_id" {
+			rule.Transform = "reference"
+		}
+		t.Rules = append(t.Rules, rule)
+	}
+	if err := t.Compile(); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// reCurrency finds the currency code printed next to an amount
+var reCurrency = regexp.MustCompile(`\b([A-Z]{3})\b`)
+
+// IsPDF reports whether a receipt is a PDF rather than an HTML page
+func IsPDF(receipt []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(receipt), []byte("%PDF-"))
+}
+
+// Details builds TransactionDetails from the values of an HTML receipt
+func Details(values map[string]string, format string) *cbeverifier.TransactionDetails {
+	var extra map[string]string
+	for field, value := range values {
+		if name, ok := strings.CutPrefix(field, "extra."); ok {
+			if extra == nil {
+				extra = make(map[string]string)
+			}
+			extra[name] = value
+		}
+	}
+
+	return &cbeverifier.TransactionDetails{
+		Payer:           values["payer"],
+		PayerAccount:    values["payer_account"],
+		Receiver:        values["receiver"],
+		ReceiverAccount: values["receiver_account"],
+		ReceiverBank:    values["receiver_bank"],
+		Amount:          Amount(values["amount"]),
+		Currency:        currency(values["amount"]),
+		ServiceCharge:   Amount(values["service_charge"]),
+		VAT:             Amount(values["vat"]),
+		TotalDebited:    Amount(values["total_debited"]),
+		DateRaw:         values["date"],
+		TransactionID:   strings.ToUpper(values["transaction_id"]),
+		Reason:          values["reason"],
+		Branch:          values["branch"],
+		TransactionType: values["transaction_type"],
+		FormatVersion:   format,
+		Extra:           extra,
+	}
+}
+
+// ParsePDF parses a PDF receipt with t, reading its date with layouts. validID
+// reports whether a transaction ID has the provider's reference format.
+func ParsePDF(pdf []byte, t *cbeverifier.ReceiptTemplate, layouts []string, validID func(string) bool) (*cbeverifier.TransactionDetails, error) {
+	receipt, err := t.Parse(pdf)
+	if receipt == nil {
+		return nil, err
+	}
+
+	details := &receipt.TransactionDetails
+	details.FormatVersion = t.Name
+	details.TransactionID = strings.ToUpper(details.TransactionID)
+	if details.Currency == "" {
+		details.Currency = "ETB"
+	}
+
+	// The template halves the confidence of dates and references that do not look
+	// like CBE's; restore it when they have the provider's own format. Pattern
+	// matches score at most 0.9, so only a halved score is below 0.5.
+	penalized := details.Date.IsZero() && details.DateRaw != ""
+	details, err = Finish(details, layouts)
+	if score, ok := details.Confidence["date"]; ok && penalized && !details.Date.IsZero() {
+		details.Confidence["date"] = min(1, score*2)
+	}
+	if score, ok := details.Confidence["transaction_id"]; ok && score < 0.5 && validID(details.TransactionID) {
+		details.Confidence["transaction_id"] = min(1, score*2)
+	}
+	return details, err
+}
+
+// Finish parses the receipt date with layouts and checks the required fields,
+// returning a *cbeverifier.MissingFieldsError with the details if any are missing
+func Finish(details *cbeverifier.TransactionDetails, layouts []string) (*cbeverifier.TransactionDetails, error) {
+	if paid, ok := Date(details.DateRaw, layouts); ok {
+		details.Date = paid
+		details.DateEC = ethiocal.FromTime(paid).String()
+	}
+
+	if missing := MissingFields(details); len(missing) > 0 {
+		return details, &cbeverifier.MissingFieldsError{Fields: missing}
+	}
+	return details, nil
+}
+
+// currency returns the currency code printed with an amount, defaulting to ETB
+func currency(amount string) string {
+	if m := reCurrency.FindStringSubmatch(amount); m != nil {
+		return m[1]
+	}
+	return "ETB"
+}
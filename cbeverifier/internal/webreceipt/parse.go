@@ -0,0 +1,198 @@
+package webreceipt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Field maps a receipt label to a field name
+type Field struct {
+	Name  string
+	Label *regexp.Regexp
+}
+
+// Label returns a Field for labels matching pattern, panicking if it does not compile
+func Label(name, pattern string) Field {
+	return Field{Name: name, Label: regexp.MustCompile(pattern)}
+}
+
+// AddisAbaba is the time zone of receipt dates, falling back to a fixed UTC+3 zone
+var AddisAbaba = func() *time.Location {
+	if loc, err := time.LoadLocation("Africa/Addis_Ababa"); err == nil {
+		return loc
+	}
+	return time.FixedZone("EAT", 3*60*60)
+}()
+
+// Values parses an HTML receipt page and returns the value of every field whose
+// label appears in one of its tables. Fields are checked in order, so more
+// specific labels must come first.
+func Values(page []byte, fields []Field) (map[string]string, error) {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", cbeverifier.ErrReceiptParseError, err)
+	}
+	return labelledValues(tableRows(doc), fields), nil
+}
+
+// Text returns the text of an HTML page with whitespace collapsed, or the page
+// itself if it is plain text
+func Text(page []byte) (string, error) {
+	if !bytes.Contains(page, []byte("<")) {
+		return strings.Join(strings.Fields(string(page)), " "), nil
+	}
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", cbeverifier.ErrReceiptParseError, err)
+	}
+	return nodeText(doc), nil
+}
+
+// tableRows returns the text of the cells of every table row in the document
+func tableRows(doc *html.Node) [][]string {
+	var (
+		rows [][]string
+		walk func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "tr" {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+					cells = append(cells, nodeText(c))
+				}
+			}
+			rows = append(rows, cells)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return rows
+}
+
+// nodeText returns the text content of n with whitespace collapsed, skipping
+// scripts and style sheets
+func nodeText(n *html.Node) string {
+	var (
+		sb   strings.Builder
+		walk func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// labelledValues pairs label cells with their values. Most rows hold a label
+// followed by its value; invoice-style tables instead have a row of labels
+// followed by a row of values.
+func labelledValues(rows [][]string, fields []Field) map[string]string {
+	values := make(map[string]string)
+	set := func(field, value string) {
+		if _, ok := values[field]; !ok && value != "" && labelField(value, fields) == "" {
+			values[field] = value
+		}
+	}
+
+	for r, row := range rows {
+		for i, cell := range row {
+			field := labelField(cell, fields)
+			if field == "" {
+				continue
+			}
+			if value, ok := nextValue(row[i+1:], fields); ok {
+				set(field, value)
+			} else if r+1 < len(rows) && i < len(rows[r+1]) {
+				set(field, rows[r+1][i])
+			}
+		}
+	}
+	return values
+}
+
+// nextValue returns the first non-empty cell of cells, unless a label comes first
+func nextValue(cells []string, fields []Field) (string, bool) {
+	for _, cell := range cells {
+		if labelField(cell, fields) != "" {
+			return "", false
+		}
+		if cell != "" {
+			return cell, true
+		}
+	}
+	return "", false
+}
+
+// labelField returns the field a label cell names, or "" if it is not a label
+func labelField(cell string, fields []Field) string {
+	if len(cell) > 120 {
+		return ""
+	}
+	for _, f := range fields {
+		if f.Label.MatchString(cell) {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// Amount parses an amount such as "1,500.00 Birr", returning 0 if it is invalid
+func Amount(value string) float64 {
+	cleaned := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return -1
+	}, value)
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0
+	}
+	return amount
+}
+
+// Date parses a receipt date in Addis Ababa time using the first matching layout
+func Date(value string, layouts []string) (time.Time, bool) {
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, AddisAbaba); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MissingFields returns the JSON names of required fields that were not extracted
+func MissingFields(d *cbeverifier.TransactionDetails) []string {
+	var missing []string
+	if d.TransactionID == "" {
+		missing = append(missing, "transaction_id")
+	}
+	if d.Amount <= 0 {
+		missing = append(missing, "amount")
+	}
+	if d.DateRaw == "" {
+		missing = append(missing, "date")
+	}
+	return missing
+}
@@ -0,0 +1,212 @@
+// Package lambda serves the verification endpoints of the server package from
+// AWS Lambda behind API Gateway, for deployments of the check as a single
+// function.
+//
+// Handler.Handle takes the API Gateway proxy events of REST APIs (payload
+// version 1.0) and HTTP APIs (payload version 2.0), whose JSON is decoded by
+// the Lambda runtime, so no AWS dependency is needed. Create the Handler once,
+// outside the function, so that warm invocations reuse the Verifier and its
+// connections; receipts are parsed in memory, without temporary files.
+//
+// Example:
+//
+//	import awslambda "github.com/aws/aws-lambda-go/lambda"
+//
+//	var handler = lambda.New(cbeverifier.New(), server.WithOptions(cbeverifier.DefaultOptions()))
+//
+//	func main() {
+//		awslambda.Start(handler.Handle)
+//	}
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+)
+
+// Request is an API Gateway proxy event, with the fields of both payload
+// versions
+type Request struct {
+	// Version is "2.0" for HTTP APIs, and empty or "1.0" for REST APIs
+	Version string `json:"version,omitempty"`
+
+	// HTTPMethod and Path are set by REST APIs
+	HTTPMethod string `json:"httpMethod,omitempty"`
+	Path       string `json:"path,omitempty"`
+	// RawPath and RawQueryString are set by HTTP APIs
+	RawPath        string `json:"rawPath,omitempty"`
+	RawQueryString string `json:"rawQueryString,omitempty"`
+
+	Headers                         map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders,omitempty"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters,omitempty"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters,omitempty"`
+	Body                            string              `json:"body,omitempty"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded,omitempty"`
+	RequestContext                  RequestContext      `json:"requestContext"`
+}
+
+// RequestContext is the context of a Request
+type RequestContext struct {
+	Stage string `json:"stage,omitempty"`
+	// HTTP is set by HTTP APIs
+	HTTP struct {
+		Method   string `json:"method,omitempty"`
+		SourceIP string `json:"sourceIp,omitempty"`
+	} `json:"http"`
+	// Identity is set by REST APIs
+	Identity struct {
+		SourceIP string `json:"sourceIp,omitempty"`
+	} `json:"identity"`
+}
+
+// Response is the API Gateway proxy response to a Request
+type Response struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
+}
+
+// Handler handles API Gateway events with a server.Server
+type Handler struct {
+	verifier *cbeverifier.Verifier
+	server   *server.Server
+}
+
+// New creates a Handler serving the endpoints of server.New(verifier,
+// options...)
+func New(verifier *cbeverifier.Verifier, options ...server.Option) *Handler {
+	return &Handler{verifier: verifier, server: server.New(verifier, options...)}
+}
+
+// Handle serves an API Gateway event. Webhooks are delivered before it
+// returns, since Lambda freezes the function between invocations; for the
+// same reason, /v1/verify?async=true and the batch endpoints, whose jobs run in
+// the background of one instance, are refused.
+func (h *Handler) Handle(ctx context.Context, event Request) (Response, error) {
+	r, err := event.httpRequest(ctx)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err), nil
+	}
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		return errorResponse(http.StatusBadRequest, errors.New("async verification is not supported on Lambda")), nil
+	}
+	if r.URL.Path == "/v1/batch" || strings.HasPrefix(r.URL.Path, "/v1/batch/") {
+		return errorResponse(http.StatusNotFound, errors.New("batch jobs are not supported on Lambda")), nil
+	}
+
+	w := &responseWriter{header: make(http.Header), status: http.StatusOK}
+	h.server.ServeHTTP(w, r)
+	// Deliveries still pending when the function is frozen may never finish
+	h.verifier.FlushWebhooks(ctx)
+	return w.response(), nil
+}
+
+// httpRequest converts an event to the request it proxies
+func (e Request) httpRequest(ctx context.Context) (*http.Request, error) {
+	method, path := e.HTTPMethod, e.Path
+	query := url.Values{}
+	for name, values := range e.MultiValueQueryStringParameters {
+		query[name] = values
+	}
+	for name, value := range e.QueryStringParameters {
+		if _, ok := query[name]; !ok {
+			query.Set(name, value)
+		}
+	}
+	if e.Version == "2.0" {
+		method, path = e.RequestContext.HTTP.Method, e.RawPath
+		// HTTP APIs include the stage in the path, unless it is $default
+		if stage := e.RequestContext.Stage; stage != "" && stage != "$default" {
+			path = strings.TrimPrefix(path, "/"+stage)
+		}
+		var err error
+		if query, err = url.ParseQuery(e.RawQueryString); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+
+	body := []byte(e.Body)
+	if e.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Body); err != nil {
+			return nil, fmt.Errorf("invalid base64 body: %w", err)
+		}
+	}
+
+	target := &url.URL{Path: path, RawQuery: query.Encode()}
+	r, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range e.MultiValueHeaders {
+		r.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	for name, value := range e.Headers {
+		if r.Header.Get(name) == "" {
+			r.Header.Set(name, value)
+		}
+	}
+	r.RemoteAddr = e.RequestContext.HTTP.SourceIP
+	if r.RemoteAddr == "" {
+		r.RemoteAddr = e.RequestContext.Identity.SourceIP
+	}
+	return r, nil
+}
+
+// responseWriter buffers the response of a server.Server
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+// Header implements http.ResponseWriter
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+}
+
+// Write implements http.ResponseWriter
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// response returns the buffered response as an API Gateway response
+func (w *responseWriter) response() Response {
+	headers := make(map[string]string, len(w.header))
+	for name, values := range w.header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	return Response{StatusCode: w.status, Headers: headers, Body: w.body.String()}
+}
+
+// errorResponse returns a JSON error response, as the server writes them
+func errorResponse(status int, err error) Response {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return Response{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body) + "\n",
+	}
+}
@@ -0,0 +1,395 @@
+package cbeverifier
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	pdf "github.com/dslipak/pdf"
+)
+
+// receiptRow is a row of receipt text. Rows read from a two-column receipt table
+// have the label column in Label and the value column in Value; rows whose layout
+// could not be determined (OCR output, untabulated text) have only Label set.
+type receiptRow struct {
+	Label string
+	Value string
+	// Confidence is the OCR engine's confidence in the row, or 0 for text read
+	// from the PDF text layer
+	Confidence float64
+	// Page is the 1-based page number the row was read from, or 0 for OCR rows
+	Page int
+}
+
+// text returns the full text of the row
+func (r receiptRow) text() string {
+	if r.Value == "" {
+		return r.Label
+	}
+	return r.Label + " " + r.Value
+}
+
+// textCell is a run of words on a row, separated from its neighbours by a column gap
+type textCell struct {
+	X    float64
+	Text string
+}
+
+// textRow is a line of glyphs sharing a baseline
+type textRow struct {
+	Y        float64
+	FontSize float64
+	Cells    []textCell
+}
+
+const (
+	// wordGap is the horizontal gap, relative to the font size, that separates words
+	wordGap = 0.15
+	// columnGap is the horizontal gap, relative to the font size, that separates
+	// the label column from the value column
+	columnGap = 1.0
+	// alignTolerance is how far, relative to the font size, a wrapped value line
+	// may start from the value column and still be treated as its continuation
+	alignTolerance = 1.0
+)
+
+// ocrRows wraps recognized lines as unlabelled rows
+func ocrRows(lines []OCRLine) []receiptRow {
+	rows := make([]receiptRow, 0, len(lines))
+	for _, line := range lines {
+		rows = append(rows, receiptRow{Label: strings.TrimSpace(line.Text), Confidence: line.Confidence})
+	}
+	return rows
+}
+
+// extractRows returns the rows of every page of the document
+func extractRows(doc *pdf.Reader) ([]receiptRow, error) {
+	var rows []receiptRow
+
+	// Process each page of the PDF
+	for i, page := range pages(doc) {
+		number := i + 1
+		blank, err := checkContent(page)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: page %d: %v", ErrReceiptParseError, ErrMalformedPDF, number, err)
+		}
+		if blank {
+			continue
+		}
+
+		if textRows, ok := glyphRows(page); ok {
+			for _, row := range tableRows(textRows) {
+				row.Page = number
+				rows = append(rows, row)
+			}
+			continue
+		}
+
+		// Without glyph widths the columns cannot be told apart, so fall back
+		// to the text of each row
+		byRow, err := page.GetTextByRow()
+		if err != nil {
+			continue
+		}
+		for _, row := range byRow {
+			rows = append(rows, receiptRow{Label: fixLineSpacing(joinWords(row.Content)), Page: number})
+		}
+	}
+
+	return rows, nil
+}
+
+const (
+	// maxPageTreeDepth and maxPageTreeNodes bound the walk of a page tree, which
+	// may be cyclic in malformed files
+	maxPageTreeDepth = 32
+	maxPageTreeNodes = 10000
+)
+
+// pages returns the pages of the document in order. Unlike pdf.Reader.Page, which
+// loops forever on a page tree node without kids, it tolerates malformed trees.
+func pages(doc *pdf.Reader) []pdf.Page {
+	var (
+		result  []pdf.Page
+		visited int
+		walk    func(node pdf.Value, depth int)
+	)
+	walk = func(node pdf.Value, depth int) {
+		if visited++; visited > maxPageTreeNodes || depth > maxPageTreeDepth {
+			return
+		}
+		switch node.Key("Type").Name() {
+		case "Pages":
+			kids := node.Key("Kids")
+			for i := 0; i < kids.Len(); i++ {
+				walk(kids.Index(i), depth+1)
+			}
+		case "Page":
+			result = append(result, pdf.Page{V: node})
+		}
+	}
+	walk(doc.Trailer().Key("Root").Key("Pages"), 0)
+	return result
+}
+
+// checkContent reads the page's content streams to make sure they decode and
+// can be tokenized, and reports whether the page has none. The PDF library's
+// content interpreter never terminates on a missing stream, one that fails to
+// decode (e.g. a truncated compressed stream) or an unterminated string.
+func checkContent(page pdf.Page) (blank bool, err error) {
+	contents := page.V.Key("Contents")
+	var streams []pdf.Value
+	switch contents.Kind() {
+	case pdf.Null:
+		return true, nil
+	case pdf.Stream:
+		streams = []pdf.Value{contents}
+	case pdf.Array:
+		for i := 0; i < contents.Len(); i++ {
+			streams = append(streams, contents.Index(i))
+		}
+	default:
+		return false, fmt.Errorf("invalid page contents %v", contents)
+	}
+
+	for _, stream := range streams {
+		if stream.Kind() != pdf.Stream {
+			return false, fmt.Errorf("invalid content stream %v", stream)
+		}
+		rd := stream.Reader()
+		data, err := io.ReadAll(rd)
+		rd.Close()
+		if err != nil {
+			return false, err
+		}
+		if unterminatedString(data) {
+			return false, fmt.Errorf("unterminated string in content stream")
+		}
+	}
+	return len(streams) == 0, nil
+}
+
+// unterminatedString reports whether a content stream ends inside a literal or
+// hex string, tokenizing it the same way as the PDF library
+func unterminatedString(data []byte) bool {
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '%':
+			for i < len(data) && data[i] != '\r' && data[i] != '\n' {
+				i++
+			}
+
+		case '/':
+			// Names end at a delimiter; '#' escapes the next two bytes
+			for i+1 < len(data) && !isPDFDelim(data[i+1]) {
+				i++
+				if data[i] == '#' {
+					i += 2
+				}
+			}
+
+		case '(':
+			depth := 1
+			for i++; depth > 0; i++ {
+				if i >= len(data) {
+					return true
+				}
+				switch data[i] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case '\\':
+					i++
+				}
+			}
+			i--
+
+		case '<':
+			if i+1 < len(data) && data[i+1] == '<' {
+				i++
+				continue
+			}
+			// Hex strings are read in pairs of digits, skipping spaces, and end at
+			// '>' or at a pair that is not hex
+			next := func() (byte, bool) {
+				for i++; i < len(data); i++ {
+					if !isPDFSpace(data[i]) {
+						return data[i], true
+					}
+				}
+				return 0, false
+			}
+			for {
+				c, ok := next()
+				if !ok {
+					return true
+				}
+				if c == '>' {
+					break
+				}
+				c2, ok := next()
+				if !ok {
+					return true
+				}
+				if !isHexDigit(c) || !isHexDigit(c2) {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isPDFDelim reports whether c ends a PDF name or keyword
+func isPDFDelim(c byte) bool {
+	return isPDFSpace(c) || strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// isPDFSpace reports whether c is PDF whitespace
+func isPDFSpace(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f\x00", c) >= 0
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// glyphRows groups the glyphs of a page into rows of cells using their coordinates.
+// It reports false when the page has no glyph widths to measure gaps with.
+func glyphRows(page pdf.Page) (rows []textRow, ok bool) {
+	defer func() {
+		// The PDF library panics on some malformed content streams
+		if recover() != nil {
+			rows, ok = nil, false
+		}
+	}()
+
+	glyphs := page.Content().Text
+	measured := false
+	for _, g := range glyphs {
+		if g.W > 0 {
+			measured = true
+			break
+		}
+	}
+	if !measured {
+		return nil, false
+	}
+
+	// Group glyphs sharing a baseline, top to bottom
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].Y > glyphs[j].Y })
+	var lines [][]pdf.Text
+	for _, g := range glyphs {
+		if n := len(lines); n > 0 {
+			last := lines[n-1][0]
+			if math.Abs(last.Y-g.Y) < math.Max(last.FontSize, g.FontSize)*0.5 {
+				lines[n-1] = append(lines[n-1], g)
+				continue
+			}
+		}
+		lines = append(lines, []pdf.Text{g})
+	}
+
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool { return line[i].X < line[j].X })
+		rows = append(rows, splitCells(line))
+	}
+	return rows, true
+}
+
+// splitCells joins the glyphs of a line into words and the words into cells
+func splitCells(line []pdf.Text) textRow {
+	row := textRow{Y: line[0].Y, FontSize: line[0].FontSize}
+
+	var (
+		sb    strings.Builder
+		cellX float64
+		end   = math.Inf(-1)
+	)
+	flush := func() {
+		if text := strings.Join(strings.Fields(sb.String()), " "); text != "" {
+			row.Cells = append(row.Cells, textCell{X: cellX, Text: text})
+		}
+		sb.Reset()
+	}
+
+	for _, g := range line {
+		size := g.FontSize
+		if size <= 0 {
+			size = 10
+		}
+		row.FontSize = math.Max(row.FontSize, size)
+
+		gap := g.X - end
+		switch {
+		case sb.Len() == 0:
+			cellX = g.X
+		case gap > size*columnGap:
+			flush()
+			cellX = g.X
+		case gap > size*wordGap:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(g.S)
+
+		end = math.Max(end, g.X+g.W)
+	}
+	flush()
+
+	return row
+}
+
+// tableRows assigns cells to the label and value columns and joins wrapped
+// values onto the row they continue
+func tableRows(rows []textRow) []receiptRow {
+	var (
+		result   []receiptRow
+		valueX   = math.NaN()
+		prev     *textRow
+		labelled bool
+	)
+
+	for i := range rows {
+		row := &rows[i]
+		if len(row.Cells) == 0 {
+			continue
+		}
+
+		switch {
+		case len(row.Cells) >= 2:
+			values := make([]string, 0, len(row.Cells)-1)
+			for _, cell := range row.Cells[1:] {
+				values = append(values, cell.Text)
+			}
+			result = append(result, receiptRow{Label: row.Cells[0].Text, Value: strings.Join(values, " ")})
+			valueX = row.Cells[1].X
+			labelled = true
+
+		case labelled && continues(prev, row, valueX):
+			// A long value wrapped onto the next line of the value column
+			last := &result[len(result)-1]
+			last.Value += " " + row.Cells[0].Text
+
+		default:
+			result = append(result, receiptRow{Label: row.Cells[0].Text})
+			labelled = false
+		}
+
+		prev = row
+	}
+
+	return result
+}
+
+// continues reports whether row is a wrapped line of the value on prev
+func continues(prev, row *textRow, valueX float64) bool {
+	if prev == nil || math.IsNaN(valueX) {
+		return false
+	}
+	size := math.Max(prev.FontSize, row.FontSize)
+	return math.Abs(row.Cells[0].X-valueX) <= size*alignTolerance && prev.Y-row.Y <= size*2
+}
@@ -0,0 +1,51 @@
+package cbeverifier
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// redact masks all but the last four characters of a value so references and
+// account numbers can be correlated in logs without being exposed
+func redact(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+// fieldNames returns the sorted keys of a mismatch map; values are never logged
+// because they contain names and account numbers
+func fieldNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logResult emits the outcome of a verification
+func (v *Verifier) logResult(ctx context.Context, transaction Transaction, result *VerificationResult) {
+	attrs := []slog.Attr{
+		slog.String("reference", redact(transaction.ID)),
+		slog.Bool("valid", result.IsValid),
+	}
+
+	switch {
+	case result.IsValid:
+		if len(result.Warnings) > 0 {
+			attrs = append(attrs, slog.Any("warning_fields", fieldNames(result.Warnings)))
+		}
+		v.logger.LogAttrs(ctx, slog.LevelInfo, "transaction verified", attrs...)
+	case len(result.Mismatches) > 0:
+		attrs = append(attrs, slog.Any("mismatch_fields", fieldNames(result.Mismatches)))
+		v.logger.LogAttrs(ctx, slog.LevelInfo, "transaction verification failed", attrs...)
+	default:
+		attrs = append(attrs, slog.String("error", result.Error))
+		v.logger.LogAttrs(ctx, slog.LevelWarn, "transaction verification error", attrs...)
+	}
+}
@@ -0,0 +1,384 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineBytes limits the lines of server responses, such as the UIDs of a
+// SEARCH
+const maxLineBytes = 1 << 20
+
+// errLineTooLong is returned for response lines longer than maxLineBytes
+var errLineTooLong = errors.New("imap: response line too long")
+
+// Precompiled patterns of server responses
+var (
+	// reLiteral matches the size of the literal that ends a line: {123}
+	reLiteral = regexp.MustCompile(`\{(\d+)\+?\}$`)
+	// reFetchUID, reFetchSize and reInternalDate match the attributes of a FETCH
+	reFetchUID     = regexp.MustCompile(`\bUID (\d+)`)
+	reFetchSize    = regexp.MustCompile(`\bRFC822\.SIZE (\d+)`)
+	reInternalDate = regexp.MustCompile(`\bINTERNALDATE "([^"]+)"`)
+)
+
+// internalDateLayout is the layout of INTERNALDATE, whose day may be padded
+// with a space
+const internalDateLayout = "_2-Jan-2006 15:04:05 -0700"
+
+// imapError is a NO or BAD answer to a command
+type imapError struct {
+	command string
+	answer  string
+}
+
+func (e *imapError) Error() string {
+	return fmt.Sprintf("imap: %s: %s", e.command, e.answer)
+}
+
+// response is an untagged response of the server, with the literals it
+// carries. Its text keeps the {n} markers in place of the literals.
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// messageInfo is what the poller needs to know of a message before fetching
+// it
+type messageInfo struct {
+	uid  uint32
+	size int64
+	date time.Time
+}
+
+// conn is a connection to an IMAP server, speaking the subset of IMAP4rev1
+// (RFC 3501) the poller needs. It is not safe for concurrent use.
+type conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	tag     int
+	caps    map[string]bool
+	timeout time.Duration
+}
+
+// dial connects to the IMAP server of account and reads its greeting
+func dial(ctx context.Context, account Account, tlsConfig *tls.Config, timeout time.Duration) (*conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var nc net.Conn
+	var err error
+	if account.Insecure {
+		nc, err = dialer.DialContext(ctx, "tcp", account.Server)
+	} else {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(account.Server)
+		}
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", account.Server)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imap: %w", err)
+	}
+
+	c := &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc), timeout: timeout}
+	// Closing the connection interrupts reads when ctx is done
+	stop := context.AfterFunc(ctx, func() { nc.Close() })
+	defer stop()
+
+	nc.SetDeadline(time.Now().Add(timeout))
+	greeting, err := c.readResponse()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		nc.Close()
+		return nil, fmt.Errorf("imap: unexpected greeting: %s", greeting.text)
+	}
+	return c, nil
+}
+
+// close logs out, and closes the connection
+func (c *conn) close() error {
+	c.command(context.Background(), "LOGOUT")
+	return c.nc.Close()
+}
+
+// command sends a command and returns its untagged responses, or an
+// *imapError if the server did not answer OK
+func (c *conn) command(ctx context.Context, format string, args ...any) ([]*response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.nc.SetDeadline(time.Now().Add(c.timeout))
+	// A deadline in the past interrupts the command when ctx is done
+	stop := context.AfterFunc(ctx, func() { c.nc.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	c.tag++
+	tag := "A" + strconv.Itoa(c.tag)
+	command := fmt.Sprintf(format, args...)
+	if _, err := c.w.WriteString(tag + " " + command + "\r\n"); err != nil {
+		return nil, c.err(ctx, err)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, c.err(ctx, err)
+	}
+
+	// Passwords are left out of errors
+	words := strings.Fields(command)
+	name := words[0]
+	if name == "UID" && len(words) > 1 {
+		name += " " + words[1]
+	}
+	var responses []*response
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, c.err(ctx, err)
+		}
+		if status, ok := strings.CutPrefix(resp.text, tag+" "); ok {
+			if strings.HasPrefix(status, "OK") {
+				return responses, nil
+			}
+			return nil, &imapError{command: name, answer: status}
+		}
+		if strings.HasPrefix(resp.text, "+") {
+			return nil, fmt.Errorf("imap: %s: unexpected continuation request", name)
+		}
+		responses = append(responses, resp)
+	}
+}
+
+// err returns the error of a failed read or write, ctx's error if ctx is done
+func (c *conn) err(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("imap: %w", err)
+}
+
+// readResponse reads a response line, and the literals it announces
+func (c *conn) readResponse() (*response, error) {
+	resp := &response{}
+	var text strings.Builder
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		text.WriteString(line)
+		m := reLiteral.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || n > maxMessageBytes {
+			return nil, fmt.Errorf("imap: literal of %s bytes too large", m[1])
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return nil, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+	resp.text = text.String()
+	return resp, nil
+}
+
+// readLine reads a line, without its CRLF
+func (c *conn) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := c.r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxLineBytes {
+			return "", errLineTooLong
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+// login authenticates, and reads the capabilities of the server
+func (c *conn) login(ctx context.Context, username, password string) error {
+	user, err := quote(username)
+	if err != nil {
+		return err
+	}
+	pass, err := quote(password)
+	if err != nil {
+		return err
+	}
+	if _, err := c.command(ctx, "LOGIN %s %s", user, pass); err != nil {
+		return err
+	}
+
+	responses, err := c.command(ctx, "CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = make(map[string]bool)
+	for _, resp := range responses {
+		if caps, ok := strings.CutPrefix(resp.text, "* CAPABILITY "); ok {
+			for _, capability := range strings.Fields(caps) {
+				c.caps[strings.ToUpper(capability)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// selectMailbox opens a mailbox for reading and writing
+func (c *conn) selectMailbox(ctx context.Context, name string) error {
+	mailbox, err := quote(name)
+	if err != nil {
+		return err
+	}
+	_, err = c.command(ctx, "SELECT %s", mailbox)
+	return err
+}
+
+// create creates a mailbox, if it does not exist
+func (c *conn) create(ctx context.Context, name string) error {
+	mailbox, err := quote(name)
+	if err != nil {
+		return err
+	}
+	// CREATE fails for mailboxes that exist, so they are listed first
+	responses, err := c.command(ctx, "LIST \"\" %s", mailbox)
+	if err != nil {
+		return err
+	}
+	if len(responses) > 0 {
+		return nil
+	}
+	_, err = c.command(ctx, "CREATE %s", mailbox)
+	return err
+}
+
+// searchUnseen returns the UIDs of the messages not seen yet, oldest first
+func (c *conn) searchUnseen(ctx context.Context) ([]uint32, error) {
+	responses, err := c.command(ctx, "UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		numbers, ok := strings.CutPrefix(resp.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, number := range strings.Fields(numbers) {
+			if uid, err := strconv.ParseUint(number, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// info returns the size and date of messages
+func (c *conn) info(ctx context.Context, uids []uint32) ([]messageInfo, error) {
+	responses, err := c.command(ctx, "UID FETCH %s (UID RFC822.SIZE INTERNALDATE)", uidSet(uids))
+	if err != nil {
+		return nil, err
+	}
+	var infos []messageInfo
+	for _, resp := range responses {
+		m := reFetchUID.FindStringSubmatch(resp.text)
+		if m == nil || !strings.Contains(resp.text, " FETCH ") {
+			continue
+		}
+		uid, _ := strconv.ParseUint(m[1], 10, 32)
+		info := messageInfo{uid: uint32(uid)}
+		if m := reFetchSize.FindStringSubmatch(resp.text); m != nil {
+			info.size, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		if m := reInternalDate.FindStringSubmatch(resp.text); m != nil {
+			info.date, _ = time.Parse(internalDateLayout, m[1])
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// fetch returns a message, without marking it seen
+func (c *conn) fetch(ctx context.Context, uid uint32) ([]byte, error) {
+	responses, err := c.command(ctx, "UID FETCH %d (UID BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(resp.text, "BODY[]") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not found", uid)
+}
+
+// markSeen flags a message seen
+func (c *conn) markSeen(ctx context.Context, uid uint32) error {
+	_, err := c.command(ctx, `UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+// move moves a message to another mailbox, with MOVE (RFC 6851) where the
+// server supports it
+func (c *conn) move(ctx context.Context, uid uint32, name string) error {
+	mailbox, err := quote(name)
+	if err != nil {
+		return err
+	}
+	if c.caps["MOVE"] {
+		_, err := c.command(ctx, "UID MOVE %d %s", uid, mailbox)
+		return err
+	}
+	if _, err := c.command(ctx, "UID COPY %d %s", uid, mailbox); err != nil {
+		return err
+	}
+	if _, err := c.command(ctx, `UID STORE %d +FLAGS.SILENT (\Deleted)`, uid); err != nil {
+		return err
+	}
+	if c.caps["UIDPLUS"] {
+		_, err = c.command(ctx, "UID EXPUNGE %d", uid)
+		return err
+	}
+	// Without UIDPLUS, this also expunges the other messages flagged deleted
+	_, err = c.command(ctx, "EXPUNGE")
+	return err
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n\x00") {
+		return "", errors.New("imap: line breaks are not allowed in arguments")
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`, nil
+}
+
+// uidSet returns the set of uids in a command: 1,5,7
+func uidSet(uids []uint32) string {
+	numbers := make([]string, len(uids))
+	for i, uid := range uids {
+		numbers[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+	return strings.Join(numbers, ",")
+}
@@ -0,0 +1,314 @@
+package mailbox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// step is a step of a transcript: a line the client sends, or the bytes the
+// server answers with
+type step struct {
+	client string
+	server string
+}
+
+// readTranscript reads an IMAP session of testdata. Lines the client sends
+// start with "C: " and lines of the server with "S: ", and are sent with a
+// CRLF; lines starting with "#" are comments.
+func readTranscript(t *testing.T, name string) []step {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []step
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "C: "):
+			steps = append(steps, step{client: strings.TrimPrefix(line, "C: ")})
+		case line == "S:" || strings.HasPrefix(line, "S: "):
+			text := strings.TrimPrefix(strings.TrimPrefix(line, "S:"), " ") + "\r\n"
+			if n := len(steps); n > 0 && steps[n-1].client == "" {
+				steps[n-1].server += text
+			} else {
+				steps = append(steps, step{server: text})
+			}
+		default:
+			t.Fatalf("%s: unexpected line %q", name, line)
+		}
+	}
+	return steps
+}
+
+// replay serves the session of a transcript to a connection, failing the
+// test when the client's commands differ from the transcript's. The
+// connection is closed at the end of the test.
+func replay(t *testing.T, name string) *conn {
+	t.Helper()
+	steps := readTranscript(t, name)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nc, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer nc.Close()
+		r := bufio.NewReader(nc)
+		for _, step := range steps {
+			if step.server != "" {
+				if _, err := nc.Write([]byte(step.server)); err != nil {
+					t.Errorf("%s: %v", name, err)
+					return
+				}
+				continue
+			}
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Errorf("%s: client stopped before %q: %v", name, step.client, err)
+				return
+			}
+			if line = strings.TrimRight(line, "\r\n"); line != step.client {
+				t.Errorf("%s: client sent %q, want %q", name, line, step.client)
+				return
+			}
+		}
+	}()
+
+	c, err := dial(context.Background(), Account{Server: listener.Addr().String(), Insecure: true}, nil, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.nc.Close()
+		<-done
+	})
+	return c
+}
+
+func TestSession(t *testing.T) {
+	c := replay(t, "session.imap")
+	ctx := context.Background()
+
+	if err := c.login(ctx, "payments@example.com", `pa"ss\word`); err != nil {
+		t.Fatal(err)
+	}
+	if !c.caps["MOVE"] || !c.caps["UIDPLUS"] {
+		t.Errorf("capabilities = %v", c.caps)
+	}
+	// The mailbox is listed, as a literal, so it is not created
+	if err := c.create(ctx, "Verified"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.selectMailbox(ctx, "INBOX"); err != nil {
+		t.Fatal(err)
+	}
+
+	uids, err := c.searchUnseen(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(uids, []uint32{7, 9}) {
+		t.Fatalf("unseen = %v, want [7 9]", uids)
+	}
+	infos, err := c.info(ctx, uids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []messageInfo{
+		{uid: 7, size: 1191, date: time.Date(2025, 9, 5, 10, 30, 2, 0, time.FixedZone("", 3*60*60))},
+		{uid: 9, size: 31457280, date: time.Date(2025, 9, 12, 8, 0, 0, 0, time.UTC)},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("info = %+v, want %+v", infos, want)
+	}
+	for i := range want {
+		if infos[i].uid != want[i].uid || infos[i].size != want[i].size || !infos[i].date.Equal(want[i].date) {
+			t.Errorf("info = %+v, want %+v", infos[i], want[i])
+		}
+	}
+
+	raw, err := c.fetch(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 1191 || !strings.HasPrefix(string(raw), "From: ") || !strings.HasSuffix(string(raw), "--outer--\r\n") {
+		t.Errorf("fetched %d bytes: %q...", len(raw), raw[:min(len(raw), 40)])
+	}
+	checkEmail(t, raw)
+
+	if err := c.markSeen(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.move(ctx, 7, "Verified"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// checkEmail checks the email fetched in session.imap
+func checkEmail(t *testing.T, raw []byte) {
+	t.Helper()
+	email, err := parseEmail(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subject is folded, and its second line an encoded word
+	if email.Subject != "Payment for order 1042 — receipt" {
+		t.Errorf("Subject = %q", email.Subject)
+	}
+	if email.From != "abebe@example.com" || email.MessageID != "20250905.1042@example.com" {
+		t.Errorf("From = %q, MessageID = %q", email.From, email.MessageID)
+	}
+	if !email.Date.Equal(time.Date(2025, 9, 5, 7, 30, 0, 0, time.UTC)) {
+		t.Errorf("Date = %v", email.Date)
+	}
+	// The quoted-printable soft line break joins the reference
+	if !strings.Contains(email.Text, "The reference is FT24123ABCDE12345678.") {
+		t.Errorf("Text = %q", email.Text)
+	}
+	if !strings.Contains(email.html, "<b>ETB 1,500.00</b>") {
+		t.Errorf("html = %q", email.html)
+	}
+
+	attachments := []Attachment{
+		{Filename: "receipt.pdf", Data: []byte("%PDF-1.4\n% fake receipt\n")},
+		// The PDF of the forwarded message is sent as application/octet-stream,
+		// quoted-printable
+		{Filename: "FT24123FGHIJ.pdf", Data: []byte("%PDF-1.4\n% forwarded\n")},
+	}
+	if len(email.Attachments) != len(attachments) {
+		t.Fatalf("%d attachments, want %d", len(email.Attachments), len(attachments))
+	}
+	for i, want := range attachments {
+		if got := email.Attachments[i]; got.Filename != want.Filename || string(got.Data) != string(want.Data) {
+			t.Errorf("attachment %d = %s %q, want %s %q", i, got.Filename, got.Data, want.Filename, want.Data)
+		}
+	}
+
+	refs := email.references()
+	if len(refs) != 1 || refs[0].id != "FT24123ABCDE" || refs[0].suffix != "12345678" {
+		t.Errorf("references = %+v", refs)
+	}
+	if amount := email.claimedAmount(); amount != 1500 {
+		t.Errorf("claimed amount = %v, want 1500", amount)
+	}
+}
+
+func TestMoveWithoutMove(t *testing.T) {
+	tests := []struct {
+		transcript string
+		create     bool
+	}{
+		{"copy.imap", true},
+		{"uidplus.imap", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transcript, func(t *testing.T) {
+			c := replay(t, tt.transcript)
+			ctx := context.Background()
+			if err := c.login(ctx, "payments@example.com", "secret"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.create {
+				if err := c.create(ctx, "Failed"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := c.move(ctx, 9, "Failed"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRefusedCommands(t *testing.T) {
+	c := replay(t, "login-no.imap")
+	err := c.login(context.Background(), "payments@example.com", "wrong-password")
+	var refused *imapError
+	if !errors.As(err, &refused) || refused.answer != "NO [AUTHENTICATIONFAILED] Authentication failed." {
+		t.Fatalf("err = %v, want the NO answer", err)
+	}
+	if strings.Contains(err.Error(), "wrong-password") || !strings.HasPrefix(err.Error(), "imap: LOGIN: NO") {
+		t.Errorf("err = %q, want the command without its arguments", err)
+	}
+
+	c = replay(t, "bad.imap")
+	err = c.selectMailbox(context.Background(), "Bad/Mailbox")
+	if !errors.As(err, &refused) || !strings.HasPrefix(refused.answer, "BAD ") || refused.command != "SELECT" {
+		t.Errorf("err = %v, want the BAD answer", err)
+	}
+	if _, err := c.searchUnseen(context.Background()); err == nil || !strings.Contains(err.Error(), "UID SEARCH: unexpected continuation request") {
+		t.Errorf("err = %v, want an unexpected continuation request", err)
+	}
+}
+
+// serve answers the first connection to a listener with response, and closes
+// it
+func serve(t *testing.T, response string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		nc, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		nc.Write([]byte(response))
+		nc.Close()
+	}()
+	return listener.Addr().String()
+}
+
+func TestInvalidResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"greeting", "* BYE Too many connections\r\n", "unexpected greeting: * BYE Too many connections"},
+		{"literal too large", fmt.Sprintf("* OK {%d}\r\n", maxMessageBytes+1), "too large"},
+		{"line too long", "* OK " + strings.Repeat("x", maxLineBytes) + "\r\n", errLineTooLong.Error()},
+		{"truncated literal", "* OK {10}\r\nshort", "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serve(t, tt.response)
+			_, err := dial(context.Background(), Account{Server: server, Insecure: true}, nil, 5*time.Second)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got, _ := quote(`pa"ss\word`); got != `"pa\"ss\\word"` {
+		t.Errorf("quote = %s", got)
+	}
+	if _, err := quote("INBOX\r\nA2 DELETE INBOX"); err == nil {
+		t.Error("quote accepted a line break")
+	}
+}
@@ -0,0 +1,259 @@
+package mailbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// maxPartDepth limits the nesting of multipart bodies and forwarded messages
+const maxPartDepth = 8
+
+// Precompiled patterns of the text of emails
+var (
+	// reHTMLTag matches the tags of HTML bodies, and the contents of their
+	// styles and scripts
+	reHTMLTag = regexp.MustCompile(`(?is)<(style|script)\b.*?</(?:style|script)>|<[^>]*>`)
+	// reAmount matches an amount tagged with its currency: "ETB 1,500.00",
+	// "1500 birr"
+	reAmount = regexp.MustCompile(`(?i)(?:\b(?:ETB|birr|br\.?)\s*([\d,]*\d(?:\.\d{1,2})?)\b|\b([\d,]*\d(?:\.\d{1,2})?)\s*(?:ETB|birr)\b)`)
+	// reTransactionID matches a reference number without its suffix
+	reTransactionID = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{10}$`)
+	// reSuffix matches the account suffix of a reference
+	reSuffix = regexp.MustCompile(`^\d{8}$`)
+)
+
+// Email is a message read from the mailbox
+type Email struct {
+	// UID is the IMAP UID of the message in the mailbox
+	UID       uint32
+	MessageID string
+	// From is the address of the sender
+	From    string
+	Subject string
+	Date    time.Time
+	// Text is the plain text of the body, or the text of its HTML
+	Text string
+	// Attachments are the PDF attachments, including those of forwarded
+	// messages
+	Attachments []Attachment
+
+	// html is the HTML of the body, whose links may hold references
+	html string
+}
+
+// Attachment is a PDF attached to an email
+type Attachment struct {
+	Filename string
+	Data     []byte
+}
+
+// parseEmail parses a message in the Internet Message Format (RFC 5322)
+func parseEmail(raw []byte) (*Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	email := &Email{
+		MessageID: strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		email.From = from.Address
+	}
+	email.Date, _ = msg.Header.Date()
+
+	var text strings.Builder
+	if err := email.walk(textproto.MIMEHeader(msg.Header), msg.Body, &text, 0); err != nil {
+		return nil, err
+	}
+	email.Text = strings.TrimSpace(text.String())
+	if email.Text == "" && email.html != "" {
+		email.Text = strings.TrimSpace(html.UnescapeString(reHTMLTag.ReplaceAllString(email.html, " ")))
+	}
+	return email, nil
+}
+
+// walk collects the text and PDF attachments of a part of a message
+func (e *Email) walk(header textproto.MIMEHeader, body io.Reader, text *strings.Builder, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	body = decodeTransfer(header.Get("Content-Transfer-Encoding"), body)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		if depth >= maxPartDepth || params["boundary"] == "" {
+			return nil
+		}
+		parts := multipart.NewReader(body, params["boundary"])
+		for {
+			// Raw parts keep their transfer encoding, decoded above for every
+			// kind of part
+			part, err := parts.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := e.walk(part.Header, part, text, depth+1); err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		if depth >= maxPartDepth {
+			return nil
+		}
+		// Forwarded messages are read as part of the email forwarding them
+		msg, err := mail.ReadMessage(body)
+		if err != nil {
+			return nil
+		}
+		return e.walk(textproto.MIMEHeader(msg.Header), msg.Body, text, depth+1)
+	case isPDF(mediaType, filename(header, params)):
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		e.Attachments = append(e.Attachments, Attachment{Filename: filename(header, params), Data: data})
+	case mediaType == "text/plain" && !isAttachment(header):
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		text.Write(data)
+		text.WriteString("\n")
+	case mediaType == "text/html" && !isAttachment(header):
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		e.html += string(data) + "\n"
+	}
+	return nil
+}
+
+// decodeTransfer decodes the Content-Transfer-Encoding of a part
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// The decoder skips line breaks
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// filename returns the file name of a part, from its Content-Disposition or
+// the name parameter of its Content-Type
+func filename(header textproto.MIMEHeader, params map[string]string) string {
+	if _, disposition, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && disposition["filename"] != "" {
+		return path.Base(decodeHeader(disposition["filename"]))
+	}
+	if params["name"] != "" {
+		return path.Base(decodeHeader(params["name"]))
+	}
+	return ""
+}
+
+// isPDF reports whether a part is a PDF: mail clients send some as
+// application/octet-stream, with a .pdf file name
+func isPDF(mediaType, filename string) bool {
+	return mediaType == "application/pdf" || strings.EqualFold(path.Ext(filename), ".pdf")
+}
+
+// isAttachment reports whether a part is an attachment rather than a body
+func isAttachment(header textproto.MIMEHeader) bool {
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	return disposition == "attachment"
+}
+
+// decodeHeader decodes the encoded words (RFC 2047) of a header
+func decodeHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// reference is a transaction named in the text of an email
+type reference struct {
+	id, suffix string
+	// amount is the amount of a forwarded SMS, or zero
+	amount float64
+}
+
+// references returns the CBE transactions named in the text of an email: the
+// reference of a forwarded CBE SMS, and full references and receipt links,
+// e.g. pasted from the SMS. References without their account suffix cannot
+// be verified, and are left out.
+func (e *Email) references() []reference {
+	var refs []reference
+	seen := make(map[string]bool)
+	add := func(ref reference) {
+		if !seen[ref.id] {
+			seen[ref.id] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	if sms, err := cbeverifier.ParseSMS(e.Text); err == nil && sms.TransactionID != "" && sms.Suffix != "" {
+		add(reference{id: sms.TransactionID, suffix: sms.Suffix, amount: sms.Amount})
+	}
+	for _, text := range []string{e.Subject, e.Text, html.UnescapeString(e.html)} {
+		tokens := strings.FieldsFunc(text, func(r rune) bool {
+			return strings.ContainsRune(" \t\r\n<>()[]{}\"',;", r)
+		})
+		for i, token := range tokens {
+			token = strings.TrimRight(token, ".:!?")
+			if id, suffix, err := cbeverifier.SplitReference(token); err == nil {
+				add(reference{id: id, suffix: suffix})
+				continue
+			}
+			// A reference and its suffix, written apart
+			if id := strings.ToUpper(token); reTransactionID.MatchString(id) && i+1 < len(tokens) {
+				if suffix := strings.TrimRight(tokens[i+1], ".:!?"); reSuffix.MatchString(suffix) {
+					add(reference{id: id, suffix: suffix})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// claimedAmount returns the amount the text of an email says was paid, if it
+// names exactly one amount in birr
+func (e *Email) claimedAmount() float64 {
+	var claimed float64
+	for _, m := range reAmount.FindAllStringSubmatch(e.Subject+"\n"+e.Text, -1) {
+		value := m[1] + m[2]
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+		if claimed != 0 && amount != claimed {
+			return 0
+		}
+		claimed = amount
+	}
+	return claimed
+}
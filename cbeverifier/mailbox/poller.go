@@ -0,0 +1,488 @@
+// Package mailbox verifies the CBE receipts customers email to a payments
+// address. A Poller watches an IMAP mailbox, reads the receipt PDFs attached
+// to new emails and the references written in their text (a forwarded CBE
+// SMS, a receipt link or a full reference), verifies each, and files the
+// email in a folder for its outcome.
+//
+// An attached receipt is compared with the amount the email names, if it
+// names exactly one, and otherwise with its own amount, like a receipt
+// dropped in the watch folder of the CLI: it then fails only the checks that
+// do not depend on the expected transaction, such as the receiver account
+// and tampering. A reference is verified against the official receipt
+// fetched from CBE. Set a Resolver to fill the expected transaction from
+// your orders instead, e.g. by the sender's address.
+//
+// Emails are read once: the poller only looks at unseen messages, and marks
+// them seen as it files them. Emails whose receipt could not be fetched from
+// CBE are left unseen, and tried again at the next poll for a day.
+//
+// The package speaks IMAP4rev1 over TLS with the standard library alone.
+// Mailbox names must be ASCII.
+//
+// Example:
+//
+//	poller := mailbox.New(verifier, mailbox.Account{
+//		Server:   "imap.example.com:993",
+//		Username: "payments@example.com",
+//		Password: os.Getenv("IMAP_PASSWORD"),
+//	},
+//		mailbox.WithOptions(cbeverifier.Options{ExpectedReceiverSuffix: "12345678"}),
+//		mailbox.WithFolders("Receipts/Verified", "Receipts/Failed", "Receipts/Review"),
+//	)
+//	if err := poller.Run(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+package mailbox
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Defaults of a Poller
+const (
+	defaultMailbox  = "INBOX"
+	defaultInterval = time.Minute
+	defaultTimeout  = time.Minute
+)
+
+// maxMessageBytes limits the size of the emails read; larger ones are filed
+// as failed without being downloaded
+const maxMessageBytes = 25 << 20
+
+// retryMaxAge is how long emails whose receipts could not be fetched are
+// tried again
+const retryMaxAge = 24 * time.Hour
+
+// SourceBody is the Receipt.Source of a reference found in the text of an
+// email
+const SourceBody = "body"
+
+// Statuses of an email, after the results of its receipts
+const (
+	// StatusVerified is an email whose receipts were all verified
+	StatusVerified = "verified"
+	// StatusFailed is an email with a receipt that failed verification
+	StatusFailed = "failed"
+	// StatusReview is an email without any receipt or reference, or with a
+	// receipt to check manually
+	StatusReview = "review"
+)
+
+// retryable are the errors after which an email is tried again: CBE answering
+// before the receipt is published, and CBE being unreachable
+var retryable = []error{
+	cbeverifier.ErrInvalidPDFResponse,
+	cbeverifier.ErrNetworkError,
+	cbeverifier.ErrUpstreamUnavailable,
+	context.DeadlineExceeded,
+}
+
+// Account is the IMAP account of the mailbox to watch
+type Account struct {
+	// Server is the host and port of the IMAP server (e.g.,
+	// "imap.gmail.com:993")
+	Server   string
+	Username string
+	Password string
+	// Mailbox is the mailbox to watch (default: "INBOX")
+	Mailbox string
+	// Insecure connects without TLS, for servers on localhost only
+	Insecure bool
+}
+
+// Receipt is a receipt or reference found in an email, and its verification
+type Receipt struct {
+	// Source is the file name of the attached PDF, or SourceBody
+	Source string
+	// Transaction is the transaction the receipt was verified against
+	Transaction cbeverifier.Transaction
+	Result      *cbeverifier.VerificationResult
+}
+
+// Result is the outcome of an email
+type Result struct {
+	Email    *Email
+	Receipts []Receipt
+	Status   string
+	// Folder is the folder the email was filed in, or empty if it was left in
+	// the mailbox
+	Folder string
+}
+
+// Handler is called with the outcome of every email, e.g. to mark orders as
+// paid or to answer the sender, before the email is filed
+type Handler func(ctx context.Context, result *Result)
+
+// Resolver returns the expected transaction of a receipt found in an email,
+// e.g. the order of the sender. The transaction it is given has the reference
+// of the receipt, and the amount the email names, if any; a zero Amount in
+// the returned transaction is replaced by the receipt's own.
+type Resolver func(ctx context.Context, email *Email, transaction cbeverifier.Transaction) (cbeverifier.Transaction, error)
+
+// Poller verifies the receipts emailed to an IMAP mailbox
+type Poller struct {
+	verifier  *cbeverifier.Verifier
+	account   Account
+	opts      cbeverifier.Options
+	interval  time.Duration
+	timeout   time.Duration
+	folders   map[string]string
+	handler   Handler
+	resolver  Resolver
+	tlsConfig *tls.Config
+	logger    *slog.Logger
+}
+
+// Option configures a Poller
+type Option func(*Poller)
+
+// WithOptions sets the options of every verification (default:
+// cbeverifier.DefaultOptions()). Set ExpectedReceiverSuffix to your own
+// account, so that receipts of payments to others fail.
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(p *Poller) {
+		p.opts = opts
+	}
+}
+
+// WithInterval sets the delay between polls of Run (default: 1m)
+func WithInterval(d time.Duration) Option {
+	return func(p *Poller) {
+		if d > 0 {
+			p.interval = d
+		}
+	}
+}
+
+// WithTimeout bounds every exchange with the IMAP server (default: 1m)
+func WithTimeout(d time.Duration) Option {
+	return func(p *Poller) {
+		if d > 0 {
+			p.timeout = d
+		}
+	}
+}
+
+// WithFolders moves emails to the folder of their status, created if it does
+// not exist. An empty folder leaves those emails in the mailbox, marked seen,
+// which is the default for all three.
+func WithFolders(verified, failed, review string) Option {
+	return func(p *Poller) {
+		p.folders = map[string]string{
+			StatusVerified: verified,
+			StatusFailed:   failed,
+			StatusReview:   review,
+		}
+	}
+}
+
+// WithHandler calls handler with the outcome of every email
+func WithHandler(handler Handler) Option {
+	return func(p *Poller) {
+		p.handler = handler
+	}
+}
+
+// WithResolver fills the expected transaction of every receipt with resolver
+func WithResolver(resolver Resolver) Option {
+	return func(p *Poller) {
+		p.resolver = resolver
+	}
+}
+
+// WithTLSConfig connects to the IMAP server with config, e.g. to trust a
+// private certificate authority
+func WithTLSConfig(config *tls.Config) Option {
+	return func(p *Poller) {
+		p.tlsConfig = config
+	}
+}
+
+// WithLogger logs the emails handled, and failures to poll, to logger
+// (default: discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Poller) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
+
+// New creates a Poller watching the mailbox of account
+func New(verifier *cbeverifier.Verifier, account Account, options ...Option) *Poller {
+	if account.Mailbox == "" {
+		account.Mailbox = defaultMailbox
+	}
+	p := &Poller{
+		verifier: verifier,
+		account:  account,
+		opts:     cbeverifier.DefaultOptions(),
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		folders:  map[string]string{},
+		logger:   slog.New(slog.DiscardHandler),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Run polls the mailbox every interval until ctx is done, returning
+// ctx.Err(). Failed polls are logged and tried again at the next interval.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			p.logger.WarnContext(ctx, "failed to poll mailbox", "mailbox", p.account.Mailbox, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll connects to the server once, and handles the unseen emails of the
+// mailbox, oldest first
+func (p *Poller) Poll(ctx context.Context) error {
+	c, err := dial(ctx, p.account, p.tlsConfig, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if err := c.login(ctx, p.account.Username, p.account.Password); err != nil {
+		return err
+	}
+	for _, status := range []string{StatusVerified, StatusFailed, StatusReview} {
+		if folder := p.folders[status]; folder != "" {
+			if err := c.create(ctx, folder); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.selectMailbox(ctx, p.account.Mailbox); err != nil {
+		return err
+	}
+
+	uids, err := c.searchUnseen(ctx)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+	infos, err := c.info(ctx, uids)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(infos, func(a, b messageInfo) int {
+		return cmp.Compare(a.uid, b.uid)
+	})
+	for _, info := range infos {
+		if err := p.handle(ctx, c, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle verifies an email and files it, returning the errors of the
+// connection
+func (p *Poller) handle(ctx context.Context, c *conn, info messageInfo) error {
+	var email *Email
+	var status string
+	var receipts []Receipt
+	if info.size > maxMessageBytes {
+		p.logger.WarnContext(ctx, "email too large", "uid", info.uid, "size", info.size)
+		email, status = &Email{UID: info.uid, Date: info.date}, StatusFailed
+	} else {
+		raw, err := c.fetch(ctx, info.uid)
+		if err != nil {
+			return err
+		}
+		if email, err = parseEmail(raw); err != nil {
+			p.logger.WarnContext(ctx, "failed to parse email", "uid", info.uid, "error", err)
+			email = &Email{UID: info.uid, Date: info.date}
+		}
+		email.UID = info.uid
+		if email.Date.IsZero() {
+			email.Date = info.date
+		}
+
+		receipts = p.verify(ctx, email)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		status = emailStatus(receipts)
+		// Emails whose receipt is not available yet are left unseen
+		if status == StatusFailed && retry(receipts) && time.Since(info.date) < retryMaxAge {
+			p.logger.InfoContext(ctx, "email left for the next poll", "uid", info.uid)
+			return nil
+		}
+	}
+
+	result := &Result{Email: email, Receipts: receipts, Status: status, Folder: p.folders[status]}
+	p.logger.InfoContext(ctx, "email handled", "uid", info.uid, "status", status, "receipts", len(receipts), "folder", result.Folder)
+	if p.handler != nil {
+		p.handler(ctx, result)
+	}
+
+	if err := c.markSeen(ctx, info.uid); err != nil {
+		return err
+	}
+	if result.Folder != "" {
+		return c.move(ctx, info.uid, result.Folder)
+	}
+	return nil
+}
+
+// verify verifies the receipts attached to an email and the references in
+// its text
+func (p *Poller) verify(ctx context.Context, email *Email) []Receipt {
+	type found struct {
+		source      string
+		transaction cbeverifier.Transaction
+		pdf         []byte
+		details     *cbeverifier.TransactionDetails
+		err         error
+	}
+	var items []found
+
+	// A reference is verified against the official receipt, in place of the
+	// attachment of the same transaction
+	refs := email.references()
+	byID := make(map[string]bool)
+	for _, ref := range refs {
+		byID[ref.id] = true
+	}
+	for _, attachment := range email.Attachments {
+		details, _, err := p.verifier.Lookup(ctx, cbeverifier.Transaction{}, attachment.Data, p.opts)
+		if err == nil && byID[details.TransactionID] {
+			continue
+		}
+		item := found{source: attachment.Filename, pdf: attachment.Data, details: details, err: err}
+		if err == nil {
+			item.transaction = cbeverifier.Transaction{ID: details.TransactionID, Currency: details.Currency}
+		}
+		items = append(items, item)
+	}
+	for _, ref := range refs {
+		items = append(items, found{
+			source:      SourceBody,
+			transaction: cbeverifier.Transaction{ID: ref.id, Suffix: ref.suffix, Amount: ref.amount},
+		})
+	}
+
+	// The amount the email names is only that of its receipt if it has one
+	claimed := 0.0
+	if len(items) == 1 {
+		claimed = email.claimedAmount()
+	}
+	receipts := make([]Receipt, 0, len(items))
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		receipt := Receipt{Source: item.source, Transaction: item.transaction}
+		if item.err != nil {
+			receipt.Result = failed(fmt.Errorf("invalid receipt: %w", item.err))
+			receipts = append(receipts, receipt)
+			continue
+		}
+		if receipt.Transaction.Amount == 0 {
+			receipt.Transaction.Amount = claimed
+		}
+		receipt.Transaction, receipt.Result = p.verifyReceipt(ctx, email, receipt.Transaction, item.pdf, item.details)
+		receipts = append(receipts, receipt)
+	}
+	return receipts
+}
+
+// verifyReceipt verifies a transaction against an attached receipt, parsed as
+// details, or against the official receipt if pdf is nil
+func (p *Poller) verifyReceipt(ctx context.Context, email *Email, transaction cbeverifier.Transaction, pdf []byte, details *cbeverifier.TransactionDetails) (cbeverifier.Transaction, *cbeverifier.VerificationResult) {
+	if p.resolver != nil {
+		resolved, err := p.resolver(ctx, email, transaction)
+		if err != nil {
+			return transaction, failed(fmt.Errorf("resolve transaction: %w", err))
+		}
+		transaction = resolved
+	}
+
+	if pdf == nil {
+		if transaction.Amount != 0 {
+			result, err := p.verifier.Verify(ctx, transaction, p.opts)
+			if err != nil {
+				return transaction, failed(err)
+			}
+			return transaction, result
+		}
+		// Without an amount, the official receipt is compared with its own
+		var err error
+		if details, pdf, err = p.verifier.Lookup(ctx, transaction, nil, p.opts); err != nil {
+			return transaction, failed(err)
+		}
+	}
+	if transaction.Amount == 0 {
+		transaction.Amount = details.Amount
+	}
+	result, err := p.verifier.VerifyPDF(ctx, pdf, transaction, p.opts)
+	if err != nil {
+		return transaction, failed(err)
+	}
+	return transaction, result
+}
+
+// failed returns the result of a receipt that could not be verified
+func failed(err error) *cbeverifier.VerificationResult {
+	return cbeverifier.NewErrorResult(err)
+}
+
+// emailStatus returns the status of an email after its receipts
+func emailStatus(receipts []Receipt) string {
+	if len(receipts) == 0 {
+		return StatusReview
+	}
+	status := StatusVerified
+	for _, receipt := range receipts {
+		switch {
+		case receipt.Result.IsValid:
+		case receipt.Result.NeedsReview && len(receipt.Result.Mismatches) == 0:
+			if status == StatusVerified {
+				status = StatusReview
+			}
+		default:
+			return StatusFailed
+		}
+	}
+	return status
+}
+
+// retry reports whether the receipts of an email that failed all failed with
+// a retryable error, whose message starts the message of the result
+func retry(receipts []Receipt) bool {
+	failures := 0
+	for _, receipt := range receipts {
+		result := receipt.Result
+		if result.IsValid || result.NeedsReview && len(result.Mismatches) == 0 {
+			continue
+		}
+		failures++
+		if len(result.Mismatches) > 0 || !slices.ContainsFunc(retryable, func(err error) bool {
+			return strings.HasPrefix(result.Error, err.Error())
+		}) {
+			return false
+		}
+	}
+	return failures > 0
+}
@@ -0,0 +1,79 @@
+package cbeverifier
+
+import "time"
+
+// Verification outcomes reported to Metrics
+const (
+	OutcomeVerified = "verified"
+	OutcomeMismatch = "mismatch"
+	OutcomeError    = "error"
+	OutcomeReview   = "review"
+)
+
+// Upstream error kinds reported to Metrics
+const (
+	UpstreamNetwork     = "network"
+	UpstreamServerError = "server_error"
+	UpstreamBreakerOpen = "breaker_open"
+)
+
+// Metrics receives instrumentation events from a Verifier.
+//
+// Implementations must be safe for concurrent use. A Prometheus
+// implementation typically maps each method onto a counter or histogram:
+//
+//	type promMetrics struct {
+//		verifications *prometheus.CounterVec   // labels: outcome
+//		mismatches    *prometheus.CounterVec   // labels: field
+//		upstream      *prometheus.CounterVec   // labels: kind
+//		fetch, parse  prometheus.Histogram
+//	}
+//
+//	func (m *promMetrics) Verification(outcome string, d time.Duration) {
+//		m.verifications.WithLabelValues(outcome).Inc()
+//	}
+type Metrics interface {
+	// Verification is called once per Verify or VerifyPDF call with its outcome
+	// (OutcomeVerified, OutcomeMismatch or OutcomeError) and total duration
+	Verification(outcome string, duration time.Duration)
+	// Mismatch is called for every required field that did not match
+	Mismatch(field string)
+	// UpstreamError is called when fetching from CBE fails
+	// (UpstreamNetwork, UpstreamServerError or UpstreamBreakerOpen)
+	UpstreamError(kind string)
+	// FetchDuration records how long a receipt download took
+	FetchDuration(duration time.Duration)
+	// ParseDuration records how long parsing a receipt took
+	ParseDuration(duration time.Duration)
+}
+
+// nopMetrics discards all events
+type nopMetrics struct{}
+
+func (nopMetrics) Verification(string, time.Duration) {}
+func (nopMetrics) Mismatch(string)                    {}
+func (nopMetrics) UpstreamError(string)               {}
+func (nopMetrics) FetchDuration(time.Duration)        {}
+func (nopMetrics) ParseDuration(time.Duration)        {}
+
+// resultOutcome classifies a verification result for metrics
+func resultOutcome(result *VerificationResult) string {
+	switch {
+	case result.IsValid:
+		return OutcomeVerified
+	case len(result.Mismatches) > 0:
+		return OutcomeMismatch
+	case result.NeedsReview:
+		return OutcomeReview
+	default:
+		return OutcomeError
+	}
+}
+
+// recordResult reports a completed verification to the metrics sink
+func (v *Verifier) recordResult(result *VerificationResult, duration time.Duration) {
+	v.metrics.Verification(resultOutcome(result), duration)
+	for field := range result.Mismatches {
+		v.metrics.Mismatch(field)
+	}
+}
@@ -0,0 +1,255 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxMiddlewareBodyBytes limits the request bodies read by Middleware
+const maxMiddlewareBodyBytes = 1 << 20
+
+// MiddlewareConfig configures Middleware
+type MiddlewareConfig struct {
+	// Verifier verifies the transactions (default: the package's shared
+	// Verifier)
+	Verifier *Verifier
+	// Options are the options of every verification
+	Options Options
+	// Path is the route whose POST requests are verified (e.g.,
+	// "/orders/confirm-payment"). Other requests are passed to the next handler
+	// as they are. When empty, every POST request is verified.
+	Path string
+	// ReferenceField, SuffixField, AmountField and ProviderField name the form
+	// or top-level JSON fields holding the transaction (default: "reference",
+	// "suffix", "amount" and "provider"). A reference without a suffix is
+	// verified as a full reference or receipt link.
+	ReferenceField string
+	SuffixField    string
+	AmountField    string
+	ProviderField  string
+	// Extract reads the transaction of a request instead of the fields above
+	Extract func(r *http.Request) (Transaction, error)
+	// AllowInvalid passes requests whose transaction did not verify to the next
+	// handler, which finds the result with ResultFromContext, instead of
+	// rejecting them
+	AllowInvalid bool
+	// OnReject responds to rejected requests: result is the verification that
+	// failed, with its error in err, or nil when no transaction could be read or
+	// verified (default: a JSON error with status 400 or 502, or the result with
+	// status 402 when the payment does not match)
+	OnReject func(w http.ResponseWriter, r *http.Request, result *VerificationResult, err error)
+}
+
+// middlewareContextKey is the context key of the verification of a request
+type middlewareContextKey struct{}
+
+// middlewareVerification is the verification of a request, as stored in its
+// context
+type middlewareVerification struct {
+	transaction Transaction
+	result      *VerificationResult
+}
+
+// Middleware verifies the payment claimed by requests to cfg.Path before they
+// reach next, so that handlers only mark orders paid for verified receipts. The
+// reference and amount are read from the request's form or JSON body, which is
+// left for next to read again; next finds the transaction and its result with
+// TransactionFromContext and ResultFromContext. Requests whose transaction
+// does not verify are rejected unless cfg.AllowInvalid is set.
+//
+// Example:
+//
+//	mux.Handle("/orders/confirm-payment", cbeverifier.Middleware(http.HandlerFunc(markPaid), cbeverifier.MiddlewareConfig{
+//		Path:    "/orders/confirm-payment",
+//		Options: cbeverifier.Options{ExpectedReceiverSuffix: "12345678"},
+//	}))
+//
+//	func markPaid(w http.ResponseWriter, r *http.Request) {
+//		result, _ := cbeverifier.ResultFromContext(r.Context())
+//		orders.MarkPaid(r.FormValue("order_id"), result.Details.TransactionID)
+//	}
+func Middleware(next http.Handler, cfg MiddlewareConfig) http.Handler {
+	if cfg.Verifier == nil {
+		cfg.Verifier = defaultVerifier
+	}
+	if cfg.Extract == nil {
+		cfg.Extract = cfg.extract
+	}
+	if cfg.OnReject == nil {
+		cfg.OnReject = rejectPayment
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || (cfg.Path != "" && r.URL.Path != cfg.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		transaction, err := cfg.Extract(r)
+		if err != nil {
+			cfg.OnReject(w, r, nil, err)
+			return
+		}
+		result, err := cfg.Verifier.Verify(r.Context(), transaction, cfg.Options)
+		if err != nil {
+			cfg.OnReject(w, r, nil, err)
+			return
+		}
+		if !result.IsValid && !cfg.AllowInvalid {
+			cfg.OnReject(w, r, result, result.Err())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), middlewareContextKey{}, middlewareVerification{transaction, result})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ResultFromContext returns the verification result of a request that passed
+// through Middleware
+func ResultFromContext(ctx context.Context) (*VerificationResult, bool) {
+	verification, ok := ctx.Value(middlewareContextKey{}).(middlewareVerification)
+	return verification.result, ok
+}
+
+// TransactionFromContext returns the transaction Middleware read from a
+// request
+func TransactionFromContext(ctx context.Context) (Transaction, bool) {
+	verification, ok := ctx.Value(middlewareContextKey{}).(middlewareVerification)
+	return verification.transaction, ok
+}
+
+// extract reads the transaction of a request from its form or JSON body,
+// restoring the body for the next handler
+func (cfg MiddlewareConfig) extract(r *http.Request) (Transaction, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var fields map[string]string
+	switch mediaType {
+	case "multipart/form-data":
+		// The parsed form stays available to the next handler
+		if err := r.ParseMultipartForm(maxMiddlewareBodyBytes); err != nil {
+			return Transaction{}, fmt.Errorf("invalid form: %w", err)
+		}
+		fields = formFields(r.MultipartForm.Value)
+	default:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxMiddlewareBodyBytes+1))
+		if err != nil {
+			return Transaction{}, err
+		}
+		if len(body) > maxMiddlewareBodyBytes {
+			return Transaction{}, errors.New("request body too large")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if mediaType == "application/json" {
+			fields, err = jsonFields(body)
+		} else {
+			var values url.Values
+			values, err = url.ParseQuery(string(body))
+			fields = formFields(values)
+		}
+		if err != nil {
+			return Transaction{}, fmt.Errorf("invalid request body: %w", err)
+		}
+	}
+
+	name := func(name, fallback string) string {
+		if name == "" {
+			return fallback
+		}
+		return name
+	}
+	referenceField := name(cfg.ReferenceField, "reference")
+	reference := strings.TrimSpace(fields[referenceField])
+	if reference == "" {
+		return Transaction{}, fmt.Errorf("%w: missing %q field", ErrInvalidReference, referenceField)
+	}
+	rawAmount := strings.TrimSpace(fields[name(cfg.AmountField, "amount")])
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(rawAmount, ",", ""), 64)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("%w: %q", ErrInvalidAmount, rawAmount)
+	}
+
+	transaction := Transaction{
+		Amount:   amount,
+		Provider: strings.TrimSpace(fields[name(cfg.ProviderField, "provider")]),
+	}
+	if suffix := strings.TrimSpace(fields[name(cfg.SuffixField, "suffix")]); suffix != "" {
+		transaction.ID, transaction.Suffix = reference, suffix
+	} else {
+		transaction.FullReference = reference
+	}
+	return transaction, nil
+}
+
+// formFields returns the first value of each form field
+func formFields(values url.Values) map[string]string {
+	fields := make(map[string]string, len(values))
+	for name, value := range values {
+		if len(value) > 0 {
+			fields[name] = value[0]
+		}
+	}
+	return fields
+}
+
+// jsonFields returns the top-level string and number fields of a JSON object
+// as text
+func jsonFields(body []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(object))
+	for name, value := range object {
+		switch v := value.(type) {
+		case string:
+			fields[name] = v
+		case json.Number:
+			fields[name] = v.String()
+		}
+	}
+	return fields, nil
+}
+
+// rejectPayment is the default MiddlewareConfig.OnReject
+func rejectPayment(w http.ResponseWriter, r *http.Request, result *VerificationResult, err error) {
+	status := rejectStatus(result, err)
+	var body any = result
+	if status != http.StatusPaymentRequired {
+		body = map[string]string{"error": err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// rejectStatus returns the status of a rejected request: 502 when CBE could not
+// be reached, 400 when the request names no valid transaction, and 402 when the
+// receipt does not prove the payment
+func rejectStatus(result *VerificationResult, err error) int {
+	switch {
+	case errors.Is(err, ErrNetworkError), errors.Is(err, ErrUpstreamUnavailable), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusBadGateway
+	case result == nil,
+		errors.Is(err, ErrInvalidTransactionID), errors.Is(err, ErrInvalidSuffix),
+		errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrInvalidReference),
+		errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrAmbiguousReference),
+		errors.Is(err, ErrFetchNotSupported):
+		return http.StatusBadRequest
+	}
+	return http.StatusPaymentRequired
+}
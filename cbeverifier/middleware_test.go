@@ -0,0 +1,172 @@
+package cbeverifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// middlewareProvider issues receipts of 1500 ETB, naming their reference
+type middlewareProvider struct{}
+
+func init() {
+	Register("middlewaretest", middlewareProvider{})
+}
+
+func (middlewareProvider) Fetch(_ context.Context, transaction Transaction, _ Options) ([]byte, error) {
+	return []byte(transaction.ID), nil
+}
+
+func (middlewareProvider) Parse(_ context.Context, receipt []byte, _ Options) (*TransactionDetails, error) {
+	return &TransactionDetails{TransactionID: string(receipt), Amount: 1500, Currency: "ETB"}, nil
+}
+
+func (middlewareProvider) Compare(transaction Transaction, details *TransactionDetails, opts Options) *VerificationResult {
+	return CompareDetails(transaction, details, opts)
+}
+
+// downTransport fails every request, as when CBE is unreachable
+type downTransport struct{}
+
+func (downTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		form   url.Values
+		json   string
+		status int
+		// error is a part of the error of the response, if it is not the result
+		error string
+	}{
+		{
+			name:   "verified",
+			form:   url.Values{"reference": {"T1"}, "amount": {"1,500.00"}, "provider": {"middlewaretest"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "verified JSON",
+			json:   `{"reference": "T1", "amount": 1500, "provider": "middlewaretest"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "amount mismatch",
+			form:   url.Values{"reference": {"T1"}, "amount": {"2000"}, "provider": {"middlewaretest"}},
+			status: http.StatusPaymentRequired,
+		},
+		{
+			name:   "missing reference",
+			form:   url.Values{"amount": {"1500"}},
+			status: http.StatusBadRequest,
+			error:  `missing "reference" field`,
+		},
+		{
+			name:   "invalid amount",
+			form:   url.Values{"reference": {"T1"}, "amount": {"much"}},
+			status: http.StatusBadRequest,
+			error:  ErrInvalidAmount.Error(),
+		},
+		{
+			// The amount is read, but rejected by the verification
+			name:   "zero amount",
+			form:   url.Values{"reference": {"FT24123ABCDE"}, "suffix": {"12345678"}, "amount": {"0"}},
+			status: http.StatusBadRequest,
+			error:  ErrInvalidAmount.Error(),
+		},
+		{
+			name:   "malformed full reference",
+			form:   url.Values{"reference": {"FT1"}, "amount": {"1500"}},
+			status: http.StatusBadRequest,
+			error:  ErrInvalidReference.Error(),
+		},
+		{
+			name:   "unknown provider",
+			form:   url.Values{"reference": {"T1"}, "amount": {"1500"}, "provider": {"nobank"}},
+			status: http.StatusBadRequest,
+			error:  ErrUnknownProvider.Error(),
+		},
+		{
+			name:   "CBE unreachable",
+			form:   url.Values{"reference": {"FT24123ABCDE"}, "suffix": {"12345678"}, "amount": {"1500"}},
+			status: http.StatusBadGateway,
+			error:  ErrNetworkError.Error(),
+		},
+	}
+
+	verifier := New(WithHTTPClient(&http.Client{Transport: downTransport{}}))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, ok := ResultFromContext(r.Context())
+		if !ok || !result.IsValid {
+			t.Errorf("next handler got result %+v", result)
+		}
+		// The body is left for the handler
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	handler := Middleware(next, MiddlewareConfig{Verifier: verifier, Path: "/confirm", Options: DefaultOptions()})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := tt.form.Encode(), "application/x-www-form-urlencoded"
+			if tt.json != "" {
+				body, contentType = tt.json, "application/json"
+			}
+			r := httptest.NewRequest(http.MethodPost, "/confirm", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			switch tt.status {
+			case http.StatusOK:
+				if w.Body.String() != body {
+					t.Errorf("next handler read %q, want %q", w.Body, body)
+				}
+			case http.StatusPaymentRequired:
+				var result VerificationResult
+				if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.IsValid || result.Mismatches["amount"] == nil {
+					t.Errorf("body %s, want the result with an amount mismatch", w.Body)
+				}
+			default:
+				var response map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || !strings.Contains(response["error"], tt.error) {
+					t.Errorf("body %s, want an error containing %q", w.Body, tt.error)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareAllowInvalid(t *testing.T) {
+	var got *VerificationResult
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ResultFromContext(r.Context())
+	})
+	handler := Middleware(next, MiddlewareConfig{AllowInvalid: true})
+
+	r := httptest.NewRequest(http.MethodPost, "/confirm", strings.NewReader("reference=T1&amount=2000&provider=middlewaretest"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || got == nil || got.IsValid {
+		t.Errorf("status %d, result %+v, want the invalid result passed on", w.Code, got)
+	}
+
+	// Other requests are passed on without a verification
+	got = nil
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/confirm", nil))
+	if w.Code != http.StatusOK || got != nil {
+		t.Errorf("GET: status %d, result %+v", w.Code, got)
+	}
+}
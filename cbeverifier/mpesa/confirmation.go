@@ -0,0 +1,86 @@
+package mpesa
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// maxConfirmationBytes bounds the size of a posted confirmation
+const maxConfirmationBytes = 64 << 10
+
+// Confirmation is the C2B confirmation Safaricom posts to the merchant's
+// confirmation URL for every completed payment
+type Confirmation struct {
+	TransactionType   string `json:"TransactionType"`
+	TransID           string `json:"TransID"`
+	TransTime         string `json:"TransTime"`
+	TransAmount       string `json:"TransAmount"`
+	BusinessShortCode string `json:"BusinessShortCode"`
+	BillRefNumber     string `json:"BillRefNumber"`
+	InvoiceNumber     string `json:"InvoiceNumber"`
+	OrgAccountBalance string `json:"OrgAccountBalance"`
+	ThirdPartyTransID string `json:"ThirdPartyTransID"`
+	MSISDN            string `json:"MSISDN"`
+	FirstName         string `json:"FirstName"`
+	MiddleName        string `json:"MiddleName"`
+	LastName          string `json:"LastName"`
+}
+
+// confirmationResponse is the acknowledgement Safaricom expects from the
+// confirmation URL
+type confirmationResponse struct {
+	ResultCode int    `json:"ResultCode"`
+	ResultDesc string `json:"ResultDesc"`
+}
+
+// ConfirmationHandler returns an http.Handler that receives Safaricom's C2B
+// confirmations into the package's default store
+func ConfirmationHandler() http.Handler {
+	return defaultClient.ConfirmationHandler()
+}
+
+// ConfirmationHandler returns an http.Handler that receives Safaricom's C2B
+// confirmations into the Client's store
+//
+// Safaricom does not sign confirmations, so serve the handler on an unguessable
+// path or restrict it to Safaricom's addresses.
+func (c *Client) ConfirmationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxConfirmationBytes+1))
+		if err != nil || len(body) > maxConfirmationBytes {
+			writeResponse(w, http.StatusBadRequest, confirmationResponse{1, ErrInvalidConfirmation.Error()})
+			return
+		}
+
+		var confirmation Confirmation
+		if err := json.Unmarshal(body, &confirmation); err != nil {
+			writeResponse(w, http.StatusBadRequest, confirmationResponse{1, ErrInvalidConfirmation.Error()})
+			return
+		}
+		code, err := ValidateTransactionCode(confirmation.TransID)
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, confirmationResponse{1, err.Error()})
+			return
+		}
+
+		if err := c.store.Save(r.Context(), code, body); err != nil {
+			writeResponse(w, http.StatusInternalServerError, confirmationResponse{1, "could not store confirmation"})
+			return
+		}
+		writeResponse(w, http.StatusOK, confirmationResponse{0, "Accepted"})
+	})
+}
+
+// writeResponse writes a JSON acknowledgement
+func writeResponse(w http.ResponseWriter, status int, response confirmationResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,215 @@
+// Package mpesa verifies Safaricom M-Pesa Ethiopia payments by their transaction
+// code (e.g., "SJK7ABC12D").
+//
+// M-Pesa has no public receipt page: Safaricom confirms each customer-to-business
+// (C2B) payment by posting a confirmation to the merchant's confirmation URL,
+// registered in the Daraja portal. ConfirmationHandler receives these callbacks
+// and keeps them in a Store, and Verify checks a transaction code against the
+// stored confirmation. The confirmation SMS the customer receives can be
+// verified offline with cbeverifier.VerifyPDF and Provider set to mpesa.Name.
+//
+// Example:
+//
+//	http.Handle("/mpesa/confirmation", mpesa.ConfirmationHandler())
+//
+//	result, err := mpesa.Verify(cbeverifier.Transaction{
+//		ID:     "SJK7ABC12D",
+//		Amount: 500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "mpesa", so
+// cbeverifier.Verify handles transactions whose Provider is mpesa.Name.
+package mpesa
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "mpesa"
+
+// Errors returned by the mpesa package
+var (
+	ErrInvalidTransactionCode = errors.New("invalid M-Pesa transaction code")
+	ErrConfirmationNotFound   = errors.New("M-Pesa confirmation not found")
+	ErrInvalidConfirmation    = errors.New("invalid M-Pesa confirmation")
+)
+
+// reTransactionCode matches a well-formed M-Pesa transaction code (e.g., "SJK7ABC12D")
+var reTransactionCode = regexp.MustCompile(`^[A-Z0-9]{10}$`)
+
+// Store keeps the confirmations Safaricom posts, keyed by transaction code
+//
+// Implementations must be safe for concurrent use. Deployments with more than
+// one instance need a shared store, since Safaricom posts each confirmation to
+// only one of them.
+type Store interface {
+	// Save records the raw confirmation for a transaction code
+	Save(ctx context.Context, code string, confirmation []byte) error
+	// Load returns the raw confirmation for a transaction code, or
+	// ErrConfirmationNotFound if none was received
+	Load(ctx context.Context, code string) ([]byte, error)
+}
+
+// MemoryStore is an in-memory Store. Confirmations are lost when the process
+// exits, so it is best suited to tests and single-instance deployments.
+type MemoryStore struct {
+	mu            sync.RWMutex
+	confirmations map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		confirmations: make(map[string][]byte),
+	}
+}
+
+// Save records the confirmation for a transaction code
+func (s *MemoryStore) Save(_ context.Context, code string, confirmation []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.confirmations[code] = append([]byte(nil), confirmation...)
+	return nil
+}
+
+// Load returns the confirmation for a transaction code
+func (s *MemoryStore) Load(_ context.Context, code string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	confirmation, ok := s.confirmations[code]
+	if !ok {
+		return nil, ErrConfirmationNotFound
+	}
+	return confirmation, nil
+}
+
+// Client receives and verifies M-Pesa confirmations. It is safe for concurrent use.
+type Client struct {
+	store Store
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options. Without WithStore,
+// confirmations are kept in a MemoryStore.
+func New(options ...Option) *Client {
+	c := &Client{}
+	for _, option := range options {
+		option(c)
+	}
+	if c.store == nil {
+		c.store = NewMemoryStore()
+	}
+	return c
+}
+
+// WithStore makes the Client keep confirmations in store
+func WithStore(store Store) Option {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// ValidateTransactionCode returns the normalized transaction code
+//
+// Example:
+//
+//	code, err := mpesa.ValidateTransactionCode(" sjk7abc12d ")
+//	// code = "SJK7ABC12D"
+func ValidateTransactionCode(code string) (string, error) {
+	ref := strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	if !reTransactionCode.MatchString(ref) {
+		return "", ErrInvalidTransactionCode
+	}
+	return ref, nil
+}
+
+// Verify looks up the confirmation Safaricom posted for transaction.ID and
+// verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the transaction code; no suffix is needed
+// 2. Loads the confirmation from the store
+// 3. Parses the confirmation into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify looks up the confirmation and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized transaction code from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	code, err := ValidateTransactionCode(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = code
+	return transaction, nil
+}
+
+// Fetch loads and parses the confirmation for a transaction code without
+// comparing it, returning the parsed details and the raw confirmation
+func Fetch(ctx context.Context, code string) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, code)
+}
+
+// Fetch loads and parses the confirmation for a transaction code
+func (c *Client) Fetch(ctx context.Context, code string) (*cbeverifier.TransactionDetails, []byte, error) {
+	code, err := ValidateTransactionCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confirmation, err := c.store.Load(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(confirmation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, confirmation, nil
+}
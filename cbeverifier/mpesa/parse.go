@@ -0,0 +1,118 @@
+package mpesa
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Confirmation layouts reported in TransactionDetails.FormatVersion
+const (
+	// FormatC2B is the C2B confirmation Safaricom posts to the merchant
+	FormatC2B = "mpesa-c2b"
+	// FormatSMS is the confirmation SMS sent to the customer
+	FormatSMS = "mpesa-sms"
+)
+
+// Patterns for the confirmation SMS, e.g. "SJK7ABC12D Confirmed. ETB500.00 sent
+// to ACME TRADING 2517****5678 on 12/5/25 at 10:22 AM. Transaction cost, ETB2.00."
+var (
+	reSMSCode     = regexp.MustCompile(`(?i)^\s*([A-Z0-9]{10})\s+confirmed\b`)
+	reSMSSent     = regexp.MustCompile(`(?i)(?:ETB|Birr)\s*([\d,]+(?:\.\d{1,2})?)\s+(?:sent|paid)\s+to\s+(.+?)(?:\s+(?:for\s+account\s+\S+|\+?[\d*]{6,}))?\s+on\s+`)
+	reSMSReceived = regexp.MustCompile(`(?i)received\s+(?:ETB|Birr)\s*([\d,]+(?:\.\d{1,2})?)\s+from\s+(.+?)(?:\s+(\+?[\d*]{6,}))?\s+on\s+`)
+	reSMSAccount  = regexp.MustCompile(`(?i)\s(\+?[\d*]{6,})\s+on\s+`)
+	reSMSDate     = regexp.MustCompile(`(?i)\bon\s+(\d{1,2}/\d{1,2}/\d{2,4})\s+at\s+(\d{1,2}:\d{2}(?::\d{2})?\s*(?:[AP]M)?)`)
+	reSMSCost     = regexp.MustCompile(`(?i)transaction\s+cost,?\s*(?:ETB|Birr)\s*([\d,]+(?:\.\d{1,2})?)`)
+)
+
+// dateLayouts are the time formats of C2B confirmations and SMS
+var dateLayouts = []string{
+	"20060102150405",
+	"2/1/06 3:04 PM",
+	"2/1/06 3:04:05 PM",
+	"2/1/2006 3:04 PM",
+	"2/1/06 15:04",
+	"2/1/2006 15:04",
+}
+
+// Parse extracts transaction information from an M-Pesa confirmation: a C2B
+// confirmation as posted by Safaricom (JSON), or the confirmation SMS
+//
+// C2B confirmations identify the payer by name and phone number and the
+// receiver by its business short code only, so the receiver name is empty.
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(confirmation []byte) (*cbeverifier.TransactionDetails, error) {
+	trimmed := bytes.TrimSpace(confirmation)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseC2B(trimmed)
+	}
+	return parseSMS(string(confirmation))
+}
+
+// parseC2B parses a C2B confirmation
+func parseC2B(body []byte) (*cbeverifier.TransactionDetails, error) {
+	var c Confirmation
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, ErrInvalidConfirmation
+	}
+
+	details := &cbeverifier.TransactionDetails{
+		Payer:           strings.Join(strings.Fields(c.FirstName+" "+c.MiddleName+" "+c.LastName), " "),
+		PayerAccount:    c.MSISDN,
+		ReceiverAccount: c.BusinessShortCode,
+		Amount:          webreceipt.Amount(c.TransAmount),
+		Currency:        "ETB",
+		DateRaw:         c.TransTime,
+		TransactionID:   strings.ToUpper(strings.TrimSpace(c.TransID)),
+		Reason:          c.BillRefNumber,
+		Channel:         cbeverifier.ChannelMobile,
+		TransactionType: c.TransactionType,
+		FormatVersion:   FormatC2B,
+	}
+	for name, value := range map[string]string{"invoice_number": c.InvoiceNumber, "third_party_trans_id": c.ThirdPartyTransID} {
+		if value != "" {
+			if details.Extra == nil {
+				details.Extra = make(map[string]string)
+			}
+			details.Extra[name] = value
+		}
+	}
+	return webreceipt.Finish(details, dateLayouts)
+}
+
+// parseSMS parses a confirmation SMS. Payments sent by the customer name the
+// receiver; payments received name the payer.
+func parseSMS(text string) (*cbeverifier.TransactionDetails, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	details := &cbeverifier.TransactionDetails{
+		Currency:      "ETB",
+		Channel:       cbeverifier.ChannelMobile,
+		FormatVersion: FormatSMS,
+	}
+
+	if m := reSMSCode.FindStringSubmatch(text); m != nil {
+		details.TransactionID = strings.ToUpper(m[1])
+	}
+	if m := reSMSReceived.FindStringSubmatch(text); m != nil {
+		details.Amount = webreceipt.Amount(m[1])
+		details.Payer, details.PayerAccount = strings.TrimSpace(m[2]), m[3]
+	} else if m := reSMSSent.FindStringSubmatch(text); m != nil {
+		details.Amount = webreceipt.Amount(m[1])
+		details.Receiver = strings.TrimSpace(m[2])
+		if a := reSMSAccount.FindStringSubmatch(text); a != nil {
+			details.ReceiverAccount = a[1]
+		}
+	}
+	if m := reSMSDate.FindStringSubmatch(text); m != nil {
+		details.DateRaw = m[1] + " " + strings.ToUpper(m[2])
+	}
+	if m := reSMSCost.FindStringSubmatch(text); m != nil {
+		details.ServiceCharge = webreceipt.Amount(m[1])
+	}
+	return webreceipt.Finish(details, dateLayouts)
+}
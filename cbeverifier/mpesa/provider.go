@@ -0,0 +1,67 @@
+package mpesa
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that loads confirmations from
+// client's store, for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch loads the confirmation Safaricom posted for the transaction code
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, _ cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.store.Load(ctx, transaction.ID)
+}
+
+// Parse parses a C2B confirmation or confirmation SMS
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is an M-Pesa transaction code,
+// which mixes letters and digits. Codes have the shape of Telebirr transaction
+// numbers, so cbeverifier.DetectProviders returns both providers for them and
+// their transactions must name the provider.
+func (p provider) DetectReference(reference string) bool {
+	code, err := ValidateTransactionCode(reference)
+	return err == nil && strings.IndexFunc(code, unicode.IsLetter) >= 0 && strings.IndexFunc(code, unicode.IsDigit) >= 0
+}
+
+// Capabilities reports lookup of the confirmations Safaricom posted and
+// uploads of C2B confirmations and confirmation SMS
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/json", "text/plain"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the confirmation details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
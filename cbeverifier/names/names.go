@@ -0,0 +1,214 @@
+// Package names normalizes and compares Ethiopian personal and business names.
+//
+// Receipts and merchant records spell the same name differently: in Ge'ez script
+// or Latin letters, with or without honorifics such as "Ato" or "W/ro", with
+// abbreviated prefixes such as "W/Mariam", and with the many Latin spellings of
+// Amharic sounds ("Yohannes", "Yohanes"). Match tolerates all of these.
+//
+// Example:
+//
+//	names.Match("Ato Abebe Kebede", "አበበ ከበደ አለሙ") // true
+//	names.Transliterate("ግርማ ወልደ")               // "Girma Welde"
+package names
+
+import (
+	"strings"
+	"unicode"
+)
+
+// honorifics are titles dropped before comparing names, in Latin and Ge'ez script
+var honorifics = map[string]bool{
+	"ato":      true,
+	"w/ro":     true,
+	"w/rt":     true,
+	"w/t":      true,
+	"weyzero":  true,
+	"weyzerit": true,
+	"mr":       true,
+	"mrs":      true,
+	"ms":       true,
+	"miss":     true,
+	"dr":       true,
+	"prof":     true,
+	"eng":      true,
+	"አቶ":       true,
+	"ወ/ሮ":      true,
+	"ወ/ሪት":     true,
+	"ወ/ት":      true,
+	"ወይዘሮ":     true,
+	"ወይዘሪት":    true,
+	"ዶ/ር":      true,
+	"ፕ/ር":      true,
+	"ኢ/ር":      true,
+}
+
+// namePrefixes expands common abbreviated name prefixes (e.g., "W/Mariam", "ገ/ሕይወት")
+var namePrefixes = map[string]string{
+	"w/": "wolde",
+	"g/": "gebre",
+	"h/": "haile",
+	"t/": "tekle",
+	"k/": "kidane",
+	"a/": "abba",
+	"ወ/": "wolde",
+	"ገ/": "gebre",
+	"ኃ/": "haile",
+	"ሀ/": "haile",
+	"ሃ/": "haile",
+	"ተ/": "tekle",
+	"ኪ/": "kidane",
+	"አ/": "abba",
+}
+
+// latinVariants folds alternative Latin spellings of the same Amharic sound
+var latinVariants = strings.NewReplacer(
+	"ph", "f",
+	"kh", "k",
+	"ck", "k",
+	"q", "k",
+	"tz", "ts",
+	"gn", "ny",
+	"ou", "u",
+	"oo", "u",
+	"ee", "i",
+	"ai", "ay",
+	"ei", "ey",
+	"ie", "iye",
+	"ia", "iya",
+	"wo", "we",
+)
+
+// StripHonorifics removes titles such as "Ato", "W/ro" or "ዶ/ር" from a name,
+// returning the remaining words separated by single spaces
+func StripHonorifics(name string) string {
+	var kept []string
+	for _, field := range strings.Fields(name) {
+		if !honorifics[strings.Trim(strings.ToLower(field), ".,")] {
+			kept = append(kept, field)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Normalize returns the tokens of a name in lowercase Latin letters, with
+// honorifics and punctuation removed and abbreviated prefixes expanded
+//
+// Example:
+//
+//	names.Normalize("W/ro ወ/ማርያም Tesfaye") // ["wolde", "maryam", "tesfaye"]
+func Normalize(name string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(strings.ToLower(name)) {
+		field = strings.Trim(field, ".,")
+		if field == "" || honorifics[field] {
+			continue
+		}
+
+		// Expand "W/Mariam" into "wolde mariam"
+		for abbr, expanded := range namePrefixes {
+			if strings.HasPrefix(field, abbr) && len(field) > len(abbr) {
+				tokens = append(tokens, expanded)
+				field = field[len(abbr):]
+				break
+			}
+		}
+
+		// Transliterate, then drop remaining punctuation
+		field = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+				return r
+			}
+			return -1
+		}, strings.ToLower(Transliterate(field)))
+		if field != "" {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// Match reports whether two names refer to the same person
+//
+// Both names are normalized and each token is reduced to a phonetic key that
+// folds spelling variants (doubled letters, "q"/"k", "ph"/"f" and so on). Names
+// match when their keys are alike ignoring spacing, or when the shorter name (at
+// least two tokens) is alike to the start of the longer one, since receipts and
+// merchant records often differ in whether the grandfather's name is included.
+// Keys are alike when they differ by at most one edit per four letters.
+func Match(a, b string) bool {
+	ka, kb := keys(Normalize(a)), keys(Normalize(b))
+	if len(ka) == 0 || len(kb) == 0 {
+		return false
+	}
+
+	if alike(strings.Join(ka, ""), strings.Join(kb, "")) {
+		return true
+	}
+
+	if len(ka) > len(kb) {
+		ka, kb = kb, ka
+	}
+	if len(ka) < 2 {
+		return false
+	}
+	for i := range ka {
+		if !alike(ka[i], kb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// keys returns the phonetic key of every token
+func keys(tokens []string) []string {
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, key(token))
+	}
+	return result
+}
+
+// key folds Latin spelling variants and doubled letters in a normalized token
+func key(token string) string {
+	var (
+		sb   strings.Builder
+		prev rune
+	)
+	for _, r := range latinVariants.Replace(token) {
+		if r != prev {
+			sb.WriteRune(r)
+		}
+		prev = r
+	}
+	return sb.String()
+}
+
+// alike reports whether two keys differ by at most one edit per four letters
+func alike(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ra, rb := []rune(a), []rune(b)
+	return distance(ra, rb) <= max(len(ra), len(rb))/4
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,163 @@
+package names
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ethiopicRow is a consonant row of the Ethiopic syllabary: eight consecutive
+// code points, one per vowel order
+type ethiopicRow struct {
+	// consonant is the Latin rendering of the consonant
+	consonant string
+	// vowels overrides the vowels of each order, for rows that differ from orderVowels
+	vowels *[8]string
+}
+
+// orderVowels are the vowels of the eight orders, in the spelling most common in
+// Latin renderings of Ethiopian names (e.g., "Kebede" for ከበደ). The sixth order
+// has no vowel.
+var orderVowels = [8]string{"e", "u", "i", "a", "e", "", "o", "wa"}
+
+// laryngealVowels are the vowels of the h and glottal rows, whose first order is
+// written "a" (e.g., "Habtamu" for ሀብታሙ, "Abebe" for አበበ)
+var laryngealVowels = [8]string{"a", "u", "i", "a", "e", "", "o", "wa"}
+
+// carrierVowels are the vowels of the glottal rows አ and ዐ, which write a vowel alone
+var carrierVowels = [8]string{"a", "u", "i", "a", "e", "e", "o", "wa"}
+
+// labializedVowels are the vowels of the labialized rows (e.g., ቈ), which only
+// use the first and third to sixth orders
+var labializedVowels = [8]string{"we", "", "wi", "wa", "we", "w", "", ""}
+
+// ethiopicRows maps the first code point of each row to its consonant. Letters
+// that sound the same in Amharic (ሀ, ሐ, ኀ; ሰ, ሠ; ጸ, ፀ) are rendered the same.
+var ethiopicRows = map[rune]ethiopicRow{
+	0x1200: {"h", &laryngealVowels},  // ሀ
+	0x1208: {"l", nil},               // ለ
+	0x1210: {"h", &laryngealVowels},  // ሐ
+	0x1218: {"m", nil},               // መ
+	0x1220: {"s", nil},               // ሠ
+	0x1228: {"r", nil},               // ረ
+	0x1230: {"s", nil},               // ሰ
+	0x1238: {"sh", nil},              // ሸ
+	0x1240: {"k", nil},               // ቀ
+	0x1248: {"k", &labializedVowels}, // ቈ
+	0x1250: {"k", nil},               // ቐ
+	0x1260: {"b", nil},               // በ
+	0x1268: {"v", nil},               // ቨ
+	0x1270: {"t", nil},               // ተ
+	0x1278: {"ch", nil},              // ቸ
+	0x1280: {"h", &laryngealVowels},  // ኀ
+	0x1288: {"h", &labializedVowels}, // ኈ
+	0x1290: {"n", nil},               // ነ
+	0x1298: {"ny", nil},              // ኘ
+	0x12A0: {"", &carrierVowels},     // አ
+	0x12A8: {"k", nil},               // ከ
+	0x12B0: {"k", &labializedVowels}, // ኰ
+	0x12B8: {"h", nil},               // ኸ
+	0x12C0: {"h", &labializedVowels}, // ዀ
+	0x12C8: {"w", nil},               // ወ
+	0x12D0: {"", &carrierVowels},     // ዐ
+	0x12D8: {"z", nil},               // ዘ
+	0x12E0: {"zh", nil},              // ዠ
+	0x12E8: {"y", nil},               // የ
+	0x12F0: {"d", nil},               // ደ
+	0x12F8: {"d", nil},               // ዸ
+	0x1300: {"j", nil},               // ጀ
+	0x1308: {"g", nil},               // ገ
+	0x1310: {"g", &labializedVowels}, // ጐ
+	0x1318: {"ng", nil},              // ጘ
+	0x1320: {"t", nil},               // ጠ
+	0x1328: {"ch", nil},              // ጨ
+	0x1330: {"p", nil},               // ጰ
+	0x1338: {"ts", nil},              // ጸ
+	0x1340: {"ts", nil},              // ፀ
+	0x1348: {"f", nil},               // ፈ
+	0x1350: {"p", nil},               // ፐ
+}
+
+// ethiopicPunctuation maps Ethiopic punctuation to its Latin equivalent
+var ethiopicPunctuation = map[rune]string{
+	'፡': " ",
+	'።': ".",
+	'፣': ",",
+	'፤': ";",
+	'፥': ":",
+	'፦': ":",
+}
+
+// Transliterate renders Ge'ez script in Latin letters, the way Ethiopian names are
+// usually spelled in English (e.g., "አበበ ከበደ" becomes "Abebe Kebede"). Words
+// are capitalized; text in other scripts is returned unchanged.
+//
+// Latin spellings of the same name vary ("Yohannes", "Yohanes"), so compare
+// transliterated names with Match rather than for equality.
+func Transliterate(s string) string {
+	var (
+		sb   strings.Builder
+		prev rune
+	)
+	runes := []rune(s)
+	for i, r := range runes {
+		if p, ok := ethiopicPunctuation[r]; ok {
+			sb.WriteString(p)
+			prev = r
+			continue
+		}
+
+		latin, ok := syllable(r, wordStart(prev), i+1 < len(runes) && isConsonant(runes[i+1]))
+		if !ok {
+			sb.WriteRune(r)
+			prev = r
+			continue
+		}
+		if wordStart(prev) {
+			latin = capitalize(latin)
+		}
+		sb.WriteString(latin)
+		prev = r
+	}
+	return sb.String()
+}
+
+// syllable returns the Latin rendering of an Ethiopic syllable. Sixth-order
+// consonants starting a word before another consonant are given an "i", as in
+// "Girma" for ግርማ.
+func syllable(r rune, initial, beforeConsonant bool) (string, bool) {
+	row, ok := ethiopicRows[r&^7]
+	if !ok {
+		return "", false
+	}
+
+	vowels := &orderVowels
+	if row.vowels != nil {
+		vowels = row.vowels
+	}
+
+	order := int(r & 7)
+	vowel := vowels[order]
+	if order == 5 && vowel == "" && initial && beforeConsonant {
+		vowel = "i"
+	}
+	return row.consonant + vowel, true
+}
+
+// isConsonant reports whether r is an Ethiopic syllable starting with a consonant
+func isConsonant(r rune) bool {
+	row, ok := ethiopicRows[r&^7]
+	return ok && row.consonant != ""
+}
+
+// wordStart reports whether a letter following prev starts a word
+func wordStart(prev rune) bool {
+	return prev == 0 || !(unicode.IsLetter(prev) || unicode.Is(unicode.Mn, prev))
+}
+
+// capitalize uppercases the first letter of s
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Colors of the attachments of chat messages
+var colors = map[Severity]string{
+	Warning:  "#e8a317",
+	Critical: "#d00000",
+}
+
+// message is an incoming webhook message, in the format of Slack, which
+// Mattermost accepts too
+type message struct {
+	Text        string       `json:"text"`
+	Channel     string       `json:"channel,omitempty"`
+	Username    string       `json:"username,omitempty"`
+	IconURL     string       `json:"icon_url,omitempty"`
+	Attachments []attachment `json:"attachments"`
+}
+
+// attachment is the colored block of a message holding the details of an
+// alert
+type attachment struct {
+	Fallback string  `json:"fallback"`
+	Color    string  `json:"color"`
+	Title    string  `json:"title"`
+	Fields   []field `json:"fields"`
+	TS       int64   `json:"ts,omitempty"`
+}
+
+// field is a detail of an alert
+type field struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// chat posts alerts to an incoming webhook
+type chat struct {
+	name       string
+	webhookURL string
+	channel    string
+	username   string
+	iconURL    string
+	mention    Severity
+	// everyone is how the chat mentions every member of a channel
+	everyone   string
+	httpClient *http.Client
+}
+
+// ChatOption configures a Slack or Mattermost notifier
+type ChatOption func(*chat)
+
+// WithChannel posts to channel (e.g., "payments-alerts") instead of the
+// webhook's own channel, if it may be overridden: Mattermost webhooks allow
+// it unless locked, and Slack only allows it for legacy webhooks
+func WithChannel(channel string) ChatOption {
+	return func(c *chat) {
+		c.channel = channel
+	}
+}
+
+// WithUsername posts as username, with the icon at iconURL if not empty,
+// where the webhook allows it
+func WithUsername(username, iconURL string) ChatOption {
+	return func(c *chat) {
+		c.username = username
+		c.iconURL = iconURL
+	}
+}
+
+// WithMention mentions the whole channel (@channel) in alerts of severity min
+// and above (default: never)
+func WithMention(min Severity) ChatOption {
+	return func(c *chat) {
+		c.mention = min
+	}
+}
+
+// WithHTTPClient posts with client (default: http.DefaultClient)
+func WithHTTPClient(client *http.Client) ChatOption {
+	return func(c *chat) {
+		if client != nil {
+			c.httpClient = client
+		}
+	}
+}
+
+// newChat creates a chat notifier
+func newChat(name, webhookURL, everyone string, options []ChatOption) chat {
+	c := chat{name: name, webhookURL: webhookURL, everyone: everyone, httpClient: http.DefaultClient}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// Slack posts alerts to a Slack channel through an incoming webhook
+type Slack struct {
+	chat
+}
+
+// NewSlack creates a notifier posting to the Slack incoming webhook at
+// webhookURL (https://hooks.slack.com/services/...)
+func NewSlack(webhookURL string, options ...ChatOption) *Slack {
+	return &Slack{newChat("slack", webhookURL, "<!channel>", options)}
+}
+
+// Notify implements Notifier
+func (s *Slack) Notify(ctx context.Context, alert *Alert) error {
+	return s.post(ctx, alert)
+}
+
+// Mattermost posts alerts to a Mattermost channel through an incoming webhook
+type Mattermost struct {
+	chat
+}
+
+// NewMattermost creates a notifier posting to the Mattermost incoming webhook
+// at webhookURL (https://mattermost.example.com/hooks/...)
+func NewMattermost(webhookURL string, options ...ChatOption) *Mattermost {
+	return &Mattermost{newChat("mattermost", webhookURL, "@channel", options)}
+}
+
+// Notify implements Notifier
+func (m *Mattermost) Notify(ctx context.Context, alert *Alert) error {
+	return m.post(ctx, alert)
+}
+
+// message returns the message of an alert
+func (c *chat) message(alert *Alert) message {
+	title := alert.Title()
+	text := title
+	if c.mention != 0 && alert.Severity >= c.mention {
+		text = c.everyone + " " + title
+	}
+
+	var fields []field
+	for _, line := range details(alert) {
+		// Long values, such as errors, get a line of their own
+		fields = append(fields, field{Title: line[0], Value: line[1], Short: len(line[1]) <= 40})
+	}
+	var ts int64
+	if !alert.Attempt.FinishedAt.IsZero() {
+		ts = alert.Attempt.FinishedAt.Unix()
+	}
+	return message{
+		Text:     text,
+		Channel:  c.channel,
+		Username: c.username,
+		IconURL:  c.iconURL,
+		Attachments: []attachment{{
+			Fallback: title,
+			Color:    colors[alert.Severity],
+			Title:    title,
+			Fields:   fields,
+			TS:       ts,
+		}},
+	}
+}
+
+// post posts the message of an alert to the webhook
+func (c *chat) post(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(c.message(alert))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// The webhook URL is a secret, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: webhook responded %s: %s", c.name, resp.Status, strings.TrimSpace(string(reason)))
+	}
+	return nil
+}
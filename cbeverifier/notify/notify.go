@@ -0,0 +1,288 @@
+// Package notify posts alerts about verifications to chat channels: receipts
+// that failed verification, receipts that look tampered with, and receipts
+// used twice. Finance and support teams watch the channel instead of the
+// logs.
+//
+// An Alerter is a cbeverifier.Recorder. It classifies every attempt of the
+// Verifier it is recorded from as an Alert, and posts the alert to the
+// notifiers whose minimum severity it reaches, in the background. Slack and
+// Mattermost post to the incoming webhooks of those chats; other chats
+// implement Notifier.
+//
+// Alerts never include the values of mismatched fields, which hold names and
+// account numbers, only the names of the fields.
+//
+// Example:
+//
+//	alerter := notify.New(
+//		// Refused payments to #payments-alerts
+//		notify.WithNotifier(notify.NewSlack(os.Getenv("SLACK_ALERTS_WEBHOOK")), notify.Critical),
+//		// Payments accepted with warnings, and receipts to check, to #payments-review
+//		notify.WithNotifier(notify.NewSlack(os.Getenv("SLACK_REVIEW_WEBHOOK")), notify.Warning),
+//	)
+//	defer alerter.Flush(context.Background())
+//	verifier := cbeverifier.New(cbeverifier.WithRecorder(cbeverifier.MultiRecorder(history, alerter)))
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// notifyTimeout bounds the posting of an alert to a notifier
+const notifyTimeout = 10 * time.Second
+
+// Kinds of alerts
+const (
+	// KindFailure is a verification that failed, or passed with warnings
+	KindFailure = "failure"
+	// KindTamper is a receipt with tamper indicators, or an invalid signature
+	KindTamper = "tamper"
+	// KindDuplicate is a receipt already used for another payment (see
+	// cbeverifier.WithReplayStore)
+	KindDuplicate = "duplicate"
+)
+
+// tamperChecks are the checks of the policy that report tampering
+var tamperChecks = []string{"tamper", "signature"}
+
+// Severity is how urgent an alert is, after the policy requirement of the
+// checks that raised it
+type Severity int
+
+const (
+	// Warning is an advisory check of the policy that failed, or a receipt to
+	// check manually: the payment may have been accepted
+	Warning Severity = iota + 1
+	// Critical is a required check that failed, a verification error or a
+	// reused receipt: the payment was refused
+	Critical
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Alert is an attempt worth posting
+type Alert struct {
+	Kind     string
+	Severity Severity
+	// Checks are the names of the checks that failed or warned (e.g., amount,
+	// receiver), or of the fields to review
+	Checks  []string
+	Attempt *cbeverifier.Attempt
+}
+
+// NewAlert classifies a finished verification, returning nil for a payment
+// verified without warnings
+func NewAlert(attempt *cbeverifier.Attempt) *Alert {
+	result := attempt.Result
+	if result == nil {
+		return nil
+	}
+	alert := &Alert{Attempt: attempt}
+	switch {
+	case result.Error == cbeverifier.ErrReceiptAlreadyUsed.Error():
+		alert.Kind, alert.Severity = KindDuplicate, Critical
+	case hasAny(result.Mismatches, tamperChecks):
+		alert.Kind, alert.Severity, alert.Checks = KindTamper, Critical, names(result.Mismatches)
+	case !result.IsValid && attempt.Outcome != cbeverifier.OutcomeReview:
+		alert.Kind, alert.Severity, alert.Checks = KindFailure, Critical, names(result.Mismatches)
+	case hasAny(result.Warnings, tamperChecks) || len(result.TamperIndicators) > 0:
+		alert.Kind, alert.Severity, alert.Checks = KindTamper, Warning, names(result.Warnings)
+	case attempt.Outcome == cbeverifier.OutcomeReview:
+		alert.Kind, alert.Severity = KindFailure, Warning
+		for field := range result.LowConfidence {
+			alert.Checks = append(alert.Checks, field)
+		}
+		sort.Strings(alert.Checks)
+	case len(result.Warnings) > 0:
+		alert.Kind, alert.Severity, alert.Checks = KindFailure, Warning, names(result.Warnings)
+	default:
+		return nil
+	}
+	return alert
+}
+
+// Title returns a one-line description of the alert
+func (a *Alert) Title() string {
+	reference := a.Attempt.Transaction.ID
+	if reference == "" {
+		reference = a.Attempt.Transaction.FullReference
+	}
+	switch {
+	case a.Kind == KindDuplicate:
+		return "Receipt reused: " + reference
+	case a.Kind == KindTamper:
+		return "Suspected tampering: " + reference
+	case a.Attempt.Outcome == cbeverifier.OutcomeReview:
+		return "Receipt to review: " + reference
+	case a.Attempt.Outcome == cbeverifier.OutcomeError:
+		return "Verification error: " + reference
+	case a.Severity == Warning:
+		return "Verified with warnings: " + reference
+	default:
+		return "Verification failed: " + reference
+	}
+}
+
+// hasAny reports whether m has any of keys
+func hasAny(m map[string]interface{}, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// names returns the sorted keys of a mismatch map
+func names(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Notifier posts alerts to a chat channel. Implementations must be safe for
+// concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// route sends the alerts of some kinds from a severity on to a notifier
+type route struct {
+	notifier Notifier
+	min      Severity
+	kinds    []string
+}
+
+// Alerter posts the alerts of verification attempts to notifiers
+type Alerter struct {
+	routes []route
+	logger *slog.Logger
+	wg     sync.WaitGroup
+}
+
+// Option configures an Alerter
+type Option func(*Alerter)
+
+// WithNotifier posts the alerts of severity min and above to notifier; with
+// kinds, only those of the kinds (e.g., KindTamper, KindDuplicate). It may be
+// given several times, e.g. to post warnings and critical alerts to different
+// channels.
+func WithNotifier(notifier Notifier, min Severity, kinds ...string) Option {
+	return func(a *Alerter) {
+		a.routes = append(a.routes, route{notifier: notifier, min: min, kinds: kinds})
+	}
+}
+
+// WithLogger logs the alerts that could not be posted to logger (default:
+// discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *Alerter) {
+		if logger != nil {
+			a.logger = logger
+		}
+	}
+}
+
+// New creates an Alerter
+func New(options ...Option) *Alerter {
+	a := &Alerter{logger: slog.New(slog.DiscardHandler)}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+// Record implements cbeverifier.Recorder, posting the alert of attempt, if
+// any, in the background. It never fails, so as not to delay verifications;
+// alerts that could not be posted are logged.
+func (a *Alerter) Record(ctx context.Context, attempt *cbeverifier.Attempt) error {
+	alert := NewAlert(attempt)
+	if alert == nil {
+		return nil
+	}
+	// The alert outlives the verification's context
+	ctx = context.WithoutCancel(ctx)
+	for _, route := range a.routes {
+		if alert.Severity < route.min || len(route.kinds) > 0 && !slices.Contains(route.kinds, alert.Kind) {
+			continue
+		}
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			defer cancel()
+			if err := route.notifier.Notify(ctx, alert); err != nil {
+				a.logger.WarnContext(ctx, "failed to post alert", "kind", alert.Kind, "severity", alert.Severity.String(), "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Flush waits for the alerts being posted, e.g. before the process exits,
+// until ctx is done
+func (a *Alerter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// details returns the lines describing an alert, shared by the chat
+// notifiers
+func details(alert *Alert) [][2]string {
+	attempt := alert.Attempt
+	lines := [][2]string{{"Severity", alert.Severity.String()}}
+	if attempt.MerchantID != "" {
+		lines = append(lines, [2]string{"Merchant", attempt.MerchantID})
+	}
+	if attempt.Transaction.Amount != 0 {
+		currency := attempt.Transaction.Currency
+		if currency == "" {
+			currency = "ETB"
+		}
+		lines = append(lines, [2]string{"Amount", fmt.Sprintf("%.2f %s", attempt.Transaction.Amount, currency)})
+	}
+	if attempt.Transaction.Provider != "" {
+		lines = append(lines, [2]string{"Provider", attempt.Transaction.Provider})
+	}
+	if len(alert.Checks) > 0 {
+		lines = append(lines, [2]string{"Checks", strings.Join(alert.Checks, ", ")})
+	}
+	if result := attempt.Result; len(result.TamperIndicators) > 0 {
+		lines = append(lines, [2]string{"Tamper indicators", strings.Join(result.TamperIndicators, ", ")})
+	}
+	if result := attempt.Result; result.Error != "" && alert.Kind != KindDuplicate {
+		lines = append(lines, [2]string{"Error", result.Error})
+	}
+	return lines
+}
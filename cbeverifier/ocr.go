@@ -0,0 +1,64 @@
+package cbeverifier
+
+import (
+	"context"
+	"fmt"
+)
+
+// OCRLine is a line of text recognized by an OCREngine
+type OCRLine struct {
+	// Text is the recognized text of the line
+	Text string `json:"text"`
+	// Confidence is the engine's confidence in the line, from 0 to 1
+	Confidence float64 `json:"confidence"`
+}
+
+// OCREngine recognizes text in scanned or image-based documents.
+//
+// contentType is "application/pdf" for PDFs without a text layer, or the image
+// type (e.g., "image/png", "image/jpeg") for screenshots. Lines must be returned
+// in reading order. See the ocr/tesseract package for a Tesseract adapter.
+type OCREngine interface {
+	Recognize(ctx context.Context, document []byte, contentType string) ([]OCRLine, error)
+}
+
+// ParseCBEReceiptWithOCR parses a CBE receipt PDF like ParseCBEReceipt, but falls
+// back to engine when the PDF has no extractable text layer, e.g. printed-and-scanned
+// receipts or image-only PDFs. The returned receipt has OCR set when the fallback
+// was used.
+//
+// Example:
+//
+//	receipt, err := cbeverifier.ParseCBEReceiptWithOCR(ctx, pdfBytes, tesseract.New())
+func ParseCBEReceiptWithOCR(ctx context.Context, pdfBytes []byte, engine OCREngine) (*ParsedReceipt, error) {
+	return parsePDF(ctx, pdfBytes, "", engine, nil)
+}
+
+// parsePDF parses a receipt PDF, decrypted with password, with the given templates,
+// falling back to engine when the PDF has no text layer
+func parsePDF(ctx context.Context, pdfBytes []byte, password string, engine OCREngine, templates []*ReceiptTemplate) (*ParsedReceipt, error) {
+	rows, err := readPDFRows(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), password)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasText(rows) || engine == nil {
+		return parseRows(rows, templates...)
+	}
+
+	return parseOCR(ctx, engine, pdfBytes, "application/pdf", templates)
+}
+
+// parseOCR runs document through engine and extracts transaction information from the result
+func parseOCR(ctx context.Context, engine OCREngine, document []byte, contentType string, templates []*ReceiptTemplate) (*ParsedReceipt, error) {
+	ocrLines, err := engine.Recognize(ctx, document, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: OCR failed: %v", ErrReceiptParseError, err)
+	}
+
+	receipt, err := parseRows(ocrRows(ocrLines), templates...)
+	if receipt != nil {
+		receipt.OCR = true
+	}
+	return receipt, err
+}
@@ -0,0 +1,205 @@
+// Package tesseract provides a cbeverifier.OCREngine backed by the Tesseract
+// command-line tool.
+//
+// PDFs are rasterized with pdftoppm (from poppler-utils) before recognition,
+// so both binaries must be installed for image-only PDF receipts:
+//
+//	apt-get install tesseract-ocr tesseract-ocr-amh poppler-utils
+//
+// Example:
+//
+//	verifier := cbeverifier.New(cbeverifier.WithOCR(tesseract.New()))
+package tesseract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Engine runs Tesseract to recognize receipt text
+type Engine struct {
+	// TesseractPath is the tesseract binary (default: "tesseract" on PATH)
+	TesseractPath string
+	// PdftoppmPath is the pdftoppm binary (default: "pdftoppm" on PATH)
+	PdftoppmPath string
+	// Languages are the Tesseract language packs to use (default: "eng+amh")
+	Languages string
+	// DPI is the rasterization resolution for PDF pages (default: 300)
+	DPI int
+}
+
+// New creates an Engine with default settings
+func New() *Engine {
+	return &Engine{
+		TesseractPath: "tesseract",
+		PdftoppmPath:  "pdftoppm",
+		Languages:     "eng+amh",
+		DPI:           300,
+	}
+}
+
+// Recognize implements cbeverifier.OCREngine
+func (e *Engine) Recognize(ctx context.Context, document []byte, contentType string) ([]cbeverifier.OCRLine, error) {
+	dir, err := os.MkdirTemp("", "cbe-ocr-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	images, err := e.prepareImages(ctx, dir, document, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []cbeverifier.OCRLine
+	for _, image := range images {
+		pageLines, err := e.recognizeImage(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, pageLines...)
+	}
+	return lines, nil
+}
+
+// prepareImages writes document to dir as one image per page
+func (e *Engine) prepareImages(ctx context.Context, dir string, document []byte, contentType string) ([]string, error) {
+	switch {
+	case contentType == "application/pdf":
+		input := filepath.Join(dir, "receipt.pdf")
+		if err := os.WriteFile(input, document, 0o600); err != nil {
+			return nil, err
+		}
+
+		prefix := filepath.Join(dir, "page")
+		cmd := exec.CommandContext(ctx, e.pdftoppm(), "-r", strconv.Itoa(e.dpi()), "-png", input, prefix)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("pdftoppm: %v: %s", err, bytes.TrimSpace(out))
+		}
+
+		pages, err := filepath.Glob(prefix + "-*.png")
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(pages)
+		return pages, nil
+
+	case strings.HasPrefix(contentType, "image/"):
+		input := filepath.Join(dir, "receipt"+imageExtension(contentType))
+		if err := os.WriteFile(input, document, 0o600); err != nil {
+			return nil, err
+		}
+		return []string{input}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// recognizeImage runs tesseract on a single image and groups its words into lines
+func (e *Engine) recognizeImage(ctx context.Context, image string) ([]cbeverifier.OCRLine, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.tesseract(), image, "stdout", "-l", e.languages(), "tsv")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return parseTSV(stdout.Bytes()), nil
+}
+
+// parseTSV converts Tesseract TSV output into lines with averaged word confidence
+func parseTSV(tsv []byte) []cbeverifier.OCRLine {
+	type lineKey struct{ page, block, par, line string }
+
+	var (
+		lines   []cbeverifier.OCRLine
+		current lineKey
+		words   []string
+		confSum float64
+	)
+	flush := func() {
+		if len(words) > 0 {
+			lines = append(lines, cbeverifier.OCRLine{
+				Text:       strings.Join(words, " "),
+				Confidence: confSum / float64(len(words)) / 100,
+			})
+		}
+		words, confSum = nil, 0
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(tsv))
+	for scanner.Scan() {
+		// level page block par line word left top width height conf text
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(cols[11])
+		conf, err := strconv.ParseFloat(cols[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		key := lineKey{cols[1], cols[2], cols[3], cols[4]}
+		if key != current {
+			flush()
+			current = key
+		}
+		words = append(words, text)
+		confSum += conf
+	}
+	flush()
+
+	return lines
+}
+
+// imageExtension returns the file extension for an image content type
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/tiff":
+		return ".tif"
+	default:
+		return ".png"
+	}
+}
+
+func (e *Engine) tesseract() string {
+	if e.TesseractPath == "" {
+		return "tesseract"
+	}
+	return e.TesseractPath
+}
+
+func (e *Engine) pdftoppm() string {
+	if e.PdftoppmPath == "" {
+		return "pdftoppm"
+	}
+	return e.PdftoppmPath
+}
+
+func (e *Engine) languages() string {
+	if e.Languages == "" {
+		return "eng+amh"
+	}
+	return e.Languages
+}
+
+func (e *Engine) dpi() int {
+	if e.DPI <= 0 {
+		return 300
+	}
+	return e.DPI
+}
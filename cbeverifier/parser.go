@@ -3,7 +3,10 @@
 package cbeverifier
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -11,36 +14,98 @@ import (
 	pdf "github.com/dslipak/pdf"
 )
 
-// VerifyResult represents the result of parsing a CBE receipt PDF
-type VerifyResult struct {
-	// Success indicates whether the PDF was successfully parsed
-	Success bool `json:"success"`
-	// Details contains the extracted transaction information or error details
-	Details map[string]interface{} `json:"details"`
+// ParsedReceipt is the transaction information extracted from a CBE receipt PDF
+type ParsedReceipt struct {
+	TransactionDetails
+	// OCR is true when the fields were recognized by an OCREngine rather than
+	// read from the PDF text layer
+	OCR bool `json:"ocr,omitempty"`
 }
 
-// Precompiled regex patterns for extracting transaction information
+// MissingFieldsError is returned by ParseCBEReceipt when required fields could
+// not be extracted from the receipt
+type MissingFieldsError struct {
+	// Fields lists the missing fields by their JSON names
+	Fields []string
+}
+
+// Error implements the error interface
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("%v: missing one or more required fields (%s)", ErrReceiptParseError, strings.Join(e.Fields, ", "))
+}
+
+// Unwrap allows errors.Is(err, ErrReceiptParseError)
+func (e *MissingFieldsError) Unwrap() error {
+	return ErrReceiptParseError
+}
+
+// datePattern matches a date and optional time in the M/D/YYYY, YYYY-MM-DD and
+// D-Mon-YYYY formats used by CBE
+const datePattern = `\d{1,2}/\d{1,2}/\d{4}(?:,?\s*\d{1,2}:\d{2}(?::\d{2})?\s*(?:AM|PM)?)?|\d{4}-\d{2}-\d{2}(?:[ T]\d{2}:\d{2}(?::\d{2})?)?|\d{1,2}[- ][A-Za-z]{3,9}[- ,]+\d{4}(?:,?\s*\d{1,2}:\d{2}(?::\d{2})?\s*(?:AM|PM)?)?`
+
+// Precompiled regex patterns for extracting transaction information.
+//
+// Each label pattern accepts both the English label and its Amharic equivalent,
+// and value classes use Unicode categories so Ge'ez names are captured.
 var (
 	// rePayer matches payer information in the receipt
-	rePayer = regexp.MustCompile(`(?i)payer\s*[:]?\s*([\w\s&\.-]+)`)
+	rePayer = regexp.MustCompile(`(?i)(?:payer|ከፋይ)\s*[:፥፦]?\s*([\p{L}\p{M}\p{N}_\s&\.-]+)`)
 
 	// reReceiver matches receiver information in the receipt
-	reReceiver = regexp.MustCompile(`(?i)receiver\s*[:]?\s*([\w\s&\.-]+)`)
+	reReceiver = regexp.MustCompile(`(?i)(?:receiver|beneficiary\s*name|ተቀባይ)\s*[:፥፦]?\s*([\p{L}\p{M}\p{N}_\s&\.-]+)`)
+
+	// reReceiverBank matches the receiver's bank on interbank transfer receipts
+	reReceiverBank = regexp.MustCompile(`(?i)^(?:receiver(?:'s)?|beneficiary|የ?ተቀባይ)\s*(?:bank|ባንክ)(?:\s*name)?\s*[:፥፦]?\s*(.+)`)
+
+	// reReceiverAccount matches account lines that name the receiver (e.g.,
+	// "Beneficiary Account 0012345678")
+	reReceiverAccount = regexp.MustCompile(`(?i)^(?:receiver(?:'s)?|beneficiary)\s*account(?:\s*(?:no\.?|number))?\s*[:፥፦]?\s*(\S+)`)
 
 	// reAccount matches account numbers in the receipt
 	reAccount = regexp.MustCompile(`(?i)account\s*[:]?\s*(\S+)`)
 
-	// reTransferredAmt matches transferred amount in ETB
-	reTransferredAmt = regexp.MustCompile(`(?i)transferred amount\s*[:]?\s*([\d,]+\.\d{2})\s*ETB`)
+	// reAmharicPayerAccount and reAmharicReceiverAccount match Amharic account
+	// lines that name the party (e.g., "የከፋይ ሂሳብ ቁጥር 1****1234"), and
+	// reAmharicAccount matches those that do not
+	reAmharicPayerAccount    = regexp.MustCompile(`የ?ከፋይ\s*የ?ሂሳብ\s*ቁጥር\s*[:፥፦]?\s*(\S+)`)
+	reAmharicReceiverAccount = regexp.MustCompile(`የ?ተቀባይ\s*የ?ሂሳብ\s*ቁጥር\s*[:፥፦]?\s*(\S+)`)
+	reAmharicAccount         = regexp.MustCompile(`የ?ሂሳብ\s*ቁጥር\s*[:፥፦]?\s*(\S+)`)
+
+	// reTransferredAmt matches the transferred amount and its currency, which may
+	// precede or follow the amount (ETB receipts use "ETB" or "ብር")
+	reTransferredAmt = regexp.MustCompile(`(?i)(?:transferred amount|የተላለፈው\s*(?:መጠን|ገንዘብ))\s*[:፥፦]?\s*(?:([A-Z]{3})\s*)?([\d,]+\.\d{2})\s*([A-Z]{3}|ብር)?`)
+
+	// reServiceCharge matches the service charge
+	reServiceCharge = regexp.MustCompile(`(?i)(?:service charge|የአገልግሎት\s*ክፍያ)\s*[:፥፦]?\s*(?:[A-Z]{3}\s*)?([\d,]+\.\d{2})`)
+
+	// reVAT matches the VAT on the service charge, optionally followed by its rate
+	reVAT = regexp.MustCompile(`(?i)(?:\bVAT|ተ\.?እ\.?ታ)\s*(?:\(\s*\d+(?:\.\d+)?\s*%\s*\))?\s*[:፥፦]?\s*(?:[A-Z]{3}\s*)?([\d,]+\.\d{2})`)
+
+	// reTotalDebited matches the total amount debited from the payer's account
+	reTotalDebited = regexp.MustCompile(`(?i)(?:total amount debited|ጠቅላላ)\D*?([\d,]+\.\d{2})`)
+
+	// reBranch matches the branch the account is held at or the transfer was made from
+	reBranch = regexp.MustCompile(`(?i)^(?:branch(?:\s*name)?|ቅርንጫፍ)\s*[:፥፦]?\s*(.+)`)
+
+	// reChannel matches the payment channel (e.g., mobile banking, ATM)
+	reChannel = regexp.MustCompile(`(?i)^(?:payment\s*channel|channel|የክፍያ\s*መንገድ)\s*[:፥፦]?\s*(.+)`)
+
+	// reTransactionType matches the transaction type (e.g., "Account to Account Transfer")
+	reTransactionType = regexp.MustCompile(`(?i)^(?:transaction\s*type|type\s*of\s*transaction|የግብይት\s*ዓይነት)\s*[:፥፦]?\s*(.+)`)
 
 	// reReason matches payment reason/description
-	reReason = regexp.MustCompile(`(?i)reason\s*[:]?\s*(.+)`)
+	reReason = regexp.MustCompile(`(?i)(?:reason|ምክንያት)\s*[:፥፦]?\s*(.+)`)
 
 	// reReferenceNo matches reference number
-	reReferenceNo = regexp.MustCompile(`(?i)reference no\.?\s*[:]?\s*(.+)`)
+	reReferenceNo = regexp.MustCompile(`(?i)(?:reference no\.?|የ?ማጣቀሻ\s*ቁጥር)\s*[:፥፦]?\s*(.+)`)
 
 	// rePaymentDate matches payment date and time
-	rePaymentDate = regexp.MustCompile(`(?i)payment date.*?(\d{1,2}/\d{1,2}/\d{4}(?:,\s*\d{1,2}:\d{2}:\d{2}\s*(?:AM|PM)?)?)`)
+	rePaymentDate = regexp.MustCompile(`(?i)(?:payment date|የክፍያ\s*ቀን).*?(` + datePattern + `)`)
+
+	// reLabelPayer and reLabelReceiver match the payer and receiver name labels
+	// in the label column of a receipt table
+	reLabelPayer    = regexp.MustCompile(`(?i)^(?:payer|ከፋይ)(?:\s*name)?\s*[:፥፦]?$`)
+	reLabelReceiver = regexp.MustCompile(`(?i)^(?:receiver|beneficiary|ተቀባይ)(?:\s*name)?\s*[:፥፦]?$`)
 
 	// reParenthetical removes parenthetical content
 	reParenthetical = regexp.MustCompile(`^\(.*?\)`)
@@ -52,14 +117,13 @@ var (
 // ParseCBEReceipt parses a CBE receipt PDF and extracts transaction information
 //
 // This function:
-// 1. Creates a temporary file from the provided PDF bytes
-// 2. Opens and processes the PDF using the pdf library
-// 3. Extracts transaction details using regex patterns
-// 4. Returns structured transaction information
+// 1. Opens the PDF directly from the provided bytes (no temporary files)
+// 2. Extracts transaction details from the receipt's label and value columns
+// 3. Returns structured transaction information
 //
-// The function returns a VerifyResult with:
-// - Success: true if parsing was successful
-// - Details: map containing extracted fields or error information
+// All returned errors wrap ErrReceiptParseError. When required fields are
+// missing, the error is a *MissingFieldsError and the partially parsed receipt
+// is returned alongside it.
 //
 // Example:
 //
@@ -68,152 +132,200 @@ var (
 //		log.Fatal(err)
 //	}
 //
-//	result := cbeverifier.ParseCBEReceipt(pdfBytes)
-//	if result.Success {
-//		fmt.Printf("Amount: %.2f ETB\n", result.Details["amount"])
-//		fmt.Printf("Payer: %s\n", result.Details["payer"])
-//	} else {
-//		fmt.Printf("Parse error: %v\n", result.Details["error"])
+//	receipt, err := cbeverifier.ParseCBEReceipt(pdfBytes)
+//	if err != nil {
+//		log.Fatalf("Parse error: %v", err)
 //	}
-func ParseCBEReceipt(pdfBytes []byte) VerifyResult {
-	// Validate PDF header
-	if !strings.HasPrefix(string(pdfBytes), "%PDF-") {
-		return VerifyResult{
-			Success: false,
-			Details: map[string]interface{}{
-				"error": "invalid PDF format: missing PDF header",
-			},
+//	fmt.Printf("Amount: %.2f ETB\n", receipt.Amount)
+//	fmt.Printf("Payer: %s\n", receipt.Payer)
+func ParseCBEReceipt(pdfBytes []byte) (*ParsedReceipt, error) {
+	return parseReaderAt(bytesReaderAt(pdfBytes), int64(len(pdfBytes)))
+}
+
+// bytesReaderAt wraps PDF bytes for random access
+func bytesReaderAt(pdfBytes []byte) io.ReaderAt {
+	return bytes.NewReader(pdfBytes)
+}
+
+// ParseCBEReceiptReader parses a CBE receipt PDF read from r
+//
+// PDF parsing needs random access, so if r is an io.ReaderAt with a known
+// size (such as *os.File or *bytes.Reader) it is parsed in place. Any other
+// reader, such as an HTTP response body, is read into a single buffer of at
+// most 5 MiB; larger inputs fail with ErrReceiptTooLarge.
+//
+// Example:
+//
+//	f, err := os.Open("receipt.pdf")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer f.Close()
+//
+//	receipt, err := cbeverifier.ParseCBEReceiptReader(f)
+func ParseCBEReceiptReader(r io.Reader) (*ParsedReceipt, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		if size, ok := readerSize(r); ok {
+			return parseReaderAt(ra, size)
 		}
 	}
 
-	// Create temporary file for PDF processing
-	tmpfile, err := os.CreateTemp("", "cbe-*.pdf")
-	if err != nil {
-		return VerifyResult{
-			Success: false,
-			Details: map[string]interface{}{
-				"error": fmt.Sprintf("could not create temp file: %v", err),
-			},
-		}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(io.LimitReader(r, defaultMaxPDFBytes+1)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPDFReadError, err)
 	}
-	defer os.Remove(tmpfile.Name())
-
-	// Write PDF content to temporary file
-	if _, err := tmpfile.Write(pdfBytes); err != nil {
-		return VerifyResult{
-			Success: false,
-			Details: map[string]interface{}{
-				"error": fmt.Sprintf("could not write to temp file: %v", err),
-			},
-		}
+	if buf.Len() > defaultMaxPDFBytes {
+		return nil, ErrReceiptTooLarge
 	}
-	tmpfile.Close()
 
-	// Open PDF document
-	doc, err := pdf.Open(tmpfile.Name())
-	if err != nil {
-		return VerifyResult{
-			Success: false,
-			Details: map[string]interface{}{
-				"error": fmt.Sprintf("failed to open PDF: %v", err),
-			},
+	return ParseCBEReceipt(buf.Bytes())
+}
+
+// readerSize returns the total size of r if it can be determined without reading it
+func readerSize(r io.Reader) (int64, bool) {
+	switch v := r.(type) {
+	case interface{ Size() int64 }:
+		return v.Size(), true
+	case interface {
+		Stat() (os.FileInfo, error)
+	}:
+		if info, err := v.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size(), true
 		}
 	}
+	return 0, false
+}
 
-	// Extract transaction information
-	details := extractTransactionDetails(doc)
-
-	// Validate extracted information
-	if isValidTransaction(details) {
-		return VerifyResult{
-			Success: true,
-			Details: details,
-		}
+// ParseCBEReceiptWithPassword parses an encrypted CBE receipt PDF like ParseCBEReceipt.
+// Receipts and statements are commonly encrypted with the customer's phone number
+// or account digits. ErrPDFEncrypted is returned when the password is wrong.
+func ParseCBEReceiptWithPassword(pdfBytes []byte, password string) (*ParsedReceipt, error) {
+	rows, err := readPDFRows(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), password)
+	if err != nil {
+		return nil, err
 	}
+	return parseRows(rows)
+}
 
-	// Return error with missing field information
-	return VerifyResult{
-		Success: false,
-		Details: map[string]interface{}{
-			"error":   "missing one or more required fields",
-			"missing": getMissingFields(details),
-		},
+// parseReaderAt parses a receipt PDF of the given size from ra
+func parseReaderAt(ra io.ReaderAt, size int64) (*ParsedReceipt, error) {
+	rows, err := readPDFRows(ra, size, "")
+	if err != nil {
+		return nil, err
 	}
+	return parseRows(rows)
 }
 
-// extractTransactionDetails processes the PDF document and extracts transaction information
-func extractTransactionDetails(doc *pdf.Reader) map[string]interface{} {
-	var (
-		payer, receiver, transferredAmt, reason, refNo, paymentDate string
-		payerAccounts, receiverAccounts                             []string
-		currentEntity                                               string
-	)
+// readPDFRows opens a PDF and returns the rows of text on every page
+func readPDFRows(ra io.ReaderAt, size int64, password string) (rows []receiptRow, err error) {
+	defer recoverMalformed(&err)
 
-	// Process each page of the PDF
-	for i := 1; i <= doc.NumPage(); i++ {
-		page := doc.Page(i)
-		if page.V.IsNull() {
-			continue
-		}
+	doc, err := openPDF(ra, size, password)
+	if err != nil {
+		return nil, err
+	}
+	return extractRows(doc)
+}
 
-		// Get text content by rows
-		rows, err := page.GetTextByRow()
-		if err != nil {
-			continue
-		}
+// recoverMalformed turns a panic inside the PDF library, which happens on some
+// malformed or truncated files, into an ErrMalformedPDF error in *err. It must
+// be deferred.
+func recoverMalformed(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %w: %v", ErrReceiptParseError, ErrMalformedPDF, r)
+	}
+}
 
-		// Process each row of text
-		for _, row := range rows {
-			line := joinWords(row.Content)
-			line = fixLineSpacing(line)
+// openPDF validates the PDF header and opens the document, decrypting it with
+// password if it is encrypted
+func openPDF(ra io.ReaderAt, size int64, password string) (*pdf.Reader, error) {
+	// Validate PDF header
+	header := make([]byte, 5)
+	if n, _ := ra.ReadAt(header, 0); n < len(header) || string(header) != "%PDF-" {
+		return nil, fmt.Errorf("%w: invalid PDF format: missing PDF header", ErrReceiptParseError)
+	}
 
-			// Extract different fields based on regex patterns
-			switch {
-			case extractField(line, rePayer) != "":
-				payer = extractField(line, rePayer)
-				currentEntity = "payer"
+	// The password callback is asked for a password until it returns ""
+	tried := false
+	next := func() string {
+		if tried {
+			return ""
+		}
+		tried = true
+		return password
+	}
 
-			case extractField(line, reReceiver) != "":
-				receiver = extractField(line, reReceiver)
-				currentEntity = "receiver"
+	// Open PDF document directly from the reader
+	doc, err := pdf.NewReaderEncrypted(ra, size, next)
+	switch {
+	case err == nil:
+		return doc, nil
+	case errors.Is(err, pdf.ErrInvalidPassword) && password == "":
+		return nil, fmt.Errorf("%w: %w: password required", ErrReceiptParseError, ErrPDFEncrypted)
+	case errors.Is(err, pdf.ErrInvalidPassword):
+		return nil, fmt.Errorf("%w: %w: wrong password", ErrReceiptParseError, ErrPDFEncrypted)
+	case strings.Contains(err.Error(), "encrypt"):
+		return nil, fmt.Errorf("%w: %w: %v", ErrReceiptParseError, ErrPDFEncrypted, err)
+	default:
+		return nil, fmt.Errorf("%w: %w: %v", ErrReceiptParseError, ErrMalformedPDF, err)
+	}
+}
 
-			case extractField(line, reAccount) != "":
-				account := extractField(line, reAccount)
-				if currentEntity == "payer" {
-					payerAccounts = append(payerAccounts, account)
-				} else if currentEntity == "receiver" {
-					receiverAccounts = append(receiverAccounts, account)
-				}
+// parseRows extracts and validates transaction information from receipt rows
+//
+// Each template is tried in order, followed by the built-in template for the
+// detected receipt layout and then the other built-in templates. The first
+// receipt with all required fields is returned; otherwise the one missing the
+// fewest fields is returned with a *MissingFieldsError.
+func parseRows(rows []receiptRow, templates ...*ReceiptTemplate) (*ParsedReceipt, error) {
+	format := detectFormat(rows)
+	receipt, err := bestReceipt(rows, append(templates, formatTemplates(format)...), missingFields)
+	if receipt != nil {
+		receipt.FormatVersion = formatVersion(format)
+		for i := range receipt.Transfers {
+			receipt.Transfers[i].FormatVersion = receipt.FormatVersion
+		}
+	}
+	return receipt, err
+}
 
-			case extractField(line, reTransferredAmt) != "":
-				transferredAmt = extractField(line, reTransferredAmt)
+// bestReceipt extracts rows with each template and returns the first receipt for
+// which missing reports no fields, or else the one missing the fewest
+func bestReceipt(rows []receiptRow, templates []*ReceiptTemplate, missing func(*ParsedReceipt) []string) (*ParsedReceipt, error) {
+	var (
+		best        *ParsedReceipt
+		bestMissing []string
+	)
 
-			case extractField(line, reReason) != "":
-				reason = extractReason(line)
+	for _, template := range templates {
+		if template == nil {
+			continue
+		}
 
-			case extractField(line, reReferenceNo) != "":
-				refNo = extractReferenceNumber(line)
+		// Extract transaction information
+		receipt := template.extractTransfers(rows)
 
-			case extractField(line, rePaymentDate) != "":
-				paymentDate = extractField(line, rePaymentDate)
-			}
+		// Validate extracted information
+		fields := missing(receipt)
+		if len(fields) == 0 {
+			return receipt, nil
+		}
+		if best == nil || len(fields) < len(bestMissing) {
+			best, bestMissing = receipt, fields
 		}
 	}
 
-	// Parse amount from string to float64
-	amount := parseAmount(transferredAmt)
-
-	// Build result map
-	return map[string]interface{}{
-		"payer":           payer,
-		"payerAccount":    getFirstAccount(payerAccounts),
-		"receiver":        receiver,
-		"receiverAccount": getFirstAccount(receiverAccounts),
-		"amount":          amount,
-		"date":            paymentDate,
-		"transaction_id":  refNo,
-		"reason":          reason,
+	return best, &MissingFieldsError{Fields: bestMissing}
+}
+
+// hasText reports whether any row contains non-whitespace text
+func hasText(rows []receiptRow) bool {
+	for _, row := range rows {
+		if strings.TrimSpace(row.text()) != "" {
+			return true
+		}
 	}
+	return false
 }
 
 // Helper functions
@@ -223,6 +335,22 @@ func fixLineSpacing(line string) string {
 	return reFixMergedWords.ReplaceAllString(line, "$1 $2")
 }
 
+// ethiopicPunctuation maps Ethiopic punctuation to its ASCII equivalent
+var ethiopicPunctuation = strings.NewReplacer(
+	"፡", " ", // wordspace
+	"።", ".", // full stop
+	"፣", ",", // comma
+	"፤", ";", // semicolon
+	"፥", ":", // colon
+	"፦", ":", // preface colon
+)
+
+// normalizeEthiopic replaces Ethiopic punctuation so labels and values can be
+// matched with the same patterns as English receipts
+func normalizeEthiopic(line string) string {
+	return ethiopicPunctuation.Replace(line)
+}
+
 // joinWords concatenates text fragments into a single string
 func joinWords(words []pdf.Text) string {
 	var sb strings.Builder
@@ -240,10 +368,26 @@ func extractField(line string, re *regexp.Regexp) string {
 	return ""
 }
 
-// extractReason extracts and cleans the payment reason
-func extractReason(line string) string {
-	rawReason := extractField(line, reReason)
+// normalizeCurrency returns the ISO code of the first non-empty currency token,
+// defaulting to ETB
+func normalizeCurrency(tokens ...string) string {
+	for _, token := range tokens {
+		token = strings.ToUpper(strings.TrimSpace(token))
+		switch token {
+		case "":
+			continue
+		case "ብር", "BIRR":
+			return "ETB"
+		default:
+			return token
+		}
+	}
+	return "ETB"
+}
 
+// cleanReason strips the "Type of service" label, or anything before the last
+// separator, from a payment reason
+func cleanReason(rawReason string) string {
 	// Handle "Type of service" prefix
 	if idx := strings.Index(rawReason, "Type of service"); idx != -1 {
 		rawReason = rawReason[idx+len("Type of service"):]
@@ -266,11 +410,50 @@ func extractReason(line string) string {
 	return strings.TrimSpace(rawReason)
 }
 
-// extractReferenceNumber extracts and cleans the reference number
-func extractReferenceNumber(line string) string {
-	ref := extractField(line, reReferenceNo)
-	ref = strings.TrimSpace(reParenthetical.ReplaceAllString(ref, ""))
-	return ref
+// Payment channels reported in TransactionDetails.Channel
+const (
+	ChannelMobile   = "mobile"
+	ChannelInternet = "internet"
+	ChannelATM      = "atm"
+	ChannelUSSD     = "ussd"
+	ChannelBranch   = "branch"
+	ChannelPOS      = "pos"
+)
+
+// channelKeywords map words in the printed channel to a channel, checked in order
+var channelKeywords = []struct {
+	keyword string
+	channel string
+}{
+	{"ussd", ChannelUSSD},
+	{"*889", ChannelUSSD},
+	{"mobile", ChannelMobile},
+	{"app", ChannelMobile},
+	{"internet", ChannelInternet},
+	{"online", ChannelInternet},
+	{"web", ChannelInternet},
+	{"atm", ChannelATM},
+	{"pos", ChannelPOS},
+	{"branch", ChannelBranch},
+	{"teller", ChannelBranch},
+	{"counter", ChannelBranch},
+}
+
+// normalizeChannel maps a printed payment channel to one of the Channel constants,
+// or returns it lowercased if it is not recognized
+func normalizeChannel(channel string) string {
+	lower := strings.ToLower(strings.TrimSpace(channel))
+	for _, k := range channelKeywords {
+		if strings.Contains(lower, k.keyword) {
+			return k.channel
+		}
+	}
+	return lower
+}
+
+// cleanReference strips the parenthetical label from a reference number
+func cleanReference(ref string) string {
+	return strings.TrimSpace(reParenthetical.ReplaceAllString(ref, ""))
 }
 
 // parseAmount converts amount string to float64
@@ -294,43 +477,49 @@ func getFirstAccount(accounts []string) string {
 	return ""
 }
 
-// isValidTransaction checks if all required fields are present
-func isValidTransaction(details map[string]interface{}) bool {
-	required := []string{"payer", "receiver", "payerAccount", "receiverAccount", "transaction_id", "date"}
-
-	for _, field := range required {
-		if value, exists := details[field]; !exists || value == "" {
-			return false
+// uniqueAccounts returns accounts without repeats, in order, or nil if there are none
+func uniqueAccounts(accounts []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, account := range accounts {
+		if key := NormalizeAccount(account); !seen[key] {
+			seen[key] = true
+			unique = append(unique, account)
 		}
 	}
-
-	// Check if amount is greater than 0
-	if amount, ok := details["amount"].(float64); !ok || amount <= 0 {
-		return false
-	}
-
-	return true
+	return unique
 }
 
-// getMissingFields returns a map indicating which required fields are missing
-func getMissingFields(details map[string]interface{}) map[string]interface{} {
-	missing := make(map[string]interface{})
+// missingFields returns the JSON names of required fields that were not extracted
+func missingFields(r *ParsedReceipt) []string {
+	var missing []string
 
 	// Check string fields
-	stringFields := []string{"payer", "receiver", "payerAccount", "receiverAccount", "transaction_id", "date"}
-	for _, field := range stringFields {
-		if value, exists := details[field]; !exists || value == "" {
-			missing[field] = true
-		} else {
-			missing[field] = false
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"payer", r.Payer},
+		{"receiver", r.Receiver},
+		{"payer_account", r.PayerAccount},
+		{"receiver_account", r.ReceiverAccount},
+		{"transaction_id", r.TransactionID},
+		{"date", r.DateRaw},
+	}
+	for _, field := range required {
+		// Interbank receipts name the receiver's bank instead of printing an
+		// account the issuing bank does not hold
+		if field.name == "receiver_account" && r.ReceiverBank != "" {
+			continue
+		}
+		if field.value == "" {
+			missing = append(missing, field.name)
 		}
 	}
 
-	// Check amount
-	if amount, ok := details["amount"].(float64); !ok || amount <= 0 {
-		missing["amount"] = true
-	} else {
-		missing["amount"] = false
+	// Check if amount is greater than 0
+	if r.Amount <= 0 {
+		missing = append(missing, "amount")
 	}
 
 	return missing
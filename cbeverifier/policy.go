@@ -0,0 +1,120 @@
+package cbeverifier
+
+// Requirement controls how a failed check affects the verification result
+type Requirement int
+
+const (
+	// Required checks fail verification when they do not match
+	Required Requirement = iota
+	// Advisory checks are reported in VerificationResult.Warnings without failing verification
+	Advisory
+	// Ignored checks are not performed
+	Ignored
+)
+
+// String returns the name of the requirement
+func (r Requirement) String() string {
+	switch r {
+	case Required:
+		return "required"
+	case Advisory:
+		return "advisory"
+	case Ignored:
+		return "ignored"
+	default:
+		return "unknown"
+	}
+}
+
+// Policy decides which fields must match and how strictly.
+//
+// Checks that depend on caller-supplied expectations (receiver suffix or name,
+// payer name, date window, reason keywords) only run when those expectations
+// are set, and the tamper check only runs with Options.TamperCheck. The zero
+// value requires every check to pass.
+type Policy struct {
+	// Reference controls the transaction ID check
+	Reference Requirement `json:"reference"`
+	// Amount controls the amount check
+	Amount Requirement `json:"amount"`
+	// Receiver controls the receiver account and receiver name checks
+	Receiver Requirement `json:"receiver"`
+	// Payer controls the payer name check
+	Payer Requirement `json:"payer"`
+	// Date controls the MaxAge/MinDate/MaxDate checks
+	Date Requirement `json:"date"`
+	// Reason controls the ReasonKeywords and Options.ExpectedReasonContains check
+	Reason Requirement `json:"reason"`
+	// Tamper controls the receipt signature check and whether tamper indicators
+	// found by Options.TamperCheck fail verification
+	Tamper Requirement `json:"tamper"`
+	// ReasonKeywords must all appear in the receipt's payment reason, ignoring case,
+	// spacing and punctuation
+	ReasonKeywords []string `json:"reason_keywords,omitempty"`
+}
+
+// StrictPolicy returns a policy where every check must pass. This is the default.
+func StrictPolicy() *Policy {
+	return &Policy{}
+}
+
+// LenientPolicy returns a policy where only the reference and amount must match;
+// receiver, payer, date, reason and tamper checks are reported as warnings
+func LenientPolicy() *Policy {
+	return &Policy{
+		Reference: Required,
+		Amount:    Required,
+		Receiver:  Advisory,
+		Payer:     Advisory,
+		Date:      Advisory,
+		Reason:    Advisory,
+		Tamper:    Advisory,
+	}
+}
+
+// policy returns the configured policy or the strict default
+func (o Options) policy() *Policy {
+	if o.Policy == nil {
+		return StrictPolicy()
+	}
+	return o.Policy
+}
+
+// reasonKeywords returns the keywords the payment reason must contain
+func (o Options) reasonKeywords() []string {
+	policy := o.policy()
+	if len(o.ExpectedReasonContains) == 0 {
+		return policy.ReasonKeywords
+	}
+	keywords := make([]string, 0, len(policy.ReasonKeywords)+len(o.ExpectedReasonContains))
+	keywords = append(keywords, policy.ReasonKeywords...)
+	return append(keywords, o.ExpectedReasonContains...)
+}
+
+// comparison collects failed checks according to their requirement
+type comparison struct {
+	mismatches map[string]interface{}
+	warnings   map[string]interface{}
+}
+
+// newComparison creates an empty comparison
+func newComparison() *comparison {
+	return &comparison{
+		mismatches: make(map[string]interface{}),
+		warnings:   make(map[string]interface{}),
+	}
+}
+
+// fail records a failed check for field
+func (c *comparison) fail(req Requirement, field string, provided, official interface{}) {
+	entry := map[string]interface{}{
+		"provided": provided,
+		"official": official,
+	}
+	switch req {
+	case Required:
+		c.mismatches[field] = entry
+	case Advisory:
+		c.warnings[field] = entry
+	}
+}
@@ -0,0 +1,336 @@
+package cbeverifier
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProviderCBE is the name of the built-in Commercial Bank of Ethiopia provider
+const ProviderCBE = "cbe"
+
+// Provider fetches, parses and compares the receipts of one bank or payment
+// service. Providers let other Ethiopian banks be verified through the same
+// Verify and VerifyPDF calls as CBE; see Register.
+//
+// Implementations must be safe for concurrent use.
+type Provider interface {
+	// Fetch downloads the official receipt for transaction
+	Fetch(ctx context.Context, transaction Transaction, opts Options) ([]byte, error)
+	// Parse extracts the transaction details from a receipt returned by Fetch or
+	// supplied by the customer
+	Parse(ctx context.Context, receipt []byte, opts Options) (*TransactionDetails, error)
+	// Compare verifies the provided transaction against the parsed details,
+	// usually by calling CompareDetails
+	Compare(transaction Transaction, details *TransactionDetails, opts Options) *VerificationResult
+}
+
+// ReferenceDetector is implemented by providers that recognize their own
+// references. Transactions without a Provider are routed to the provider that
+// detects their reference; see DetectProvider.
+type ReferenceDetector interface {
+	// DetectReference reports whether reference (an ID, an ID with its suffix
+	// appended, or a receipt URL) has the provider's format
+	DetectReference(reference string) bool
+}
+
+// Capabilities describe what a provider supports, so that multi-bank frontends
+// can adapt, e.g. by offering only a receipt upload for banks without an online
+// lookup
+type Capabilities struct {
+	// Fetch reports whether receipts can be looked up online by reference (Verify)
+	Fetch bool `json:"fetch"`
+	// Upload reports whether receipts supplied by the customer can be verified (VerifyPDF)
+	Upload bool `json:"upload"`
+	// UploadTypes lists the media types of the receipts Upload accepts (e.g.,
+	// "application/pdf", "text/html")
+	UploadTypes []string `json:"upload_types,omitempty"`
+	// QR reports whether receipts carry a QR code that is decoded and checked
+	QR bool `json:"qr"`
+	// Currencies lists the ISO 4217 codes of the provider's receipts, or is empty
+	// if any currency printed on the receipt is accepted
+	Currencies []string `json:"currencies,omitempty"`
+}
+
+// CapabilityReporter is implemented by providers that describe their
+// Capabilities; see ProviderCapabilities
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// defaultCapabilities returns the capabilities assumed for providers that do not
+// implement CapabilityReporter: an online lookup and PDF uploads of ETB receipts
+func defaultCapabilities() Capabilities {
+	return Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+func init() {
+	Register(ProviderCBE, cbeProvider{defaultVerifier})
+}
+
+// Register makes a provider available under name, which transactions select
+// with Transaction.Provider. Names are case-insensitive. Register is meant to be
+// called from the init function of the provider's package and panics if name is
+// already registered or provider is nil.
+//
+// Example:
+//
+//	func init() {
+//		cbeverifier.Register("mybank", &myBankProvider{})
+//	}
+func Register(name string, provider Provider) {
+	name = providerName(name)
+	if provider == nil {
+		panic("cbeverifier: Register provider " + name + " is nil")
+	}
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, dup := providers[name]; dup {
+		panic("cbeverifier: Register called twice for provider " + name)
+	}
+	providers[name] = provider
+}
+
+// LookupProvider returns the provider registered under name
+func LookupProvider(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[providerName(name)]
+	return provider, ok
+}
+
+// Providers returns the sorted names of the registered providers
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProviderCapabilities returns the capabilities of the provider registered under
+// name, or ErrUnknownProvider
+//
+// Example:
+//
+//	caps, err := cbeverifier.ProviderCapabilities(hibret.Name)
+//	if err == nil && !caps.Fetch {
+//		// Only offer a receipt upload
+//	}
+func ProviderCapabilities(name string) (Capabilities, error) {
+	return defaultVerifier.ProviderCapabilities(name)
+}
+
+// ProviderCapabilities returns the capabilities of a provider as used by the
+// Verifier, so that CBE reports QR checks when the Verifier has a QR decoder
+func (v *Verifier) ProviderCapabilities(name string) (Capabilities, error) {
+	provider, err := v.provider(Transaction{Provider: name})
+	if err != nil {
+		return Capabilities{}, err
+	}
+	if reporter, ok := provider.(CapabilityReporter); ok {
+		return reporter.Capabilities(), nil
+	}
+	return defaultCapabilities(), nil
+}
+
+// DetectProviders returns the names of the registered providers whose
+// reference format matches reference. CBE is tried first and returned alone
+// when it matches, since bare FT references are also issued by other banks;
+// otherwise every other matching provider is returned, in name order.
+//
+// Example:
+//
+//	names := cbeverifier.DetectProviders("SJK7ABC12D")
+//	// names = ["mpesa", "telebirr"], with both packages imported
+func DetectProviders(reference string) []string {
+	if strings.TrimSpace(reference) == "" {
+		return nil
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	if detector, ok := providers[ProviderCBE].(ReferenceDetector); ok && detector.DetectReference(reference) {
+		return []string{ProviderCBE}
+	}
+
+	var names []string
+	for name, provider := range providers {
+		if detector, ok := provider.(ReferenceDetector); ok && name != ProviderCBE && detector.DetectReference(reference) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectProvider returns the name of the registered provider whose reference
+// format matches reference, or false if none does, or several do (see
+// DetectProviders)
+//
+// Example:
+//
+//	name, ok := cbeverifier.DetectProvider("FT24123ABCDE12345678")
+//	// name = "cbe", ok = true
+func DetectProvider(reference string) (string, bool) {
+	names := DetectProviders(reference)
+	if len(names) != 1 {
+		return "", false
+	}
+	return names[0], true
+}
+
+// transactionProvider returns the provider name of a transaction: its Provider,
+// or else the provider detected from its reference, defaulting to ProviderCBE.
+// References of several providers fail with ErrAmbiguousReference.
+func transactionProvider(t Transaction) (string, error) {
+	if strings.TrimSpace(t.Provider) != "" {
+		return t.Provider, nil
+	}
+
+	reference := t.FullReference
+	if strings.TrimSpace(reference) == "" {
+		reference = strings.TrimSpace(t.ID) + strings.TrimSpace(t.Suffix)
+	}
+	switch names := DetectProviders(reference); len(names) {
+	case 0:
+		return ProviderCBE, nil
+	case 1:
+		return names[0], nil
+	default:
+		return "", fmt.Errorf("%w: %s; set the provider", ErrAmbiguousReference, strings.Join(names, ", "))
+	}
+}
+
+// providerName normalizes a provider name, defaulting to ProviderCBE
+func providerName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ProviderCBE
+	}
+	return name
+}
+
+// provider returns the provider for a transaction. CBE transactions use the
+// Verifier itself, so that its HTTP client, limits, cache and parsers apply.
+func (v *Verifier) provider(transaction Transaction) (Provider, error) {
+	name, err := transactionProvider(transaction)
+	if err != nil {
+		return nil, err
+	}
+	if name = providerName(name); name == ProviderCBE {
+		return cbeProvider{v}, nil
+	}
+	provider, ok := LookupProvider(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
+
+// verifyProvider verifies a transaction of a provider other than CBE
+//
+// This function:
+// 1. Fetches the official receipt from the provider
+// 2. Parses the receipt to extract transaction details
+// 3. Compares the provided data using the provider's Compare
+// 4. Claims the receipt in the replay store
+//
+// It also returns the fetched receipt, if any.
+func (v *Verifier) verifyProvider(ctx context.Context, provider Provider, transaction Transaction, opts Options) (*VerificationResult, []byte) {
+	receipt, err := provider.Fetch(ctx, transaction, opts)
+	if err != nil {
+		return NewErrorResult(err), nil
+	}
+	return v.compareProvider(ctx, provider, receipt, transaction, opts), receipt
+}
+
+// compareProvider parses a receipt with provider and compares it with transaction
+func (v *Verifier) compareProvider(ctx context.Context, provider Provider, receipt []byte, transaction Transaction, opts Options) *VerificationResult {
+	details, err := provider.Parse(ctx, receipt, opts)
+	if err != nil {
+		return NewErrorResult(err)
+	}
+
+	_, span := v.startSpan(ctx, spanCompare)
+	defer span.End()
+	// Claim the transfer the provider's Compare matched, as CompareDetails does
+	result := provider.Compare(transaction, details, opts)
+	return v.claimReceipt(ctx, result, selectTransfer(transaction, details, opts), opts)
+}
+
+// cbeProvider is the Provider for Commercial Bank of Ethiopia receipts, backed
+// by a Verifier
+type cbeProvider struct {
+	v *Verifier
+}
+
+// Fetch downloads the receipt PDF for the transaction's reference and suffix
+func (p cbeProvider) Fetch(ctx context.Context, transaction Transaction, opts Options) ([]byte, error) {
+	transaction, err := normalizeTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTransaction(transaction); err != nil {
+		return nil, err
+	}
+	return p.v.fetchReceiptPDF(ctx, transaction.ID+transaction.Suffix, opts.withDefaults())
+}
+
+// Parse parses a CBE receipt PDF
+func (p cbeProvider) Parse(ctx context.Context, receipt []byte, opts Options) (*TransactionDetails, error) {
+	opts = opts.withDefaults()
+	if int64(len(receipt)) > opts.MaxPDFBytes {
+		return nil, ErrReceiptTooLarge
+	}
+	return p.v.parseReceipt(ctx, receipt, opts)
+}
+
+// DetectReference reports whether reference is a CBE FT reference (e.g.,
+// "FT24123ABCDE", with or without the suffix) or receipt URL. References with
+// other prefixes are left to other providers; unrecognized references fall back
+// to CBE anyway.
+func (p cbeProvider) DetectReference(reference string) bool {
+	id, _, err := SplitReference(reference)
+	if err != nil {
+		id = strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+	}
+	return strings.HasPrefix(id, "FT") && reTransactionID.MatchString(id)
+}
+
+// Capabilities reports online lookup and PDF uploads in any currency, with QR
+// checks when the Verifier has a QR decoder
+func (p cbeProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"application/pdf"},
+		QR:          p.v.qr != nil,
+	}
+}
+
+// Compare verifies the transaction against CBE receipt details
+func (p cbeProvider) Compare(transaction Transaction, details *TransactionDetails, opts Options) *VerificationResult {
+	transaction, err := normalizeTransaction(transaction)
+	if err != nil {
+		return NewErrorResult(err)
+	}
+	result, _ := buildResult(transaction, details, nil, opts)
+	return result
+}
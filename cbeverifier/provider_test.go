@@ -0,0 +1,45 @@
+package cbeverifier_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/mpesa"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/telebirr"
+)
+
+func TestDetectProviders(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      []string
+	}{
+		{"FT24123ABCDE12345678", []string{cbeverifier.ProviderCBE}},
+		{"SJK7ABC12D", []string{mpesa.Name, telebirr.Name}},
+		{"https://transactioninfo.ethiotelecom.et/receipt/CE12ABC3DE", []string{telebirr.Name}},
+		{"0912345678", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := cbeverifier.DetectProviders(tt.reference); !slices.Equal(got, tt.want) {
+			t.Errorf("DetectProviders(%q) = %v, want %v", tt.reference, got, tt.want)
+		}
+	}
+
+	if name, ok := cbeverifier.DetectProvider("SJK7ABC12D"); ok {
+		t.Errorf("DetectProvider detected %q for a reference of two providers", name)
+	}
+}
+
+func TestAmbiguousReference(t *testing.T) {
+	v := cbeverifier.New()
+	result, err := v.Verify(context.Background(), cbeverifier.Transaction{ID: "SJK7ABC12D", Amount: 100}, cbeverifier.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsValid || !strings.HasPrefix(result.Error, cbeverifier.ErrAmbiguousReference.Error()) {
+		t.Errorf("IsValid = %v, Error = %q, want %v", result.IsValid, result.Error, cbeverifier.ErrAmbiguousReference)
+	}
+}
@@ -0,0 +1,152 @@
+package cbeverifier
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strings"
+
+	pdf "github.com/dslipak/pdf"
+)
+
+// ErrQRNotFound is returned when no decodable QR code is found in a receipt
+var ErrQRNotFound = errors.New("no QR code found in receipt")
+
+// reQRReference finds a CBE reference number inside a QR payload
+var reQRReference = regexp.MustCompile(`FT[A-Z0-9]{10}`)
+
+// QRDecoder decodes a QR code from an image.
+//
+// See the qr/zxing package for an implementation backed by a pure-Go decoder.
+type QRDecoder interface {
+	Decode(img image.Image) (string, error)
+}
+
+// ExtractQRPayload decodes the QR code embedded in a CBE receipt PDF
+//
+// Every image on each page is passed to decoder until one decodes.
+// ErrQRNotFound is returned when none do.
+func ExtractQRPayload(pdfBytes []byte, decoder QRDecoder) (string, error) {
+	return extractQRPayload(pdfBytes, "", decoder)
+}
+
+// extractQRPayload decodes the QR code embedded in a receipt PDF decrypted with password
+func extractQRPayload(pdfBytes []byte, password string, decoder QRDecoder) (payload string, err error) {
+	defer recoverMalformed(&err)
+
+	doc, err := openPDF(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), password)
+	if err != nil {
+		return "", err
+	}
+
+	for _, page := range pages(doc) {
+		xobjects := page.Resources().Key("XObject")
+		for _, name := range xobjects.Keys() {
+			img, err := decodePDFImage(xobjects.Key(name))
+			if err != nil {
+				continue
+			}
+			if payload, err := decoder.Decode(img); err == nil && payload != "" {
+				return payload, nil
+			}
+		}
+	}
+
+	return "", ErrQRNotFound
+}
+
+// QRReference extracts the transaction reference from a QR payload, which is
+// usually the receipt URL carrying the reference and account suffix
+func QRReference(payload string) string {
+	if id, _, err := SplitReference(payload); err == nil {
+		return id
+	}
+	return reQRReference.FindString(strings.ToUpper(payload))
+}
+
+// decodePDFImage converts an uncompressed or Flate-compressed image XObject
+// with a gray or RGB color space into an image.Image
+func decodePDFImage(v pdf.Value) (img image.Image, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, fmt.Errorf("unsupported image: %v", r)
+		}
+	}()
+
+	if v.Key("Subtype").Name() != "Image" {
+		return nil, errors.New("not an image")
+	}
+
+	// Only filters the PDF library can decode are supported
+	switch filter := v.Key("Filter"); filter.Kind() {
+	case pdf.Null:
+	case pdf.Name:
+		if filter.Name() != "FlateDecode" {
+			return nil, fmt.Errorf("unsupported filter %s", filter.Name())
+		}
+	default:
+		return nil, errors.New("unsupported filter chain")
+	}
+
+	width := int(v.Key("Width").Int64())
+	height := int(v.Key("Height").Int64())
+	bpc := int(v.Key("BitsPerComponent").Int64())
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid image size")
+	}
+
+	components := 0
+	switch v.Key("ColorSpace").Name() {
+	case "DeviceGray":
+		components = 1
+	case "DeviceRGB":
+		components = 3
+	default:
+		return nil, errors.New("unsupported color space")
+	}
+
+	rc := v.Reader()
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bpc == 8:
+		if len(data) < width*height*components {
+			return nil, errors.New("truncated image data")
+		}
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		for i := 0; i < width*height; i++ {
+			p := data[i*components : i*components+components]
+			if components == 1 {
+				gray.Pix[i] = p[0]
+			} else {
+				gray.Pix[i] = color.GrayModel.Convert(color.RGBA{p[0], p[1], p[2], 255}).(color.Gray).Y
+			}
+		}
+		return gray, nil
+
+	case bpc == 1 && components == 1:
+		stride := (width + 7) / 8
+		if len(data) < stride*height {
+			return nil, errors.New("truncated image data")
+		}
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if data[y*stride+x/8]&(0x80>>(x%8)) != 0 {
+					gray.Pix[y*width+x] = 255
+				}
+			}
+		}
+		return gray, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported bits per component %d", bpc)
+	}
+}
@@ -0,0 +1,43 @@
+// Package zxing provides a cbeverifier.QRDecoder backed by gozxing, a pure-Go
+// port of the ZXing barcode library.
+//
+// Example:
+//
+//	verifier := cbeverifier.New(cbeverifier.WithQRDecoder(zxing.New()))
+package zxing
+
+import (
+	"image"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Decoder decodes QR codes using gozxing
+type Decoder struct {
+	hints map[gozxing.DecodeHintType]interface{}
+}
+
+// New creates a Decoder that tries harder to find QR codes in noisy images,
+// such as photographed receipts
+func New() *Decoder {
+	return &Decoder{
+		hints: map[gozxing.DecodeHintType]interface{}{
+			gozxing.DecodeHintType_TRY_HARDER: true,
+		},
+	}
+}
+
+// Decode implements cbeverifier.QRDecoder
+func (d *Decoder) Decode(img image.Image) (string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, d.hints)
+	if err != nil {
+		return "", err
+	}
+	return result.GetText(), nil
+}
@@ -0,0 +1,113 @@
+package cbeverifier
+
+import (
+	"maps"
+	"time"
+)
+
+// Receipt is the bank-neutral view of a receipt issued by any provider, for
+// consumers that aggregate verifications across banks and payment services.
+// Every provider's TransactionDetails map into it with NewReceipt; fields that
+// only some providers print are kept in Extra.
+type Receipt struct {
+	// Provider is the registered name of the provider that issued the receipt
+	Provider string `json:"provider"`
+	// Reference is the transaction reference printed on the receipt
+	Reference string `json:"reference"`
+	// Payer is the party that made the payment
+	Payer Party `json:"payer"`
+	// Receiver is the party that received the payment
+	Receiver Party `json:"receiver"`
+	// Amount is the amount transferred to the receiver
+	Amount Money `json:"amount"`
+	// Fees are the charges on top of the amount
+	Fees Fees `json:"fees"`
+	// Total is the total debited from the payer, including fees, when printed
+	Total Money `json:"total"`
+	// PaidAt is the payment time in Africa/Addis_Ababa time, or zero if the printed
+	// time could not be parsed
+	PaidAt time.Time `json:"paid_at,omitzero"`
+	// PaidAtRaw is the payment time exactly as printed on the receipt
+	PaidAtRaw string `json:"paid_at_raw,omitempty"`
+	// PaidAtEC is the payment date in the Ethiopian calendar, formatted as DD/MM/YYYY
+	PaidAtEC string `json:"paid_at_ec,omitempty"`
+	// Channel is the payment channel, one of the Channel constants when recognized
+	Channel string `json:"channel,omitempty"`
+	// Type is the transaction type as printed (e.g., "Account to Account Transfer")
+	Type string `json:"type,omitempty"`
+	// Reason is the payment reason/description
+	Reason string `json:"reason,omitempty"`
+	// Branch is the branch printed on the receipt
+	Branch string `json:"branch,omitempty"`
+	// Format is the receipt layout, as in TransactionDetails.FormatVersion
+	Format string `json:"format,omitempty"`
+	// Extra holds the provider-specific fields of TransactionDetails.Extra
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// Party is the payer or receiver of a transaction
+type Party struct {
+	// Name is the party's name as printed
+	Name string `json:"name,omitempty"`
+	// Account is the party's account: an account number, or a phone number for
+	// wallets
+	Account string `json:"account,omitempty"`
+	// Accounts lists every account printed for the party, starting with Account
+	Accounts []string `json:"accounts,omitempty"`
+	// Bank is the party's bank when it differs from the issuing provider, as on
+	// interbank transfers
+	Bank string `json:"bank,omitempty"`
+}
+
+// Money is an amount in a currency
+type Money struct {
+	// Value is the amount in Currency
+	Value float64 `json:"value"`
+	// Currency is the ISO 4217 currency code (e.g., "ETB")
+	Currency string `json:"currency,omitempty"`
+}
+
+// Fees are the charges of a transfer
+type Fees struct {
+	// ServiceCharge is the fee charged for the transfer
+	ServiceCharge Money `json:"service_charge"`
+	// VAT is the VAT charged on the service charge
+	VAT Money `json:"vat"`
+}
+
+// NewReceipt maps the details parsed by provider into a Receipt. Fees and totals
+// share the currency of the amount, as every supported receipt prints them in
+// one currency.
+//
+// Example:
+//
+//	details, _, err := telebirr.Fetch(ctx, "CE12ABC3DE", cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	receipt := cbeverifier.NewReceipt(telebirr.Name, details)
+//	fmt.Println(receipt.Payer.Name, receipt.Amount.Value, receipt.Amount.Currency)
+func NewReceipt(provider string, details *TransactionDetails) *Receipt {
+	money := func(value float64) Money {
+		return Money{Value: value, Currency: details.Currency}
+	}
+
+	return &Receipt{
+		Provider:  providerName(provider),
+		Reference: details.TransactionID,
+		Payer:     Party{Name: details.Payer, Account: details.PayerAccount, Accounts: details.PayerAccounts},
+		Receiver:  Party{Name: details.Receiver, Account: details.ReceiverAccount, Accounts: details.ReceiverAccounts, Bank: details.ReceiverBank},
+		Amount:    money(details.Amount),
+		Fees:      Fees{ServiceCharge: money(details.ServiceCharge), VAT: money(details.VAT)},
+		Total:     money(details.TotalDebited),
+		PaidAt:    details.Date,
+		PaidAtRaw: details.DateRaw,
+		PaidAtEC:  details.DateEC,
+		Channel:   details.Channel,
+		Type:      details.TransactionType,
+		Reason:    details.Reason,
+		Branch:    details.Branch,
+		Format:    details.FormatVersion,
+		Extra:     maps.Clone(details.Extra),
+	}
+}
@@ -0,0 +1,454 @@
+// Package reconcile matches the payments received against the payments
+// expected, such as the orders of a shop that accepts bank transfers.
+//
+// Each payment is looked up, by fetching the official receipt of its reference
+// or by parsing the receipt the customer uploaded, and matched to an order:
+// exactly, when the payment reason contains the order's token, or else
+// heuristically, when its amount and date fit a single order. Matched receipts
+// are then verified against their order, so receivers, tampering, date windows
+// and duplicates are checked as for any other verification. The Report lists
+// the orders paid in full and in part, and the orders and receipts left
+// unmatched.
+//
+// Example:
+//
+//	reconciler := reconcile.New(cbeverifier.New())
+//	report, err := reconciler.Reconcile(ctx, []reconcile.Order{
+//		{ID: "1042", Amount: 1500, Reason: "ORD1042", From: placed, To: placed.Add(72 * time.Hour)},
+//	}, []reconcile.Payment{
+//		{Transaction: cbeverifier.Transaction{FullReference: "FT24123ABCDE12345678"}},
+//	}, opts)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, match := range report.Partial {
+//		fmt.Printf("order %s: %.2f ETB outstanding\n", match.Order.ID, match.Balance)
+//	}
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Methods a receipt is matched to its order by, as reported in Receipt.Method
+const (
+	// MethodReason matches a receipt whose reason contains the order's token
+	MethodReason = "reason"
+	// MethodAmount matches a receipt paying the amount of the only order it fits
+	MethodAmount = "amount"
+)
+
+// defaultConcurrency is the number of receipts looked up at once by default
+const defaultConcurrency = 4
+
+// errDuplicate is the error of a receipt listed more than once
+var errDuplicate = errors.New("receipt listed more than once")
+
+// Order is a payment expected
+type Order struct {
+	// ID identifies the order in the report
+	ID string `json:"id"`
+	// Amount is the amount due
+	Amount float64 `json:"amount"`
+	// Currency is the ISO 4217 code of Amount (default: "ETB")
+	Currency string `json:"currency,omitempty"`
+	// Reason is the token the payer was asked to write in the payment reason
+	// (e.g., "ORD1042"), compared ignoring case, spaces and punctuation. Orders
+	// without one are only matched by amount.
+	Reason string `json:"reason,omitempty"`
+	// From and To bound when the payment is expected; unset bounds leave the
+	// window open
+	From time.Time `json:"from,omitzero"`
+	To   time.Time `json:"to,omitzero"`
+}
+
+// Payment is a payment received: a reference to look up, or the receipt the
+// customer supplied
+type Payment struct {
+	// Transaction is the reference of the payment (ID and Suffix, or
+	// FullReference) and its Provider; the amount is ignored
+	Transaction cbeverifier.Transaction
+	// Receipt is the receipt supplied by the customer, or nil to fetch the
+	// official one
+	Receipt []byte
+}
+
+// Receipt is a reconciled payment
+type Receipt struct {
+	// Reference is the reference of the payment, as submitted or else as
+	// printed on the receipt
+	Reference string `json:"reference"`
+	// Details are the details of the receipt, if it was looked up
+	Details *cbeverifier.TransactionDetails `json:"details,omitempty"`
+	// Method is how the receipt was matched to its order: MethodReason or
+	// MethodAmount
+	Method string `json:"method,omitempty"`
+	// Candidates lists the orders an unmatched receipt fits equally well
+	Candidates []string `json:"candidates,omitempty"`
+	// Result is the verification of a matched receipt against its order
+	Result *cbeverifier.VerificationResult `json:"result,omitempty"`
+	// Error is why the receipt was rejected
+	Error string `json:"error,omitempty"`
+
+	payment Payment
+	receipt []byte
+}
+
+// Match is an order with the receipts paying it
+type Match struct {
+	Order    Order     `json:"order"`
+	Receipts []Receipt `json:"receipts"`
+	// Paid is the total amount of Receipts
+	Paid float64 `json:"paid"`
+	// Balance is the amount still due, negative for overpaid orders
+	Balance float64 `json:"balance"`
+}
+
+// Report is the outcome of a reconciliation. Orders and receipts are listed in
+// the order they were passed to Reconcile.
+type Report struct {
+	// Paid lists the orders paid in full, within the amount tolerance of the
+	// options
+	Paid []Match `json:"paid"`
+	// Partial lists the orders paid in part
+	Partial []Match `json:"partial"`
+	// UnmatchedOrders lists the orders no receipt was matched to
+	UnmatchedOrders []Order `json:"unmatched_orders"`
+	// UnmatchedReceipts lists the receipts matched to no order
+	UnmatchedReceipts []Receipt `json:"unmatched_receipts"`
+	// Rejected lists the receipts that could not be looked up, were listed
+	// more than once, or failed verification against the order they matched
+	Rejected []Receipt `json:"rejected"`
+}
+
+// Reconciler reconciles orders with the payments received, looking up and
+// verifying receipts with a Verifier
+type Reconciler struct {
+	verifier    *cbeverifier.Verifier
+	concurrency int
+}
+
+// Option configures a Reconciler
+type Option func(*Reconciler)
+
+// WithConcurrency sets how many receipts are looked up and verified at once
+// (default: 4)
+func WithConcurrency(n int) Option {
+	return func(r *Reconciler) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// New creates a Reconciler looking up and verifying receipts with verifier
+func New(verifier *cbeverifier.Verifier, options ...Option) *Reconciler {
+	r := &Reconciler{
+		verifier:    verifier,
+		concurrency: defaultConcurrency,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// order is an order being reconciled
+type order struct {
+	Order
+	token    string
+	receipts []*Receipt
+}
+
+// Reconcile matches payments to orders and verifies the matched receipts with
+// opts, to which the window and token of their order are added
+//
+// Receipts are first matched by token: a receipt whose reason contains the
+// tokens of several orders pays the one with the longest token, and an order
+// may be paid by several receipts. Receipts without a token are then matched by
+// amount to the orders left unpaid, when the receipt fits a single order and no
+// other receipt fits it. Receipts whose date is unknown fit every window.
+//
+// Only matched receipts are verified, so a receipt that pays no order is not
+// claimed in the replay store and can still be matched later. An error is only
+// returned if ctx is done.
+func (r *Reconciler) Reconcile(ctx context.Context, orders []Order, payments []Payment, opts cbeverifier.Options) (*Report, error) {
+	receipts := make([]*Receipt, len(payments))
+	r.each(len(payments), func(i int) {
+		receipts[i] = r.lookup(ctx, payments[i], opts)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rejectDuplicates(receipts)
+
+	states := make([]*order, len(orders))
+	for i, o := range orders {
+		states[i] = &order{Order: o, token: normalizeToken(o.Reason)}
+	}
+	matchByReason(states, receipts)
+	matchByAmount(states, receipts, opts)
+
+	r.each(len(receipts), func(i int) {
+		if receipts[i].Method != "" {
+			r.verify(ctx, receipts[i], states, opts)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return report(states, receipts, opts), nil
+}
+
+// each calls fn with 0 to n-1, running up to the Reconciler's concurrency at
+// once
+func (r *Reconciler) each(n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(r.concurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// lookup looks up the receipt of a payment
+func (r *Reconciler) lookup(ctx context.Context, payment Payment, opts cbeverifier.Options) *Receipt {
+	receipt := &Receipt{
+		Reference: reference(payment.Transaction),
+		payment:   payment,
+	}
+	details, raw, err := r.verifier.Lookup(ctx, payment.Transaction, payment.Receipt, opts)
+	if err != nil {
+		receipt.Error = err.Error()
+		return receipt
+	}
+	receipt.Details, receipt.receipt = details, raw
+	if receipt.Reference == "" {
+		receipt.Reference = details.TransactionID
+	}
+	return receipt
+}
+
+// verify verifies a matched receipt against its order, rejecting it if it
+// fails
+func (r *Reconciler) verify(ctx context.Context, receipt *Receipt, states []*order, opts cbeverifier.Options) {
+	o := orderOf(receipt, states)
+
+	// The amount is reconciled over all the receipts of the order, so each is
+	// verified against its own
+	transaction := receipt.payment.Transaction
+	if transaction.ID == "" && transaction.FullReference == "" {
+		transaction.ID = receipt.Details.TransactionID
+	}
+	transaction.Amount = receipt.Details.Amount
+	transaction.Currency = currency(o.Currency)
+
+	if !o.From.IsZero() && o.From.After(opts.MinDate) {
+		opts.MinDate = o.From
+	}
+	if !o.To.IsZero() && (opts.MaxDate.IsZero() || o.To.Before(opts.MaxDate)) {
+		opts.MaxDate = o.To
+	}
+	if receipt.Method == MethodReason {
+		opts.ExpectedReasonContains = append(append([]string(nil), opts.ExpectedReasonContains...), o.Reason)
+	}
+
+	// The receipt looked up is verified, rather than fetched again
+	result, err := r.verifier.VerifyPDF(ctx, receipt.receipt, transaction, opts)
+	switch {
+	case err != nil:
+		receipt.Error = err.Error()
+	case !result.IsValid:
+		receipt.Error = result.Error
+	}
+	receipt.Result = result
+}
+
+// orderOf returns the order a receipt was matched to
+func orderOf(receipt *Receipt, states []*order) *order {
+	for _, o := range states {
+		for _, r := range o.receipts {
+			if r == receipt {
+				return o
+			}
+		}
+	}
+	return nil
+}
+
+// rejectDuplicates rejects the receipts already listed, by the reference
+// printed on them
+func rejectDuplicates(receipts []*Receipt) {
+	seen := make(map[string]bool)
+	for _, receipt := range receipts {
+		if receipt.Details == nil || receipt.Details.TransactionID == "" {
+			continue
+		}
+		id := strings.ToUpper(receipt.Details.TransactionID)
+		if seen[id] {
+			receipt.Error = errDuplicate.Error()
+		}
+		seen[id] = true
+	}
+}
+
+// matchByReason matches receipts to the order whose token their reason
+// contains
+func matchByReason(states []*order, receipts []*Receipt) {
+	for _, receipt := range receipts {
+		if receipt.Error != "" {
+			continue
+		}
+		reason := normalizeToken(receipt.Details.Reason)
+
+		var best *order
+		for _, o := range states {
+			if o.token == "" || !strings.Contains(reason, o.token) || !fits(o, receipt.Details) {
+				continue
+			}
+			if best == nil || len(o.token) > len(best.token) {
+				best = o
+			}
+		}
+		if best != nil {
+			receipt.Method = MethodReason
+			best.receipts = append(best.receipts, receipt)
+		}
+	}
+}
+
+// matchByAmount matches the receipts left to the unpaid order they are the
+// only receipt to fit, when it is the only order they fit
+func matchByAmount(states []*order, receipts []*Receipt, opts cbeverifier.Options) {
+	candidates := make(map[*Receipt][]*order)
+	fitting := make(map[*order]int)
+	for _, receipt := range receipts {
+		if receipt.Error != "" || receipt.Method != "" {
+			continue
+		}
+		for _, o := range states {
+			if len(o.receipts) == 0 && fits(o, receipt.Details) && amountMatches(o.Amount, receipt.Details.Amount, opts) {
+				candidates[receipt] = append(candidates[receipt], o)
+				fitting[o]++
+			}
+		}
+	}
+
+	for _, receipt := range receipts {
+		orders := candidates[receipt]
+		if len(orders) == 1 && fitting[orders[0]] == 1 {
+			receipt.Method = MethodAmount
+			orders[0].receipts = append(orders[0].receipts, receipt)
+			continue
+		}
+		for _, o := range orders {
+			receipt.Candidates = append(receipt.Candidates, o.ID)
+		}
+	}
+}
+
+// fits reports whether a receipt is in the currency and window of an order
+func fits(o *order, details *cbeverifier.TransactionDetails) bool {
+	if !strings.EqualFold(currency(o.Currency), currency(details.Currency)) {
+		return false
+	}
+	if details.Date.IsZero() {
+		return true
+	}
+	return (o.From.IsZero() || !details.Date.Before(o.From)) && (o.To.IsZero() || !details.Date.After(o.To))
+}
+
+// report builds the report of reconciled orders and receipts
+func report(states []*order, receipts []*Receipt, opts cbeverifier.Options) *Report {
+	rep := &Report{}
+	for _, o := range states {
+		match := Match{Order: o.Order}
+		for _, receipt := range o.receipts {
+			if receipt.Error != "" {
+				continue
+			}
+			match.Receipts = append(match.Receipts, *receipt)
+			match.Paid += receipt.Details.Amount
+		}
+		match.Paid = round2(match.Paid)
+		match.Balance = round2(o.Amount - match.Paid)
+
+		switch {
+		case len(match.Receipts) == 0:
+			rep.UnmatchedOrders = append(rep.UnmatchedOrders, o.Order)
+		case match.Paid >= o.Amount || amountMatches(o.Amount, match.Paid, opts):
+			rep.Paid = append(rep.Paid, match)
+		default:
+			rep.Partial = append(rep.Partial, match)
+		}
+	}
+
+	for _, receipt := range receipts {
+		switch {
+		case receipt.Error != "":
+			rep.Rejected = append(rep.Rejected, *receipt)
+		case receipt.Method == "":
+			rep.UnmatchedReceipts = append(rep.UnmatchedReceipts, *receipt)
+		}
+	}
+	return rep
+}
+
+// amountMatches compares an amount paid with the amount due, within the
+// tolerance of opts
+func amountMatches(due, paid float64, opts cbeverifier.Options) bool {
+	tolerance := opts.AmountTolerance
+	if pct := due * opts.AmountTolerancePercent / 100; pct > tolerance {
+		tolerance = pct
+	}
+	return math.Abs(round2(paid)-round2(due)) <= round2(tolerance)+0.001
+}
+
+// reference returns the reference a payment was submitted with
+func reference(t cbeverifier.Transaction) string {
+	if t.FullReference != "" {
+		return t.FullReference
+	}
+	return t.ID + t.Suffix
+}
+
+// currency returns an ISO 4217 code, defaulting to ETB
+func currency(code string) string {
+	if code = strings.TrimSpace(code); code != "" {
+		return code
+	}
+	return "ETB"
+}
+
+// normalizeToken lowercases s and keeps only its letters and digits
+func normalizeToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// round2 rounds an amount to cents
+func round2(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,100 @@
+package cbeverifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Attempt is a finished verification, as passed to a Recorder
+type Attempt struct {
+	// Transaction is the transaction as submitted
+	Transaction Transaction
+	// Result is the outcome of the verification
+	Result *VerificationResult
+	// Outcome classifies Result: OutcomeVerified, OutcomeMismatch, OutcomeReview
+	// or OutcomeError
+	Outcome string
+	// MerchantID is Options.MerchantID
+	MerchantID string
+	// ReceiptSHA256 is the hex SHA-256 of the receipt verified against, or empty
+	// if none was fetched or supplied
+	ReceiptSHA256 string
+	// ReceiptKey is the key the receipt was archived under, or empty without a
+	// Verifier archiver (see WithArchiver)
+	ReceiptKey string
+	// StartedAt and FinishedAt bound the verification
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Recorder records every verification attempt, e.g. to keep a history for
+// audits and disputes. Implementations must be safe for concurrent use.
+type Recorder interface {
+	// Record stores a finished verification
+	Record(ctx context.Context, attempt *Attempt) error
+}
+
+// WithRecorder makes the Verifier pass every finished verification to recorder.
+// Recording happens before Verify returns; failures are logged at warn level
+// and do not change the result.
+func WithRecorder(recorder Recorder) VerifierOption {
+	return func(v *Verifier) {
+		v.recorder = recorder
+	}
+}
+
+// MultiRecorder returns a Recorder passing every attempt to each of recorders
+// in turn, e.g. to keep a history and an audit log, and post alerts. It
+// returns the errors of the recorders that failed, joined.
+func MultiRecorder(recorders ...Recorder) Recorder {
+	return multiRecorder(recorders)
+}
+
+// multiRecorder is the Recorder of MultiRecorder
+type multiRecorder []Recorder
+
+// Record passes attempt to every recorder
+func (m multiRecorder) Record(ctx context.Context, attempt *Attempt) error {
+	var errs []error
+	for _, recorder := range m {
+		if err := recorder.Record(ctx, attempt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReceiptFingerprint returns the hex SHA-256 of a receipt, as recorded in
+// Attempt.ReceiptSHA256
+func ReceiptFingerprint(receipt []byte) string {
+	sum := sha256.Sum256(receipt)
+	return hex.EncodeToString(sum[:])
+}
+
+// record passes a finished verification, whose receipt was archived under
+// receiptKey, to the recorder
+func (v *Verifier) record(ctx context.Context, start time.Time, transaction Transaction, result *VerificationResult, receipt []byte, receiptKey string, opts Options) {
+	if v.recorder == nil {
+		return
+	}
+
+	attempt := &Attempt{
+		Transaction: transaction,
+		Result:      result,
+		Outcome:     resultOutcome(result),
+		MerchantID:  opts.MerchantID,
+		ReceiptKey:  receiptKey,
+		StartedAt:   start,
+		FinishedAt:  time.Now(),
+	}
+	if len(receipt) > 0 {
+		attempt.ReceiptSHA256 = ReceiptFingerprint(receipt)
+	}
+	// Attempts that ran out of time are recorded too
+	if err := v.recorder.Record(context.WithoutCancel(ctx), attempt); err != nil {
+		v.logger.WarnContext(ctx, "failed to record verification", "reference", redact(transaction.ID), "error", err)
+	}
+}
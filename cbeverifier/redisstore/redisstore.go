@@ -0,0 +1,136 @@
+// Package redisstore implements the cbeverifier Cache and ReplayStore
+// interfaces on Redis, so that horizontally scaled servers share cached
+// receipts and claimed references.
+//
+// It is a module of its own, so that the verifier does not depend on a Redis
+// client. Any redis.UniversalClient can be used: a single node, a Sentinel
+// failover client or a Cluster.
+//
+// Example:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	verifier := cbeverifier.New(
+//		cbeverifier.WithCache(redisstore.NewCache(client, 24*time.Hour)),
+//		cbeverifier.WithReplayStore(redisstore.NewReplayStore(client, 0)),
+//	)
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Default key prefixes of the cache and the replay store
+const (
+	defaultCachePrefix  = "cbe:receipt:"
+	defaultReplayPrefix = "cbe:claim:"
+)
+
+// Option configures a Cache or a ReplayStore
+type Option func(*config)
+
+// config holds the settings shared by Cache and ReplayStore
+type config struct {
+	prefix string
+}
+
+// WithPrefix sets the prefix of the keys (default: "cbe:receipt:" for the
+// cache, "cbe:claim:" for the replay store), e.g. to share a database between
+// environments
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// newConfig applies options over the default prefix
+func newConfig(prefix string, options []Option) config {
+	c := config{prefix: prefix}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// Cache is a cbeverifier.Cache storing receipts as JSON strings that expire
+// after a TTL
+type Cache struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	prefix string
+}
+
+// NewCache creates a cache keeping each receipt in client for at most ttl. A
+// non-positive ttl keeps receipts until Redis evicts them, which requires a
+// maxmemory policy such as allkeys-lru.
+func NewCache(client redis.UniversalClient, ttl time.Duration, options ...Option) *Cache {
+	c := newConfig(defaultCachePrefix, options)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &Cache{client: client, ttl: ttl, prefix: c.prefix}
+}
+
+// Get returns the cached receipt for reference. Redis errors and undecodable
+// entries are cache misses.
+func (c *Cache) Get(ctx context.Context, reference string) (*cbeverifier.CacheEntry, bool) {
+	data, err := c.client.Get(ctx, c.prefix+reference).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	var entry cbeverifier.CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set stores the receipt for reference. Failures are ignored: the receipt is
+// fetched from CBE again on the next miss.
+func (c *Cache) Set(ctx context.Context, reference string, entry *cbeverifier.CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.client.Set(ctx, c.prefix+reference, data, c.ttl)
+}
+
+// ReplayStore is a cbeverifier.ReplayStore claiming references with SET NX, so
+// that only one of the servers verifying a reference at once succeeds
+type ReplayStore struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	prefix string
+}
+
+// NewReplayStore creates a replay store keeping each claim in client for ttl.
+// Once a claim expires the receipt can be used again, so ttl should be longer
+// than Options.MaxAge; a non-positive ttl keeps claims until they are released.
+func NewReplayStore(client redis.UniversalClient, ttl time.Duration, options ...Option) *ReplayStore {
+	c := newConfig(defaultReplayPrefix, options)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return &ReplayStore{client: client, ttl: ttl, prefix: c.prefix}
+}
+
+// Claim marks reference as used by merchant, storing the time of the claim
+func (s *ReplayStore) Claim(ctx context.Context, merchant, reference string) (bool, error) {
+	return s.client.SetNX(ctx, s.key(merchant, reference), time.Now().UTC().Format(time.RFC3339), s.ttl).Result()
+}
+
+// Release removes a claim
+func (s *ReplayStore) Release(ctx context.Context, merchant, reference string) error {
+	return s.client.Del(ctx, s.key(merchant, reference)).Err()
+}
+
+// key returns the key of a claim. References have no colons, so the key is
+// unambiguous whatever the merchant ID.
+func (s *ReplayStore) key(merchant, reference string) string {
+	return s.prefix + merchant + ":" + reference
+}
@@ -0,0 +1,72 @@
+package cbeverifier
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// reFullReference matches a reference number followed by the 8-digit account suffix
+var reFullReference = regexp.MustCompile(`^([A-Z]{2}[A-Z0-9]{10})(\d{8})$`)
+
+// reTransactionID matches a well-formed reference number (e.g., "FT24123ABCDE")
+var reTransactionID = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{10}$`)
+
+// SplitReference splits a full CBE reference into the transaction ID and suffix
+//
+// It accepts:
+//   - the ID concatenated with the suffix (e.g., "FT24123ABCDE12345678")
+//   - a receipt URL as sent in the CBE SMS (e.g., "https://apps.cbe.com.et:100/?id=FT24123ABCDE12345678")
+//
+// Surrounding whitespace, inner spaces and lowercase letters are normalized.
+// ErrInvalidReference is returned when the input cannot be split.
+//
+// Example:
+//
+//	id, suffix, err := cbeverifier.SplitReference("FT24123ABCDE12345678")
+//	// id = "FT24123ABCDE", suffix = "12345678"
+func SplitReference(full string) (id, suffix string, err error) {
+	ref := strings.TrimSpace(full)
+
+	// Extract the id query parameter from receipt URLs
+	if strings.Contains(ref, "://") || strings.Contains(ref, "?id=") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", "", ErrInvalidReference
+		}
+		ref = u.Query().Get("id")
+	}
+
+	ref = strings.ToUpper(strings.Join(strings.Fields(ref), ""))
+
+	matches := reFullReference.FindStringSubmatch(ref)
+	if matches == nil {
+		return "", "", ErrInvalidReference
+	}
+
+	return matches[1], matches[2], nil
+}
+
+// normalizeTransaction fills ID and Suffix from FullReference, or splits an ID
+// that already has the suffix appended
+func normalizeTransaction(t Transaction) (Transaction, error) {
+	if strings.TrimSpace(t.FullReference) != "" {
+		id, suffix, err := SplitReference(t.FullReference)
+		if err != nil {
+			return t, err
+		}
+		t.ID, t.Suffix = id, suffix
+		return t, nil
+	}
+
+	// Users often paste the concatenated reference into ID and leave Suffix empty
+	if strings.TrimSpace(t.Suffix) == "" {
+		if id, suffix, err := SplitReference(t.ID); err == nil {
+			t.ID, t.Suffix = id, suffix
+		}
+	}
+
+	t.ID = strings.TrimSpace(t.ID)
+	t.Suffix = strings.TrimSpace(t.Suffix)
+	return t, nil
+}
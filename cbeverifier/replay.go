@@ -0,0 +1,156 @@
+package cbeverifier
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplayStore records which receipts have already been used so that the same
+// reference can only verify successfully once per merchant.
+//
+// Implementations must be safe for concurrent use, and Claim must be atomic:
+// when two verifications of the same reference race, only one may succeed.
+type ReplayStore interface {
+	// Claim marks reference as used by merchant. It returns false if the
+	// reference was already claimed.
+	Claim(ctx context.Context, merchant, reference string) (bool, error)
+	// Release removes a claim, e.g. when the order it was used for is cancelled
+	Release(ctx context.Context, merchant, reference string) error
+}
+
+// replayKey normalizes a reference for replay tracking
+func replayKey(reference string) string {
+	return strings.ToUpper(strings.TrimSpace(reference))
+}
+
+// claimReceipt claims the verified transfer in the replay store, turning a valid
+// result into a failure if it was already used. Each transfer of a bulk receipt
+// is claimed under its own reference, so transfer is the one that was matched.
+func (v *Verifier) claimReceipt(ctx context.Context, result *VerificationResult, transfer *TransactionDetails, opts Options) *VerificationResult {
+	if v.replay == nil || !result.IsValid {
+		return result
+	}
+
+	claimed, err := v.replay.Claim(ctx, opts.MerchantID, replayKey(transfer.TransactionID))
+	if err != nil {
+		return NewErrorResult(fmt.Errorf("could not record receipt use: %w", err))
+	}
+	if !claimed {
+		return NewErrorResult(ErrReceiptAlreadyUsed)
+	}
+	return result
+}
+
+// MemoryReplayStore is an in-memory ReplayStore. Claims are lost when the
+// process exits, so it is best suited to tests and single-instance deployments.
+type MemoryReplayStore struct {
+	mu     sync.Mutex
+	claims map[[2]string]time.Time
+}
+
+// NewMemoryReplayStore creates an empty in-memory replay store
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{
+		claims: make(map[[2]string]time.Time),
+	}
+}
+
+// Claim marks reference as used by merchant
+func (s *MemoryReplayStore) Claim(_ context.Context, merchant, reference string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{merchant, reference}
+	if _, ok := s.claims[key]; ok {
+		return false, nil
+	}
+	s.claims[key] = time.Now()
+	return true, nil
+}
+
+// Release removes a claim
+func (s *MemoryReplayStore) Release(_ context.Context, merchant, reference string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claims, [2]string{merchant, reference})
+	return nil
+}
+
+// reTableName restricts table names to safe SQL identifiers
+var reTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLReplayStore is a ReplayStore backed by a database/sql table with a primary
+// key on (merchant, reference). It uses INSERT ... ON CONFLICT DO NOTHING and
+// works with PostgreSQL and SQLite.
+type SQLReplayStore struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+}
+
+// NewSQLReplayStore creates a replay store using table in db. Set dollarPlaceholders
+// for drivers using $1-style placeholders (PostgreSQL); otherwise ? is used.
+func NewSQLReplayStore(db *sql.DB, table string, dollarPlaceholders bool) (*SQLReplayStore, error) {
+	if !reTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &SQLReplayStore{
+		db:     db,
+		table:  table,
+		dollar: dollarPlaceholders,
+	}, nil
+}
+
+// CreateTable creates the replay table if it does not already exist
+func (s *SQLReplayStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	merchant  VARCHAR(128) NOT NULL,
+	reference VARCHAR(64)  NOT NULL,
+	used_at   TIMESTAMP    NOT NULL,
+	PRIMARY KEY (merchant, reference)
+)`, s.table))
+	return err
+}
+
+// Claim marks reference as used by merchant
+func (s *SQLReplayStore) Claim(ctx context.Context, merchant, reference string) (bool, error) {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (merchant, reference, used_at) VALUES (%s, %s, %s) ON CONFLICT DO NOTHING",
+		s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3),
+	)
+
+	res, err := s.db.ExecContext(ctx, query, merchant, reference, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Release removes a claim
+func (s *SQLReplayStore) Release(ctx context.Context, merchant, reference string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE merchant = %s AND reference = %s",
+		s.table, s.placeholder(1), s.placeholder(2),
+	)
+	_, err := s.db.ExecContext(ctx, query, merchant, reference)
+	return err
+}
+
+// placeholder returns the nth bind parameter for the configured driver
+func (s *SQLReplayStore) placeholder(n int) string {
+	if s.dollar {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
@@ -0,0 +1,34 @@
+package cbeverifier
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClaimBulkTransfers(t *testing.T) {
+	v := New(WithReplayStore(NewMemoryReplayStore()))
+	ctx := context.Background()
+	opts := DefaultOptions()
+
+	verify := func(transaction Transaction) *VerificationResult {
+		return v.compare(ctx, transaction, bulkReceipt(true), nil, opts)
+	}
+
+	// Each transfer of the receipt is claimed under its own reference
+	second := Transaction{ID: "FT24123FGHIJ", Amount: 2750}
+	if result := verify(second); !result.IsValid {
+		t.Fatalf("second transfer: %s %v", result.Error, result.Mismatches)
+	}
+	first := Transaction{ID: "FT24123ABCDE", Amount: 1500}
+	if result := verify(first); !result.IsValid {
+		t.Fatalf("first transfer after the second: %s %v", result.Error, result.Mismatches)
+	}
+
+	// Replaying a transfer fails, whichever transfer was verified first
+	if result := verify(second); result.IsValid || result.Error != ErrReceiptAlreadyUsed.Error() {
+		t.Errorf("replayed second transfer: IsValid = %v, Error = %q", result.IsValid, result.Error)
+	}
+	if result := verify(first); result.IsValid || result.Error != ErrReceiptAlreadyUsed.Error() {
+		t.Errorf("replayed first transfer: IsValid = %v, Error = %q", result.IsValid, result.Error)
+	}
+}
@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownJob is returned by MemoryQueue.Update for jobs it does not hold
+var ErrUnknownJob = errors.New("retry: unknown job")
+
+// MemoryQueue is a Queue held in memory, whose jobs are lost when the process
+// exits
+type MemoryQueue struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewMemoryQueue creates an empty MemoryQueue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{jobs: make(map[string]Job)}
+}
+
+// Add queues a new job
+func (q *MemoryQueue) Add(_ context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[job.ID] = *job
+	return nil
+}
+
+// Due returns copies of the jobs due at now, the longest overdue first
+func (q *MemoryQueue) Due(_ context.Context, now time.Time) ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []*Job
+	for _, job := range q.jobs {
+		if !job.NextAttempt.After(now) {
+			due = append(due, &job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	return due, nil
+}
+
+// Update stores a job after a failed attempt
+func (q *MemoryQueue) Update(_ context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.jobs[job.ID]; !ok {
+		return ErrUnknownJob
+	}
+	q.jobs[job.ID] = *job
+	return nil
+}
+
+// Remove deletes a finished job
+func (q *MemoryQueue) Remove(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.jobs, id)
+	return nil
+}
+
+// Len returns the number of jobs waiting
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
@@ -0,0 +1,315 @@
+// Package retry re-attempts verifications that failed upstream. CBE sometimes
+// publishes a receipt minutes after the transfer, and answers lookups of it
+// with a page that is not a PDF until then; the receipt services of the banks
+// are also unreachable at times.
+//
+// A Scheduler verifies a transaction and, when the verification fails with a
+// retryable error, queues it and attempts it again at increasing intervals
+// until it gets a conclusive result or is older than the maximum age. The
+// conclusive result is posted to the transaction's Options.WebhookURL as
+// cbeverifier.WebhookEventRetried, and a verification given up as
+// cbeverifier.WebhookEventExpired.
+//
+// Example:
+//
+//	scheduler := retry.New(verifier, retry.WithMaxAge(6*time.Hour))
+//	go scheduler.Run(ctx)
+//
+//	opts.WebhookURL = "https://shop.example.com/payments/cbe"
+//	result, job, err := scheduler.Verify(ctx, transaction, opts)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if job != nil {
+//		// Tell the customer the payment is being confirmed
+//	}
+package retry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Defaults of a Scheduler
+const (
+	defaultMaxAge       = 24 * time.Hour
+	defaultPollInterval = 15 * time.Second
+)
+
+// defaultIntervals are the delays before each retry by default, the last one
+// repeating until the job expires
+var defaultIntervals = []time.Duration{
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+}
+
+// defaultRetryable are the errors retried by default: CBE answering before the
+// receipt is published, and CBE being unreachable
+var defaultRetryable = []error{
+	cbeverifier.ErrInvalidPDFResponse,
+	cbeverifier.ErrNetworkError,
+	cbeverifier.ErrUpstreamUnavailable,
+	context.DeadlineExceeded,
+}
+
+// Job is a verification waiting to be retried
+type Job struct {
+	// ID identifies the job
+	ID string `json:"id"`
+	// Transaction and Options are those of the verification
+	Transaction cbeverifier.Transaction `json:"transaction"`
+	Options     cbeverifier.Options     `json:"options"`
+	// Attempts is the number of attempts made, including the first
+	Attempts int `json:"attempts"`
+	// CreatedAt is the time of the first attempt, from which the job's age is
+	// counted
+	CreatedAt time.Time `json:"created_at"`
+	// NextAttempt is when the job is due
+	NextAttempt time.Time `json:"next_attempt"`
+	// LastError is the error of the last attempt
+	LastError string `json:"last_error"`
+}
+
+// Queue holds the jobs of a Scheduler. MemoryQueue keeps them in memory; other
+// implementations may persist them so that retries survive restarts, but must
+// keep the Options fields that are not encoded as JSON, such as WebhookSecret,
+// themselves. Implementations must be safe for concurrent use.
+type Queue interface {
+	// Add queues a new job
+	Add(ctx context.Context, job *Job) error
+	// Due returns the jobs whose NextAttempt is not after now
+	Due(ctx context.Context, now time.Time) ([]*Job, error)
+	// Update stores a job after a failed attempt
+	Update(ctx context.Context, job *Job) error
+	// Remove deletes a finished job
+	Remove(ctx context.Context, id string) error
+}
+
+// Scheduler verifies transactions, retrying those that fail upstream
+type Scheduler struct {
+	verifier  *cbeverifier.Verifier
+	queue     Queue
+	intervals []time.Duration
+	maxAge    time.Duration
+	poll      time.Duration
+	retryable []error
+	logger    *slog.Logger
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// WithQueue keeps jobs in queue (default: a MemoryQueue)
+func WithQueue(queue Queue) Option {
+	return func(s *Scheduler) {
+		if queue != nil {
+			s.queue = queue
+		}
+	}
+}
+
+// WithIntervals sets the delays before each retry, the last one repeating
+// until the job expires (default: 1m, 2m, 5m, 10m, 30m, 1h)
+func WithIntervals(intervals ...time.Duration) Option {
+	return func(s *Scheduler) {
+		if len(intervals) > 0 {
+			s.intervals = intervals
+		}
+	}
+}
+
+// WithMaxAge gives up on jobs whose first attempt is older than d (default:
+// 24h)
+func WithMaxAge(d time.Duration) Option {
+	return func(s *Scheduler) {
+		if d > 0 {
+			s.maxAge = d
+		}
+	}
+}
+
+// WithPollInterval sets how often Run looks for due jobs (default: 15s)
+func WithPollInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		if d > 0 {
+			s.poll = d
+		}
+	}
+}
+
+// WithRetryableErrors retries verifications failing with errs too, such as the
+// network and not found errors of other providers (e.g.,
+// telebirr.ErrNetworkError, telebirr.ErrReceiptNotFound)
+func WithRetryableErrors(errs ...error) Option {
+	return func(s *Scheduler) {
+		s.retryable = append(s.retryable, errs...)
+	}
+}
+
+// WithLogger logs attempts and their outcome to logger (default: discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Scheduler) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// New creates a Scheduler verifying transactions with verifier
+func New(verifier *cbeverifier.Verifier, options ...Option) *Scheduler {
+	s := &Scheduler{
+		verifier:  verifier,
+		queue:     NewMemoryQueue(),
+		intervals: defaultIntervals,
+		maxAge:    defaultMaxAge,
+		poll:      defaultPollInterval,
+		retryable: append([]error(nil), defaultRetryable...),
+		logger:    slog.New(slog.DiscardHandler),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Verify verifies transaction and, if it failed with a retryable error,
+// schedules it for retry, returning its job. An error is only returned if the
+// job could not be queued.
+func (s *Scheduler) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, *Job, error) {
+	result, err := s.verifier.Verify(ctx, transaction, opts)
+	if err != nil {
+		return result, nil, err
+	}
+	if !s.Retryable(result) {
+		return result, nil, nil
+	}
+
+	job, err := s.Schedule(ctx, transaction, opts, result.Error)
+	if err != nil {
+		return result, nil, err
+	}
+	return result, job, nil
+}
+
+// Schedule queues a transaction whose verification failed with lastError, for
+// its first retry after the first interval
+func (s *Scheduler) Schedule(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options, lastError string) (*Job, error) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	now := time.Now()
+	job := &Job{
+		ID:          hex.EncodeToString(id),
+		Transaction: transaction,
+		Options:     opts,
+		Attempts:    1,
+		CreatedAt:   now,
+		NextAttempt: now.Add(s.interval(1)),
+		LastError:   lastError,
+	}
+	if err := s.queue.Add(ctx, job); err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "scheduled verification retry", "job", job.ID, "next_attempt", job.NextAttempt, "error", lastError)
+	return job, nil
+}
+
+// Retryable reports whether a verification failed with one of the retryable
+// errors. Results report their error as a message, which starts with the
+// message of the error that caused it.
+func (s *Scheduler) Retryable(result *cbeverifier.VerificationResult) bool {
+	if result.IsValid || len(result.Mismatches) > 0 || result.Error == "" {
+		return false
+	}
+	for _, err := range s.retryable {
+		if message := err.Error(); result.Error == message || strings.HasPrefix(result.Error, message+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Run attempts the due jobs every poll interval until ctx is done, returning
+// ctx.Err()
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.poll)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue attempts the jobs that are due once, e.g. from a cron job instead of
+// Run
+func (s *Scheduler) RunDue(ctx context.Context) {
+	jobs, err := s.queue.Due(ctx, time.Now())
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to list due verification retries", "error", err)
+		return
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.attempt(ctx, job)
+	}
+}
+
+// attempt retries the verification of a job, and finishes the job or
+// schedules its next attempt
+func (s *Scheduler) attempt(ctx context.Context, job *Job) {
+	// Failed attempts are not posted; the conclusive result is
+	opts := job.Options
+	opts.WebhookURL = ""
+	result, err := s.verifier.Verify(ctx, job.Transaction, opts)
+	if err != nil || ctx.Err() != nil {
+		return
+	}
+	job.Attempts++
+	logger := s.logger.With("job", job.ID, "attempts", job.Attempts)
+
+	now := time.Now()
+	switch {
+	case !s.Retryable(result):
+		logger.InfoContext(ctx, "verification retried", "valid", result.IsValid)
+		s.finish(ctx, job, cbeverifier.WebhookEventRetried, result)
+	case now.Sub(job.CreatedAt) >= s.maxAge:
+		logger.WarnContext(ctx, "verification retry expired", "error", result.Error)
+		s.finish(ctx, job, cbeverifier.WebhookEventExpired, result)
+	default:
+		job.LastError = result.Error
+		job.NextAttempt = now.Add(s.interval(job.Attempts))
+		if err := s.queue.Update(ctx, job); err != nil {
+			logger.WarnContext(ctx, "failed to update verification retry", "error", err)
+		}
+	}
+}
+
+// finish removes a job and posts its result as event
+func (s *Scheduler) finish(ctx context.Context, job *Job, event string, result *cbeverifier.VerificationResult) {
+	if err := s.queue.Remove(ctx, job.ID); err != nil {
+		s.logger.WarnContext(ctx, "failed to remove verification retry", "job", job.ID, "error", err)
+	}
+	if job.Options.WebhookURL != "" {
+		s.verifier.SendWebhook(ctx, event, job.Transaction, result, job.Options)
+	}
+}
+
+// interval returns the delay before the attempt that follows attempts
+func (s *Scheduler) interval(attempts int) time.Duration {
+	return s.intervals[min(attempts, len(s.intervals))-1]
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// maxBatchTransactions limits the transactions of a batch job
+const maxBatchTransactions = 1000
+
+// maxBatchBytes limits the size of POST /v1/batch request bodies
+const maxBatchBytes = 1 << 20
+
+// batchRetention is how long the results of a finished batch job are kept
+const batchRetention = time.Hour
+
+// heartbeatInterval is how often an idle event stream gets a comment, so that
+// proxies do not close it
+const heartbeatInterval = 15 * time.Second
+
+// defaultBatchLimit is the default number of batch jobs running at once
+const defaultBatchLimit = 8
+
+// errBatchBusy is the error of batch jobs submitted while as many as the
+// server's limit are running
+var errBatchBusy = errors.New("too many batch jobs in progress")
+
+// errUnknownBatch is the error of requests for a batch job that does not
+// exist, has expired or belongs to another merchant
+var errUnknownBatch = errors.New("unknown batch job")
+
+// BatchResult is the result of one transaction of a batch job
+type BatchResult struct {
+	// Index is the position of the transaction in the batch
+	Index       int                             `json:"index"`
+	Transaction cbeverifier.Transaction         `json:"transaction"`
+	Result      *cbeverifier.VerificationResult `json:"result"`
+}
+
+// BatchStatus is the progress of a batch job
+type BatchStatus struct {
+	ID string `json:"id"`
+	// Total is the number of transactions of the batch
+	Total int `json:"total"`
+	// Completed is the number of transactions verified
+	Completed int `json:"completed"`
+	// Done reports whether every transaction has been verified
+	Done bool `json:"done"`
+	// Results are the results so far, in the order they completed; they are
+	// left out of the responses of POST /v1/batch and of the done event
+	Results []BatchResult `json:"results,omitempty"`
+}
+
+// WithBatchConcurrency sets how many transactions of each batch job are
+// verified at once (default: 4)
+func WithBatchConcurrency(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WithBatchLimit sets how many batch jobs may run at once (default: 8).
+// Batches submitted past the limit get 503.
+func WithBatchLimit(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.batchJobs = make(chan struct{}, n)
+		}
+	}
+}
+
+// batchJob is a batch being verified, or verified less than batchRetention ago
+type batchJob struct {
+	id       string
+	merchant string
+	total    int
+
+	mu       sync.Mutex
+	results  []BatchResult
+	finished time.Time
+	// changed is closed, and replaced, when a result is added
+	changed chan struct{}
+}
+
+// add records the result of a transaction
+func (j *batchJob) add(result BatchResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results = append(j.results, result)
+	if len(j.results) == j.total {
+		j.finished = time.Now()
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// status returns the progress of the job, with the results from position from
+// on, and a channel closed when the next result is added
+func (j *batchJob) status(from int) (BatchStatus, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := BatchStatus{
+		ID:        j.id,
+		Total:     j.total,
+		Completed: len(j.results),
+		Done:      len(j.results) == j.total,
+	}
+	if from < len(j.results) {
+		status.Results = append([]BatchResult(nil), j.results[from:]...)
+	}
+	return status, j.changed
+}
+
+// expired reports whether a job finished more than batchRetention before now
+func (j *batchJob) expired(now time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero() && now.Sub(j.finished) > batchRetention
+}
+
+// ServeBatch serves POST /v1/batch, starting the verification of the JSON
+// array of transactions in the request body and responding 202 with the
+// job's BatchStatus, or 503 while as many jobs as WithBatchLimit allows are
+// running. The results are read from GET /v1/batch/{id}, or
+// streamed from GET /v1/batch/{id}/events as they complete. Like ServeVerify,
+// it skips the API key checks of ServeHTTP.
+func (s *Server) ServeBatch(w http.ResponseWriter, r *http.Request) {
+	opts, status, err := s.options(r)
+	if err != nil {
+		s.error(w, r, status, err)
+		return
+	}
+
+	var transactions []cbeverifier.Transaction
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&transactions); err != nil {
+		s.error(w, r, requestStatus(err), fmt.Errorf("invalid batch: %w", err))
+		return
+	}
+	switch {
+	case len(transactions) == 0:
+		s.error(w, r, http.StatusBadRequest, errors.New("empty batch"))
+		return
+	case len(transactions) > maxBatchTransactions:
+		s.error(w, r, http.StatusRequestEntityTooLarge, fmt.Errorf("batch of more than %d transactions", maxBatchTransactions))
+		return
+	}
+
+	select {
+	case s.batchJobs <- struct{}{}:
+	default:
+		s.error(w, r, http.StatusServiceUnavailable, errBatchBusy)
+		return
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		<-s.batchJobs
+		s.error(w, r, http.StatusInternalServerError, fmt.Errorf("batch job ID: %w", err))
+		return
+	}
+	job := &batchJob{
+		id:       hex.EncodeToString(id),
+		merchant: opts.MerchantID,
+		total:    len(transactions),
+		changed:  make(chan struct{}),
+	}
+	s.addBatch(job)
+
+	// The job outlives the request
+	go func() {
+		defer func() { <-s.batchJobs }()
+		s.runBatch(context.WithoutCancel(r.Context()), job, transactions, opts)
+	}()
+	initial, _ := job.status(len(transactions))
+	writeJSON(w, http.StatusAccepted, initial)
+}
+
+// runBatch verifies the transactions of a job, up to the server's concurrency
+// at once
+func (s *Server) runBatch(ctx context.Context, job *batchJob, transactions []cbeverifier.Transaction, opts cbeverifier.Options) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.concurrency, len(transactions)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result, err := s.verifier.Verify(ctx, transactions[i], opts)
+				if err != nil {
+					result = cbeverifier.NewErrorResult(err)
+				}
+				job.add(BatchResult{Index: i, Transaction: transactions[i], Result: result})
+			}
+		}()
+	}
+	for i := range transactions {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// addBatch registers a job, dropping the jobs that expired
+func (s *Server) addBatch(job *batchJob) {
+	s.batchMu.Lock()
+	defer s.batchMu.Unlock()
+	now := time.Now()
+	for id, old := range s.batches {
+		if old.expired(now) {
+			delete(s.batches, id)
+		}
+	}
+	s.batches[job.id] = job
+}
+
+// batch returns the job of a request's {id}, if it belongs to the request's
+// merchant
+func (s *Server) batch(w http.ResponseWriter, r *http.Request) (*batchJob, bool) {
+	opts, status, err := s.options(r)
+	if err != nil {
+		s.error(w, r, status, err)
+		return nil, false
+	}
+
+	s.batchMu.Lock()
+	job, ok := s.batches[r.PathValue("id")]
+	s.batchMu.Unlock()
+	if !ok || job.merchant != opts.MerchantID || job.expired(time.Now()) {
+		s.error(w, r, http.StatusNotFound, errUnknownBatch)
+		return nil, false
+	}
+	return job, true
+}
+
+// ServeBatchStatus serves GET /v1/batch/{id}, responding with the progress
+// and results of a batch job
+func (s *Server) ServeBatchStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.batch(w, r)
+	if !ok {
+		return
+	}
+	status, _ := job.status(0)
+	writeJSON(w, http.StatusOK, status)
+}
+
+// ServeBatchEvents serves GET /v1/batch/{id}/events, streaming the results of
+// a batch job as Server-Sent Events: a "result" event with each BatchResult,
+// whose event ID is its position in the stream, then a "done" event with the
+// BatchStatus. Results already completed are sent first; clients that
+// reconnect with Last-Event-ID resume after that event.
+func (s *Server) ServeBatchEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.batch(w, r)
+	if !ok {
+		return
+	}
+	sent := 0
+	if last, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil && last >= 0 {
+		sent = last + 1
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		status, changed := job.status(sent)
+		for _, result := range status.Results {
+			data, _ := json.Marshal(result)
+			fmt.Fprintf(w, "event: result\nid: %d\ndata: %s\n\n", sent, data)
+			sent++
+		}
+		if status.Done {
+			status.Results = nil
+			data, _ := json.Marshal(status)
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
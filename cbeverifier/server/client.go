@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Client calls the endpoints of a Server, following its OpenAPI document
+//
+// Example:
+//
+//	client := server.NewClient("http://verifier.internal:8080", server.WithAPIKey(key))
+//	result, err := client.Verify(ctx, cbeverifier.Transaction{
+//		ID:     "FT24123ABCDE",
+//		Suffix: "12345678",
+//		Amount: 1500,
+//	})
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	merchant   string
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithAPIKey authenticates the requests with key, for servers with API keys
+func WithAPIKey(key string) ClientOption {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithMerchant names the merchant the requests are made for, for admin keys
+// of servers with merchants (see WithMerchantAdmins)
+func WithMerchant(id string) ClientOption {
+	return func(c *Client) {
+		c.merchant = id
+	}
+}
+
+// WithHTTPClient sends the requests with httpClient (default:
+// http.DefaultClient)
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// NewClient creates a Client calling the server at baseURL (e.g.,
+// "http://localhost:8080")
+func NewClient(baseURL string, options ...ClientOption) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// StatusError is an error response of the server
+type StatusError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Message is the error reported by the server
+	Message string
+	// RetryAfter is how long to wait before retrying a 429 response
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Verify verifies a transaction against its official receipt. A transaction
+// that does not match is a result with IsValid false, not an error.
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction) (*cbeverifier.VerificationResult, error) {
+	body, err := json.Marshal(transaction)
+	if err != nil {
+		return nil, err
+	}
+	var result cbeverifier.VerificationResult
+	if err := c.do(ctx, "/v1/verify", "application/json", body, http.StatusOK, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// VerifyAsync submits a transaction for verification, leaving the result to
+// the server's webhook
+func (c *Client) VerifyAsync(ctx context.Context, transaction cbeverifier.Transaction) error {
+	body, err := json.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "/v1/verify?async=true", "application/json", body, http.StatusAccepted, nil)
+}
+
+// SubmitBatch starts the verification of transactions as a batch job, whose
+// results are read with Batch or StreamBatch
+func (c *Client) SubmitBatch(ctx context.Context, transactions []cbeverifier.Transaction) (*BatchStatus, error) {
+	body, err := json.Marshal(transactions)
+	if err != nil {
+		return nil, err
+	}
+	var status BatchStatus
+	if err := c.do(ctx, "/v1/batch", "application/json", body, http.StatusAccepted, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Batch returns the progress of a batch job, with its results so far
+func (c *Client) Batch(ctx context.Context, id string) (*BatchStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/batch/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var status BatchStatus
+	if err := c.send(req, http.StatusOK, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// StreamBatch calls fn with each result of a batch job as it completes, until
+// the job is done or fn fails, and returns the final status
+func (c *Client) StreamBatch(ctx context.Context, id string, fn func(BatchResult) error) (*BatchStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/batch/"+url.PathEscape(id)+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			switch event {
+			case "result":
+				var result BatchResult
+				if err := json.Unmarshal([]byte(data), &result); err != nil {
+					return nil, fmt.Errorf("invalid event: %w", err)
+				}
+				if err := fn(result); err != nil {
+					return nil, err
+				}
+			case "done":
+				var status BatchStatus
+				if err := json.Unmarshal([]byte(data), &status); err != nil {
+					return nil, fmt.Errorf("invalid event: %w", err)
+				}
+				return &status, nil
+			}
+			event, data = "", ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// ParseReceipt extracts the details of a receipt issued by provider (default:
+// CBE)
+func (c *Client) ParseReceipt(ctx context.Context, receipt []byte, provider string) (*cbeverifier.TransactionDetails, error) {
+	path := "/v1/parse"
+	if provider != "" {
+		path += "?provider=" + url.QueryEscape(provider)
+	}
+	var details cbeverifier.TransactionDetails
+	if err := c.do(ctx, path, "application/pdf", receipt, http.StatusOK, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// Health reports whether the server is up
+func (c *Client) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/healthz", nil)
+	if err != nil {
+		return err
+	}
+	return c.send(req, http.StatusOK, nil)
+}
+
+// Ready reports whether the server can verify receipts, failing with a
+// StatusError of 503 while CBE is unreachable
+func (c *Client) Ready(ctx context.Context) (*Readiness, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/readyz", nil)
+	if err != nil {
+		return nil, err
+	}
+	var readiness Readiness
+	if err := c.send(req, http.StatusOK, &readiness); err != nil {
+		return nil, err
+	}
+	return &readiness, nil
+}
+
+// do posts body to path and decodes the JSON response into v, failing with a
+// StatusError unless the server responds with status
+func (c *Client) do(ctx context.Context, path, contentType string, body []byte, status int, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.send(req, status, v)
+}
+
+// send sends a request and decodes its JSON response into v, if not nil
+func (c *Client) send(req *http.Request, status int, v any) error {
+	req.Header.Set("Accept", "application/json")
+	c.authorize(req)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return responseError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// authorize sets the API key and merchant headers of a request
+func (c *Client) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.merchant != "" {
+		req.Header.Set(MerchantHeader, c.merchant)
+	}
+}
+
+// responseError returns the StatusError of an unexpected response
+func responseError(resp *http.Response) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil {
+		statusErr.Message = body.Error
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		statusErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return statusErr
+}
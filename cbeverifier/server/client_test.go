@@ -0,0 +1,351 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// openAPI is the decoded OpenAPI document
+type openAPI map[string]any
+
+func loadOpenAPI(t *testing.T) openAPI {
+	t.Helper()
+	// Nested objects are decoded with the type of the document, so it is
+	// decoded as a plain map
+	var doc map[string]any
+	if err := yaml.Unmarshal(OpenAPI, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// node returns the object at a path of keys below node, following $refs
+func (doc openAPI) node(node map[string]any, keys ...string) map[string]any {
+	for _, key := range keys {
+		next, _ := node[key].(map[string]any)
+		node = doc.resolve(next)
+	}
+	return node
+}
+
+// resolve returns the object a node refers to with $ref, or node itself
+func (doc openAPI) resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	return doc.node(doc, strings.Split(strings.TrimPrefix(ref, "#/"), "/")...)
+}
+
+// operation returns the path template and operation of a request
+func (doc openAPI) operation(method, path string) (string, map[string]any) {
+	for template, item := range doc.node(doc, "paths") {
+		pattern := "^" + regexp.MustCompile(`\\\{[^}]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), `[^/]+`) + "$"
+		if regexp.MustCompile(pattern).MatchString(path) {
+			if op := doc.node(item.(map[string]any), strings.ToLower(method)); op != nil {
+				return template, op
+			}
+		}
+	}
+	return "", nil
+}
+
+// parameters returns the names of the parameters of an operation in a place
+// (query, header or path)
+func (doc openAPI) parameters(op map[string]any, in string) []string {
+	var names []string
+	params, _ := op["parameters"].([]any)
+	for _, param := range params {
+		if p := doc.resolve(param.(map[string]any)); p["in"] == in {
+			names = append(names, p["name"].(string))
+		}
+	}
+	return names
+}
+
+// sample returns a JSON value of a schema, with every property set, nesting
+// objects depth levels at most
+func (doc openAPI) sample(schema map[string]any, depth int) any {
+	schema = doc.resolve(schema)
+	if values, ok := schema["enum"].([]any); ok {
+		return values[0]
+	}
+	switch schema["type"] {
+	case "string":
+		if schema["format"] == "date-time" {
+			return "2025-09-05T10:30:00Z"
+		}
+		return "FT24123ABCDE"
+	case "number":
+		return 1500.5
+	case "integer":
+		return 2
+	case "boolean":
+		return true
+	case "array":
+		if depth == 0 {
+			return []any{}
+		}
+		return []any{doc.sample(doc.node(schema, "items"), depth-1)}
+	}
+	object := make(map[string]any)
+	if depth == 0 {
+		return object
+	}
+	for name, property := range doc.node(schema, "properties") {
+		object[name] = doc.sample(property.(map[string]any), depth-1)
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+		object["key"] = doc.sample(additional, depth-1)
+	}
+	return object
+}
+
+// clientOperations are the operations the Client calls. The others are for
+// GraphQL clients and SDK generators.
+var clientOperations = []string{"verify", "parseReceipt", "submitBatch", "batchStatus", "batchEvents", "health", "ready"}
+
+func TestClientRoutes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		operation string
+		// status is the status the server answers with
+		status int
+		call   func(c *Client) error
+	}{
+		{"verify", http.StatusOK, func(c *Client) error {
+			_, err := c.Verify(ctx, cbeverifier.Transaction{ID: "FT24123ABCDE", Suffix: "12345678", Amount: 1500})
+			return err
+		}},
+		{"verify", http.StatusAccepted, func(c *Client) error {
+			return c.VerifyAsync(ctx, cbeverifier.Transaction{ID: "FT24123ABCDE", Suffix: "12345678", Amount: 1500})
+		}},
+		{"parseReceipt", http.StatusOK, func(c *Client) error {
+			_, err := c.ParseReceipt(ctx, []byte("%PDF-1.4"), "telebirr")
+			return err
+		}},
+		{"submitBatch", http.StatusAccepted, func(c *Client) error {
+			_, err := c.SubmitBatch(ctx, []cbeverifier.Transaction{{ID: "FT24123ABCDE", Suffix: "12345678", Amount: 1500}})
+			return err
+		}},
+		{"batchStatus", http.StatusOK, func(c *Client) error {
+			_, err := c.Batch(ctx, "b1")
+			return err
+		}},
+		{"batchEvents", http.StatusOK, func(c *Client) error {
+			_, err := c.StreamBatch(ctx, "b1", func(BatchResult) error { return nil })
+			return err
+		}},
+		{"health", http.StatusOK, func(c *Client) error { return c.Health(ctx) }},
+		{"ready", http.StatusOK, func(c *Client) error {
+			_, err := c.Ready(ctx)
+			return err
+		}},
+	}
+
+	called := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.operation, tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				template, op := doc.operation(r.Method, r.URL.Path)
+				if op == nil {
+					t.Errorf("%s %s is not in the OpenAPI document", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				if op["operationId"] != tt.operation {
+					t.Errorf("%s %s is operation %v, want %s", r.Method, template, op["operationId"], tt.operation)
+				}
+				called[tt.operation] = true
+
+				for name := range r.URL.Query() {
+					if !slices.Contains(doc.parameters(op, "query"), name) {
+						t.Errorf("%s %s: query parameter %q is not declared", r.Method, template, name)
+					}
+				}
+				// Public operations ignore credentials
+				if public := op["security"] != nil; !public && r.Header.Get(MerchantHeader) != "" && !slices.Contains(doc.parameters(op, "header"), MerchantHeader) {
+					t.Errorf("%s %s: header %s is not declared", r.Method, template, MerchantHeader)
+				}
+				if contentType := r.Header.Get("Content-Type"); contentType != "" && doc.node(op, "requestBody", "content", contentType) == nil {
+					t.Errorf("%s %s: request content type %s is not declared", r.Method, template, contentType)
+				}
+
+				response := doc.node(op, "responses", fmt.Sprint(tt.status))
+				if response == nil {
+					t.Errorf("%s %s: status %d is not declared", r.Method, template, tt.status)
+				}
+				accept := r.Header.Get("Accept")
+				content := doc.node(response, "content", accept)
+				if content == nil {
+					t.Errorf("%s %s: response content type %s is not declared", r.Method, template, accept)
+				}
+				body, _ := json.Marshal(doc.sample(doc.node(content, "schema"), 4))
+				w.Header().Set("Content-Type", accept)
+				w.WriteHeader(tt.status)
+				if accept == "text/event-stream" {
+					result, _ := json.Marshal(doc.sample(doc.node(doc, "components", "schemas", "BatchResult"), 4))
+					status, _ := json.Marshal(doc.sample(doc.node(doc, "components", "schemas", "BatchStatus"), 4))
+					fmt.Fprintf(w, "id: 1\nevent: result\ndata: %s\n\nevent: done\ndata: %s\n\n", result, status)
+					return
+				}
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			client := NewClient(srv.URL, WithAPIKey("secret"), WithMerchant("habesha-crafts"))
+			if err := tt.call(client); err != nil {
+				t.Errorf("client: %v", err)
+			}
+		})
+	}
+
+	for _, operation := range clientOperations {
+		if !called[operation] {
+			t.Errorf("the client does not call %s", operation)
+		}
+	}
+	for _, item := range doc.node(doc, "paths") {
+		for _, op := range item.(map[string]any) {
+			id := doc.resolve(op.(map[string]any))["operationId"].(string)
+			if !slices.Contains(clientOperations, id) && id != "graphql" && id != "openAPI" {
+				t.Errorf("operation %s is neither called by the client nor left out on purpose", id)
+			}
+		}
+	}
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+func TestClientTypes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	schemas := map[string]reflect.Type{
+		"Transaction":        reflect.TypeFor[cbeverifier.Transaction](),
+		"VerificationResult": reflect.TypeFor[cbeverifier.VerificationResult](),
+		"TransactionDetails": reflect.TypeFor[cbeverifier.TransactionDetails](),
+		"BatchStatus":        reflect.TypeFor[BatchStatus](),
+		"BatchResult":        reflect.TypeFor[BatchResult](),
+		"Readiness":          reflect.TypeFor[Readiness](),
+	}
+	names := make(map[reflect.Type]string, len(schemas))
+	for name, typ := range schemas {
+		names[typ] = name
+	}
+
+	for name, typ := range schemas {
+		t.Run(name, func(t *testing.T) {
+			schema := doc.node(doc, "components", "schemas", name)
+			properties := doc.node(schema, "properties")
+			required, _ := schema["required"].([]any)
+
+			var keys []string
+			for i := range typ.NumField() {
+				field := typ.Field(i)
+				tag := field.Tag.Get("json")
+				key, options, _ := strings.Cut(tag, ",")
+				if !field.IsExported() || key == "-" {
+					continue
+				}
+				keys = append(keys, key)
+				property, ok := properties[key].(map[string]any)
+				if !ok {
+					t.Errorf("%s.%s: %q is not a property of the schema", typ.Name(), field.Name, key)
+					continue
+				}
+				if slices.Contains(required, any(key)) && options != "" {
+					t.Errorf("%s.%s: required property %q is left out when empty", typ.Name(), field.Name, key)
+				}
+				if err := matchType(doc, property, field.Type, names); err != nil {
+					t.Errorf("%s.%s: %v", typ.Name(), field.Name, err)
+				}
+			}
+			for property := range properties {
+				if !slices.Contains(keys, property) {
+					t.Errorf("property %q of the schema is not a field of %s", property, typ.Name())
+				}
+			}
+
+			// Responses of the document decode into the type, and back into the
+			// same JSON
+			sample, _ := json.Marshal(doc.sample(schema, 1))
+			decoder := json.NewDecoder(bytes.NewReader(sample))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(reflect.New(typ).Interface()); err != nil {
+				t.Errorf("decoding %s: %v", sample, err)
+			}
+		})
+	}
+}
+
+// matchType checks that a property of the document has the JSON type of a Go
+// type; struct types must refer to the schema they are named after
+func matchType(doc openAPI, property map[string]any, typ reflect.Type, names map[reflect.Type]string) error {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if ref, ok := property["$ref"].(string); ok {
+		want, ok := names[typ]
+		if !ok || ref != "#/components/schemas/"+want {
+			return fmt.Errorf("%s refers to %s", typ, ref)
+		}
+		return nil
+	}
+
+	var want string
+	switch {
+	case typ == timeType:
+		if property["format"] != "date-time" {
+			return fmt.Errorf("time.Time is not a date-time: %v", property)
+		}
+		want = "string"
+	case typ == durationType:
+		want = "integer"
+	case typ.Kind() == reflect.String:
+		want = "string"
+	case typ.Kind() == reflect.Bool:
+		want = "boolean"
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		want = "number"
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		want = "integer"
+	case typ.Kind() == reflect.Slice:
+		if property["type"] != "array" {
+			return fmt.Errorf("%s is not an array: %v", typ, property["type"])
+		}
+		items, _ := property["items"].(map[string]any)
+		return matchType(doc, items, typ.Elem(), names)
+	case typ.Kind() == reflect.Map:
+		if property["type"] != "object" {
+			return fmt.Errorf("%s is not an object: %v", typ, property["type"])
+		}
+		if additional, ok := property["additionalProperties"].(map[string]any); ok && typ.Elem().Kind() != reflect.Interface {
+			return matchType(doc, additional, typ.Elem(), names)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unexpected type %s", typ)
+	}
+	if property["type"] != want {
+		return fmt.Errorf("%s is %v, want %s", typ, property["type"], want)
+	}
+	return nil
+}
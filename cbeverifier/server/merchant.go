@@ -0,0 +1,259 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+)
+
+// MerchantHeader names the merchant of a request made with an admin key (see
+// WithMerchantAdmins), or to a server trusting the header (see
+// WithTrustedMerchantHeader)
+const MerchantHeader = "X-Merchant-ID"
+
+// Errors of requests to a server with merchants
+var (
+	ErrMissingMerchant = errors.New("missing merchant")
+	ErrUnknownMerchant = errors.New("unknown merchant")
+	ErrForeignMerchant = errors.New("API key belongs to another merchant")
+	ErrNotMerchant     = errors.New("request is not authenticated as a merchant")
+)
+
+// Merchant is a tenant of a server serving the shops of a marketplace, each
+// verified against its own account, with its own policy and webhook
+//
+// Merchants are listed in code or in a YAML file:
+//
+//	merchants:
+//	  - id: habesha-crafts
+//	    receiver_suffix: "12345678"
+//	    policy: lenient
+//	    webhook_url: https://habesha-crafts.example.com/payments/cbe
+//	    webhook_secret: 3f9a1c7e
+//	    keys:
+//	      - name: habesha-crafts
+//	        key: 8c1f0e7d4b2a49f6a3d5
+//	        rps: 2
+type Merchant struct {
+	// ID identifies the merchant, as Options.MerchantID, so that each merchant
+	// has its own replay claims and history
+	ID string
+	// ReceiverSuffix overrides Options.ExpectedReceiverSuffix
+	ReceiverSuffix string
+	// Policy overrides Options.Policy
+	Policy *cbeverifier.Policy
+	// WebhookURL and WebhookSecret override those of the options
+	WebhookURL    string
+	WebhookSecret string
+	// Keys are the API keys of the merchant, whose requests are verified for
+	// the merchant alone
+	Keys []apikey.Key
+}
+
+// Merchants is a set of merchants, selected per request by API key, or by
+// MerchantHeader for admins
+type Merchants struct {
+	byID  map[string]*Merchant
+	byKey map[string]*Merchant
+	keys  *apikey.Keys
+}
+
+// NewMerchants creates a set of merchants. IDs must be unique, and key names
+// unique across merchants and distinct from those of the server's own keys
+// (see CheckKeys).
+func NewMerchants(merchants ...Merchant) (*Merchants, error) {
+	m := &Merchants{
+		byID:  make(map[string]*Merchant, len(merchants)),
+		byKey: make(map[string]*Merchant),
+	}
+	var keys []apikey.Key
+	for i, merchant := range merchants {
+		switch {
+		case merchant.ID == "":
+			return nil, fmt.Errorf("merchant %d has no ID", i+1)
+		case m.byID[merchant.ID] != nil:
+			return nil, fmt.Errorf("merchant ID %q is used twice", merchant.ID)
+		}
+		m.byID[merchant.ID] = &merchant
+		for _, key := range merchant.Keys {
+			m.byKey[key.Name] = &merchant
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) > 0 {
+		k, err := apikey.New(keys...)
+		if err != nil {
+			return nil, err
+		}
+		m.keys = k
+	}
+	return m, nil
+}
+
+// LoadMerchants reads the merchants listed in a YAML file. Policies are named
+// "strict" (the default) or "lenient".
+func LoadMerchants(path string) (*Merchants, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Merchants []struct {
+			ID             string       `yaml:"id"`
+			ReceiverSuffix string       `yaml:"receiver_suffix"`
+			Policy         string       `yaml:"policy"`
+			WebhookURL     string       `yaml:"webhook_url"`
+			WebhookSecret  string       `yaml:"webhook_secret"`
+			Keys           []apikey.Key `yaml:"keys"`
+		} `yaml:"merchants"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	merchants := make([]Merchant, 0, len(file.Merchants))
+	for _, entry := range file.Merchants {
+		merchant := Merchant{
+			ID:             entry.ID,
+			ReceiverSuffix: entry.ReceiverSuffix,
+			WebhookURL:     entry.WebhookURL,
+			WebhookSecret:  entry.WebhookSecret,
+			Keys:           entry.Keys,
+		}
+		switch entry.Policy {
+		case "", "strict":
+		case "lenient":
+			merchant.Policy = cbeverifier.LenientPolicy()
+		default:
+			return nil, fmt.Errorf("%s: merchant %q has an unknown policy %q", path, entry.ID, entry.Policy)
+		}
+		merchants = append(merchants, merchant)
+	}
+	m, err := NewMerchants(merchants...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// CheckKeys returns an error if one of keys, the server's own keys of
+// WithAPIKeys, has the name of a merchant's key, since requests are bound to
+// the merchant owning their key by its name
+func (m *Merchants) CheckKeys(keys *apikey.Keys) error {
+	for _, name := range keys.Names() {
+		if owner, ok := m.byKey[name]; ok {
+			return fmt.Errorf("key name %q is used by the server and by merchant %q", name, owner.ID)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the merchant with id
+func (m *Merchants) Lookup(id string) (Merchant, bool) {
+	merchant, ok := m.byID[id]
+	if !ok {
+		return Merchant{}, false
+	}
+	return *merchant, true
+}
+
+// Options returns opts with the settings of the merchant overriding them
+func (m Merchant) Options(opts cbeverifier.Options) cbeverifier.Options {
+	opts.MerchantID = m.ID
+	if m.ReceiverSuffix != "" {
+		opts.ExpectedReceiverSuffix = m.ReceiverSuffix
+	}
+	if m.Policy != nil {
+		opts.Policy = m.Policy
+	}
+	if m.WebhookURL != "" {
+		opts.WebhookURL = m.WebhookURL
+		opts.WebhookSecret = m.WebhookSecret
+	}
+	return opts
+}
+
+// WithMerchants serves merchants, each request being verified with the options
+// of its merchant: the merchant whose key authenticated it, or, for admins, the
+// one named by MerchantHeader. Requests naming another merchant than their
+// key's get 403, as do requests made with another key, unless it is an admin's
+// (see WithMerchantAdmins and WithTrustedMerchantHeader). The keys of the
+// merchants are accepted alongside those of WithAPIKeys, whose names must
+// differ from theirs (see Merchants.CheckKeys).
+func WithMerchants(merchants *Merchants) Option {
+	return func(s *Server) {
+		s.merchants = merchants
+	}
+}
+
+// WithMerchantAdmins lets the server's keys (see WithAPIKeys) with names act
+// for any merchant, e.g. for a back office, by naming it in MerchantHeader.
+// Requests naming no merchant, or an unknown one, get 400. Other server keys
+// cannot select a merchant.
+func WithMerchantAdmins(names ...string) Option {
+	return func(s *Server) {
+		for _, name := range names {
+			s.merchantAdmins[name] = true
+		}
+	}
+}
+
+// WithTrustedMerchantHeader selects the merchant of requests made without an
+// API key by MerchantHeader, for servers without keys behind a gateway that
+// authenticates the merchants itself and sets the header. Without it, such
+// requests get 403 on servers with merchants.
+func WithTrustedMerchantHeader() Option {
+	return func(s *Server) {
+		s.trustMerchantHeader = true
+	}
+}
+
+// merchant returns the merchant of a request
+func (s *Server) merchant(r *http.Request) (Merchant, error) {
+	named := r.Header.Get(MerchantHeader)
+	key, authenticated := apikey.FromContext(r.Context())
+	if authenticated {
+		if owner, ok := s.merchants.byKey[key.Name]; ok {
+			if named != "" && named != owner.ID {
+				return Merchant{}, ErrForeignMerchant
+			}
+			return *owner, nil
+		}
+	}
+
+	// Only admins choose the merchant they act for
+	if authenticated && !s.merchantAdmins[key.Name] || !authenticated && !s.trustMerchantHeader {
+		return Merchant{}, ErrNotMerchant
+	}
+	if named == "" {
+		return Merchant{}, ErrMissingMerchant
+	}
+	merchant, ok := s.merchants.Lookup(named)
+	if !ok {
+		return Merchant{}, fmt.Errorf("%w: %q", ErrUnknownMerchant, named)
+	}
+	return merchant, nil
+}
+
+// options returns the options of a request's verifications: those of the
+// server, with the settings of the request's merchant
+func (s *Server) options(r *http.Request) (cbeverifier.Options, int, error) {
+	if s.merchants == nil {
+		return s.opts, 0, nil
+	}
+	merchant, err := s.merchant(r)
+	switch {
+	case errors.Is(err, ErrForeignMerchant), errors.Is(err, ErrNotMerchant):
+		return s.opts, http.StatusForbidden, err
+	case err != nil:
+		return s.opts, http.StatusBadRequest, err
+	}
+	return merchant.Options(s.opts), 0, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+)
+
+// testMerchants returns two merchants, the first with a key
+func testMerchants(t *testing.T) *Merchants {
+	t.Helper()
+	merchants, err := NewMerchants(
+		Merchant{ID: "habesha-crafts", Keys: []apikey.Key{{Name: "habesha-crafts", Key: "merchant-secret"}}},
+		Merchant{ID: "merkato-books"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merchants
+}
+
+func TestMerchantSelection(t *testing.T) {
+	keys, err := apikey.New(
+		apikey.Key{Name: "back-office", Key: "admin-secret"},
+		apikey.Key{Name: "billing", Key: "server-secret"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyed := New(cbeverifier.New(), WithAPIKeys(keys), WithMerchants(testMerchants(t)), WithMerchantAdmins("back-office"))
+	// Servers whose merchants have no keys do not authenticate requests
+	unkeyed, err := NewMerchants(Merchant{ID: "habesha-crafts"}, Merchant{ID: "merkato-books"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyless := New(cbeverifier.New(), WithMerchants(unkeyed))
+	trusting := New(cbeverifier.New(), WithMerchants(unkeyed), WithTrustedMerchantHeader())
+
+	tests := []struct {
+		name     string
+		server   *Server
+		key      string
+		merchant string
+		want     int
+	}{
+		{"merchant key", keyed, "merchant-secret", "", http.StatusNotFound},
+		{"merchant key naming its merchant", keyed, "merchant-secret", "habesha-crafts", http.StatusNotFound},
+		{"merchant key naming another merchant", keyed, "merchant-secret", "merkato-books", http.StatusForbidden},
+		{"server key naming a merchant", keyed, "server-secret", "merkato-books", http.StatusForbidden},
+		{"server key naming no merchant", keyed, "server-secret", "", http.StatusForbidden},
+		{"admin key naming a merchant", keyed, "admin-secret", "merkato-books", http.StatusNotFound},
+		{"admin key naming no merchant", keyed, "admin-secret", "", http.StatusBadRequest},
+		{"admin key naming an unknown merchant", keyed, "admin-secret", "unknown", http.StatusBadRequest},
+		{"no key", keyed, "", "merkato-books", http.StatusUnauthorized},
+		{"keyless server", keyless, "", "merkato-books", http.StatusForbidden},
+		{"trusted header", trusting, "", "merkato-books", http.StatusNotFound},
+		{"trusted header naming no merchant", trusting, "", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Unknown batch jobs get 404 once the merchant has been selected
+			r := httptest.NewRequest(http.MethodGet, "/v1/batch/unknown", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.merchant != "" {
+				r.Header.Set(MerchantHeader, tt.merchant)
+			}
+			w := httptest.NewRecorder()
+			tt.server.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestMerchantKeyNames(t *testing.T) {
+	_, err := NewMerchants(
+		Merchant{ID: "habesha-crafts", Keys: []apikey.Key{{Name: "shop", Key: "first-secret"}}},
+		Merchant{ID: "merkato-books", Keys: []apikey.Key{{Name: "shop", Key: "second-secret"}}},
+	)
+	if err == nil {
+		t.Error("NewMerchants accepted a key name used by two merchants")
+	}
+
+	merchants := testMerchants(t)
+	keys, err := apikey.New(apikey.Key{Name: "habesha-crafts", Key: "server-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merchants.CheckKeys(keys); err == nil {
+		t.Error("CheckKeys accepted a server key named like a merchant's key")
+	}
+
+	keys, err = apikey.New(apikey.Key{Name: "billing", Key: "server-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merchants.CheckKeys(keys); err != nil {
+		t.Errorf("CheckKeys: %v", err)
+	}
+}
+
+func TestGraphQLMerchantOptions(t *testing.T) {
+	var got cbeverifier.Options
+	graphql := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = OptionsFromContext(r.Context())
+	})
+	srv := New(cbeverifier.New(), WithMerchants(testMerchants(t)), WithGraphQL(graphql))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/graphql", nil)
+	r.Header.Set("X-API-Key", "merchant-secret")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || got.MerchantID != "habesha-crafts" {
+		t.Errorf("status %d, merchant %q: %s", w.Code, got.MerchantID, w.Body)
+	}
+}
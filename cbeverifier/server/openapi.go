@@ -0,0 +1,20 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI is the OpenAPI 3 document of the endpoints, served at
+// GET /v1/openapi.yaml. It is maintained by hand next to the handlers, and the
+// tests check the routes and types of Client against it; SDKs for other
+// languages can be generated from it.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// handleOpenAPI serves the OpenAPI document
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(OpenAPI)
+}
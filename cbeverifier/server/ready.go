@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// probeTimeout bounds a probe of CBE made by GET /readyz
+const probeTimeout = 5 * time.Second
+
+// Statuses of GET /readyz
+const (
+	statusReady       = "ready"
+	statusUnavailable = "unavailable"
+)
+
+// Readiness is the response of GET /readyz
+type Readiness struct {
+	// Status is "ready", or "unavailable" while CBE is unreachable
+	Status string `json:"status"`
+	// Breaker is the state of the verifier's circuit breaker (see
+	// cbeverifier.WithCircuitBreaker): closed, open or half-open
+	Breaker string `json:"breaker"`
+	// Error is why the server is unavailable
+	Error string `json:"error,omitempty"`
+	// CheckedAt is when CBE was last probed, with WithReadinessProbe
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
+// readiness caches the result of the last probe of CBE
+type readiness struct {
+	interval time.Duration
+
+	// mu is held while probing, so that concurrent checks share a probe
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// WithReadinessProbe makes GET /readyz probe CBE's receipt service with a
+// HEAD request (see cbeverifier.Verifier.Probe), at most once per interval,
+// and report the server unavailable while CBE is unreachable. Without it,
+// /readyz only reports the state of the verifier's circuit breaker.
+func WithReadinessProbe(interval time.Duration) Option {
+	return func(s *Server) {
+		if interval > 0 {
+			s.readiness = &readiness{interval: interval}
+		}
+	}
+}
+
+// probe returns the result of the last probe, probing again if it is older
+// than the interval
+func (r *readiness) probe(ctx context.Context, verifier *cbeverifier.Verifier) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < r.interval {
+		return r.checkedAt, r.err
+	}
+	// The probe outlives the request that made it, as it is shared
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), probeTimeout)
+	defer cancel()
+	r.err = verifier.Probe(ctx)
+	r.checkedAt = time.Now()
+	return r.checkedAt, r.err
+}
+
+// handleReady reports whether the server can verify receipts: 200 unless the
+// circuit breaker is open or, with WithReadinessProbe, CBE did not answer the
+// last probe, and 503 otherwise, so that orchestrators stop routing
+// verifications to it during CBE outages. An open breaker is ready again once
+// its cooldown has elapsed, and a successful probe closes it, so that a server
+// no longer sent requests still recovers.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	readiness := Readiness{Status: statusReady}
+	if s.readiness != nil {
+		checkedAt, err := s.readiness.probe(r.Context(), s.verifier)
+		readiness.CheckedAt = checkedAt
+		if err != nil {
+			readiness.Status, readiness.Error = statusUnavailable, err.Error()
+		}
+	}
+	state := s.verifier.BreakerState()
+	readiness.Breaker = state.String()
+	if state == cbeverifier.BreakerOpen && readiness.Status == statusReady {
+		readiness.Status, readiness.Error = statusUnavailable, cbeverifier.ErrUpstreamUnavailable.Error()
+	}
+
+	status := http.StatusOK
+	if readiness.Status != statusReady {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, readiness)
+}
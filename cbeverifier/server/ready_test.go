@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// outageTransport fails receipt requests, and answers HEAD probes with the
+// status in probe, or fails them while it is zero
+type outageTransport struct {
+	probe *atomic.Int32
+}
+
+func (t outageTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method == http.MethodHead {
+		if status := t.probe.Load(); status != 0 {
+			return &http.Response{StatusCode: int(status), Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		}
+	}
+	return nil, errors.New("connection refused")
+}
+
+// tripBreaker makes a verification fail upstream, opening a breaker with a
+// threshold of one
+func tripBreaker(t *testing.T, verifier *cbeverifier.Verifier) {
+	t.Helper()
+	result, err := verifier.Verify(context.Background(), cbeverifier.Transaction{ID: "FT24123ABCDE", Suffix: "12345678", Amount: 1}, cbeverifier.DefaultOptions())
+	if err != nil || !errors.Is(result.Err(), cbeverifier.ErrNetworkError) {
+		t.Fatalf("verification = %+v, %v, want a network error", result, err)
+	}
+	if state := verifier.BreakerState(); state != cbeverifier.BreakerOpen {
+		t.Fatalf("breaker %s, want open", state)
+	}
+}
+
+// ready returns the status and body of GET /readyz
+func ready(t *testing.T, srv *Server) (int, Readiness) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var readiness Readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &readiness); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, readiness
+}
+
+func TestReadyAfterCooldown(t *testing.T) {
+	transport := outageTransport{probe: new(atomic.Int32)}
+	verifier := cbeverifier.New(
+		cbeverifier.WithHTTPClient(&http.Client{Transport: transport}),
+		cbeverifier.WithCircuitBreaker(1, 50*time.Millisecond, nil),
+	)
+	srv := New(verifier)
+
+	if status, readiness := ready(t, srv); status != http.StatusOK || readiness.Breaker != "closed" {
+		t.Fatalf("before the outage: %d %+v, want 200", status, readiness)
+	}
+	tripBreaker(t, verifier)
+	if status, readiness := ready(t, srv); status != http.StatusServiceUnavailable || readiness.Breaker != "open" {
+		t.Errorf("breaker open: %d %+v, want 503", status, readiness)
+	}
+
+	// No request reaches the verifier of an unready server, yet it becomes
+	// ready again once the cooldown has elapsed
+	time.Sleep(60 * time.Millisecond)
+	if status, readiness := ready(t, srv); status != http.StatusOK || readiness.Breaker != "half-open" {
+		t.Errorf("after the cooldown: %d %+v, want 200 and half-open", status, readiness)
+	}
+}
+
+func TestReadinessProbeClosesBreaker(t *testing.T) {
+	transport := outageTransport{probe: new(atomic.Int32)}
+	verifier := cbeverifier.New(
+		cbeverifier.WithHTTPClient(&http.Client{Transport: transport}),
+		cbeverifier.WithCircuitBreaker(1, time.Hour, nil),
+	)
+	// Probe on every check
+	srv := New(verifier, WithReadinessProbe(time.Nanosecond))
+
+	tripBreaker(t, verifier)
+	if status, readiness := ready(t, srv); status != http.StatusServiceUnavailable || readiness.CheckedAt.IsZero() {
+		t.Errorf("CBE down: %d %+v, want 503 after a probe", status, readiness)
+	}
+
+	// CBE answers errors: still unavailable, with the probe's error
+	transport.probe.Store(http.StatusBadGateway)
+	status, readiness := ready(t, srv)
+	if status != http.StatusServiceUnavailable || !strings.Contains(readiness.Error, cbeverifier.ErrUpstreamUnavailable.Error()) || readiness.Breaker != "open" {
+		t.Errorf("CBE failing: %d %+v, want 503 with the breaker open", status, readiness)
+	}
+
+	// CBE answers again: the probe closes the breaker long before its cooldown
+	transport.probe.Store(http.StatusOK)
+	if status, readiness := ready(t, srv); status != http.StatusOK || readiness.Breaker != "closed" {
+		t.Errorf("CBE back: %d %+v, want 200 and the breaker closed", status, readiness)
+	}
+	if state := verifier.BreakerState(); state != cbeverifier.BreakerClosed {
+		t.Errorf("breaker %s, want closed", state)
+	}
+}
@@ -0,0 +1,471 @@
+// Package server serves receipt verification over HTTP, for backends written in
+// other languages that would rather call the verifier than port it.
+//
+// The server exposes these endpoints:
+//
+//	POST /v1/verify             JSON Transaction in, JSON VerificationResult out
+//	POST /v1/parse              receipt upload in, JSON TransactionDetails out
+//	POST /v1/batch              JSON array of transactions in, BatchStatus out
+//	GET  /v1/batch/{id}         BatchStatus of the batch job, with its results
+//	GET  /v1/batch/{id}/events  Server-Sent Events, one per result
+//	POST /v1/graphql            GraphQL over the verification history (see WithGraphQL)
+//	GET  /healthz               {"status":"ok"} while the server is up
+//	GET  /readyz                Readiness, 503 while CBE is unreachable (see WithReadinessProbe)
+//	GET  /dashboard/            web dashboard of the verification history (see WithDashboard)
+//
+// GET /v1/openapi.yaml serves the OpenAPI document of the endpoints, and
+// Client calls them from Go.
+//
+// Errors are JSON objects with an "error" message. A transaction that does not
+// match its receipt is not an error: /v1/verify responds 200 with IsValid false.
+// With ?async=true, /v1/verify responds 202 at once and the result is posted to
+// Options.WebhookURL when the verification finishes.
+//
+// A server may serve several merchants, each with its own receiving account,
+// policy, webhook and API keys (see WithMerchants).
+//
+// Example:
+//
+//	srv := server.New(cbeverifier.New(), server.WithOptions(cbeverifier.DefaultOptions()))
+//	log.Fatal(http.ListenAndServe(":8080", srv))
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/dashboard"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// maxTransactionBytes limits the size of /v1/verify request bodies
+const maxTransactionBytes = 64 << 10
+
+// defaultMaxReceiptBytes limits the size of /v1/parse uploads when
+// Options.MaxPDFBytes is unset, matching the parser's own limit
+const defaultMaxReceiptBytes = 5 << 20
+
+// defaultAsyncLimit is the default number of async verifications in progress
+// at once
+const defaultAsyncLimit = 64
+
+// errAsyncBusy is the error of async verifications while as many as the
+// server's limit are in progress
+var errAsyncBusy = errors.New("too many async verifications in progress")
+
+// public are the paths served without an API key
+var public = map[string]bool{
+	"/healthz":         true,
+	"/readyz":          true,
+	"/v1/openapi.yaml": true,
+}
+
+// Server is an http.Handler serving the verification endpoints
+type Server struct {
+	verifier  *cbeverifier.Verifier
+	opts      cbeverifier.Options
+	logger    *slog.Logger
+	keys      *apikey.Keys
+	merchants *Merchants
+	graphql   http.Handler
+	readiness *readiness
+	mux       *http.ServeMux
+
+	// merchantAdmins are the names of the server keys that may name the
+	// merchant of their requests
+	merchantAdmins      map[string]bool
+	trustMerchantHeader bool
+
+	// dashboardHistory is the history shown by the dashboard
+	dashboardHistory store.Store
+	dashboard        *dashboard.Handler
+
+	concurrency int
+	// async holds a token per async verification in progress
+	async chan struct{}
+	// batchJobs holds a token per batch job running
+	batchJobs chan struct{}
+	batchMu   sync.Mutex
+	batches   map[string]*batchJob
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithOptions sets the options of every verification and parse (default:
+// cbeverifier.DefaultOptions())
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(s *Server) {
+		s.opts = opts
+	}
+}
+
+// WithLogger logs each request, and why failed requests failed, to logger
+// (default: discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithAPIKeys requires every request but GET /healthz, GET /readyz and the
+// OpenAPI document to carry one of keys, as "Authorization: Bearer KEY" or "X-API-Key: KEY", and
+// limits each key to its own request rate. Requests without a valid key get 401, and requests over
+// their key's quota get 429 with a Retry-After header.
+func WithAPIKeys(keys *apikey.Keys) Option {
+	return func(s *Server) {
+		s.keys = keys
+	}
+}
+
+// WithGraphQL serves handler, such as a graphql.Handler of the
+// cbeverifier/graphql module, at GET and POST /v1/graphql behind the API keys
+// of the server. Its requests carry the options of their merchant, read with
+// OptionsFromContext, so that queries only return the merchant's records and
+// the verify mutation verifies with its options.
+func WithGraphQL(handler http.Handler) Option {
+	return func(s *Server) {
+		s.graphql = handler
+	}
+}
+
+// WithDashboard serves the web dashboard of package dashboard over history at
+// /dashboard/. The page and its assets are public; the page asks for an API
+// key and calls the endpoints of the dashboard with it, which only show the
+// records of the key's merchant. The verifier should record to history (see
+// cbeverifier.WithRecorder).
+func WithDashboard(history store.Store) Option {
+	return func(s *Server) {
+		s.dashboardHistory = history
+	}
+}
+
+// WithAsyncLimit sets how many verifications requested with ?async=true may be
+// in progress at once (default: 64). Requests past the limit get 503.
+func WithAsyncLimit(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.async = make(chan struct{}, n)
+		}
+	}
+}
+
+// New creates a Server verifying transactions with verifier
+func New(verifier *cbeverifier.Verifier, options ...Option) *Server {
+	s := &Server{
+		verifier:    verifier,
+		opts:        cbeverifier.DefaultOptions(),
+		logger:      slog.New(slog.DiscardHandler),
+		mux:         http.NewServeMux(),
+		concurrency: 4,
+		async:       make(chan struct{}, defaultAsyncLimit),
+		batchJobs:   make(chan struct{}, defaultBatchLimit),
+		batches:     make(map[string]*batchJob),
+
+		merchantAdmins: make(map[string]bool),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	s.mux.HandleFunc("POST /v1/verify", s.ServeVerify)
+	s.mux.HandleFunc("POST /v1/parse", s.ServeParse)
+	s.mux.HandleFunc("POST /v1/batch", s.ServeBatch)
+	s.mux.HandleFunc("GET /v1/batch/{id}", s.ServeBatchStatus)
+	s.mux.HandleFunc("GET /v1/batch/{id}/events", s.ServeBatchEvents)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("GET /v1/openapi.yaml", s.handleOpenAPI)
+	if s.graphql != nil {
+		s.mux.HandleFunc("GET /v1/graphql", s.ServeGraphQL)
+		s.mux.HandleFunc("POST /v1/graphql", s.ServeGraphQL)
+	}
+	if s.dashboardHistory != nil {
+		s.dashboard = dashboard.New(s.dashboardHistory, s.verifier)
+		s.mux.HandleFunc("GET /dashboard/", s.ServeDashboard)
+		s.mux.HandleFunc("POST /dashboard/api/verify", s.ServeDashboard)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler, logging each request with the name of
+// its client's key
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	client := ""
+	defer func() {
+		s.logger.InfoContext(r.Context(), "request", "client", client, "method", r.Method, "path", r.URL.Path,
+			"status", recorder.status, "elapsed", time.Since(start))
+	}()
+
+	if s.authenticates() && !s.public(r.URL.Path) {
+		key, retryAfter, err := s.allow(apikey.FromRequest(r))
+		client = key.Name
+		if err == nil || errors.Is(err, apikey.ErrQuotaExceeded) {
+			r = r.WithContext(apikey.NewContext(r.Context(), key))
+		}
+		switch {
+		case errors.Is(err, apikey.ErrQuotaExceeded):
+			recorder.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			s.error(recorder, r, http.StatusTooManyRequests, err)
+			return
+		case err != nil:
+			recorder.Header().Set("WWW-Authenticate", "Bearer")
+			s.error(recorder, r, http.StatusUnauthorized, err)
+			return
+		}
+	}
+	s.mux.ServeHTTP(recorder, r)
+}
+
+// public reports whether path is served without an API key: the endpoints of
+// public, and the page and assets of the dashboard
+func (s *Server) public(path string) bool {
+	if s.dashboard != nil && (path == "/dashboard" || path == "/dashboard/" || strings.HasPrefix(path, "/dashboard/static/")) {
+		return true
+	}
+	return public[path]
+}
+
+// authenticates reports whether requests need an API key
+func (s *Server) authenticates() bool {
+	return s.keys != nil || (s.merchants != nil && s.merchants.keys != nil)
+}
+
+// allow authenticates a request made with secret, with the keys of the server
+// or else those of its merchants
+func (s *Server) allow(secret string) (key apikey.Key, retryAfter time.Duration, err error) {
+	if s.keys != nil {
+		key, retryAfter, err = s.keys.Allow(secret)
+		if !errors.Is(err, apikey.ErrUnknownKey) {
+			return key, retryAfter, err
+		}
+	}
+	if s.merchants != nil && s.merchants.keys != nil {
+		return s.merchants.keys.Allow(secret)
+	}
+	return key, retryAfter, err
+}
+
+// statusRecorder records the status of a response for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the recorded ResponseWriter, for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// ServeVerify serves POST /v1/verify, verifying the JSON transaction in the
+// request body. Unlike ServeHTTP, it neither checks API keys nor logs the
+// request; it is meant for routers of other frameworks (see the ginadapter and
+// echoadapter packages), which authenticate requests themselves.
+func (s *Server) ServeVerify(w http.ResponseWriter, r *http.Request) {
+	opts, status, err := s.options(r)
+	if err != nil {
+		s.error(w, r, status, err)
+		return
+	}
+
+	var transaction cbeverifier.Transaction
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTransactionBytes)).Decode(&transaction); err != nil {
+		s.error(w, r, requestStatus(err), fmt.Errorf("invalid transaction: %w", err))
+		return
+	}
+
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		if opts.WebhookURL == "" {
+			s.error(w, r, http.StatusBadRequest, errors.New("async verification needs a webhook URL"))
+			return
+		}
+		// The result is delivered to the webhook once Verify returns
+		select {
+		case s.async <- struct{}{}:
+		default:
+			s.error(w, r, http.StatusServiceUnavailable, errAsyncBusy)
+			return
+		}
+		go func() {
+			defer func() { <-s.async }()
+			s.verifier.Verify(context.WithoutCancel(r.Context()), transaction, opts)
+		}()
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+		return
+	}
+
+	// Transactions that cannot be verified, such as those whose receipt could
+	// not be fetched, are results with their error
+	result, err := s.verifier.Verify(r.Context(), transaction, opts)
+	if err != nil {
+		result = cbeverifier.NewErrorResult(err)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ServeParse serves POST /v1/parse, parsing an uploaded receipt: the request
+// body itself, or the "receipt" file of a multipart form. The provider query
+// parameter names the issuer of the receipt (default: CBE). Like ServeVerify,
+// it skips the API key checks of ServeHTTP.
+func (s *Server) ServeParse(w http.ResponseWriter, r *http.Request) {
+	opts, status, err := s.options(r)
+	if err != nil {
+		s.error(w, r, status, err)
+		return
+	}
+
+	name := r.URL.Query().Get("provider")
+	if name == "" {
+		name = cbeverifier.ProviderCBE
+	}
+	if _, err := s.verifier.ProviderCapabilities(name); err != nil {
+		s.error(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	receipt, err := s.readReceipt(w, r)
+	if err != nil {
+		s.error(w, r, requestStatus(err), err)
+		return
+	}
+	// The verifier parses CBE receipts with its own OCR engine, templates, QR
+	// decoder and signature roots, as /v1/verify does
+	details, _, err := s.verifier.Lookup(r.Context(), cbeverifier.Transaction{Provider: name}, receipt, opts)
+	if err != nil {
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+// readReceipt reads the receipt uploaded with a parse request
+func (s *Server) readReceipt(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	limit := s.opts.MaxPDFBytes
+	if limit <= 0 {
+		limit = defaultMaxReceiptBytes
+	}
+	// Leave room for the multipart headers around the file
+	body := http.MaxBytesReader(w, r.Body, limit+64<<10)
+
+	var receipt io.Reader = body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		r.Body = body
+		file, _, err := r.FormFile("receipt")
+		if err != nil {
+			return nil, fmt.Errorf("missing receipt file: %w", err)
+		}
+		defer file.Close()
+		receipt = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(receipt, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, cbeverifier.ErrReceiptTooLarge
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty receipt")
+	}
+	return data, nil
+}
+
+// ServeGraphQL serves GET and POST /v1/graphql with the options of the
+// request's merchant, if the server was created WithGraphQL
+func (s *Server) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	if s.graphql == nil {
+		s.error(w, r, http.StatusNotFound, errors.New("GraphQL is not enabled"))
+		return
+	}
+	opts, status, err := s.options(r)
+	if err != nil {
+		s.error(w, r, status, err)
+		return
+	}
+	s.graphql.ServeHTTP(w, r.WithContext(NewContext(r.Context(), opts)))
+}
+
+// optionsKey is the context key of the options of a request
+type optionsKey struct{}
+
+// NewContext returns a copy of ctx carrying the options of a request's
+// merchant, for the handlers the server mounts
+func NewContext(ctx context.Context, opts cbeverifier.Options) context.Context {
+	return context.WithValue(ctx, optionsKey{}, opts)
+}
+
+// OptionsFromContext returns the options NewContext put in ctx
+func OptionsFromContext(ctx context.Context) (cbeverifier.Options, bool) {
+	opts, ok := ctx.Value(optionsKey{}).(cbeverifier.Options)
+	return opts, ok
+}
+
+// ServeDashboard serves the dashboard under /dashboard/, with the options of
+// the request's merchant, if the server was created WithDashboard
+func (s *Server) ServeDashboard(w http.ResponseWriter, r *http.Request) {
+	if s.dashboard == nil {
+		s.error(w, r, http.StatusNotFound, errors.New("the dashboard is not enabled"))
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/dashboard/api/") {
+		opts, status, err := s.options(r)
+		if err != nil {
+			s.error(w, r, status, err)
+			return
+		}
+		r = r.WithContext(dashboard.NewContext(r.Context(), opts))
+	}
+	http.StripPrefix("/dashboard", s.dashboard).ServeHTTP(w, r)
+}
+
+// handleHealth reports that the server is up
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// error responds with err as a JSON error
+func (s *Server) error(w http.ResponseWriter, r *http.Request, status int, err error) {
+	key, _ := apikey.FromContext(r.Context())
+	s.logger.WarnContext(r.Context(), "request failed", "client", key.Name, "method", r.Method, "path", r.URL.Path, "status", status, "error", err)
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// requestStatus returns the status of a request whose body could not be read
+func requestStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || errors.Is(err, cbeverifier.ErrReceiptTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// writeJSON responds with v as JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// imagePDF returns a one-page PDF without a text layer, as scanned receipts are
+func imagePDF() []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Resources << >> >>",
+		"<< /Length 0 >>\nstream\n\nendstream",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// countingOCR recognizes no text, counting the documents it was given
+type countingOCR struct {
+	calls atomic.Int32
+}
+
+func (o *countingOCR) Recognize(context.Context, []byte, string) ([]cbeverifier.OCRLine, error) {
+	o.calls.Add(1)
+	return nil, nil
+}
+
+func TestParseUsesServerVerifier(t *testing.T) {
+	engine := &countingOCR{}
+	srv := New(cbeverifier.New(cbeverifier.WithOCR(engine)))
+
+	for _, provider := range []string{"", cbeverifier.ProviderCBE} {
+		r := httptest.NewRequest(http.MethodPost, "/v1/parse?provider="+provider, bytes.NewReader(imagePDF()))
+		r.Header.Set("Content-Type", "application/pdf")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		// The engine found no receipt, but it was asked
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("provider %q: status %d, want 422: %s", provider, w.Code, w.Body)
+		}
+	}
+	if calls := engine.calls.Load(); calls != 2 {
+		t.Errorf("the verifier's OCR engine parsed %d receipts, want 2", calls)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/parse?provider=unknown", bytes.NewReader(imagePDF()))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown provider: status %d, want 400", w.Code)
+	}
+}
+
+// blockingTransport holds every request until release is closed
+type blockingTransport struct {
+	release chan struct{}
+}
+
+func (t blockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	select {
+	case <-t.release:
+	case <-r.Context().Done():
+	}
+	return nil, fmt.Errorf("blocked")
+}
+
+func TestAsyncLimit(t *testing.T) {
+	transport := blockingTransport{release: make(chan struct{})}
+	defer close(transport.release)
+	verifier := cbeverifier.New(cbeverifier.WithHTTPClient(&http.Client{Transport: transport}))
+
+	opts := cbeverifier.DefaultOptions()
+	opts.WebhookURL = "https://shop.example.com/webhook"
+	srv := New(verifier, WithOptions(opts), WithAsyncLimit(2))
+
+	verify := func() int {
+		body := `{"id":"FT24123ABCDE","suffix":"12345678","amount":1500}`
+		r := httptest.NewRequest(http.MethodPost, "/v1/verify?async=true", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := range 2 {
+		if status := verify(); status != http.StatusAccepted {
+			t.Fatalf("verification %d: status %d, want 202", i+1, status)
+		}
+	}
+	if status := verify(); status != http.StatusServiceUnavailable {
+		t.Errorf("verification past the limit: status %d, want 503", status)
+	}
+}
+
+func TestVerifyUnreachable(t *testing.T) {
+	// A released blockingTransport fails every request at once
+	transport := blockingTransport{release: make(chan struct{})}
+	close(transport.release)
+	srv := New(cbeverifier.New(cbeverifier.WithHTTPClient(&http.Client{Transport: transport})))
+
+	body := `{"id":"FT24123ABCDE","suffix":"12345678","amount":1500}`
+	r := httptest.NewRequest(http.MethodPost, "/v1/verify", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+
+	// The failure is the result's, as for mismatches
+	var result cbeverifier.VerificationResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || result.IsValid || !strings.Contains(result.Error, cbeverifier.ErrNetworkError.Error()) {
+		t.Errorf("status %d with %+v, want 200 with the network error", w.Code, result)
+	}
+}
+
+func TestBatchLimit(t *testing.T) {
+	transport := blockingTransport{release: make(chan struct{})}
+	verifier := cbeverifier.New(cbeverifier.WithHTTPClient(&http.Client{Transport: transport}))
+	srv := New(verifier, WithBatchLimit(1))
+
+	submit := func() *httptest.ResponseRecorder {
+		body := `[{"id":"FT24123ABCDE","suffix":"12345678","amount":1500}]`
+		r := httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := submit(); w.Code != http.StatusAccepted {
+		t.Fatalf("first batch: status %d, want 202: %s", w.Code, w.Body)
+	}
+	if w := submit(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("batch past the limit: status %d, want 503: %s", w.Code, w.Body)
+	}
+
+	// A finished job frees its place
+	close(transport.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := submit()
+		if w.Code == http.StatusAccepted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("batch after the first finished: status %d, want 202: %s", w.Code, w.Body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
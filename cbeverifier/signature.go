@@ -0,0 +1,343 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// errUnsupportedSignature marks signatures that cannot be checked, as opposed
+// to signatures that do not verify
+var errUnsupportedSignature = errors.New("unsupported signature")
+
+// CMS object identifiers (RFC 5652, RFC 5754)
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidRSASSAPSS     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// cmsContentInfo is a CMS ContentInfo
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0"`
+}
+
+// cmsSignedData is a CMS SignedData
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsEncapsulatedContent
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+// cmsEncapsulatedContent is a CMS EncapsulatedContentInfo; detached signatures have no content
+type cmsEncapsulatedContent struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"optional,explicit,tag:0"`
+}
+
+// cmsSignerInfo is a CMS SignerInfo
+type cmsSignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// cmsIssuerAndSerial identifies a signer certificate by issuer and serial number
+type cmsIssuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+// cmsAttribute is a signed attribute of a SignerInfo
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// VerifyPDFSignature validates the digital signatures of a receipt PDF
+//
+// Each signature must verify against the bytes it covers and the last one must
+// cover the whole file, so content added after signing is detected. When roots
+// is not nil, each signer's certificate must also chain to one of them.
+// ErrPDFUnsigned is returned for PDFs without a signature and ErrSignatureInvalid
+// for signatures that do not verify.
+//
+// Example:
+//
+//	if err := cbeverifier.VerifyPDFSignature(pdfBytes, nil); err != nil {
+//		log.Printf("signature check failed: %v", err)
+//	}
+func VerifyPDFSignature(pdfBytes []byte, roots *x509.CertPool) error {
+	// Each byte range skips exactly the hex-encoded signature in /Contents
+	ranges := reByteRange.FindAllSubmatch(pdfBytes, -1)
+	if len(ranges) == 0 {
+		return ErrPDFUnsigned
+	}
+
+	for i, match := range ranges {
+		var offsets [4]int
+		for j := range offsets {
+			n, err := strconv.Atoi(string(match[j+1]))
+			if err != nil {
+				return fmt.Errorf("%w: malformed byte range", ErrSignatureInvalid)
+			}
+			offsets[j] = n
+		}
+		start1, len1, start2, len2 := offsets[0], offsets[1], offsets[2], offsets[3]
+		if start1 != 0 || len1 < 0 || start2 < len1 || len2 < 0 || start2+len2 > len(pdfBytes) {
+			return fmt.Errorf("%w: byte range outside the file", ErrSignatureInvalid)
+		}
+
+		// Content added after the last signature is not covered by it
+		if i == len(ranges)-1 && start2+len2 < len(bytes.TrimRight(pdfBytes, " \t\r\n\x00")) {
+			return fmt.Errorf("%w: file was modified after signing", ErrSignatureInvalid)
+		}
+
+		// The gap between the two ranges is the hex string holding the signature
+		contents := bytes.Trim(pdfBytes[len1:start2], "<> \t\r\n")
+		der, err := hex.DecodeString(string(contents))
+		if err != nil {
+			return fmt.Errorf("%w: malformed signature contents", ErrSignatureInvalid)
+		}
+
+		signed := make([]byte, 0, len1+len2)
+		signed = append(signed, pdfBytes[:len1]...)
+		signed = append(signed, pdfBytes[start2:start2+len2]...)
+		if err := verifyCMS(der, signed, roots); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signatureValid reports whether a receipt PDF's signatures are valid, or nil
+// when it is unsigned or signed with an algorithm that cannot be checked
+func signatureValid(pdfBytes []byte, roots *x509.CertPool) *bool {
+	err := VerifyPDFSignature(pdfBytes, roots)
+	if errors.Is(err, ErrPDFUnsigned) || errors.Is(err, errUnsupportedSignature) {
+		return nil
+	}
+	valid := err == nil
+	return &valid
+}
+
+// verifyCMS verifies a CMS SignedData signature over content
+func verifyCMS(der, content []byte, roots *x509.CertPool) error {
+	// Only well-formed signatures using an algorithm this package does not
+	// implement are unsupported; anything else fails to verify
+	var info cmsContentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return fmt.Errorf("%w: content type %v", ErrSignatureInvalid, info.ContentType)
+	}
+
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	if len(sd.SignerInfos) == 0 {
+		return fmt.Errorf("%w: no signers", ErrSignatureInvalid)
+	}
+
+	for _, signer := range sd.SignerInfos {
+		cert := signerCertificate(signer, certs)
+		if cert == nil {
+			return fmt.Errorf("%w: signer certificate not included", ErrSignatureInvalid)
+		}
+		hash, ok := digestHash(signer.DigestAlgorithm.Algorithm)
+		if !ok {
+			return fmt.Errorf("%w: digest algorithm %v", errUnsupportedSignature, signer.DigestAlgorithm.Algorithm)
+		}
+
+		// adbe.pkcs7.sha1 signatures encapsulate the digest of the byte range
+		// instead of signing it directly
+		signed := content
+		if sd.EncapContentInfo.Content != nil {
+			if !bytes.Equal(sd.EncapContentInfo.Content, digest(hash, content)) {
+				return fmt.Errorf("%w: content digest mismatch", ErrSignatureInvalid)
+			}
+			signed = sd.EncapContentInfo.Content
+		}
+
+		signingTime, err := checkSignedAttributes(signer, hash, signed)
+		if err != nil {
+			return err
+		}
+
+		// With signed attributes, the signature covers their DER encoding as a SET
+		if len(signer.SignedAttrs.FullBytes) > 0 {
+			signed = append([]byte{0x31}, signer.SignedAttrs.FullBytes[1:]...)
+		}
+		if err := checkSignerSignature(cert, signer, hash, signed); err != nil {
+			return err
+		}
+
+		if roots != nil {
+			intermediates := x509.NewCertPool()
+			for _, c := range certs {
+				intermediates.AddCert(c)
+			}
+			_, err := cert.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				CurrentTime:   signingTime,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			})
+			if err != nil {
+				return fmt.Errorf("%w: untrusted signer: %v", ErrSignatureInvalid, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// signerCertificate returns the certificate identified by the signer's SID
+func signerCertificate(signer cmsSignerInfo, certs []*x509.Certificate) *x509.Certificate {
+	// Version 3 signers are identified by subject key identifier
+	if signer.SID.Class == asn1.ClassContextSpecific && signer.SID.Tag == 0 {
+		for _, cert := range certs {
+			if bytes.Equal(cert.SubjectKeyId, signer.SID.Bytes) {
+				return cert
+			}
+		}
+		return nil
+	}
+
+	var id cmsIssuerAndSerial
+	if _, err := asn1.Unmarshal(signer.SID.FullBytes, &id); err != nil {
+		return nil
+	}
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, id.Issuer.FullBytes) && cert.SerialNumber.Cmp(id.Serial) == 0 {
+			return cert
+		}
+	}
+	return nil
+}
+
+// checkSignedAttributes checks that the signed message digest matches content
+// and returns the signing time, or the current time if none was signed
+func checkSignedAttributes(signer cmsSignerInfo, hash crypto.Hash, content []byte) (time.Time, error) {
+	signingTime := time.Now()
+	if len(signer.SignedAttrs.FullBytes) == 0 {
+		return signingTime, nil
+	}
+
+	var messageDigest []byte
+	for rest := signer.SignedAttrs.Bytes; len(rest) > 0; {
+		var attr cmsAttribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return signingTime, fmt.Errorf("%w: malformed signed attributes", ErrSignatureInvalid)
+		}
+		switch {
+		case attr.Type.Equal(oidMessageDigest):
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &messageDigest); err != nil {
+				return signingTime, fmt.Errorf("%w: malformed message digest", ErrSignatureInvalid)
+			}
+		case attr.Type.Equal(oidSigningTime):
+			var t time.Time
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &t); err == nil {
+				signingTime = t
+			}
+		}
+	}
+
+	if !bytes.Equal(messageDigest, digest(hash, content)) {
+		return signingTime, fmt.Errorf("%w: message digest mismatch", ErrSignatureInvalid)
+	}
+	return signingTime, nil
+}
+
+// checkSignerSignature verifies the signer's signature over signed with the
+// certificate's public key
+func checkSignerSignature(cert *x509.Certificate, signer cmsSignerInfo, hash crypto.Hash, signed []byte) error {
+	if signer.SignatureAlgorithm.Algorithm.Equal(oidRSASSAPSS) {
+		return fmt.Errorf("%w: RSASSA-PSS", errUnsupportedSignature)
+	}
+
+	var algo x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		algo = map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA1:   x509.SHA1WithRSA,
+			crypto.SHA256: x509.SHA256WithRSA,
+			crypto.SHA384: x509.SHA384WithRSA,
+			crypto.SHA512: x509.SHA512WithRSA,
+		}[hash]
+	case *ecdsa.PublicKey:
+		algo = map[crypto.Hash]x509.SignatureAlgorithm{
+			crypto.SHA1:   x509.ECDSAWithSHA1,
+			crypto.SHA256: x509.ECDSAWithSHA256,
+			crypto.SHA384: x509.ECDSAWithSHA384,
+			crypto.SHA512: x509.ECDSAWithSHA512,
+		}[hash]
+	case ed25519.PublicKey:
+		algo = x509.PureEd25519
+	default:
+		return fmt.Errorf("%w: public key type %T", errUnsupportedSignature, cert.PublicKey)
+	}
+
+	if err := cert.CheckSignature(algo, signed, signer.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	return nil
+}
+
+// digestHash returns the hash function identified by a digest algorithm OID
+func digestHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return crypto.SHA1, true
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// digest hashes data with hash
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,192 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oidECDSAWithSHA256 identifies ECDSA signatures with SHA-256 (RFC 5758)
+var oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+
+// testSigner is a signing certificate issued by a test CA
+type testSigner struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	ca   *x509.Certificate
+}
+
+// newTestCertificate issues a certificate for template, self-signed when
+// parent is nil
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestSigner(t *testing.T) testSigner {
+	t.Helper()
+	ca, caKey := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Receipts CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	cert, key := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Receipts"},
+		SubjectKeyId: []byte{1, 2, 3, 4},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, caKey)
+	return testSigner{key: key, cert: cert, ca: ca}
+}
+
+// sign returns a detached CMS SignedData over content, signed with SHA-256
+// and signed attributes, with its signature algorithm set to algorithm
+func (s testSigner) sign(t *testing.T, content []byte, algorithm asn1.ObjectIdentifier) []byte {
+	t.Helper()
+	mustMarshal := func(v any) []byte {
+		der, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	sum := sha256.Sum256(content)
+	attrs := mustMarshal(cmsAttribute{
+		Type:   oidMessageDigest,
+		Values: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(sum[:])},
+	})
+	// The signature covers the attributes encoded as a SET
+	signedAttrs := sha256.Sum256(mustMarshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs}))
+	signature, err := ecdsa.SignASN1(rand.Reader, s.key, signedAttrs[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sha256ID := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	signedData := mustMarshal(cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256ID},
+		EncapContentInfo: cmsEncapsulatedContent{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: append(bytes.Clone(s.cert.Raw), s.ca.Raw...)},
+		SignerInfos: []cmsSignerInfo{{
+			Version:            3,
+			SID:                asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: s.cert.SubjectKeyId},
+			DigestAlgorithm:    sha256ID,
+			SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: algorithm},
+			Signature:          signature,
+		}},
+	})
+	return mustMarshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+// signedPDF builds a minimal PDF whose /Contents holds the hex-encoded result
+// of sign over the bytes its /ByteRange covers
+func signedPDF(sign func(signed []byte) []byte) []byte {
+	const hexLen = 8192
+	header := func(len1, start2, len2 int) string {
+		return fmt.Sprintf("%%PDF-1.7\n1 0 obj\n<< /Type /Sig /ByteRange [0 %010d %010d %010d] /Contents ", len1, start2, len2)
+	}
+	trailer := " >>\nendobj\n%%EOF\n"
+	len1 := len(header(0, 0, 0))
+	start2 := len1 + hexLen + 2
+	head := header(len1, start2, len(trailer))
+
+	contents := hex.EncodeToString(sign([]byte(head + trailer)))
+	contents += strings.Repeat("0", hexLen-len(contents))
+	return []byte(head + "<" + contents + ">" + trailer)
+}
+
+func TestVerifyPDFSignature(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+	// forged names the signer's certificate but signs with another key
+	forged := signer
+	forged.key = other.key
+	valid := signedPDF(func(signed []byte) []byte { return signer.sign(t, signed, oidECDSAWithSHA256) })
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(signer.ca)
+	untrusted := x509.NewCertPool()
+	untrusted.AddCert(other.ca)
+
+	tests := []struct {
+		name  string
+		pdf   []byte
+		roots *x509.CertPool
+		want  error
+		// valid is the SignatureValid reported for the receipt
+		valid *bool
+	}{
+		{"valid", valid, nil, nil, boolPtr(true)},
+		{"chains to a trusted root", valid, trusted, nil, boolPtr(true)},
+		{"untrusted signer", valid, untrusted, ErrSignatureInvalid, boolPtr(false)},
+		{"unsigned", []byte("%PDF-1.7\n%%EOF\n"), trusted, ErrPDFUnsigned, nil},
+		{"signed content changed", bytes.Replace(valid, []byte("/Type /Sig"), []byte("/Type /Sih"), 1), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"content appended", append(bytes.Clone(valid), "2 0 obj\n<< >>\nendobj\n"...), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"byte range outside the file", bytes.TrimSuffix(valid, []byte("%%EOF\n")), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"contents not hex", bytes.Replace(valid, []byte("<3"), []byte("<z"), 1), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"contents not CMS", signedPDF(func([]byte) []byte { return []byte{0xde, 0xad, 0xbe, 0xef} }), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"signed by another key", signedPDF(func(signed []byte) []byte { return forged.sign(t, signed, oidECDSAWithSHA256) }), nil, ErrSignatureInvalid, boolPtr(false)},
+		{"unsupported algorithm", signedPDF(func(signed []byte) []byte { return signer.sign(t, signed, oidRSASSAPSS) }), nil, errUnsupportedSignature, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyPDFSignature(tt.pdf, tt.roots)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			got := signatureValid(tt.pdf, tt.roots)
+			if (got == nil) != (tt.valid == nil) || got != nil && *got != *tt.valid {
+				t.Errorf("signatureValid = %v, want %v", fmtBool(got), fmtBool(tt.valid))
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// fmtBool formats an optional bool
+func fmtBool(b *bool) string {
+	if b == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*b)
+}
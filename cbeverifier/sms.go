@@ -0,0 +1,163 @@
+package cbeverifier
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/ethiocal"
+)
+
+// SMSConfirmation is the transaction information extracted from a CBE SMS
+type SMSConfirmation struct {
+	TransactionDetails
+	// Suffix is the account suffix from the receipt link, if the SMS has one
+	Suffix string `json:"suffix,omitempty"`
+	// Credit is true for SMS sent to the receiver, false for SMS sent to the payer
+	Credit bool `json:"credit"`
+	// Balance is the account balance reported after the transaction
+	Balance float64 `json:"balance,omitempty"`
+}
+
+// Precompiled regex patterns for extracting information from CBE SMS
+var (
+	// reSMSLink matches the receipt link, whose id parameter is the full reference
+	reSMSLink = regexp.MustCompile(`https?://\S*[?&]id=([A-Za-z0-9]+)`)
+
+	// reSMSReference matches an explicit reference number
+	reSMSReference = regexp.MustCompile(`(?i)ref(?:erence)?\.?\s*(?:no\.?|number)?\s*[:#]?\s*([A-Z]{2}[A-Z0-9]{10})\b`)
+
+	// reSMSAmount matches the transferred amount and its currency
+	reSMSAmount = regexp.MustCompile(`(?i)(?:transfer+ed|debited with|credited with|sent|received)\s*(?:an amount of\s*)?([A-Z]{3}|birr)\s*([\d,]+\.\d{2})`)
+
+	// reSMSCredit matches SMS sent to the receiver
+	reSMSCredit = regexp.MustCompile(`(?i)credited`)
+
+	// reSMSAccount matches the account the SMS was sent for
+	reSMSAccount = regexp.MustCompile(`(?i)account\s*(?:no\.?|number)?\s*[:]?\s*(\d[\d*X]{5,})`)
+
+	// reSMSReceiver matches the receiver name after "to"
+	reSMSReceiver = regexp.MustCompile(`(?i)\bto\s+([\p{L}\p{M}][\p{L}\p{M}&\.\s'-]*?)\s*(?:,|\.\s|\s+on\s|\s+from\s|\s+with\s|$)`)
+
+	// reSMSPayer matches the payer name after "from"
+	reSMSPayer = regexp.MustCompile(`(?i)\bfrom\s+([\p{L}\p{M}][\p{L}\p{M}&\.\s'-]*?)\s*(?:,|\.\s|\s+on\s|\s+to\s|\s+with\s|$)`)
+
+	// reSMSDate matches the transaction date and time (day first)
+	reSMSDate = regexp.MustCompile(`(?i)\bon\s+(\d{1,2}/\d{1,2}/\d{4})(?:\s+at\s+(\d{1,2}:\d{2}(?::\d{2})?))?`)
+
+	// reSMSServiceCharge, reSMSVAT and reSMSTotal match the fees and total debited
+	reSMSServiceCharge = regexp.MustCompile(`(?i)s(?:ervice)?\.?\s*charge of\s*[A-Z]{3}\s*([\d,]+\.\d{2})`)
+	reSMSVAT           = regexp.MustCompile(`(?i)VAT of\s*[A-Z]{3}\s*([\d,]+\.\d{2})`)
+	reSMSTotal         = regexp.MustCompile(`(?i)total of\s*[A-Z]{3}\s*([\d,]+\.\d{2})`)
+
+	// reSMSBalance matches the balance after the transaction
+	reSMSBalance = regexp.MustCompile(`(?i)balance is\s*[A-Z]{3}\s*([\d,]+\.\d{2})`)
+)
+
+// smsDateLayouts are the day-first date formats used in CBE SMS
+var smsDateLayouts = []string{
+	"2/1/2006 15:04:05",
+	"2/1/2006 15:04",
+	"2/1/2006",
+}
+
+// ParseSMS extracts the reference, amount, parties and date from the SMS CBE
+// sends after a transfer, so a copy-pasted SMS can pre-fill a Transaction
+//
+// The SMS is not proof of payment: anyone can type one. Use Transaction to build
+// the input for a full verification against the official receipt. ErrSMSParseError
+// is returned when neither a reference nor an amount can be found.
+//
+// Example:
+//
+//	sms, err := cbeverifier.ParseSMS(message)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	result, err := cbeverifier.Verify(sms.Transaction(), cbeverifier.DefaultOptions())
+func ParseSMS(text string) (*SMSConfirmation, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	sms := &SMSConfirmation{Credit: reSMSCredit.MatchString(text)}
+
+	// Prefer the receipt link, which also carries the suffix
+	if m := reSMSLink.FindStringSubmatch(text); m != nil {
+		if id, suffix, err := SplitReference(m[1]); err == nil {
+			sms.TransactionID, sms.Suffix = id, suffix
+		}
+	}
+	if sms.TransactionID == "" {
+		sms.TransactionID = strings.ToUpper(extractField(text, reSMSReference))
+	}
+
+	if m := reSMSAmount.FindStringSubmatch(text); m != nil {
+		sms.Currency = normalizeCurrency(m[1])
+		sms.Amount = parseAmount(m[2])
+	}
+
+	if sms.TransactionID == "" && sms.Amount == 0 {
+		return nil, ErrSMSParseError
+	}
+
+	// The account is the recipient's own: the receiver's on credit SMS, the payer's otherwise
+	account := extractField(text, reSMSAccount)
+	if sms.Credit {
+		sms.ReceiverAccount = account
+	} else {
+		sms.PayerAccount = account
+	}
+
+	// Skip "from your account" and similar references to the recipient
+	if receiver := extractField(text, reSMSReceiver); !isSMSSelfReference(receiver) {
+		sms.Receiver = receiver
+	}
+	if payer := extractField(text, reSMSPayer); !isSMSSelfReference(payer) {
+		sms.Payer = payer
+	}
+
+	if m := reSMSDate.FindStringSubmatch(text); m != nil {
+		sms.DateRaw = strings.TrimSpace(m[1] + " " + m[2])
+		if paid, err := parseSMSDate(sms.DateRaw); err == nil {
+			sms.Date = paid
+			sms.DateEC = ethiocal.FromTime(paid).String()
+		}
+	}
+
+	sms.ServiceCharge = parseAmount(extractField(text, reSMSServiceCharge))
+	sms.VAT = parseAmount(extractField(text, reSMSVAT))
+	sms.TotalDebited = parseAmount(extractField(text, reSMSTotal))
+	sms.Balance = parseAmount(extractField(text, reSMSBalance))
+
+	return sms, nil
+}
+
+// Transaction returns a Transaction pre-filled from the SMS, to be verified against
+// the official receipt. The receiver name is included so a mismatch is detected.
+func (s *SMSConfirmation) Transaction() Transaction {
+	return Transaction{
+		ID:           s.TransactionID,
+		Suffix:       s.Suffix,
+		Amount:       s.Amount,
+		Currency:     s.Currency,
+		ReceiverName: s.Receiver,
+		PayerName:    s.Payer,
+	}
+}
+
+// isSMSSelfReference reports whether a captured name refers to the SMS recipient
+// (e.g., "your account") rather than a counterparty
+func isSMSSelfReference(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "" || strings.HasPrefix(lower, "your") || strings.HasPrefix(lower, "account")
+}
+
+// parseSMSDate parses a day-first SMS date in Addis Ababa time
+func parseSMSDate(value string) (time.Time, error) {
+	for _, layout := range smsDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, addisAbaba); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized SMS date %q", value)
+}
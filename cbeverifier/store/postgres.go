@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// postgres stores the transaction and the result as JSONB
+var postgres = dialect{
+	createTable: `CREATE TABLE IF NOT EXISTS %s (
+	id             BIGSERIAL    PRIMARY KEY,
+	reference      VARCHAR(64)  NOT NULL,
+	merchant_id    VARCHAR(128) NOT NULL DEFAULT '',
+	submitted      JSONB        NOT NULL,
+	outcome        VARCHAR(16)  NOT NULL,
+	result         JSONB        NOT NULL,
+	receipt_sha256 CHAR(64),
+	receipt_key    VARCHAR(256),
+	started_at     TIMESTAMPTZ  NOT NULL,
+	finished_at    TIMESTAMPTZ  NOT NULL
+)`,
+	dollar:  true,
+	noLimit: "ALL",
+	timeArg: func(t time.Time) any { return t.UTC() },
+}
+
+// NewPostgres creates a store using table in db, which must be opened with a
+// PostgreSQL driver such as pgx or lib/pq
+func NewPostgres(db *sql.DB, table string) (*SQLStore, error) {
+	return newSQLStore(db, table, postgres)
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// reTableName restricts table names to safe SQL identifiers, optionally
+// qualified by a schema
+var reTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// dialect holds what differs between the databases of an SQLStore
+type dialect struct {
+	// createTable creates the table named by its %s verb
+	createTable string
+	// dollar selects $1-style placeholders instead of ?
+	dollar bool
+	// noLimit is the LIMIT of queries that only have an OFFSET
+	noLimit string
+	// timeArg converts a timestamp to a query argument
+	timeArg func(time.Time) any
+}
+
+// SQLStore is a Store keeping records in a database/sql table, with the
+// transaction and the result as JSON. Create it with NewPostgres or NewSQLite.
+type SQLStore struct {
+	db      *sql.DB
+	table   string
+	dialect dialect
+}
+
+// newSQLStore validates table and creates a store using it
+func newSQLStore(db *sql.DB, table string, d dialect) (*SQLStore, error) {
+	if !reTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &SQLStore{db: db, table: table, dialect: d}, nil
+}
+
+// CreateTable creates the table and its indexes if they do not already exist
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	// Indexes are created in the schema of their table, so their names are
+	// not qualified
+	index := strings.ReplaceAll(s.table, ".", "_")
+	statements := []string{
+		fmt.Sprintf(s.dialect.createTable, s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_reference_idx ON %s (reference)", index, s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_started_at_idx ON %s (started_at)", index, s.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_outcome_idx ON %s (outcome, started_at)", index, s.table),
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Record implements cbeverifier.Recorder
+func (s *SQLStore) Record(ctx context.Context, attempt *cbeverifier.Attempt) error {
+	return s.Save(ctx, NewRecord(attempt))
+}
+
+// Save stores a record and sets its ID
+func (s *SQLStore) Save(ctx context.Context, record *Record) error {
+	transaction, err := json.Marshal(record.Transaction)
+	if err != nil {
+		return err
+	}
+	result, err := json.Marshal(record.Result)
+	if err != nil {
+		return err
+	}
+	placeholders := make([]string, 9)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	query := fmt.Sprintf(`INSERT INTO %s
+	(reference, merchant_id, submitted, outcome, result, receipt_sha256, receipt_key, started_at, finished_at)
+	VALUES (%s) RETURNING id`, s.table, strings.Join(placeholders, ", "))
+	return s.db.QueryRowContext(ctx, query,
+		record.Reference, record.MerchantID, string(transaction), record.Outcome, string(result),
+		nullString(record.ReceiptSHA256), nullString(record.ReceiptKey),
+		s.dialect.timeArg(record.StartedAt), s.dialect.timeArg(record.FinishedAt),
+	).Scan(&record.ID)
+}
+
+// Query returns the records matching filter, most recent first
+func (s *SQLStore) Query(ctx context.Context, filter Filter) ([]Record, error) {
+	var where []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where = append(where, condition+" "+s.placeholder(len(args)))
+	}
+	if filter.Reference != "" {
+		add("reference =", NormalizeReference(filter.Reference))
+	}
+	if filter.MerchantID != "" {
+		add("merchant_id =", filter.MerchantID)
+	}
+	if filter.Outcome != "" {
+		add("outcome =", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		add("started_at >=", s.dialect.timeArg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		add("started_at <", s.dialect.timeArg(filter.To))
+	}
+
+	query := fmt.Sprintf(`SELECT id, reference, merchant_id, submitted, outcome, result,
+	receipt_sha256, receipt_key, started_at, finished_at FROM %s`, s.table)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	switch {
+	case filter.Limit > 0:
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	case filter.Offset > 0:
+		// SQLite only accepts OFFSET after a LIMIT
+		query += " LIMIT " + s.dialect.noLimit
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filter.Offset)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// placeholder returns the nth bind parameter for the dialect
+func (s *SQLStore) placeholder(n int) string {
+	if s.dialect.dollar {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// scanRecord reads the record in the current row
+func scanRecord(rows *sql.Rows) (Record, error) {
+	var record Record
+	var transaction, result []byte
+	var receiptSHA256, receiptKey sql.NullString
+	err := rows.Scan(&record.ID, &record.Reference, &record.MerchantID, &transaction, &record.Outcome,
+		&result, &receiptSHA256, &receiptKey, timestamp{&record.StartedAt}, timestamp{&record.FinishedAt})
+	if err != nil {
+		return Record{}, err
+	}
+	if err := json.Unmarshal(transaction, &record.Transaction); err != nil {
+		return Record{}, fmt.Errorf("record %d: invalid transaction: %w", record.ID, err)
+	}
+	if err := json.Unmarshal(result, &record.Result); err != nil {
+		return Record{}, fmt.Errorf("record %d: invalid result: %w", record.ID, err)
+	}
+	record.ReceiptSHA256, record.ReceiptKey = receiptSHA256.String, receiptKey.String
+	return record, nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// timestamp scans a timestamp column, whether the driver returns a time.Time
+// or the text written by textTime
+type timestamp struct {
+	t *time.Time
+}
+
+// Scan implements sql.Scanner
+func (ts timestamp) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		*ts.t = v.UTC()
+		return nil
+	case string:
+		return ts.parse(v)
+	case []byte:
+		return ts.parse(string(v))
+	}
+	return fmt.Errorf("unsupported timestamp %T", value)
+}
+
+// parse parses a timestamp written by textTime
+func (ts timestamp) parse(value string) error {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return err
+	}
+	*ts.t = t.UTC()
+	return nil
+}
+
+// textTimeLayout has a fixed width, so that timestamps stored as text sort
+// chronologically
+const textTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// textTime converts a timestamp to text, for databases without a timestamp
+// type
+func textTime(t time.Time) any {
+	return t.UTC().Format(textTimeLayout)
+}
@@ -0,0 +1,29 @@
+package store
+
+import "database/sql"
+
+// sqlite stores timestamps as fixed-width RFC 3339 text in UTC, which SQLite
+// compares in chronological order
+var sqlite = dialect{
+	createTable: `CREATE TABLE IF NOT EXISTS %s (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	reference      TEXT    NOT NULL,
+	merchant_id    TEXT    NOT NULL DEFAULT '',
+	submitted      TEXT    NOT NULL,
+	outcome        TEXT    NOT NULL,
+	result         TEXT    NOT NULL,
+	receipt_sha256 TEXT,
+	receipt_key    TEXT,
+	started_at     TEXT    NOT NULL,
+	finished_at    TEXT    NOT NULL
+)`,
+	noLimit: "-1",
+	timeArg: textTime,
+}
+
+// NewSQLite creates a store using table in db, which must be opened with an
+// SQLite driver (3.35 or later). The sqlitestore module opens one with no
+// configuration.
+func NewSQLite(db *sql.DB, table string) (*SQLStore, error) {
+	return newSQLStore(db, table, sqlite)
+}
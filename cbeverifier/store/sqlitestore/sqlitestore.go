@@ -0,0 +1,107 @@
+// Package sqlitestore keeps the verification history and the claimed receipts
+// in a single SQLite file, for deployments that cannot run PostgreSQL or
+// Redis: CLI batch runs, kiosks and single-binary installs.
+//
+// It is a module of its own, so that the verifier does not depend on an SQLite
+// driver. The driver is modernc.org/sqlite, which is written in Go and needs no
+// C toolchain.
+//
+// Example:
+//
+//	ledger, err := sqlitestore.Open("cbe-verifier.db")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer ledger.Close()
+//
+//	verifier := cbeverifier.New(
+//		cbeverifier.WithRecorder(ledger),
+//		cbeverifier.WithReplayStore(ledger),
+//	)
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/store"
+)
+
+// Tables of a Ledger
+const (
+	historyTable = "verifications"
+	claimsTable  = "receipt_claims"
+)
+
+// pragmas let readers work while a verification is recorded, and wait for the
+// writer instead of failing when the file is locked
+var pragmas = url.Values{"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)"}}
+
+// Ledger is a store.Store and a cbeverifier.ReplayStore in one SQLite database
+type Ledger struct {
+	*store.SQLStore
+	db     *sql.DB
+	claims *cbeverifier.SQLReplayStore
+}
+
+// Open opens the ledger in the file at path, creating the file and its tables
+// if needed. Use ":memory:" for a ledger that lasts as long as the process.
+func Open(path string) (*Ledger, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer, and every connection to ":memory:" opens a
+	// database of its own
+	db.SetMaxOpenConns(1)
+
+	ledger, err := newLedger(context.Background(), db)
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return ledger, nil
+}
+
+// newLedger creates the tables of a ledger in db
+func newLedger(ctx context.Context, db *sql.DB) (*Ledger, error) {
+	history, err := store.NewSQLite(db, historyTable)
+	if err != nil {
+		return nil, err
+	}
+	if err := history.CreateTable(ctx); err != nil {
+		return nil, err
+	}
+	claims, err := cbeverifier.NewSQLReplayStore(db, claimsTable, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := claims.CreateTable(ctx); err != nil {
+		return nil, err
+	}
+	return &Ledger{SQLStore: history, db: db, claims: claims}, nil
+}
+
+// Claim marks reference as used by merchant
+func (l *Ledger) Claim(ctx context.Context, merchant, reference string) (bool, error) {
+	return l.claims.Claim(ctx, merchant, reference)
+}
+
+// Release removes a claim
+func (l *Ledger) Release(ctx context.Context, merchant, reference string) error {
+	return l.claims.Release(ctx, merchant, reference)
+}
+
+// DB returns the database of the ledger, e.g. for reports
+func (l *Ledger) DB() *sql.DB {
+	return l.db
+}
+
+// Close closes the database
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
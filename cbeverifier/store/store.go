@@ -0,0 +1,138 @@
+// Package store keeps the history of verification attempts: what was
+// submitted, the outcome and its mismatches, the fingerprint of the receipt
+// and when it happened. Audits and payment disputes are settled from it.
+//
+// A Store is a cbeverifier.Recorder, so it records every verification made
+// through a Verifier created with cbeverifier.WithRecorder. SQLStore keeps the
+// history in PostgreSQL (NewPostgres) or SQLite (NewSQLite) through
+// database/sql, with any driver.
+//
+// Example:
+//
+//	db, err := sql.Open("pgx", os.Getenv("DATABASE_URL"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	history, err := store.NewPostgres(db, "cbe_verifications")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := history.CreateTable(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	verifier := cbeverifier.New(cbeverifier.WithRecorder(history))
+//
+//	// Later, when a customer disputes a payment
+//	records, err := store.ByReference(ctx, history, "FT24123ABCDE")
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Record is a stored verification attempt
+type Record struct {
+	// ID is assigned by the store when the record is saved
+	ID int64 `json:"id"`
+	// Reference is the normalized reference of the transaction, without the
+	// suffix (e.g., "FT24123ABCDE")
+	Reference  string `json:"reference"`
+	MerchantID string `json:"merchant_id,omitempty"`
+	// Transaction is the transaction as submitted
+	Transaction cbeverifier.Transaction `json:"transaction"`
+	// Outcome is cbeverifier.OutcomeVerified, OutcomeMismatch, OutcomeReview or
+	// OutcomeError
+	Outcome string                          `json:"outcome"`
+	Result  *cbeverifier.VerificationResult `json:"result"`
+	// ReceiptSHA256 is the hex SHA-256 of the receipt, if one was fetched or
+	// supplied
+	ReceiptSHA256 string `json:"receipt_sha256,omitempty"`
+	// ReceiptKey is the key the receipt was archived under, to be loaded from
+	// the Verifier's cbeverifier.Archiver
+	ReceiptKey string    `json:"receipt_key,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// NewRecord converts a verification attempt to a Record
+func NewRecord(attempt *cbeverifier.Attempt) *Record {
+	reference := NormalizeReference(attempt.Transaction.FullReference)
+	if reference == "" {
+		reference = NormalizeReference(attempt.Transaction.ID)
+	}
+	// The receipt names the reference when the input was unusual
+	if result := attempt.Result; result != nil && result.Details != nil && result.Details.TransactionID != "" {
+		reference = NormalizeReference(result.Details.TransactionID)
+	}
+
+	return &Record{
+		Reference:     reference,
+		MerchantID:    attempt.MerchantID,
+		Transaction:   attempt.Transaction,
+		Outcome:       attempt.Outcome,
+		Result:        attempt.Result,
+		ReceiptSHA256: attempt.ReceiptSHA256,
+		ReceiptKey:    attempt.ReceiptKey,
+		StartedAt:     attempt.StartedAt.UTC(),
+		FinishedAt:    attempt.FinishedAt.UTC(),
+	}
+}
+
+// NormalizeReference returns the reference records are stored under: upper
+// case, without spaces, and without the account suffix of CBE references and
+// receipt URLs
+func NormalizeReference(reference string) string {
+	if id, _, err := cbeverifier.SplitReference(reference); err == nil {
+		return id
+	}
+	return strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+}
+
+// Filter selects records. Zero fields match every record.
+type Filter struct {
+	// Reference matches records of a reference, normalized with
+	// NormalizeReference
+	Reference  string
+	MerchantID string
+	// Outcome matches records of an outcome (e.g., cbeverifier.OutcomeMismatch)
+	Outcome string
+	// From and To match records started at or after From, and before To
+	From time.Time
+	To   time.Time
+	// Limit caps the number of records returned (default: no limit), and
+	// Offset skips the first records, for paging
+	Limit  int
+	Offset int
+}
+
+// Store saves and queries verification records. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	cbeverifier.Recorder
+	// Save stores a record and sets its ID
+	Save(ctx context.Context, record *Record) error
+	// Query returns the records matching filter, most recent first
+	Query(ctx context.Context, filter Filter) ([]Record, error)
+}
+
+// ByReference returns the attempts to verify reference, most recent first
+func ByReference(ctx context.Context, s Store, reference string) ([]Record, error) {
+	return s.Query(ctx, Filter{Reference: reference})
+}
+
+// Between returns the attempts started at or after from, and before to, most
+// recent first
+func Between(ctx context.Context, s Store, from, to time.Time) ([]Record, error) {
+	return s.Query(ctx, Filter{From: from, To: to})
+}
+
+// ByOutcome returns the attempts with an outcome (e.g.,
+// cbeverifier.OutcomeReview, for the receipts to check manually), most recent
+// first
+func ByOutcome(ctx context.Context, s Store, outcome string) ([]Record, error) {
+	return s.Query(ctx, Filter{Outcome: outcome})
+}
@@ -0,0 +1,230 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pdf "github.com/dslipak/pdf"
+)
+
+// Tamper indicators reported in VerificationResult.TamperIndicators. Indicators
+// naming a tool or font are followed by ": " and the name.
+const (
+	// TamperIncrementalUpdate means the PDF was saved again after it was generated
+	TamperIncrementalUpdate = "incremental_update"
+	// TamperModifiedAfterSigning means content was appended after the signed byte range
+	TamperModifiedAfterSigning = "modified_after_signing"
+	// TamperProducerMismatch means the document info and XMP metadata name different producers
+	TamperProducerMismatch = "producer_mismatch"
+	// TamperEditingTool means the metadata names PDF editing software
+	TamperEditingTool = "editing_tool"
+	// TamperEditHistory means the XMP metadata records the document being saved or converted
+	TamperEditHistory = "edit_history"
+	// TamperFontSubstitution means the same font is embedded more than once, as
+	// happens when text is retyped in an editor
+	TamperFontSubstitution = "font_substitution"
+)
+
+// editingTools are producers and creators that edit PDFs rather than generate
+// receipts. CBE receipts are generated server-side, so none of them appear on
+// a genuine receipt.
+var editingTools = []string{
+	"Adobe Acrobat",
+	"Canva",
+	"Foxit",
+	"GIMP",
+	"iLovePDF",
+	"Inkscape",
+	"LibreOffice",
+	"Master PDF Editor",
+	"Microsoft Word",
+	"Nitro",
+	"PDF Candy",
+	"PDF-XChange",
+	"PDFelement",
+	"PDFescape",
+	"Photoshop",
+	"pdftk",
+	"Sejda",
+	"Smallpdf",
+	"Soda PDF",
+}
+
+var (
+	// reByteRange matches the byte range covered by a digital signature
+	reByteRange = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+	// reXMPProducer matches the producer recorded in XMP metadata
+	reXMPProducer = regexp.MustCompile(`<pdf:Producer>([^<]*)</pdf:Producer>|pdf:Producer="([^"]*)"`)
+	// reXMPCreatorTool matches the creator tool recorded in XMP metadata
+	reXMPCreatorTool = regexp.MustCompile(`<xmp:CreatorTool>([^<]*)</xmp:CreatorTool>|xmp:CreatorTool="([^"]*)"`)
+	// reXMPHistory matches edit events recorded in XMP metadata
+	reXMPHistory = regexp.MustCompile(`stEvt:action(?:>|=")(saved|converted|derived|edited)`)
+	// reSubsetTag matches the random tag prefixed to the names of subset fonts
+	reSubsetTag = regexp.MustCompile(`^[A-Z]{6}\+`)
+)
+
+// DetectTampering inspects a receipt PDF for signs of editing: incremental
+// updates, content appended after a digital signature, mismatched or editing
+// software in the metadata, and fonts embedded more than once
+//
+// The checks are heuristics: an empty result does not prove a receipt is
+// genuine, and a printed-to-PDF copy of a genuine receipt may be flagged.
+// Prefer Verify, which fetches the official receipt, whenever possible.
+//
+// Example:
+//
+//	indicators, err := cbeverifier.DetectTampering(pdfBytes)
+//	if err == nil && len(indicators) > 0 {
+//		fmt.Println("receipt may have been edited:", indicators)
+//	}
+func DetectTampering(pdfBytes []byte) ([]string, error) {
+	return detectTampering(pdfBytes, "")
+}
+
+// detectTampering implements DetectTampering for a PDF decrypted with password
+func detectTampering(pdfBytes []byte, password string) (indicators []string, err error) {
+	defer recoverMalformed(&err)
+
+	doc, err := openPDF(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), password)
+	if err != nil {
+		return nil, err
+	}
+
+	indicators = append(indicators, revisionIndicators(pdfBytes)...)
+	indicators = append(indicators, metadataIndicators(doc)...)
+	indicators = append(indicators, fontIndicators(doc)...)
+	return indicators, nil
+}
+
+// revisionIndicators checks the raw PDF bytes for incremental updates
+func revisionIndicators(pdfBytes []byte) []string {
+	var indicators []string
+	data := bytes.TrimRight(pdfBytes, " \t\r\n\x00")
+
+	// Every save appends a new trailer ending in %%EOF; linearized files start
+	// with an extra one for the first page
+	revisions := bytes.Count(data, []byte("%%EOF"))
+	head := data[:min(len(data), 1024)]
+	if bytes.Contains(head, []byte("/Linearized")) {
+		revisions--
+	}
+
+	// Signing is itself saved as an incremental update, so signed files are
+	// only flagged when the last signature does not cover the whole file
+	if ranges := reByteRange.FindAllSubmatch(data, -1); len(ranges) > 0 {
+		last := ranges[len(ranges)-1]
+		start, _ := strconv.Atoi(string(last[3]))
+		length, _ := strconv.Atoi(string(last[4]))
+		if start+length < len(data) {
+			indicators = append(indicators, TamperModifiedAfterSigning)
+		}
+		return indicators
+	}
+
+	if revisions > 1 {
+		indicators = append(indicators, TamperIncrementalUpdate)
+	}
+	return indicators
+}
+
+// metadataIndicators checks the document info dictionary and XMP metadata for
+// editing software and inconsistent producers
+func metadataIndicators(doc *pdf.Reader) []string {
+	var indicators []string
+
+	info := doc.Trailer().Key("Info")
+	producer := strings.TrimSpace(info.Key("Producer").Text())
+	creator := strings.TrimSpace(info.Key("Creator").Text())
+
+	xmp := readMetadata(doc)
+	xmpProducer := firstSubmatch(reXMPProducer, xmp)
+	xmpCreator := firstSubmatch(reXMPCreatorTool, xmp)
+
+	if producer != "" && xmpProducer != "" && !strings.EqualFold(producer, xmpProducer) {
+		indicators = append(indicators, TamperProducerMismatch)
+	}
+
+	seen := make(map[string]bool)
+	for _, software := range []string{producer, creator, xmpProducer, xmpCreator} {
+		if tool := editingTool(software); tool != "" && !seen[tool] {
+			seen[tool] = true
+			indicators = append(indicators, TamperEditingTool+": "+tool)
+		}
+	}
+
+	if reXMPHistory.MatchString(xmp) {
+		indicators = append(indicators, TamperEditHistory)
+	}
+	return indicators
+}
+
+// readMetadata returns the document's XMP metadata, or "" if it has none
+func readMetadata(doc *pdf.Reader) string {
+	metadata := doc.Trailer().Key("Root").Key("Metadata")
+	if metadata.Kind() != pdf.Stream {
+		return ""
+	}
+	rd := metadata.Reader()
+	defer rd.Close()
+	data, _ := io.ReadAll(io.LimitReader(rd, 1<<20))
+	return string(data)
+}
+
+// firstSubmatch returns the first non-empty capture group of the first match of re in s
+func firstSubmatch(re *regexp.Regexp, s string) string {
+	match := re.FindStringSubmatch(s)
+	for i := 1; i < len(match); i++ {
+		if group := match[i]; group != "" {
+			return strings.TrimSpace(group)
+		}
+	}
+	return ""
+}
+
+// editingTool returns the editing tool named in software, or "" if there is none
+func editingTool(software string) string {
+	lower := strings.ToLower(software)
+	for _, tool := range editingTools {
+		if strings.Contains(lower, strings.ToLower(tool)) {
+			return tool
+		}
+	}
+	return ""
+}
+
+// fontIndicators reports fonts embedded more than once on the same page, either
+// as several subsets or as a subset alongside the full font
+func fontIndicators(doc *pdf.Reader) []string {
+	substitutions := make(map[string]bool)
+	for _, page := range pages(doc) {
+		// Generators may subset fonts per page, so only compare fonts within a page
+		embeddings := make(map[string]string)
+		for _, name := range page.Fonts() {
+			base := page.Font(name).BaseFont()
+			if base == "" {
+				continue
+			}
+			family := reSubsetTag.ReplaceAllString(base, "")
+			if seen, ok := embeddings[family]; ok && seen != base {
+				substitutions[family] = true
+			}
+			embeddings[family] = base
+		}
+	}
+
+	substituted := make([]string, 0, len(substitutions))
+	for family := range substitutions {
+		substituted = append(substituted, family)
+	}
+	sort.Strings(substituted)
+
+	indicators := make([]string, 0, len(substituted))
+	for _, family := range substituted {
+		indicators = append(indicators, TamperFontSubstitution+": "+family)
+	}
+	return indicators
+}
@@ -0,0 +1,107 @@
+package telebirr
+
+import (
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/ethiocal"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// receiptFields map the English part of the bilingual labels on a receipt page
+// (e.g., "የከፋይ ስም/Payer Name") to fields, checked in order
+var receiptFields = []webreceipt.Field{
+	webreceipt.Label("payer", `(?i)payer\s*name\s*[:.]?$`),
+	webreceipt.Label("payer_account", `(?i)payer\s*telebirr\s*no\.?\s*[:]?$`),
+	webreceipt.Label("receiver", `(?i)credited\s*party\s*name\s*[:.]?$`),
+	webreceipt.Label("receiver_account", `(?i)credited\s*party\s*account\s*no\.?\s*[:]?$`),
+	webreceipt.Label("bank_account", `(?i)bank\s*account\s*(?:number|no\.?)\s*[:]?$`),
+	webreceipt.Label("status", `(?i)transaction\s*status\s*[:.]?$`),
+	webreceipt.Label("transaction_id", `(?i)(?:invoice|receipt)\s*no\.?\s*[:]?$`),
+	webreceipt.Label("date", `(?i)payment\s*date\s*[:.]?$`),
+	webreceipt.Label("amount", `(?i)settled\s*amount\s*[:.]?$`),
+	webreceipt.Label("vat", `(?i)(?:\bvat|service\s*fee\s*vat)\s*[:.]?$`),
+	webreceipt.Label("service_charge", `(?i)service\s*fee\s*[:.]?$`),
+	webreceipt.Label("total_debited", `(?i)total\s*(?:amount\s*)?paid(?:\s*amount)?\s*[:.]?$`),
+	webreceipt.Label("reason", `(?i)payment\s*reason\s*[:.]?$`),
+	webreceipt.Label("channel", `(?i)payment\s*channel\s*[:.]?$`),
+	webreceipt.Label("transaction_type", `(?i)payment\s*mode\s*[:.]?$`),
+}
+
+// dateLayouts are the payment date formats seen on receipt pages
+var dateLayouts = []string{
+	"02-01-2006 15:04:05",
+	"2-1-2006 15:04:05",
+	"2006-01-02 15:04:05",
+	"02/01/2006 15:04:05",
+	"02-01-2006",
+}
+
+// Parse extracts transaction information from a Telebirr receipt page
+//
+// ErrReceiptNotFound is returned for pages without a receipt, which Telebirr
+// serves for unknown transaction numbers, and ErrTransactionNotCompleted for
+// failed or pending transactions. When required fields are missing, the error is
+// a *cbeverifier.MissingFieldsError and the partial details are returned with it.
+func Parse(page []byte) (*cbeverifier.TransactionDetails, error) {
+	values, err := webreceipt.Values(page, receiptFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrReceiptNotFound
+	}
+	if status := values["status"]; status != "" && !strings.EqualFold(status, "completed") {
+		return nil, ErrTransactionNotCompleted
+	}
+
+	details := &cbeverifier.TransactionDetails{
+		Payer:           values["payer"],
+		PayerAccount:    values["payer_account"],
+		Receiver:        values["receiver"],
+		ReceiverAccount: values["receiver_account"],
+		Amount:          webreceipt.Amount(values["amount"]),
+		Currency:        "ETB",
+		ServiceCharge:   webreceipt.Amount(values["service_charge"]),
+		VAT:             webreceipt.Amount(values["vat"]),
+		TotalDebited:    webreceipt.Amount(values["total_debited"]),
+		DateRaw:         values["date"],
+		TransactionID:   strings.ToUpper(values["transaction_id"]),
+		Reason:          values["reason"],
+		Channel:         normalizeChannel(values["channel"]),
+		TransactionType: values["transaction_type"],
+	}
+
+	if status := values["status"]; status != "" {
+		details.Extra = map[string]string{"status": status}
+	}
+
+	// Transfers to a bank account name the account instead of a telebirr number
+	if details.ReceiverAccount == "" {
+		details.ReceiverAccount = values["bank_account"]
+	}
+
+	if paid, ok := webreceipt.Date(details.DateRaw, dateLayouts); ok {
+		details.Date = paid
+		details.DateEC = ethiocal.FromTime(paid).String()
+	}
+
+	if missing := webreceipt.MissingFields(details); len(missing) > 0 {
+		return details, &cbeverifier.MissingFieldsError{Fields: missing}
+	}
+	return details, nil
+}
+
+// normalizeChannel maps the printed payment channel to a cbeverifier Channel constant
+func normalizeChannel(channel string) string {
+	lower := strings.ToLower(strings.TrimSpace(channel))
+	switch {
+	case strings.Contains(lower, "ussd"):
+		return cbeverifier.ChannelUSSD
+	case strings.Contains(lower, "app"), strings.Contains(lower, "mobile"):
+		return cbeverifier.ChannelMobile
+	case strings.Contains(lower, "web"), strings.Contains(lower, "online"):
+		return cbeverifier.ChannelInternet
+	}
+	return lower
+}
@@ -0,0 +1,67 @@
+package telebirr
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, NewProvider(defaultClient))
+}
+
+// provider adapts a Client to cbeverifier.Provider
+type provider struct {
+	client *Client
+}
+
+// NewProvider returns a cbeverifier.Provider that fetches receipts with client,
+// for registering a configured Client under a name of its own
+func NewProvider(client *Client) cbeverifier.Provider {
+	return provider{client}
+}
+
+// Fetch downloads the receipt page for the transaction number
+func (p provider) Fetch(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) ([]byte, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return nil, err
+	}
+	return p.client.fetchPage(ctx, transaction.ID, opts)
+}
+
+// Parse parses a receipt page
+func (p provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// DetectReference reports whether reference is a Telebirr transaction number or
+// receipt URL. Transaction numbers always mix letters and digits, which tells
+// them apart from phone numbers, but not from M-Pesa transaction codes: bare
+// numbers are detected by both providers, and only receipt URLs are Telebirr's
+// alone.
+func (p provider) DetectReference(reference string) bool {
+	number, err := ValidateTransactionNumber(reference)
+	return err == nil && strings.IndexFunc(number, unicode.IsLetter) >= 0
+}
+
+// Capabilities reports online lookup and uploads of saved receipt pages
+func (p provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Fetch:       true,
+		Upload:      true,
+		UploadTypes: []string{"text/html"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the receipt details
+func (p provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
@@ -0,0 +1,201 @@
+// Package telebirr verifies Telebirr transactions by fetching and parsing the
+// receipt page ethio telecom publishes for every transaction number.
+//
+// Results have the same shape as CBE verifications, so both payment rails can be
+// handled by the same code: the parsed receipt is returned as a
+// cbeverifier.TransactionDetails and compared with cbeverifier.CompareDetails.
+//
+// Example:
+//
+//	result, err := telebirr.Verify(cbeverifier.Transaction{
+//		ID:     "CE12ABC3DE",
+//		Amount: 1500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "telebirr", so
+// cbeverifier.Verify handles transactions whose Provider is telebirr.Name.
+package telebirr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "telebirr"
+
+// DefaultBaseURL is the Telebirr receipt endpoint; the transaction number is appended to it
+const DefaultBaseURL = "https://transactioninfo.ethiotelecom.et/receipt/"
+
+// Errors returned by the telebirr package
+var (
+	ErrInvalidTransactionNumber = errors.New("invalid telebirr transaction number")
+	ErrNetworkError             = errors.New("network error while requesting telebirr receipt")
+	ErrInvalidResponse          = errors.New("invalid response from telebirr")
+	ErrReceiptTooLarge          = errors.New("telebirr receipt exceeds maximum size")
+	ErrReceiptNotFound          = errors.New("telebirr receipt not found")
+	ErrTransactionNotCompleted  = errors.New("telebirr transaction is not completed")
+)
+
+// reTransactionNumber matches a well-formed Telebirr transaction number (e.g., "CE12ABC3DE")
+var reTransactionNumber = regexp.MustCompile(`^[A-Z0-9]{10}$`)
+
+// Client fetches and verifies Telebirr receipts. It is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// New creates a Client configured with the given options
+func New(options ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		baseURL:    DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithHTTPClient makes the Client send requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithBaseURL makes the Client fetch receipts from baseURL instead of DefaultBaseURL,
+// e.g. a caching proxy or a test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// defaultClient is the Client used by the package-level functions
+var defaultClient = New()
+
+// ValidateTransactionNumber returns the normalized transaction number, accepting
+// the number itself or the receipt URL sent in the Telebirr SMS
+//
+// Example:
+//
+//	number, err := telebirr.ValidateTransactionNumber("https://transactioninfo.ethiotelecom.et/receipt/CE12ABC3DE")
+//	// number = "CE12ABC3DE"
+func ValidateTransactionNumber(reference string) (string, error) {
+	ref := strings.TrimSpace(reference)
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", ErrInvalidTransactionNumber
+		}
+		ref = u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+
+	ref = strings.ToUpper(strings.Join(strings.Fields(ref), ""))
+	if !reTransactionNumber.MatchString(ref) {
+		return "", ErrInvalidTransactionNumber
+	}
+	return ref, nil
+}
+
+// Verify fetches the Telebirr receipt for transaction.ID (or FullReference) and
+// verifies the provided transaction data against it
+//
+// This function:
+// 1. Validates the transaction number; no suffix is needed
+// 2. Fetches the receipt page from ethio telecom
+// 3. Parses the page into TransactionDetails
+// 4. Compares the provided data using the checks and policy in opts
+func Verify(transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	return defaultClient.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the Telebirr receipt and verifies the provided transaction data
+func (c *Client) Verify(ctx context.Context, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, _, err := c.Fetch(ctx, transaction.ID, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized transaction number from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	number, err := ValidateTransactionNumber(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = number
+	return transaction, nil
+}
+
+// Fetch fetches and parses the Telebirr receipt for a transaction number without
+// comparing it, returning the parsed details and the raw receipt page
+func Fetch(ctx context.Context, transactionNumber string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	return defaultClient.Fetch(ctx, transactionNumber, opts)
+}
+
+// Fetch fetches and parses the Telebirr receipt for a transaction number
+func (c *Client) Fetch(ctx context.Context, transactionNumber string, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	number, err := ValidateTransactionNumber(transactionNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page, err := c.fetchPage(ctx, number, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := Parse(page)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, page, nil
+}
+
+// fetchPage downloads the receipt page for a validated transaction number
+func (c *Client) fetchPage(ctx context.Context, number string, opts cbeverifier.Options) ([]byte, error) {
+	page, _, err := webreceipt.Fetch(ctx, c.httpClient, c.baseURL+number, "text/html", opts, webreceipt.Errors{
+		Network:         ErrNetworkError,
+		InvalidResponse: ErrInvalidResponse,
+		NotFound:        ErrReceiptNotFound,
+		TooLarge:        ErrReceiptTooLarge,
+	})
+	return page, err
+}
@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAPIURL is the Telegram Bot API
+const defaultAPIURL = "https://api.telegram.org"
+
+// pollTimeout is how long getUpdates waits for an update before returning
+// none
+const pollTimeout = 30 * time.Second
+
+// APIError is an error reported by the Bot API
+type APIError struct {
+	Code        int
+	Description string
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// Update is an update received from Telegram. Only messages are handled.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// Message is a message sent to the bot
+type Message struct {
+	MessageID int64     `json:"message_id"`
+	Chat      Chat      `json:"chat"`
+	From      *User     `json:"from,omitempty"`
+	Text      string    `json:"text,omitempty"`
+	Caption   string    `json:"caption,omitempty"`
+	Document  *Document `json:"document,omitempty"`
+}
+
+// Chat is the chat a message was sent in
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// User is the sender of a message
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// Document is a file attached to a message
+type Document struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+}
+
+// call calls a Bot API method with a JSON request, decoding its result into
+// v when v is not nil
+func (b *Bot) call(ctx context.Context, method string, request, v any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.apiURL+"/bot"+b.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		// The URL carries the token, which must not end up in logs
+		return fmt.Errorf("telegram: %s: %w", method, redact(err, b.token))
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("telegram: %s: invalid response: %w", method, err)
+	}
+	if !response.OK {
+		return &APIError{Code: response.ErrorCode, Description: response.Description}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, v)
+}
+
+// getUpdates waits for the updates after offset
+func (b *Bot) getUpdates(ctx context.Context, offset int64) ([]Update, error) {
+	var updates []Update
+	err := b.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(pollTimeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// reply sends text to the chat of message, as a reply to it
+func (b *Bot) reply(ctx context.Context, message *Message, text string) error {
+	return b.call(ctx, "sendMessage", map[string]any{
+		"chat_id":          message.Chat.ID,
+		"text":             text,
+		"reply_parameters": map[string]any{"message_id": message.MessageID, "allow_sending_without_reply": true},
+	}, nil)
+}
+
+// download downloads a document sent to the bot, failing if it is larger than
+// limit bytes
+func (b *Bot) download(ctx context.Context, fileID string, limit int64) ([]byte, error) {
+	var file struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := b.call(ctx, "getFile", map[string]any{"file_id": fileID}, &file); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.apiURL+"/file/bot"+b.token+"/"+file.FilePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: download: %w", redact(err, b.token))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram: download: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
+
+// redact removes the bot token from an error
+func redact(err error, token string) error {
+	return errors.New(strings.ReplaceAll(err.Error(), token, "<token>"))
+}
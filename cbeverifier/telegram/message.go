@@ -0,0 +1,221 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// usage is the reply to /start, /help and messages without a transaction
+const usage = `Send me a payment to verify:
+• forward the SMS from CBE,
+• paste the reference and the amount, e.g. FT24123ABCDE12345678 1500, or the receipt link and the amount,
+• or upload the receipt PDF with the amount as its caption.`
+
+var (
+	// reReference matches a reference token: letters and digits, with at least
+	// one of each (e.g., FT24123ABCDE, FT24123ABCDE12345678)
+	reReference = regexp.MustCompile(`^[A-Z0-9]*[A-Z][A-Z0-9]*\d[A-Z0-9]*$|^[A-Z0-9]*\d[A-Z0-9]*[A-Z][A-Z0-9]*$`)
+	// reSuffix matches an 8-digit account suffix
+	reSuffix = regexp.MustCompile(`^\d{8}$`)
+)
+
+// Errors of messages that do not name a transaction
+var (
+	errNoReference = errors.New("no reference")
+	errNoAmount    = errors.New("no amount")
+)
+
+// answer returns the reply to a message
+func (b *Bot) answer(ctx context.Context, message *Message) string {
+	if message.Document != nil {
+		return b.answerReceipt(ctx, message)
+	}
+
+	text := strings.TrimSpace(message.Text)
+	if text == "" || strings.HasPrefix(text, "/start") || strings.HasPrefix(text, "/help") {
+		return usage
+	}
+	transaction, err := parseText(text)
+	switch {
+	case errors.Is(err, errNoAmount):
+		return "Send the amount with the reference, e.g. FT24123ABCDE12345678 1500."
+	case err != nil:
+		return usage
+	}
+
+	result, err := b.verifier.Verify(ctx, transaction, b.opts)
+	if err != nil {
+		b.logger.WarnContext(ctx, "failed to verify", "chat", message.Chat.ID, "error", err)
+		return "The payment could not be verified right now. Please try again later."
+	}
+	b.logResult(ctx, message, transaction, result)
+	return formatResult(result)
+}
+
+// answerReceipt returns the reply to an uploaded receipt
+func (b *Bot) answerReceipt(ctx context.Context, message *Message) string {
+	document := message.Document
+	if document.MimeType != "application/pdf" && !strings.HasSuffix(strings.ToLower(document.FileName), ".pdf") {
+		return "Please send the receipt as a PDF file."
+	}
+	limit := b.opts.MaxPDFBytes
+	if limit <= 0 {
+		limit = defaultMaxFileBytes
+	}
+	if document.FileSize > limit {
+		return "The file is too large to be a receipt."
+	}
+
+	receipt, err := b.download(ctx, document.FileID, limit)
+	if errors.Is(err, errFileTooLarge) {
+		return "The file is too large to be a receipt."
+	}
+	if err != nil {
+		b.logger.WarnContext(ctx, "failed to download receipt", "chat", message.Chat.ID, "error", err)
+		return "The receipt could not be downloaded. Please send it again."
+	}
+	parsed, err := cbeverifier.ParseCBEReceipt(receipt)
+	if err != nil {
+		return "The file is not a CBE receipt I can read."
+	}
+
+	// The receipt names its own reference; only the amount is claimed
+	claimed, _ := parseText(message.Caption)
+	if claimed.Amount <= 0 {
+		return formatDetails(&parsed.TransactionDetails) + "\n\nAdd the amount you paid as the caption of the PDF to verify it."
+	}
+	transaction := cbeverifier.Transaction{ID: parsed.TransactionID, Amount: claimed.Amount}
+
+	result, err := b.verifier.VerifyPDF(ctx, receipt, transaction, b.opts)
+	if err != nil {
+		b.logger.WarnContext(ctx, "failed to verify receipt", "chat", message.Chat.ID, "error", err)
+		return "The receipt could not be verified right now. Please try again later."
+	}
+	b.logResult(ctx, message, transaction, result)
+	return formatResult(result)
+}
+
+// logResult logs the verification of a message
+func (b *Bot) logResult(ctx context.Context, message *Message, transaction cbeverifier.Transaction, result *cbeverifier.VerificationResult) {
+	var user string
+	if message.From != nil {
+		user = message.From.Username
+	}
+	reference := transaction.ID
+	if reference == "" {
+		reference = transaction.FullReference
+	}
+	b.logger.InfoContext(ctx, "verified payment", "chat", message.Chat.ID, "user", user,
+		"reference", reference, "amount", transaction.Amount, "valid", result.IsValid)
+}
+
+// parseText reads the transaction named by a message: a forwarded CBE SMS, or
+// a reference, an optional suffix and an amount in any order
+func parseText(text string) (cbeverifier.Transaction, error) {
+	if sms, err := cbeverifier.ParseSMS(text); err == nil && sms.Suffix != "" && sms.Amount > 0 {
+		return sms.Transaction(), nil
+	}
+
+	var reference, suffix string
+	var amount float64
+	for _, field := range strings.Fields(text) {
+		field = strings.Trim(field, ",;")
+		upper := strings.ToUpper(field)
+		switch {
+		case strings.HasPrefix(strings.ToLower(field), "http") && reference == "":
+			reference = field
+		case reSuffix.MatchString(field) && reference != "" && suffix == "":
+			suffix = field
+		case reReference.MatchString(upper) && reference == "":
+			reference = upper
+		default:
+			value, err := strconv.ParseFloat(strings.ReplaceAll(field, ",", ""), 64)
+			if err == nil && value > 0 && amount == 0 {
+				amount = value
+			}
+		}
+	}
+
+	switch {
+	case reference == "":
+		return cbeverifier.Transaction{Amount: amount}, errNoReference
+	case amount == 0:
+		return cbeverifier.Transaction{}, errNoAmount
+	case suffix != "":
+		return cbeverifier.Transaction{ID: reference, Suffix: suffix, Amount: amount}, nil
+	}
+	return cbeverifier.Transaction{FullReference: reference, Amount: amount}, nil
+}
+
+// formatResult formats a verification result as a reply
+func formatResult(result *cbeverifier.VerificationResult) string {
+	var b strings.Builder
+	if result.IsValid {
+		b.WriteString("✅ Payment verified.")
+	} else {
+		fmt.Fprintf(&b, "❌ Payment not verified: %s", result.Error)
+	}
+	writeFields(&b, result.Mismatches)
+	if result.NeedsReview {
+		b.WriteString("\nThe receipt was hard to read and needs a manual check.")
+	}
+	if result.Details != nil {
+		b.WriteString("\n\n")
+		b.WriteString(formatDetails(result.Details))
+	}
+	return b.String()
+}
+
+// writeFields writes the mismatches of a result, sorted by field
+func writeFields(b *strings.Builder, fields map[string]interface{}) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if values, ok := fields[name].(map[string]interface{}); ok {
+			fmt.Fprintf(b, "\n• %s: sent %s, receipt %s", name, formatValue(values["provided"]), formatValue(values["official"]))
+			continue
+		}
+		fmt.Fprintf(b, "\n• %s: %s", name, formatValue(fields[name]))
+	}
+}
+
+// formatDetails formats the details of a receipt as a reply
+func formatDetails(details *cbeverifier.TransactionDetails) string {
+	lines := []string{
+		"Reference: " + details.TransactionID,
+		fmt.Sprintf("Amount: %.2f %s", details.Amount, details.Currency),
+		"Payer: " + details.Payer,
+		"Receiver: " + details.Receiver,
+	}
+	if details.DateRaw != "" {
+		lines = append(lines, "Date: "+details.DateRaw)
+	}
+	if details.Reason != "" {
+		lines = append(lines, "Reason: "+details.Reason)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatValue formats a compared value, with amounts to two decimals
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return fmt.Sprint(value)
+}
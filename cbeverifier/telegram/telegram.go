@@ -0,0 +1,205 @@
+// Package telegram implements a Telegram bot verifying the payments customers
+// send to merchants. Customers forward the CBE SMS, paste a reference and an
+// amount, or upload the receipt PDF with the amount as its caption, and the bot
+// replies with the verification result.
+//
+// The bot polls the Bot API with Run, or receives updates on a webhook when
+// served as an http.Handler.
+//
+// Example:
+//
+//	bot := telegram.New(os.Getenv("TELEGRAM_BOT_TOKEN"), cbeverifier.New(),
+//		telegram.WithOptions(cbeverifier.Options{ExpectedReceiverSuffix: "12345678"}))
+//	if err := bot.Run(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/audit"
+)
+
+// retryDelay is the delay before polling again after getUpdates failed
+const retryDelay = 5 * time.Second
+
+// defaultMaxFileBytes limits downloaded receipts when Options.MaxPDFBytes is
+// unset
+const defaultMaxFileBytes = 5 << 20
+
+// errFileTooLarge is returned for documents larger than the receipt limit
+var errFileTooLarge = errors.New("file too large")
+
+// Bot is a Telegram bot verifying payments
+type Bot struct {
+	token       string
+	verifier    *cbeverifier.Verifier
+	opts        cbeverifier.Options
+	logger      *slog.Logger
+	httpClient  *http.Client
+	apiURL      string
+	secretToken string
+
+	// handling tracks the messages being handled, which Run waits for
+	handling sync.WaitGroup
+}
+
+// Option configures a Bot
+type Option func(*Bot)
+
+// WithOptions sets the options of every verification (default:
+// cbeverifier.DefaultOptions()). Set ExpectedReceiverSuffix to your own
+// account, so that receipts of payments to others are rejected.
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(b *Bot) {
+		b.opts = opts
+	}
+}
+
+// WithLogger logs the messages handled, and failures to handle them, to
+// logger (default: discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Bot) {
+		if logger != nil {
+			b.logger = logger
+		}
+	}
+}
+
+// WithHTTPClient calls the Bot API with client (default: an http.Client whose
+// timeout allows for long polling)
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) {
+		if client != nil {
+			b.httpClient = client
+		}
+	}
+}
+
+// WithAPIURL calls the Bot API at url instead of https://api.telegram.org, for
+// self-hosted Bot API servers
+func WithAPIURL(url string) Option {
+	return func(b *Bot) {
+		b.apiURL = url
+	}
+}
+
+// WithSecretToken makes ServeHTTP reject updates without token in their
+// X-Telegram-Bot-Api-Secret-Token header, as set with setWebhook's
+// secret_token
+func WithSecretToken(token string) Option {
+	return func(b *Bot) {
+		b.secretToken = token
+	}
+}
+
+// New creates a bot authenticated with token, as given by @BotFather
+func New(token string, verifier *cbeverifier.Verifier, options ...Option) *Bot {
+	b := &Bot{
+		token:      token,
+		verifier:   verifier,
+		opts:       cbeverifier.DefaultOptions(),
+		logger:     slog.New(slog.DiscardHandler),
+		httpClient: &http.Client{Timeout: pollTimeout + 30*time.Second},
+		apiURL:     defaultAPIURL,
+	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// Run polls Telegram for messages and answers them until ctx is done, then
+// waits for the replies being prepared. It fails at once if the token is
+// rejected; other errors are logged and polling resumes after a delay.
+func (b *Bot) Run(ctx context.Context) error {
+	defer b.handling.Wait()
+
+	var offset int64
+	for {
+		updates, err := b.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusNotFound) {
+			return err
+		}
+		if err != nil {
+			b.logger.WarnContext(ctx, "failed to poll Telegram", "error", err)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			b.handleUpdate(ctx, update)
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler, receiving the updates Telegram posts to
+// the bot's webhook
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if b.secretToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(b.secretToken)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var update Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+	// Telegram retries updates that are not acknowledged quickly
+	b.handleUpdate(context.WithoutCancel(r.Context()), update)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUpdate answers the message of an update in the background
+func (b *Bot) handleUpdate(ctx context.Context, update Update) {
+	if update.Message == nil {
+		return
+	}
+	b.handling.Add(1)
+	go func() {
+		defer b.handling.Done()
+
+		// Replies are sent even when Run is stopping
+		ctx := context.WithoutCancel(ctx)
+		message := update.Message
+		ctx = audit.ContextWithActor(ctx, actor(message))
+		reply := b.answer(ctx, message)
+		if err := b.reply(ctx, message, reply); err != nil {
+			b.logger.WarnContext(ctx, "failed to reply", "chat", message.Chat.ID, "error", err)
+		}
+	}()
+}
+
+// actor names the sender of a message in audit logs
+func actor(message *Message) string {
+	if message.From == nil {
+		return fmt.Sprintf("telegram:chat:%d", message.Chat.ID)
+	}
+	if message.From.Username != "" {
+		return "telegram:@" + message.From.Username
+	}
+	return fmt.Sprintf("telegram:user:%d", message.From.ID)
+}
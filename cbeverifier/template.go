@@ -0,0 +1,435 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/ethiocal"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidTemplate is returned when a receipt template cannot be loaded or compiled
+var ErrInvalidTemplate = errors.New("invalid receipt template")
+
+// templateFields are the fields a FieldRule can extract. "account" assigns the
+// account number to whichever party (payer or receiver) was seen last. Fields
+// named "extra.<name>" are stored in TransactionDetails.Extra under name.
+var templateFields = map[string]bool{
+	"payer":            true,
+	"receiver":         true,
+	"account":          true,
+	"payer_account":    true,
+	"receiver_account": true,
+	"receiver_bank":    true,
+	"amount":           true,
+	"currency":         true,
+	"service_charge":   true,
+	"vat":              true,
+	"total_debited":    true,
+	"date":             true,
+	"transaction_id":   true,
+	"reason":           true,
+	"branch":           true,
+	"channel":          true,
+	"transaction_type": true,
+}
+
+// isExtraField reports whether a rule field names a TransactionDetails.Extra entry
+func isExtraField(field string) bool {
+	name, ok := strings.CutPrefix(field, "extra.")
+	return ok && name != ""
+}
+
+// templateTransforms clean up an extracted value before it is stored
+var templateTransforms = map[string]func(string) string{
+	"reason":    cleanReason,
+	"reference": cleanReference,
+	"upper":     strings.ToUpper,
+	"channel":   normalizeChannel,
+}
+
+// FieldRule describes how to extract one field from a receipt row
+//
+// A rule matches either by Label, a pattern matched against the label column of
+// a receipt table whose value column is then taken verbatim, or by Pattern, a
+// pattern applied to the full row text. The value is the capture group named
+// "value", or the first group if there is none. Amount rules may also capture
+// the currency in groups named "currency".
+type FieldRule struct {
+	// Field is the JSON name of the extracted field (e.g., "payer", "amount"), or
+	// "extra.<name>" for a field without one of its own
+	Field string `json:"field" yaml:"field"`
+	// Label is a regular expression matched against the label column
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+	// Pattern is a regular expression matched against the row text
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Transform post-processes the value: "reason", "reference", "channel" or "upper"
+	Transform string `json:"transform,omitempty" yaml:"transform,omitempty"`
+
+	label   *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+// ReceiptTemplate maps receipt rows to TransactionDetails fields
+//
+// Rules are tried in order and the first matching rule consumes the row, so more
+// specific rules must come first. When CBE changes its receipt layout, a template
+// can be loaded from a JSON or YAML file and configured with WithReceiptTemplates
+// instead of waiting for a library release.
+//
+// Example:
+//
+//	name: cbe-2026
+//	rules:
+//	  - field: payer
+//	    label: '(?i)^sender name$'
+//	  - field: amount
+//	    pattern: '(?i)amount\s*(?P<value>[\d,]+\.\d{2})\s*(?P<currency>[A-Z]{3})?'
+type ReceiptTemplate struct {
+	// Name identifies the template in logs and errors
+	Name string `json:"name" yaml:"name"`
+	// Rules are the extraction rules, in priority order
+	Rules []FieldRule `json:"rules" yaml:"rules"`
+}
+
+// CBETemplate returns the built-in template for the current CBE receipt layout.
+// It can be used as a starting point for custom templates.
+func CBETemplate() *ReceiptTemplate {
+	return &ReceiptTemplate{
+		Name: "cbe",
+		Rules: []FieldRule{
+			{Field: "payer", Label: reLabelPayer.String()},
+			{Field: "receiver", Label: reLabelReceiver.String()},
+			{Field: "payer_account", Pattern: reAmharicPayerAccount.String()},
+			{Field: "receiver_account", Pattern: reAmharicReceiverAccount.String()},
+			{Field: "account", Pattern: reAmharicAccount.String()},
+			// Interbank receipts label the receiver's bank and, on some layouts, the
+			// beneficiary's account, which the generic receiver rule would take as a name
+			{Field: "receiver_bank", Pattern: reReceiverBank.String()},
+			{Field: "receiver_account", Pattern: reReceiverAccount.String()},
+			{Field: "branch", Pattern: reBranch.String()},
+			{Field: "channel", Pattern: reChannel.String(), Transform: "channel"},
+			{Field: "transaction_type", Pattern: reTransactionType.String()},
+			{Field: "payer", Pattern: rePayer.String()},
+			{Field: "receiver", Pattern: reReceiver.String()},
+			// The total line mentions the payer's account, so it must precede the account rule
+			{Field: "total_debited", Pattern: reTotalDebited.String()},
+			{Field: "service_charge", Pattern: reServiceCharge.String()},
+			{Field: "vat", Pattern: reVAT.String()},
+			{Field: "account", Pattern: reAccount.String()},
+			{Field: "amount", Pattern: reTransferredAmt.String()},
+			{Field: "reason", Pattern: reReason.String(), Transform: "reason"},
+			{Field: "transaction_id", Pattern: reReferenceNo.String(), Transform: "reference"},
+			{Field: "date", Pattern: rePaymentDate.String()},
+		},
+	}
+}
+
+// defaultTemplate is the compiled built-in template
+var defaultTemplate = mustCompileTemplate(CBETemplate())
+
+// mustCompileTemplate compiles a built-in template, panicking on error
+func mustCompileTemplate(t *ReceiptTemplate) *ReceiptTemplate {
+	if err := t.Compile(); err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseReceiptTemplate parses a template from its JSON or YAML definition and compiles it
+func ParseReceiptTemplate(data []byte) (*ReceiptTemplate, error) {
+	var (
+		t   ReceiptTemplate
+		err error
+	)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(data, &t)
+	} else {
+		err = yaml.Unmarshal(data, &t)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+
+	if err := t.Compile(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LoadReceiptTemplate reads and compiles a template from a JSON or YAML file
+func LoadReceiptTemplate(path string) (*ReceiptTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return ParseReceiptTemplate(data)
+}
+
+// Compile validates the template and compiles its patterns. It is called by
+// ParseReceiptTemplate and LoadReceiptTemplate, and must be called before
+// using a template built in code.
+func (t *ReceiptTemplate) Compile() error {
+	if len(t.Rules) == 0 {
+		return fmt.Errorf("%w %q: no rules", ErrInvalidTemplate, t.Name)
+	}
+
+	for i := range t.Rules {
+		rule := &t.Rules[i]
+		if !templateFields[rule.Field] && !isExtraField(rule.Field) {
+			return fmt.Errorf("%w %q: rule %d: unknown field %q", ErrInvalidTemplate, t.Name, i, rule.Field)
+		}
+		if rule.Transform != "" && templateTransforms[rule.Transform] == nil {
+			return fmt.Errorf("%w %q: rule %d: unknown transform %q", ErrInvalidTemplate, t.Name, i, rule.Transform)
+		}
+		if (rule.Label == "") == (rule.Pattern == "") {
+			return fmt.Errorf("%w %q: rule %d: exactly one of label or pattern is required", ErrInvalidTemplate, t.Name, i)
+		}
+
+		var err error
+		if rule.Label != "" {
+			rule.label, err = regexp.Compile(rule.Label)
+		} else {
+			rule.pattern, err = regexp.Compile(rule.Pattern)
+		}
+		if err != nil {
+			return fmt.Errorf("%w %q: rule %d: %v", ErrInvalidTemplate, t.Name, i, err)
+		}
+	}
+
+	return nil
+}
+
+// Parse parses a receipt PDF using the template, falling back to the built-in
+// template if it leaves required fields missing
+func (t *ReceiptTemplate) Parse(pdfBytes []byte) (*ParsedReceipt, error) {
+	rows, err := readPDFRows(bytesReaderAt(pdfBytes), int64(len(pdfBytes)), "")
+	if err != nil {
+		return nil, err
+	}
+	return parseRows(rows, t)
+}
+
+// match applies the rule to a row, returning the value and any currency tokens
+func (r *FieldRule) match(label, value, line string) (string, []string, bool) {
+	if r.label == nil && r.pattern == nil {
+		// The template was not compiled
+		return "", nil, false
+	}
+
+	if r.label != nil {
+		if value == "" || !r.label.MatchString(label) {
+			return "", nil, false
+		}
+		return r.transform(value), nil, true
+	}
+
+	m := r.pattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", nil, false
+	}
+
+	var (
+		extracted  string
+		currencies []string
+	)
+	if len(m) > 1 {
+		extracted = m[1]
+	}
+	for i, name := range r.pattern.SubexpNames() {
+		switch name {
+		case "value":
+			extracted = m[i]
+		case "currency":
+			currencies = append(currencies, m[i])
+		}
+	}
+
+	// Without named groups, amount rules follow the built-in layout of
+	// (currency before, amount, currency after)
+	if r.Field == "amount" && len(currencies) == 0 && len(m) == 4 {
+		extracted, currencies = m[2], []string{m[1], m[3]}
+	}
+
+	extracted = strings.TrimSpace(extracted)
+	if extracted == "" {
+		return "", nil, false
+	}
+	return r.transform(extracted), currencies, true
+}
+
+// transform applies the rule's transform to a value
+func (r *FieldRule) transform(value string) string {
+	if fn := templateTransforms[r.Transform]; fn != nil {
+		value = fn(value)
+	}
+	return strings.TrimSpace(value)
+}
+
+// extract extracts transaction information from receipt rows
+func (t *ReceiptTemplate) extract(rows []receiptRow) *ParsedReceipt {
+	return t.extractWith(rows, nil)
+}
+
+// extractWith extracts transaction information from receipt rows, calling onMatch,
+// if not nil, with the index of every row consumed by a rule
+func (t *ReceiptTemplate) extractWith(rows []receiptRow, onMatch func(row int, rule *FieldRule, value string)) *ParsedReceipt {
+	var (
+		payer, receiver, transferredAmt, currency, reason, refNo, paymentDate string
+		serviceCharge, vat, totalDebited                                      string
+		branch, channel, transactionType, receiverBank                        string
+		payerAccounts, receiverAccounts                                       []string
+		currentEntity                                                         string
+		extra                                                                 map[string]string
+	)
+
+	scores := newFieldScores()
+
+	// Process each row of text
+	for r, row := range rows {
+		label := strings.TrimSpace(normalizeEthiopic(row.Label))
+		value := strings.TrimSpace(normalizeEthiopic(row.Value))
+		line := strings.TrimSpace(label + " " + value)
+
+		for i := range t.Rules {
+			rule := &t.Rules[i]
+			extracted, currencies, ok := rule.match(label, value, line)
+			if !ok {
+				continue
+			}
+			if onMatch != nil {
+				onMatch(r, rule, extracted)
+			}
+
+			score := matchConfidence(rule, row)
+			switch rule.Field {
+			case "payer":
+				payer, currentEntity = extracted, "payer"
+				scores.record("payer", extracted, score)
+			case "receiver":
+				receiver, currentEntity = extracted, "receiver"
+				scores.record("receiver", extracted, score)
+			case "payer_account":
+				payerAccounts, currentEntity = append(payerAccounts, extracted), "payer"
+				scores.recordFirst("payer_account", score)
+			case "receiver_account":
+				receiverAccounts, currentEntity = append(receiverAccounts, extracted), "receiver"
+				scores.recordFirst("receiver_account", score)
+			case "account":
+				// The party is inferred from the preceding rows
+				score *= inferredPenalty
+				if currentEntity == "payer" {
+					payerAccounts = append(payerAccounts, extracted)
+					scores.recordFirst("payer_account", score)
+				} else if currentEntity == "receiver" {
+					receiverAccounts = append(receiverAccounts, extracted)
+					scores.recordFirst("receiver_account", score)
+				}
+			case "receiver_bank":
+				receiverBank = extracted
+				scores.record("receiver_bank", extracted, score)
+			case "amount":
+				transferredAmt = extracted
+				scores.record("amount", extracted, score)
+				if code := normalizeCurrency(currencies...); currency == "" || code != "ETB" {
+					currency = code
+					scores.record("currency", code, score)
+				}
+			case "currency":
+				currency = normalizeCurrency(extracted)
+				scores.record("currency", currency, score)
+			case "service_charge":
+				serviceCharge = extracted
+				scores.record("service_charge", extracted, score)
+			case "vat":
+				vat = extracted
+				scores.record("vat", extracted, score)
+			case "total_debited":
+				totalDebited = extracted
+				scores.record("total_debited", extracted, score)
+			case "date":
+				paymentDate = extracted
+				scores.record("date", extracted, score)
+			case "transaction_id":
+				refNo = extracted
+				scores.record("transaction_id", extracted, score)
+			case "reason":
+				reason = extracted
+				scores.record("reason", extracted, score)
+			case "branch":
+				branch = extracted
+				scores.record("branch", extracted, score)
+			case "channel":
+				channel = extracted
+				scores.record("channel", extracted, score)
+			case "transaction_type":
+				transactionType = extracted
+				scores.record("transaction_type", extracted, score)
+			default:
+				if name, ok := strings.CutPrefix(rule.Field, "extra."); ok {
+					if extra == nil {
+						extra = make(map[string]string)
+					}
+					extra[name] = extracted
+				}
+			}
+			break
+		}
+	}
+
+	// Parse the payment date and convert it to the Ethiopian calendar
+	var (
+		paid   time.Time
+		dateEC string
+	)
+	if parsed, err := parseReceiptDate(paymentDate); err == nil {
+		paid = parsed
+		dateEC = ethiocal.FromTime(paid).String()
+	} else if paymentDate != "" {
+		scores.penalize("date")
+	}
+
+	// Values that do not look like what the field should hold are less certain
+	if refNo != "" && !reTransactionID.MatchString(refNo) {
+		scores.penalize("transaction_id")
+	}
+	for field, raw := range map[string]string{"amount": transferredAmt, "service_charge": serviceCharge, "vat": vat, "total_debited": totalDebited} {
+		if raw != "" && parseAmount(raw) <= 0 {
+			scores.penalize(field)
+		}
+	}
+
+	// Build the parsed receipt
+	return &ParsedReceipt{
+		TransactionDetails: TransactionDetails{
+			Payer:            payer,
+			PayerAccount:     getFirstAccount(payerAccounts),
+			Receiver:         receiver,
+			ReceiverAccount:  getFirstAccount(receiverAccounts),
+			PayerAccounts:    uniqueAccounts(payerAccounts),
+			ReceiverAccounts: uniqueAccounts(receiverAccounts),
+			ReceiverBank:     receiverBank,
+			Amount:           parseAmount(transferredAmt),
+			Currency:         currency,
+			ServiceCharge:    parseAmount(serviceCharge),
+			VAT:              parseAmount(vat),
+			TotalDebited:     parseAmount(totalDebited),
+			Date:             paid,
+			DateRaw:          paymentDate,
+			DateEC:           dateEC,
+			TransactionID:    refNo,
+			Reason:           reason,
+			Branch:           branch,
+			Channel:          channel,
+			TransactionType:  transactionType,
+			Extra:            extra,
+			Confidence:       scores.result(),
+		},
+	}
+}
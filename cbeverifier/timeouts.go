@@ -0,0 +1,119 @@
+package cbeverifier
+
+import (
+	"context"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Default time budgets applied when Options leaves them unset
+const (
+	defaultTimeout               = 30 * time.Second
+	defaultDialTimeout           = 10 * time.Second
+	defaultResponseHeaderTimeout = 20 * time.Second
+	defaultParseTimeout          = 10 * time.Second
+)
+
+// defaultMaxPDFBytes is the receipt size limit used when MaxPDFBytes is unset;
+// genuine CBE receipts are well under 100 KiB
+const defaultMaxPDFBytes = 5 << 20
+
+// withDefaults fills unset time budgets and size limits. FetchTimeout is always
+// set, from Timeout when only that is.
+func (o Options) withDefaults() Options {
+	if o.FetchTimeout <= 0 && o.Timeout > 0 {
+		o.FetchTimeout = time.Duration(o.Timeout) * time.Second
+	}
+	if o.FetchTimeout <= 0 {
+		o.FetchTimeout = defaultTimeout
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = defaultDialTimeout
+	}
+	if o.ResponseHeaderTimeout <= 0 {
+		o.ResponseHeaderTimeout = defaultResponseHeaderTimeout
+	}
+	if o.ParseTimeout <= 0 {
+		o.ParseTimeout = defaultParseTimeout
+	}
+	if o.MaxPDFBytes <= 0 {
+		o.MaxPDFBytes = defaultMaxPDFBytes
+	}
+	return o
+}
+
+// withPhaseDeadlines returns a context that is cancelled with ErrDialTimeout when
+// obtaining a connection takes longer than DialTimeout, or with
+// ErrResponseTimeout when the first response byte does not arrive within
+// ResponseHeaderTimeout of the request being written. The budgets are enforced
+// through httptrace so they also apply to caller-supplied HTTP clients.
+func withPhaseDeadlines(ctx context.Context, opts Options) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+	start := func(d time.Duration, cause error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(d, func() { cancel(cause) })
+	}
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+	}
+
+	trace := &httptrace.ClientTrace{
+		GetConn:              func(string) { start(opts.DialTimeout, ErrDialTimeout) },
+		GotConn:              func(httptrace.GotConnInfo) { stop() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { start(opts.ResponseHeaderTimeout, ErrResponseTimeout) },
+		GotFirstResponseByte: stop,
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// parseWithTimeout runs parse, giving up after timeout. The parse keeps running
+// in the background until it finishes, but the caller is released. A non-positive
+// timeout waits indefinitely.
+func parseWithTimeout(timeout time.Duration, parse func() (*TransactionDetails, error)) (*TransactionDetails, error) {
+	if timeout <= 0 {
+		return parse()
+	}
+
+	type outcome struct {
+		details *TransactionDetails
+		err     error
+	}
+
+	done := make(chan outcome, 1)
+	go func() {
+		// A panic here cannot be recovered by the caller and would crash the process
+		var o outcome
+		defer func() { done <- o }()
+		defer recoverMalformed(&o.err)
+		o.details, o.err = parse()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case o := <-done:
+		return o.details, o.err
+	case <-timer.C:
+		return nil, ErrParseTimeout
+	}
+}
@@ -0,0 +1,82 @@
+package cbeverifier
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeoutDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want time.Duration
+	}{
+		{"unset", Options{}, defaultTimeout},
+		{"default options", DefaultOptions(), 30 * time.Second},
+		{"seconds", Options{Timeout: 120}, 120 * time.Second},
+		{"duration", Options{FetchTimeout: 1500 * time.Millisecond}, 1500 * time.Millisecond},
+		{"duration overrides seconds", Options{Timeout: 120, FetchTimeout: 5 * time.Second}, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.withDefaults().FetchTimeout; got != tt.want {
+			t.Errorf("%s: FetchTimeout = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// stalledServer accepts connections and never writes to them
+func stalledServer(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return listener
+}
+
+func TestPhaseTimeouts(t *testing.T) {
+	listener := stalledServer(t)
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", listener.Addr().String())
+	}
+
+	tests := []struct {
+		name      string
+		transport *http.Transport
+		want      error
+	}{
+		// The TLS handshake never completes, so no connection is obtained
+		{"dial", &http.Transport{DialContext: dial}, ErrDialTimeout},
+		// The connection is taken as already secured, and the request is written
+		// but never answered
+		{"response", &http.Transport{DialTLSContext: dial}, ErrResponseTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(WithHTTPClient(&http.Client{Transport: tt.transport}))
+			opts := Options{
+				FetchTimeout:          5 * time.Second,
+				DialTimeout:           100 * time.Millisecond,
+				ResponseHeaderTimeout: 100 * time.Millisecond,
+			}
+			_, _, err := v.FetchReceipt(context.Background(), "FT24123ABCDE", "12345678", opts)
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrNetworkError) {
+				t.Errorf("err = %v, want %v and %v", err, tt.want, ErrNetworkError)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package cbeverifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies this library's spans
+const tracerName = "github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+
+// Span names emitted by the Verifier
+const (
+	spanVerify  = "cbe.verify"
+	spanFetch   = "cbe.fetch"
+	spanParse   = "cbe.parse"
+	spanCompare = "cbe.compare"
+)
+
+// hashReference returns a short, stable hash of a reference so traces can be
+// correlated without exposing the reference itself
+func hashReference(reference string) string {
+	sum := sha256.Sum256([]byte(reference))
+	return hex.EncodeToString(sum[:8])
+}
+
+// startSpan starts a span from the Verifier's tracer
+func (v *Verifier) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return v.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -28,7 +28,7 @@
 //		// Verify against official records
 //		result, err := cbeverifier.Verify(transaction, cbeverifier.Options{
 //			IncludeDetails: true,
-//			Timeout:        120,
+//			Timeout:        30,
 //		})
 //
 //		if err != nil {
@@ -51,14 +51,14 @@ package cbeverifier
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Common errors that may be returned by the library
@@ -71,31 +71,137 @@ var (
 	ErrPDFReadError         = errors.New("could not read PDF content")
 	ErrReceiptParseError    = errors.New("failed to parse receipt")
 	ErrVerificationFailed   = errors.New("transaction verification failed")
+	ErrUpstreamUnavailable  = errors.New("CBE receipt service unavailable")
+	ErrInvalidReference     = errors.New("invalid full reference")
+	ErrReceiptAlreadyUsed   = errors.New("receipt has already been used")
+	ErrParseTimeout         = errors.New("receipt parsing timed out")
+	ErrDialTimeout          = errors.New("connecting to CBE timed out")
+	ErrResponseTimeout      = errors.New("CBE did not start responding in time")
+	ErrReceiptTooLarge      = errors.New("receipt PDF exceeds maximum size")
+	ErrSMSParseError        = errors.New("failed to parse CBE SMS")
+	ErrLowConfidence        = errors.New("receipt parsed with low confidence")
+	ErrPDFEncrypted         = errors.New("receipt PDF is encrypted")
+	ErrPDFUnsigned          = errors.New("receipt PDF is not signed")
+	ErrSignatureInvalid     = errors.New("receipt PDF signature is invalid")
+	ErrMalformedPDF         = errors.New("malformed receipt PDF")
+	ErrUnknownProvider      = errors.New("unknown receipt provider")
+	ErrAmbiguousReference   = errors.New("reference matches several receipt providers")
+	ErrFetchNotSupported    = errors.New("provider cannot fetch receipts; verify a supplied receipt")
+	ErrWebhookSignature     = errors.New("invalid webhook signature")
 )
 
 // Transaction represents a CBE transaction to be verified
 type Transaction struct {
 	// ID is the transaction reference number (e.g., "xxxxxxxx")
-	ID string `json:"id"`
+	ID string `json:"id" form:"id"`
 	// Suffix is the transaction suffix (e.g., "xxxxxxxx")
-	Suffix string `json:"suffix"`
-	// Amount is the transaction amount in ETB
-	Amount float64 `json:"amount"`
+	Suffix string `json:"suffix" form:"suffix"`
+	// Amount is the transaction amount, in ETB unless Currency says otherwise
+	Amount float64 `json:"amount" form:"amount"`
+	// Currency is the expected ISO 4217 currency code of the receipt (default: "ETB")
+	Currency string `json:"currency,omitempty" form:"currency"`
+	// FullReference is the ID with the suffix already appended, or the receipt URL
+	// from the CBE SMS. When set, it takes precedence over ID and Suffix.
+	FullReference string `json:"full_reference,omitempty" form:"full_reference"`
+	// ReceiverName is the expected receiver name. When set, it is compared with the
+	// receipt after normalizing case, spacing, honorifics and abbreviations.
+	ReceiverName string `json:"receiver_name,omitempty" form:"receiver_name"`
+	// PayerName is the expected payer name, compared the same way as ReceiverName
+	PayerName string `json:"payer_name,omitempty" form:"payer_name"`
+	// Provider is the registered name of the bank or payment service that issued
+	// the receipt. When empty, it is detected from the reference (see
+	// DetectProviders), defaulting to ProviderCBE; references of several
+	// providers fail with ErrAmbiguousReference. See Register.
+	Provider string `json:"provider,omitempty" form:"provider"`
 }
 
 // Options configures the verification process
 type Options struct {
 	// IncludeDetails returns the full transaction details from the official receipt
 	IncludeDetails bool `json:"include_details"`
-	// Timeout specifies the HTTP request timeout in seconds (default: 120)
+	// Timeout is the total time budget for fetching a receipt, in seconds
+	// (default: 30)
 	Timeout int `json:"timeout"`
+	// FetchTimeout is the total time budget for fetching a receipt with a finer
+	// precision than Timeout, which it overrides when set
+	FetchTimeout time.Duration `json:"fetch_timeout,omitempty"`
+	// DialTimeout bounds obtaining a connection to CBE, including DNS and TLS (default: 10s)
+	DialTimeout time.Duration `json:"dial_timeout,omitempty"`
+	// ResponseHeaderTimeout bounds the wait for CBE to start responding after the
+	// request is sent (default: 20s)
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+	// ParseTimeout bounds parsing the receipt PDF (default: 10s)
+	ParseTimeout time.Duration `json:"parse_timeout,omitempty"`
+	// MaxPDFBytes is the largest receipt PDF that will be downloaded or parsed
+	// (default: 5 MiB)
+	MaxPDFBytes int64 `json:"max_pdf_bytes,omitempty"`
+	// ExpectedReceiverSuffix is the merchant's own account suffix. When set, verification
+	// fails if none of the receiver accounts on the receipt matches it.
+	ExpectedReceiverSuffix string `json:"expected_receiver_suffix,omitempty"`
+	// AmountTolerance is the absolute difference in ETB allowed between the provided
+	// and official amounts
+	AmountTolerance float64 `json:"amount_tolerance,omitempty"`
+	// AmountTolerancePercent is the allowed difference as a percentage of the provided
+	// amount. When both tolerances are set, the larger one applies.
+	AmountTolerancePercent float64 `json:"amount_tolerance_percent,omitempty"`
+	// AmountAtLeast accepts any official amount greater than or equal to the provided
+	// amount (minus tolerance), for tips and over-payments
+	AmountAtLeast bool `json:"amount_at_least,omitempty"`
+	// MinConfidence routes verifications to manual review when a required field was
+	// parsed with a lower confidence (0 to 1). Such results are not valid and have
+	// NeedsReview set. Zero disables the check.
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	// ExpectedReasonContains lists values, such as an order number, that must all
+	// appear in the receipt's payment reason. Case, spacing and punctuation are
+	// ignored. They are checked together with Policy.ReasonKeywords.
+	ExpectedReasonContains []string `json:"expected_reason_contains,omitempty"`
+	// CompareTotalDebited compares the provided amount against the total debited
+	// from the payer (transfer plus fees) instead of the transferred amount
+	CompareTotalDebited bool `json:"compare_total_debited,omitempty"`
+	// TamperCheck inspects the receipt PDF for signs of editing (see DetectTampering)
+	// and reports them in VerificationResult.TamperIndicators. Policy.Tamper
+	// decides whether they fail verification.
+	TamperCheck bool `json:"tamper_check,omitempty"`
+	// MaxAge fails verification if the receipt's payment date is older than this
+	MaxAge time.Duration `json:"max_age,omitempty"`
+	// MinDate fails verification if the receipt was paid before this time
+	MinDate time.Time `json:"min_date,omitzero"`
+	// MaxDate fails verification if the receipt was paid after this time
+	MaxDate time.Time `json:"max_date,omitzero"`
+	// Policy decides which checks must pass and which are only reported as
+	// warnings (default: StrictPolicy)
+	Policy *Policy `json:"policy,omitempty"`
+	// PDFPassword decrypts password-protected receipts, which CBE commonly encrypts
+	// with the customer's phone number or account digits
+	PDFPassword string `json:"-"`
+	// MerchantID scopes replay detection, so each merchant can use a receipt once
+	MerchantID string `json:"merchant_id,omitempty"`
+
+	// OnRequest is called with every outgoing request to CBE before it is sent,
+	// e.g. to attach correlation IDs
+	OnRequest func(req *http.Request) `json:"-"`
+	// OnResponse is called when a request to CBE completes, with the response
+	// (nil on network errors), the error and the request latency
+	OnResponse func(resp *http.Response, err error, elapsed time.Duration) `json:"-"`
+	// OnResult is called with the final result of every Verify and VerifyPDF call
+	OnResult func(transaction Transaction, result *VerificationResult) `json:"-"`
+
+	// WebhookURL, if set, is sent a signed JSON WebhookPayload with the result of
+	// every Verify and VerifyPDF call, in the background (see FlushWebhooks)
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// WebhookSecret is the key of the HMAC-SHA256 signature of webhook payloads
+	WebhookSecret string `json:"-"`
 }
 
 // DefaultOptions returns the default verification options
 func DefaultOptions() Options {
 	return Options{
-		IncludeDetails: false,
-		Timeout:        120,
+		IncludeDetails:        false,
+		Timeout:               int(defaultTimeout / time.Second),
+		DialTimeout:           defaultDialTimeout,
+		ResponseHeaderTimeout: defaultResponseHeaderTimeout,
+		ParseTimeout:          defaultParseTimeout,
+		MaxPDFBytes:           defaultMaxPDFBytes,
 	}
 }
 
@@ -109,14 +215,64 @@ type TransactionDetails struct {
 	Receiver string `json:"receiver"`
 	// ReceiverAccount is the account number of the receiver
 	ReceiverAccount string `json:"receiver_account"`
-	// Amount is the transaction amount in ETB
+	// ReceiverBank is the receiver's bank on interbank transfers (e.g., "Awash
+	// Bank"), whose receipts may not print the receiver's account; it is empty for
+	// transfers within the issuing bank
+	ReceiverBank string `json:"receiver_bank,omitempty"`
+	// PayerAccounts lists every account printed for the payer, starting with
+	// PayerAccount, for receipts of joint or linked accounts
+	PayerAccounts []string `json:"payer_accounts,omitempty"`
+	// ReceiverAccounts lists every account printed for the receiver, starting
+	// with ReceiverAccount
+	ReceiverAccounts []string `json:"receiver_accounts,omitempty"`
+	// Amount is the transaction amount in Currency
 	Amount float64 `json:"amount"`
-	// Date is the payment date as a string
-	Date string `json:"date"`
+	// Currency is the ISO 4217 currency code of the amount (e.g., "ETB", "USD")
+	Currency string `json:"currency"`
+	// ServiceCharge is the fee charged for the transfer
+	ServiceCharge float64 `json:"service_charge,omitempty"`
+	// VAT is the VAT charged on the service charge
+	VAT float64 `json:"vat,omitempty"`
+	// TotalDebited is the total amount debited from the payer, including fees
+	TotalDebited float64 `json:"total_debited,omitempty"`
+	// Date is the payment date in Africa/Addis_Ababa time, or zero if the printed
+	// date could not be parsed
+	Date time.Time `json:"date,omitzero"`
+	// DateRaw is the payment date exactly as printed on the receipt
+	DateRaw string `json:"date_raw"`
+	// DateEC is the payment date in the Ethiopian calendar, formatted as DD/MM/YYYY
+	DateEC string `json:"date_ec,omitempty"`
 	// TransactionID is the reference number from the receipt
 	TransactionID string `json:"transaction_id"`
 	// Reason is the payment reason/description
 	Reason string `json:"reason"`
+	// Branch is the branch printed on the receipt
+	Branch string `json:"branch,omitempty"`
+	// Channel is the payment channel, normalized to one of the Channel constants
+	// (e.g., ChannelMobile) when recognized
+	Channel string `json:"channel,omitempty"`
+	// TransactionType is the transaction type (e.g., "Account to Account Transfer")
+	TransactionType string `json:"transaction_type,omitempty"`
+	// FormatVersion is the receipt layout detected from its labels, one of the
+	// Format constants (e.g., FormatPortalV2) or a provider's own layout, or ""
+	// if it was not recognized
+	FormatVersion string `json:"format_version,omitempty"`
+	// QRPayload is the decoded content of the receipt's QR code, when a QR decoder is configured
+	QRPayload string `json:"qr_payload,omitempty"`
+	// SignatureValid reports whether the receipt PDF's digital signature verifies
+	// and covers the whole file, or is nil if the receipt is not signed
+	SignatureValid *bool `json:"signature_valid,omitempty"`
+	// Extra holds provider-specific fields printed on the receipt that have no
+	// field of their own (e.g., a Telebirr transaction status), keyed by name
+	Extra map[string]string `json:"extra,omitempty"`
+	// Confidence is the parser's confidence in each extracted field, from 0 to 1,
+	// keyed by JSON field name. It reflects how the field was located (table
+	// layout, text pattern or OCR) and whether its value is well-formed.
+	Confidence map[string]float64 `json:"confidence,omitempty"`
+	// Transfers lists every transfer on a bulk receipt (e.g., a salary batch),
+	// starting with the one described by the other fields. It is empty for
+	// receipts with a single transfer.
+	Transfers []TransactionDetails `json:"transfers,omitempty"`
 }
 
 // VerificationResult represents the result of a transaction verification
@@ -129,6 +285,38 @@ type VerificationResult struct {
 	Error string `json:"error,omitempty"`
 	// Mismatches contains specific field mismatches if verification failed
 	Mismatches map[string]interface{} `json:"mismatches,omitempty"`
+	// Warnings contains mismatches of advisory checks, which do not fail verification
+	Warnings map[string]interface{} `json:"warnings,omitempty"`
+	// NeedsReview is true when a required field was parsed with less than
+	// Options.MinConfidence, so the result should be checked manually
+	NeedsReview bool `json:"needs_review,omitempty"`
+	// LowConfidence lists the required fields below Options.MinConfidence
+	LowConfidence map[string]float64 `json:"low_confidence,omitempty"`
+	// TamperIndicators lists signs that the receipt PDF was edited, when
+	// Options.TamperCheck is set (e.g., TamperIncrementalUpdate)
+	TamperIndicators []string `json:"tamper_indicators,omitempty"`
+
+	// err is the error reported in Error
+	err error
+}
+
+// NewErrorResult returns the invalid result of a verification that failed
+// with err, for providers and other callers reporting errors as results
+func NewErrorResult(err error) *VerificationResult {
+	return &VerificationResult{IsValid: false, Error: err.Error(), err: err}
+}
+
+// Err returns the error reported in Error, to be matched with errors.Is and
+// errors.As, or nil if the result has no error. Results decoded from JSON
+// only have the message of their error.
+//
+// Example:
+//
+//	if errors.Is(result.Err(), cbeverifier.ErrNetworkError) {
+//		// retry later
+//	}
+func (r *VerificationResult) Err() error {
+	return r.err
 }
 
 // Verify fetches the official CBE receipt and verifies the provided transaction data
@@ -140,6 +328,10 @@ type VerificationResult struct {
 // 4. Compares the provided data with the official records
 // 5. Returns a verification result
 //
+// Transactions of other banks are verified by the provider named in
+// Transaction.Provider instead (see Register), or, when it is empty, by the
+// provider detected from the reference (see DetectProviders).
+//
 // Example:
 //
 //	result, err := cbeverifier.Verify(cbeverifier.Transaction{
@@ -148,41 +340,279 @@ type VerificationResult struct {
 //		Amount: xxxx.xx,
 //	}, cbeverifier.DefaultOptions())
 func Verify(transaction Transaction, opts Options) (*VerificationResult, error) {
+	return defaultVerifier.Verify(context.Background(), transaction, opts)
+}
+
+// Verify fetches the official CBE receipt and verifies the provided transaction data.
+// Requests are subject to the Verifier's rate limit and the context's deadline.
+func (v *Verifier) Verify(ctx context.Context, transaction Transaction, opts Options) (*VerificationResult, error) {
+	ctx, span := v.startSpan(ctx, spanVerify)
+	defer span.End()
+
+	start := time.Now()
+	result, receipt, err := v.verify(ctx, transaction, opts)
+	v.complete(ctx, span, start, transaction, result, receipt, opts)
+	return result, err
+}
+
+// verify implements Verify, also returning the receipt verified against
+func (v *Verifier) verify(ctx context.Context, transaction Transaction, opts Options) (*VerificationResult, []byte, error) {
+	// Receipts of other banks are handled by their registered provider
+	provider, err := v.provider(transaction)
+	if err != nil {
+		return NewErrorResult(err), nil, nil
+	}
+	if _, ok := provider.(cbeProvider); !ok {
+		result, receipt := v.verifyProvider(ctx, provider, transaction, opts.withDefaults())
+		return result, receipt, nil
+	}
+
+	// Split a full reference into ID and suffix
+	transaction, err = normalizeTransaction(transaction)
+	if err != nil {
+		return NewErrorResult(err), nil, nil
+	}
+
 	// Validate input
 	if err := validateTransaction(transaction); err != nil {
-		return &VerificationResult{
-			IsValid: false,
-			Error:   err.Error(),
-		}, nil
+		return NewErrorResult(err), nil, nil
 	}
 
-	// Set default timeout if not specified
-	if opts.Timeout <= 0 {
-		opts.Timeout = 120
-	}
+	// Set default timeouts if not specified
+	opts = opts.withDefaults()
 
 	// Fetch and parse the official receipt
-	details, err := fetchAndParseReceipt(transaction.ID, transaction.Suffix, opts)
+	details, pdfBytes, err := v.fetchAndParseReceipt(ctx, transaction.ID, transaction.Suffix, opts)
 	if err != nil {
-		return &VerificationResult{
-			IsValid: false,
-			Error:   err.Error(),
-		}, nil
+		return NewErrorResult(err), nil, nil
+	}
+
+	return v.compare(ctx, transaction, details, v.inspectPDF(ctx, pdfBytes, opts), opts), pdfBytes, nil
+}
+
+// VerifyPDF verifies the provided transaction data against a locally supplied receipt PDF
+//
+// No request is made to CBE, which makes this suitable for customer-uploaded
+// receipts and for offline verification when the CBE portal is unreachable.
+// The transaction suffix is not required. Receipts of other providers (see
+// Transaction.Provider) are parsed by the provider's Parse method.
+//
+// Note that a locally supplied PDF is only as trustworthy as its source; prefer
+// Verify when the official receipt can be fetched.
+//
+// Example:
+//
+//	pdfBytes, err := os.ReadFile("receipt.pdf")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	result, err := cbeverifier.VerifyPDF(pdfBytes, cbeverifier.Transaction{
+//		ID:     "xxxxxxxxx",
+//		Amount: xxxx.xx,
+//	}, cbeverifier.DefaultOptions())
+func VerifyPDF(pdfBytes []byte, transaction Transaction, opts Options) (*VerificationResult, error) {
+	return defaultVerifier.VerifyPDF(context.Background(), pdfBytes, transaction, opts)
+}
+
+// VerifyPDF verifies the provided transaction data against a locally supplied receipt PDF
+func (v *Verifier) VerifyPDF(ctx context.Context, pdfBytes []byte, transaction Transaction, opts Options) (*VerificationResult, error) {
+	ctx, span := v.startSpan(ctx, spanVerify, attribute.Bool("cbe.local_pdf", true))
+	defer span.End()
+
+	start := time.Now()
+	result, err := v.verifyPDF(ctx, pdfBytes, transaction, opts)
+	v.complete(ctx, span, start, transaction, result, pdfBytes, opts)
+	return result, err
+}
+
+// verifyPDF implements VerifyPDF
+func (v *Verifier) verifyPDF(ctx context.Context, pdfBytes []byte, transaction Transaction, opts Options) (*VerificationResult, error) {
+	// Receipts of other banks are parsed by their registered provider
+	provider, err := v.provider(transaction)
+	if err != nil {
+		return NewErrorResult(err), nil
+	}
+	if _, ok := provider.(cbeProvider); !ok {
+		return v.compareProvider(ctx, provider, pdfBytes, transaction, opts.withDefaults()), nil
+	}
+
+	// Split a full reference into ID and suffix
+	transaction, err = normalizeTransaction(transaction)
+	if err != nil {
+		return NewErrorResult(err), nil
+	}
+
+	// Validate input; the suffix is only needed to fetch from CBE
+	if err := validatePDFTransaction(transaction); err != nil {
+		return NewErrorResult(err), nil
+	}
+
+	// Parse the supplied receipt
+	opts = opts.withDefaults()
+	if int64(len(pdfBytes)) > opts.MaxPDFBytes {
+		return NewErrorResult(ErrReceiptTooLarge), nil
+	}
+	details, err := v.parseReceipt(ctx, pdfBytes, opts)
+	if err != nil {
+		return NewErrorResult(err), nil
+	}
+
+	return v.compare(ctx, transaction, details, v.inspectPDF(ctx, pdfBytes, opts), opts), nil
+}
+
+// FetchReceipt fetches and parses the official CBE receipt without comparing it
+// against expected transaction data
+//
+// It returns the parsed details together with the raw PDF bytes, which is useful
+// for ingesting receipt data when the amount is not known up front.
+//
+// Example:
+//
+//	details, pdfBytes, err := cbeverifier.FetchReceipt(ctx, "xxxxxxxxx", "xxxxx", cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Printf("Amount: %.2f ETB\n", details.Amount)
+func FetchReceipt(ctx context.Context, reference, suffix string, opts Options) (*TransactionDetails, []byte, error) {
+	return defaultVerifier.FetchReceipt(ctx, reference, suffix, opts)
+}
+
+// FetchReceipt fetches and parses the official CBE receipt without comparing it
+func (v *Verifier) FetchReceipt(ctx context.Context, reference, suffix string, opts Options) (*TransactionDetails, []byte, error) {
+	if strings.TrimSpace(reference) == "" {
+		return nil, nil, ErrInvalidTransactionID
+	}
+	if strings.TrimSpace(suffix) == "" {
+		return nil, nil, ErrInvalidSuffix
+	}
+
+	// Set default timeouts if not specified
+	opts = opts.withDefaults()
+
+	return v.fetchAndParseReceipt(ctx, reference, suffix, opts)
+}
+
+// Lookup returns the details of a transaction's receipt without comparing them,
+// together with the receipt: receipt itself, parsed by the transaction's
+// provider, or, when receipt is nil, the official receipt fetched by reference.
+// Unlike FetchReceipt, it handles the receipts of every provider. The amount of
+// the transaction is ignored.
+func (v *Verifier) Lookup(ctx context.Context, transaction Transaction, receipt []byte, opts Options) (*TransactionDetails, []byte, error) {
+	provider, err := v.provider(transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = opts.withDefaults()
+
+	if receipt == nil {
+		// CBE receipts go through the cache
+		if _, ok := provider.(cbeProvider); ok {
+			transaction, err := normalizeTransaction(transaction)
+			if err != nil {
+				return nil, nil, err
+			}
+			return v.FetchReceipt(ctx, transaction.ID, transaction.Suffix, opts)
+		}
+		if receipt, err = provider.Fetch(ctx, transaction, opts); err != nil {
+			return nil, nil, err
+		}
 	}
 
+	details, err := provider.Parse(ctx, receipt, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, receipt, nil
+}
+
+// compare builds the verification result inside a cbe.compare span
+func (v *Verifier) compare(ctx context.Context, transaction Transaction, details *TransactionDetails, indicators []string, opts Options) *VerificationResult {
+	_, span := v.startSpan(ctx, spanCompare)
+	defer span.End()
+
+	result, transfer := buildResult(transaction, details, indicators, opts)
+	result = v.claimReceipt(ctx, result, transfer, opts)
+	span.SetAttributes(
+		attribute.Bool("cbe.valid", result.IsValid),
+		attribute.StringSlice("cbe.mismatch_fields", fieldNames(result.Mismatches)),
+	)
+	return result
+}
+
+// CompareDetails verifies the provided transaction against receipt details that
+// were obtained elsewhere, such as another payment provider's receipt, applying
+// the checks and policy in opts exactly as Verify does
+//
+// Example:
+//
+//	details, err := telebirr.Parse(page)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	result := cbeverifier.CompareDetails(transaction, details, cbeverifier.DefaultOptions())
+func CompareDetails(transaction Transaction, details *TransactionDetails, opts Options) *VerificationResult {
+	result, _ := buildResult(transaction, details, nil, opts)
+	return result
+}
+
+// buildResult compares the provided transaction with the official details and
+// tamper indicators, and builds the result. It also returns the details compared
+// against, which are those of the matching transfer on bulk receipts.
+func buildResult(transaction Transaction, details *TransactionDetails, indicators []string, opts Options) (*VerificationResult, *TransactionDetails) {
+	// Bulk receipts list several transfers; verify against the matching one
+	details = selectTransfer(transaction, details, opts)
+
 	// Compare provided data with official data
-	isValid, mismatches := compareTransaction(transaction, details)
+	isValid, mismatches, warnings := compareTransaction(transaction, details, opts)
+	if len(indicators) > 0 {
+		switch opts.policy().Tamper {
+		case Required:
+			isValid = false
+			mismatches["tamper"] = indicators
+		case Advisory:
+			warnings["tamper"] = indicators
+		}
+	}
+	if len(warnings) == 0 {
+		warnings = nil
+	}
+
+	// Route uncertain parses to manual review rather than trusting them
+	lowConfidence := lowConfidenceFields(transaction, details, opts)
 
 	if !isValid {
 		return &VerificationResult{
-			IsValid:    false,
-			Error:      "transaction verification failed",
-			Mismatches: mismatches,
-		}, nil
+			IsValid:          false,
+			Error:            "transaction verification failed",
+			Mismatches:       mismatches,
+			Warnings:         warnings,
+			NeedsReview:      lowConfidence != nil,
+			LowConfidence:    lowConfidence,
+			TamperIndicators: indicators,
+		}, details
+	}
+
+	if lowConfidence != nil {
+		result := &VerificationResult{
+			IsValid:          false,
+			Error:            ErrLowConfidence.Error(),
+			err:              ErrLowConfidence,
+			Warnings:         warnings,
+			NeedsReview:      true,
+			LowConfidence:    lowConfidence,
+			TamperIndicators: indicators,
+		}
+		if opts.IncludeDetails {
+			result.Details = details
+		}
+		return result, details
 	}
 
 	result := &VerificationResult{
-		IsValid: true,
+		IsValid:          true,
+		Warnings:         warnings,
+		TamperIndicators: indicators,
 	}
 
 	// Include details if requested
@@ -190,7 +620,7 @@ func Verify(transaction Transaction, opts Options) (*VerificationResult, error)
 		result.Details = details
 	}
 
-	return result, nil
+	return result, details
 }
 
 // validateTransaction validates the provided transaction data
@@ -207,23 +637,70 @@ func validateTransaction(t Transaction) error {
 	return nil
 }
 
-// fetchAndParseReceipt fetches the official CBE receipt and parses it
-func fetchAndParseReceipt(reference, suffix string, opts Options) (*TransactionDetails, error) {
+// validatePDFTransaction validates transaction data verified against a local PDF
+func validatePDFTransaction(t Transaction) error {
+	if strings.TrimSpace(t.ID) == "" {
+		return ErrInvalidTransactionID
+	}
+	if t.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// fetchAndParseReceipt returns the official receipt for reference+suffix, consulting the cache first
+func (v *Verifier) fetchAndParseReceipt(ctx context.Context, reference, suffix string, opts Options) (*TransactionDetails, []byte, error) {
 	fullID := reference + suffix
+
+	// Receipts never change once issued, so a cached copy is authoritative
+	if v.cache != nil {
+		if entry, ok := v.cache.Get(ctx, fullID); ok && entry != nil && entry.Details != nil {
+			v.logger.DebugContext(ctx, "CBE receipt cache hit", "reference", redact(fullID))
+			return entry.Details, entry.PDF, nil
+		}
+	}
+
+	pdfBytes, err := v.fetchReceiptPDF(ctx, fullID, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	details, err := v.parseReceipt(ctx, pdfBytes, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if v.cache != nil {
+		v.cache.Set(ctx, fullID, &CacheEntry{Details: details, PDF: pdfBytes})
+	}
+
+	return details, pdfBytes, nil
+}
+
+// fetchReceiptPDF downloads the official receipt PDF for the full reference from CBE
+func (v *Verifier) fetchReceiptPDF(ctx context.Context, fullID string, opts Options) (pdfBytes []byte, err error) {
+	ctx, span := v.startSpan(ctx, spanFetch, attribute.String("cbe.reference_hash", hashReference(fullID)))
+	defer func() {
+		span.SetAttributes(attribute.Int("cbe.response_size", len(pdfBytes)))
+		endSpan(span, err)
+	}()
+
 	url := fmt.Sprintf("https://apps.cbe.com.et:100/?id=%s", fullID)
 
-	// Create HTTP client with custom timeout and TLS config
-	client := &http.Client{
-		Timeout: time.Duration(opts.Timeout) * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // Note: This is required for CBE's server
-			},
-		},
+	// Apply the total and per-phase time budgets
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, opts.FetchTimeout)
+	defer cancel()
+	ctx, cancelPhases := withPhaseDeadlines(ctx, opts)
+	defer cancelPhases()
+
+	// Respect the shared rate limit
+	if err := v.wait(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
 	}
 
 	// Create request with proper headers
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
 	}
@@ -232,97 +709,137 @@ func fetchAndParseReceipt(reference, suffix string, opts Options) (*TransactionD
 	req.Header.Set("Accept", "application/pdf")
 	req.Header.Set("Accept-Encoding", "identity")
 
+	// Let integrators attach headers or log the outgoing fetch
+	if opts.OnRequest != nil {
+		opts.OnRequest(req)
+	}
+
+	// Fail fast while CBE is known to be down
+	if !v.allowUpstream() {
+		v.metrics.UpstreamError(UpstreamBreakerOpen)
+		return nil, ErrUpstreamUnavailable
+	}
+
 	// Execute request
-	resp, err := client.Do(req)
+	v.logger.DebugContext(ctx, "fetching CBE receipt", "reference", redact(fullID))
+	start := time.Now()
+	resp, err := v.client.Do(req)
+	if opts.OnResponse != nil {
+		opts.OnResponse(resp, err, time.Since(start))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrNetworkError, err)
+		// Name the phase that ran out of time, as the client only reports a cancellation
+		cause := context.Cause(ctx)
+		phase := errors.Is(cause, ErrDialTimeout) || errors.Is(cause, ErrResponseTimeout)
+		if phase {
+			err = cause
+		}
+		// Requests cancelled by the caller, such as a client disconnecting or a
+		// short deadline, do not trip the breaker while CBE is healthy
+		if phase || parent.Err() == nil {
+			v.recordUpstream(true)
+		} else {
+			v.abandonUpstream()
+		}
+		v.metrics.UpstreamError(UpstreamNetwork)
+		v.logger.WarnContext(ctx, "CBE receipt request failed", "reference", redact(fullID), "error", err)
+		return nil, fmt.Errorf("%w: %w", ErrNetworkError, err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	v.recordUpstream(resp.StatusCode >= 500)
+	if resp.StatusCode >= 500 {
+		v.metrics.UpstreamError(UpstreamServerError)
+		v.logger.WarnContext(ctx, "CBE receipt service error", "reference", redact(fullID), "status", resp.StatusCode)
+	}
 
 	// Validate response
 	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
 	if resp.StatusCode != 200 || !strings.Contains(contentType, "application/pdf") {
+		v.logger.DebugContext(ctx, "unexpected CBE receipt response",
+			"reference", redact(fullID), "status", resp.StatusCode, "content_type", contentType)
 		return nil, ErrInvalidPDFResponse
 	}
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Reject oversized responses before buffering them
+	if resp.ContentLength > opts.MaxPDFBytes {
+		return nil, ErrReceiptTooLarge
+	}
+
+	// Read response body, reading one byte past the limit to detect overflow
+	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, opts.MaxPDFBytes+1))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrPDFReadError, err)
 	}
-
-	// Parse the PDF
-	result := ParseCBEReceipt(bodyBytes)
-	if !result.Success {
-		return nil, fmt.Errorf("%w: %v", ErrReceiptParseError, result.Details["error"])
+	if int64(len(bodyBytes)) > opts.MaxPDFBytes {
+		return nil, ErrReceiptTooLarge
 	}
 
-	// Convert to TransactionDetails
-	details := &TransactionDetails{
-		Payer:           getString(result.Details, "payer"),
-		PayerAccount:    getString(result.Details, "payerAccount"),
-		Receiver:        getString(result.Details, "receiver"),
-		ReceiverAccount: getString(result.Details, "receiverAccount"),
-		Amount:          getFloat64(result.Details, "amount"),
-		Date:            getString(result.Details, "date"),
-		TransactionID:   getString(result.Details, "transaction_id"),
-		Reason:          getString(result.Details, "reason"),
+	v.metrics.FetchDuration(time.Since(start))
+	v.logger.DebugContext(ctx, "received CBE receipt",
+		"reference", redact(fullID), "bytes", len(bodyBytes), "elapsed", time.Since(start))
+
+	return bodyBytes, nil
+}
+
+// parseReceipt parses receipt PDF bytes and logs the parse duration
+func (v *Verifier) parseReceipt(ctx context.Context, pdfBytes []byte, opts Options) (*TransactionDetails, error) {
+	ctx, span := v.startSpan(ctx, spanParse, attribute.Int("cbe.pdf_size", len(pdfBytes)))
+
+	start := time.Now()
+	details, err := parseWithTimeout(opts.ParseTimeout, func() (*TransactionDetails, error) {
+		return v.parseReceiptDetails(ctx, pdfBytes, opts.PDFPassword)
+	})
+	v.metrics.ParseDuration(time.Since(start))
+	endSpan(span, err)
+	if err != nil {
+		v.logger.DebugContext(ctx, "failed to parse CBE receipt", "elapsed", time.Since(start), "error", err)
+		return nil, err
 	}
 
+	v.logger.DebugContext(ctx, "parsed CBE receipt", "elapsed", time.Since(start))
 	return details, nil
 }
 
-// compareTransaction compares provided transaction data with official details
-func compareTransaction(provided Transaction, official *TransactionDetails) (bool, map[string]interface{}) {
-	mismatches := make(map[string]interface{})
-
-	// Compare transaction ID
-	providedID := strings.TrimSpace(provided.ID)
-	officialID := strings.TrimSpace(official.TransactionID)
-	if providedID != officialID {
-		mismatches["transaction_id"] = map[string]interface{}{
-			"provided": providedID,
-			"official": officialID,
-		}
+// inspectPDF returns the tamper indicators of a receipt PDF when opts.TamperCheck is set
+func (v *Verifier) inspectPDF(ctx context.Context, pdfBytes []byte, opts Options) []string {
+	if !opts.TamperCheck {
+		return nil
 	}
 
-	// Compare amount (with rounding to handle floating point precision)
-	if round2(provided.Amount) != round2(official.Amount) {
-		mismatches["amount"] = map[string]interface{}{
-			"provided": provided.Amount,
-			"official": official.Amount,
-		}
+	indicators, err := detectTampering(pdfBytes, opts.PDFPassword)
+	if err != nil {
+		v.logger.DebugContext(ctx, "failed to inspect CBE receipt for tampering", "error", err)
+		return nil
 	}
-
-	return len(mismatches) == 0, mismatches
+	if len(indicators) > 0 {
+		v.logger.InfoContext(ctx, "CBE receipt shows signs of tampering", "indicators", indicators)
+	}
+	return indicators
 }
 
-// Helper functions
-func getString(m map[string]interface{}, key string) string {
-	if val, ok := m[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
-		}
+// parseReceiptDetails parses receipt PDF bytes, decrypted with password, into
+// TransactionDetails, using the OCR engine for PDFs without a text layer,
+// decoding the QR code when the Verifier has a QR decoder and validating the
+// receipt's digital signature
+func (v *Verifier) parseReceiptDetails(ctx context.Context, pdfBytes []byte, password string) (*TransactionDetails, error) {
+	receipt, err := parsePDF(ctx, pdfBytes, password, v.ocr, v.templates)
+	if err != nil {
+		return nil, err
 	}
-	return ""
-}
 
-func getFloat64(m map[string]interface{}, key string) float64 {
-	if val, ok := m[key]; ok {
-		if f, ok := val.(float64); ok {
-			return f
-		}
-		// Try to convert from string
-		if str, ok := val.(string); ok {
-			if f, err := strconv.ParseFloat(str, 64); err == nil {
-				return f
-			}
+	if v.qr != nil {
+		if payload, err := extractQRPayload(pdfBytes, password, v.qr); err == nil {
+			receipt.QRPayload = payload
 		}
 	}
-	return 0
+
+	receipt.SignatureValid = signatureValid(pdfBytes, v.signatureRoots)
+
+	return &receipt.TransactionDetails, nil
 }
 
+// round2 rounds to two decimal places
 func round2(val float64) float64 {
 	return float64(int(val*100+0.5)) / 100
 }
-
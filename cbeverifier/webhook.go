@@ -0,0 +1,198 @@
+package cbeverifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of webhook requests
+const (
+	// WebhookSignatureHeader holds "sha256=" and the hex HMAC-SHA256 of the
+	// timestamp, a dot and the body, keyed with Options.WebhookSecret
+	WebhookSignatureHeader = "X-CBE-Signature"
+	// WebhookTimestampHeader holds the Unix time at which the request was signed
+	WebhookTimestampHeader = "X-CBE-Timestamp"
+	// WebhookDeliveryHeader holds the delivery ID, which stays the same across
+	// retries so that receivers can ignore repeated deliveries
+	WebhookDeliveryHeader = "X-CBE-Delivery"
+)
+
+// Events of webhook payloads
+const (
+	// WebhookEventVerified is sent when a verification finishes
+	WebhookEventVerified = "verification.completed"
+	// WebhookEventRetried is sent when a verification that failed upstream is
+	// retried to a conclusive result (see the retry package)
+	WebhookEventRetried = "verification.retried"
+	// WebhookEventExpired is sent when a verification that failed upstream is
+	// given up, with the result of its last attempt
+	WebhookEventExpired = "verification.expired"
+)
+
+// webhookAttempts is how many times a webhook is sent before it is dropped
+const webhookAttempts = 5
+
+// webhookBackoff is the delay before the first retry of a webhook, doubled
+// before each following one
+const webhookBackoff = time.Second
+
+// webhookTimeout limits each webhook request
+const webhookTimeout = 10 * time.Second
+
+// WebhookPayload is the JSON body of webhook requests
+type WebhookPayload struct {
+	Event string `json:"event"`
+	// DeliveryID identifies the delivery, as in WebhookDeliveryHeader
+	DeliveryID  string              `json:"delivery_id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Transaction Transaction         `json:"transaction"`
+	Result      *VerificationResult `json:"result"`
+}
+
+// WithWebhookClient sends webhooks with client (default: an http.Client with
+// TLS verification, unlike the client used for CBE)
+func WithWebhookClient(client *http.Client) VerifierOption {
+	return func(v *Verifier) {
+		if client != nil {
+			v.webhookClient = client
+		}
+	}
+}
+
+// FlushWebhooks waits until the webhooks being sent have been delivered or
+// dropped, or until ctx is done. Programs that exit after verifying should call
+// it first.
+func (v *Verifier) FlushWebhooks(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		v.webhooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendWebhook posts the result of a verification to opts.WebhookURL as event in
+// the background, signed with opts.WebhookSecret, retrying network errors, 429
+// and 5xx responses with exponential backoff. Verify and VerifyPDF send
+// WebhookEventVerified themselves.
+func (v *Verifier) SendWebhook(ctx context.Context, event string, transaction Transaction, result *VerificationResult, opts Options) {
+	id := make([]byte, 16)
+	rand.Read(id)
+	payload := WebhookPayload{
+		Event:       event,
+		DeliveryID:  hex.EncodeToString(id),
+		CreatedAt:   time.Now().UTC(),
+		Transaction: transaction,
+		Result:      result,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		v.logger.WarnContext(ctx, "failed to encode webhook", "error", err)
+		return
+	}
+
+	// The delivery outlives the verification's context
+	ctx = context.WithoutCancel(ctx)
+	v.webhooks.Add(1)
+	go func() {
+		defer v.webhooks.Done()
+
+		backoff := webhookBackoff
+		for attempt := 1; ; attempt++ {
+			retry, err := v.postWebhook(ctx, opts, payload.DeliveryID, body)
+			if err == nil {
+				v.logger.DebugContext(ctx, "delivered webhook", "delivery", payload.DeliveryID, "attempt", attempt)
+				return
+			}
+			if !retry || attempt == webhookAttempts {
+				v.logger.WarnContext(ctx, "failed to deliver webhook", "delivery", payload.DeliveryID, "attempts", attempt, "error", err)
+				return
+			}
+			v.logger.DebugContext(ctx, "retrying webhook", "delivery", payload.DeliveryID, "attempt", attempt, "error", err)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}()
+}
+
+// postWebhook sends one webhook request, reporting whether a failure is worth
+// retrying
+func (v *Verifier) postWebhook(ctx context.Context, opts Options, delivery string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookDeliveryHeader, delivery)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	if opts.WebhookSecret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(opts.WebhookSecret, timestamp, body))
+	}
+
+	resp, err := v.webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded %s", resp.Status)
+}
+
+// SignWebhook returns the signature of a webhook body sent at timestamp, as in
+// WebhookSignatureHeader
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature of a webhook request received by
+// a callback handler, failing with ErrWebhookSignature if it does not match
+// secret or, when tolerance is positive, if it was signed more than tolerance
+// ago, which rejects replayed requests.
+//
+// Example:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	if err := cbeverifier.VerifyWebhookSignature(secret, r.Header, body, 5*time.Minute); err != nil {
+//		http.Error(w, err.Error(), http.StatusUnauthorized)
+//		return
+//	}
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrWebhookSignature)
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: signed %s ago", ErrWebhookSignature, age.Round(time.Second))
+		}
+	}
+	expected := SignWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(WebhookSignatureHeader)), []byte(expected)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+module github.com/Zahir-Seid/cbe-verifier/cbeverifier/worker/amqpdriver
+
+go 1.24
+
+require (
+	github.com/Zahir-Seid/cbe-verifier v0.1.0
+	github.com/rabbitmq/amqp091-go v1.10.0
+)
+
+require (
+	github.com/dslipak/pdf v0.0.2 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.35.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+	go.opentelemetry.io/otel/trace v1.35.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ChannelDriver is a Driver queueing jobs in memory, for programs that submit
+// verifications to their own workers
+//
+// Example:
+//
+//	driver := worker.NewChannelDriver(100)
+//	go worker.New(driver, cbeverifier.New()).Run(ctx)
+//	driver.Submit(ctx, worker.Job{ID: "order-1042", Transaction: transaction})
+//	for result := range driver.Results() {
+//		...
+//	}
+type ChannelDriver struct {
+	jobs    chan []byte
+	results chan Result
+	done    chan struct{}
+}
+
+// NewChannelDriver creates a ChannelDriver holding up to size jobs and size
+// results
+func NewChannelDriver(size int) *ChannelDriver {
+	return &ChannelDriver{
+		jobs:    make(chan []byte, size),
+		results: make(chan Result, size),
+		done:    make(chan struct{}),
+	}
+}
+
+// Submit queues a job, blocking while the queue is full
+func (d *ChannelDriver) Submit(ctx context.Context, job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	select {
+	case d.jobs <- body:
+		return nil
+	case <-d.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Results returns the results of the jobs, which must be read for the
+// workers to go on
+func (d *ChannelDriver) Results() <-chan Result {
+	return d.results
+}
+
+// Close stops the driver. Receive and Submit fail with ErrClosed afterwards.
+func (d *ChannelDriver) Close() error {
+	close(d.done)
+	return nil
+}
+
+// Receive implements Driver
+func (d *ChannelDriver) Receive(ctx context.Context) (Message, error) {
+	select {
+	case body := <-d.jobs:
+		return channelMessage{d, body}, nil
+	case <-d.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Publish implements Driver
+func (d *ChannelDriver) Publish(ctx context.Context, body []byte) error {
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	select {
+	case d.results <- result:
+		return nil
+	case <-d.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// channelMessage is a job received from a ChannelDriver
+type channelMessage struct {
+	driver *ChannelDriver
+	body   []byte
+}
+
+// Body implements Message
+func (m channelMessage) Body() []byte {
+	return m.body
+}
+
+// Ack implements Message
+func (m channelMessage) Ack(context.Context) error {
+	return nil
+}
+
+// Nack implements Message, queueing the job again
+func (m channelMessage) Nack(ctx context.Context) error {
+	select {
+	case m.driver.jobs <- m.body:
+		return nil
+	case <-m.driver.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
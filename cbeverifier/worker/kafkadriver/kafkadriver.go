@@ -0,0 +1,128 @@
+// Package kafkadriver connects verification workers to Kafka: jobs are read
+// from a topic by a consumer group and results are written to another topic.
+//
+// It is a module of its own, so that the verifier does not depend on a Kafka
+// client.
+//
+// Example:
+//
+//	driver, err := kafkadriver.New(kafkadriver.Config{
+//		Brokers:      []string{"kafka:9092"},
+//		GroupID:      "cbe-verifier",
+//		JobsTopic:    "payments.verify",
+//		ResultsTopic: "payments.verified",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer driver.Close()
+//	worker.New(driver, cbeverifier.New()).Run(ctx)
+package kafkadriver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/worker"
+)
+
+// Config configures a Driver
+type Config struct {
+	// Brokers are the addresses of the Kafka brokers
+	Brokers []string
+	// GroupID is the consumer group shared by the workers
+	GroupID string
+	// JobsTopic is the topic of the jobs
+	JobsTopic string
+	// ResultsTopic is the topic the results are written to
+	ResultsTopic string
+}
+
+// Driver is a worker.Driver on Kafka.
+//
+// Kafka acknowledges jobs by committing their offset, so a job returned to the
+// queue is written to the jobs topic again. Jobs verified concurrently are
+// committed as they finish: if a worker crashes, jobs of a partition still
+// being verified when a later one was committed are not read again. Run
+// workers with worker.WithConcurrency(1) where every job must be verified.
+type Driver struct {
+	cfg    Config
+	reader *kafka.Reader
+	writer *kafka.Writer
+}
+
+// New creates a Driver reading jobs and writing results as configured
+func New(cfg Config) (*Driver, error) {
+	switch {
+	case len(cfg.Brokers) == 0:
+		return nil, errors.New("kafkadriver: no brokers")
+	case cfg.GroupID == "":
+		return nil, errors.New("kafkadriver: no consumer group")
+	case cfg.JobsTopic == "" || cfg.ResultsTopic == "":
+		return nil, errors.New("kafkadriver: both topics are required")
+	}
+	return &Driver{
+		cfg: cfg,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: cfg.Brokers,
+			GroupID: cfg.GroupID,
+			Topic:   cfg.JobsTopic,
+		}),
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}, nil
+}
+
+// Close leaves the consumer group and flushes the results being written
+func (d *Driver) Close() error {
+	return errors.Join(d.reader.Close(), d.writer.Close())
+}
+
+// Receive implements worker.Driver
+func (d *Driver) Receive(ctx context.Context) (worker.Message, error) {
+	msg, err := d.reader.FetchMessage(ctx)
+	if errors.Is(err, io.EOF) {
+		return nil, worker.ErrClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return message{d, msg}, nil
+}
+
+// Publish implements worker.Driver
+func (d *Driver) Publish(ctx context.Context, body []byte) error {
+	return d.writer.WriteMessages(ctx, kafka.Message{Topic: d.cfg.ResultsTopic, Value: body})
+}
+
+// message is a job read from Kafka
+type message struct {
+	driver *Driver
+	msg    kafka.Message
+}
+
+// Body implements worker.Message
+func (m message) Body() []byte {
+	return m.msg.Value
+}
+
+// Ack implements worker.Message, committing the job's offset
+func (m message) Ack(ctx context.Context) error {
+	return m.driver.reader.CommitMessages(ctx, m.msg)
+}
+
+// Nack implements worker.Message, writing the job to the jobs topic again
+// before committing it
+func (m message) Nack(ctx context.Context) error {
+	retry := kafka.Message{Topic: m.driver.cfg.JobsTopic, Key: m.msg.Key, Value: m.msg.Value, Headers: m.msg.Headers}
+	if err := m.driver.writer.WriteMessages(ctx, retry); err != nil {
+		return err
+	}
+	return m.Ack(ctx)
+}
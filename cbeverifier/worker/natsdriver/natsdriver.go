@@ -0,0 +1,108 @@
+// Package natsdriver connects verification workers to NATS JetStream: jobs
+// are pulled from a durable consumer of a subject and results are published
+// on another.
+//
+// It is a module of its own, so that the verifier does not depend on NATS.
+//
+// Example:
+//
+//	nc, err := nats.Connect(nats.DefaultURL)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	js, err := nc.JetStream()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	driver, err := natsdriver.New(js, "payments.verify", "cbe-verifier", "payments.verified")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer driver.Close()
+//	worker.New(driver, cbeverifier.New()).Run(ctx)
+package natsdriver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/worker"
+)
+
+// fetchWait is how long a fetch waits for a job before fetching again
+const fetchWait = 30 * time.Second
+
+// Driver is a worker.Driver on NATS JetStream
+type Driver struct {
+	js      nats.JetStreamContext
+	sub     *nats.Subscription
+	results string
+}
+
+// New pulls jobs published on the jobs subject with the durable consumer
+// named durable, shared by the workers, and publishes results on the results
+// subject. The stream of the jobs subject must exist.
+func New(js nats.JetStreamContext, jobs, durable, results string) (*Driver, error) {
+	sub, err := js.PullSubscribe(jobs, durable, nats.ManualAck())
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{js: js, sub: sub, results: results}, nil
+}
+
+// Close stops pulling jobs; the durable consumer keeps the jobs left
+func (d *Driver) Close() error {
+	return d.sub.Unsubscribe()
+}
+
+// Receive implements worker.Driver
+func (d *Driver) Receive(ctx context.Context) (worker.Message, error) {
+	for {
+		// Fetch needs a deadline, so wait for jobs a while at a time
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchWait)
+		msgs, err := d.sub.Fetch(1, nats.Context(fetchCtx))
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case errors.Is(err, nats.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+			continue
+		case errors.Is(err, nats.ErrBadSubscription), errors.Is(err, nats.ErrConnectionClosed):
+			return nil, worker.ErrClosed
+		case err != nil:
+			return nil, err
+		case len(msgs) == 0:
+			continue
+		}
+		return message{msgs[0]}, nil
+	}
+}
+
+// Publish implements worker.Driver
+func (d *Driver) Publish(ctx context.Context, body []byte) error {
+	_, err := d.js.Publish(d.results, body, nats.Context(ctx))
+	return err
+}
+
+// message is a job pulled from JetStream
+type message struct {
+	msg *nats.Msg
+}
+
+// Body implements worker.Message
+func (m message) Body() []byte {
+	return m.msg.Data
+}
+
+// Ack implements worker.Message, waiting for JetStream to confirm it
+func (m message) Ack(ctx context.Context) error {
+	return m.msg.AckSync(nats.Context(ctx))
+}
+
+// Nack implements worker.Message, for JetStream to redeliver the job
+func (m message) Nack(ctx context.Context) error {
+	return m.msg.Nak(nats.Context(ctx))
+}
@@ -0,0 +1,222 @@
+// Package worker verifies transactions consumed from a message queue and
+// publishes the results, so that checkout pipelines can submit verifications
+// without waiting on CBE and absorb spikes in the queue.
+//
+// Workers are independent of the queue: a Driver receives job messages and
+// publishes result messages. The natsdriver, kafkadriver and amqpdriver
+// modules implement it for NATS JetStream, Kafka and RabbitMQ, and
+// ChannelDriver for jobs submitted in the same process.
+//
+// Jobs and results are JSON:
+//
+//	{"id": "order-1042", "transaction": {"id": "FT24123ABCDE", "suffix": "12345678", "amount": 1500}}
+//	{"id": "order-1042", "transaction": {...}, "result": {"is_valid": true, ...}}
+//
+// Example:
+//
+//	driver, err := kafkadriver.New(kafkadriver.Config{
+//		Brokers:      []string{"kafka:9092"},
+//		GroupID:      "cbe-verifier",
+//		JobsTopic:    "payments.verify",
+//		ResultsTopic: "payments.verified",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	w := worker.New(driver, cbeverifier.New(), worker.WithConcurrency(8))
+//	if err := w.Run(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// defaultConcurrency is the number of jobs a Worker verifies at once
+const defaultConcurrency = 4
+
+// retryDelay is the delay before receiving again after the driver failed
+const retryDelay = time.Second
+
+// ErrClosed is returned by drivers once closed, which ends Worker.Run
+var ErrClosed = errors.New("worker: driver closed")
+
+// Driver connects a Worker to a queue. Its methods are called concurrently.
+type Driver interface {
+	// Receive returns the next job message, blocking until one arrives or ctx
+	// is done
+	Receive(ctx context.Context) (Message, error)
+	// Publish publishes a result message
+	Publish(ctx context.Context, body []byte) error
+}
+
+// Message is a job message received from a queue
+type Message interface {
+	// Body returns the content of the message
+	Body() []byte
+	// Ack removes the message from the queue once its result is published
+	Ack(ctx context.Context) error
+	// Nack returns the message to the queue, to be verified again
+	Nack(ctx context.Context) error
+}
+
+// Job is a verification requested on the queue
+type Job struct {
+	// ID identifies the job in its result, e.g. the order being paid
+	ID          string                  `json:"id,omitempty"`
+	Transaction cbeverifier.Transaction `json:"transaction"`
+}
+
+// Result is the outcome of a Job, as published on the queue
+type Result struct {
+	ID          string                          `json:"id,omitempty"`
+	Transaction cbeverifier.Transaction         `json:"transaction"`
+	Result      *cbeverifier.VerificationResult `json:"result,omitempty"`
+	// Error is set instead of Result for jobs that could not be read or
+	// verified
+	Error string `json:"error,omitempty"`
+}
+
+// Worker verifies the jobs received from a Driver
+type Worker struct {
+	driver      Driver
+	verifier    *cbeverifier.Verifier
+	opts        cbeverifier.Options
+	concurrency int
+	logger      *slog.Logger
+}
+
+// Option configures a Worker
+type Option func(*Worker)
+
+// WithOptions sets the options of every verification (default:
+// cbeverifier.DefaultOptions())
+func WithOptions(opts cbeverifier.Options) Option {
+	return func(w *Worker) {
+		w.opts = opts
+	}
+}
+
+// WithConcurrency sets how many jobs are verified at once (default: 4). No
+// more jobs are received than can be verified, so the others wait in the
+// queue.
+func WithConcurrency(n int) Option {
+	return func(w *Worker) {
+		if n > 0 {
+			w.concurrency = n
+		}
+	}
+}
+
+// WithLogger logs the jobs verified, and failures to handle them, to logger
+// (default: discarded)
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *Worker) {
+		if logger != nil {
+			w.logger = logger
+		}
+	}
+}
+
+// New creates a Worker verifying the jobs of driver with verifier
+func New(driver Driver, verifier *cbeverifier.Verifier, options ...Option) *Worker {
+	w := &Worker{
+		driver:      driver,
+		verifier:    verifier,
+		opts:        cbeverifier.DefaultOptions(),
+		concurrency: defaultConcurrency,
+		logger:      slog.New(slog.DiscardHandler),
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Run verifies jobs until ctx is done or the driver is closed, then finishes
+// the jobs being verified and returns nil. Other driver errors are logged and
+// receiving resumes after a delay.
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Jobs received are finished even when Run is stopping
+	jobCtx := context.WithoutCancel(ctx)
+	slots := make(chan struct{}, w.concurrency)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		message, err := w.driver.Receive(ctx)
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			<-slots
+			w.logger.WarnContext(ctx, "failed to receive job", "error", err)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			w.handle(jobCtx, message)
+		}()
+	}
+}
+
+// handle verifies the job of a message and publishes its result, returning
+// the message to the queue if the result cannot be published
+func (w *Worker) handle(ctx context.Context, message Message) {
+	result := w.process(ctx, message.Body())
+	body, err := json.Marshal(result)
+	if err == nil {
+		err = w.driver.Publish(ctx, body)
+	}
+	if err != nil {
+		w.logger.WarnContext(ctx, "failed to publish result", "job", result.ID, "error", err)
+		if err := message.Nack(ctx); err != nil {
+			w.logger.WarnContext(ctx, "failed to return job to the queue", "job", result.ID, "error", err)
+		}
+		return
+	}
+	if err := message.Ack(ctx); err != nil {
+		w.logger.WarnContext(ctx, "failed to acknowledge job", "job", result.ID, "error", err)
+	}
+}
+
+// process verifies the job in a message body. Malformed jobs get a result with
+// an error, so that they are not received again.
+func (w *Worker) process(ctx context.Context, body []byte) Result {
+	var job Job
+	if err := json.Unmarshal(body, &job); err != nil {
+		w.logger.WarnContext(ctx, "invalid job", "error", err)
+		return Result{Error: fmt.Sprintf("invalid job: %v", err)}
+	}
+
+	result, err := w.verifier.Verify(ctx, job.Transaction, w.opts)
+	if err != nil {
+		w.logger.WarnContext(ctx, "failed to verify job", "job", job.ID, "error", err)
+		return Result{ID: job.ID, Transaction: job.Transaction, Error: err.Error()}
+	}
+	w.logger.InfoContext(ctx, "verified job", "job", job.ID, "valid", result.IsValid)
+	return Result{ID: job.ID, Transaction: job.Transaction, Result: result}
+}
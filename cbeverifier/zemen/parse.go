@@ -0,0 +1,47 @@
+package zemen
+
+import (
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/internal/webreceipt"
+)
+
+// FormatPDF is the Zemen transfer advice PDF, reported in
+// TransactionDetails.FormatVersion
+const FormatPDF = "zemen-pdf"
+
+// adviceTemplate matches the text rows of transfer advices, in priority order
+var adviceTemplate = webreceipt.Labels{
+	{"payer_account", `ordering\s*account(?:\s*(?:number|no\.?))?|debit(?:ed)?\s*account(?:\s*(?:number|no\.?))?`},
+	{"receiver_account", `beneficiary\s*account(?:\s*(?:number|no\.?))?|credit(?:ed)?\s*account(?:\s*(?:number|no\.?))?`},
+	{"payer", `ordering\s*customer(?:\s*name)?|payer\s*name|sender\s*name`},
+	{"receiver_bank", `beneficiary\s*bank(?:\s*name)?`},
+	{"receiver", `beneficiary\s*customer(?:\s*name)?|beneficiary\s*name|receiver\s*name`},
+	{"service_charge", `charge\s*amount|(?:service\s*)?charges?|commission`},
+	{"vat", `vat(?:\s*\(15%\))?`},
+	{"total_debited", `total(?:\s*amount)?(?:\s*debited)?`},
+	{"amount", `(?:transfer(?:red)?\s*|transaction\s*)?amount`},
+	{"date", `(?:transaction|value)\s*date(?:\s*(?:&|and)\s*time)?|date`},
+	{"transaction_id", `transaction\s*ref(?:erence|\.)?(?:\s*no\.?)?|reference(?:\s*no\.?)?`},
+	{"reason", `remarks?|narrative|reason|purpose|payment\s*details`},
+	{"branch", `branch(?:\s*name)?`},
+}.Template(FormatPDF)
+
+// dateLayouts are the date formats seen on transfer advices
+var dateLayouts = []string{
+	"02/01/2006 15:04:05",
+	"02/01/2006 3:04:05 PM",
+	"02/01/2006",
+	"02-Jan-2006 15:04:05",
+	"02-Jan-2006",
+	"02 Jan 2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parse extracts transaction information from a Zemen transfer advice PDF
+//
+// When required fields are missing, the error is a *cbeverifier.MissingFieldsError
+// and the partial details are returned with it.
+func Parse(pdf []byte) (*cbeverifier.TransactionDetails, error) {
+	return webreceipt.ParsePDF(pdf, adviceTemplate, dateLayouts, reReference.MatchString)
+}
@@ -0,0 +1,42 @@
+package zemen
+
+import (
+	"context"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+func init() {
+	cbeverifier.Register(Name, provider{})
+}
+
+// provider implements cbeverifier.Provider for supplied transfer advices
+type provider struct{}
+
+// Fetch fails: Zemen publishes no receipt endpoint
+func (provider) Fetch(context.Context, cbeverifier.Transaction, cbeverifier.Options) ([]byte, error) {
+	return nil, cbeverifier.ErrFetchNotSupported
+}
+
+// Parse parses a transfer advice PDF
+func (provider) Parse(_ context.Context, receipt []byte, _ cbeverifier.Options) (*cbeverifier.TransactionDetails, error) {
+	return Parse(receipt)
+}
+
+// Capabilities reports uploads of advice PDFs; there is no online lookup
+func (provider) Capabilities() cbeverifier.Capabilities {
+	return cbeverifier.Capabilities{
+		Upload:      true,
+		UploadTypes: []string{"application/pdf"},
+		Currencies:  []string{"ETB"},
+	}
+}
+
+// Compare verifies the transaction against the advice details
+func (provider) Compare(transaction cbeverifier.Transaction, details *cbeverifier.TransactionDetails, opts cbeverifier.Options) *cbeverifier.VerificationResult {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err)
+	}
+	return cbeverifier.CompareDetails(transaction, details, opts)
+}
@@ -0,0 +1,97 @@
+// Package zemen verifies Zemen Bank transfers from the transfer advice PDF
+// downloaded from Zemen internet or mobile banking.
+//
+// There is no Zemen endpoint to fetch advices from; uploaded advices are parsed
+// into a cbeverifier.TransactionDetails and policy-checked with
+// cbeverifier.CompareDetails like any other receipt. As with every supplied
+// document, a forged advice cannot be told from a real one by parsing alone.
+//
+// Example:
+//
+//	result, err := zemen.VerifyPDF(pdfBytes, cbeverifier.Transaction{
+//		ID:     "FT25123ABCDE",
+//		Amount: 1500,
+//	}, cbeverifier.DefaultOptions())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(result.IsValid)
+//
+// Importing the package also registers a provider named "zemen", so
+// cbeverifier.VerifyPDF handles transactions whose Provider is zemen.Name.
+// cbeverifier.Verify fails with cbeverifier.ErrFetchNotSupported.
+package zemen
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// Name is the name the package registers its cbeverifier.Provider under
+const Name = "zemen"
+
+// ErrInvalidReference is returned for malformed Zemen references
+var ErrInvalidReference = errors.New("invalid Zemen reference")
+
+// reReference matches a Zemen transfer reference (e.g., "FT25123ABCDE")
+var reReference = regexp.MustCompile(`^FT[A-Z0-9]{10}$`)
+
+// ValidateReference returns the normalized transfer reference
+//
+// Example:
+//
+//	ref, err := zemen.ValidateReference("ft25123 abcde")
+//	// ref = "FT25123ABCDE"
+func ValidateReference(reference string) (string, error) {
+	ref := strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+	if !reReference.MatchString(ref) {
+		return "", ErrInvalidReference
+	}
+	return ref, nil
+}
+
+// VerifyPDF verifies the provided transaction data against a Zemen transfer advice PDF
+//
+// This function:
+// 1. Validates the reference and amount
+// 2. Parses the advice into TransactionDetails
+// 3. Compares the provided data using the checks and policy in opts
+func VerifyPDF(pdf []byte, transaction cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	transaction, err := validateTransaction(transaction)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	details, err := Parse(pdf)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil
+	}
+
+	result := cbeverifier.CompareDetails(transaction, details, opts)
+	if opts.OnResult != nil {
+		opts.OnResult(transaction, result)
+	}
+	return result, nil
+}
+
+// validateTransaction checks the amount and replaces the transaction ID with the
+// normalized reference from ID or FullReference
+func validateTransaction(transaction cbeverifier.Transaction) (cbeverifier.Transaction, error) {
+	reference := transaction.ID
+	if strings.TrimSpace(transaction.FullReference) != "" {
+		reference = transaction.FullReference
+	}
+
+	ref, err := ValidateReference(reference)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Amount <= 0 {
+		return transaction, cbeverifier.ErrInvalidAmount
+	}
+	transaction.ID = ref
+	return transaction, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/audit"
+)
+
+// auditFlag registers the -audit-log flag on fs
+func auditFlag(fs *flag.FlagSet) {
+	fs.String("audit-log", "", "append every verification to the hash-chained audit log in `FILE`")
+}
+
+// openAudit opens the audit log at path, recording the user running the
+// command as the actor of verifications that name none
+func (c *cli) openAudit(path string) error {
+	var options []audit.Option
+	if u, err := user.Current(); err == nil {
+		options = append(options, audit.WithActor(u.Username))
+	}
+	l, err := audit.Open(path, options...)
+	if err != nil {
+		return err
+	}
+	c.auditLog = l
+	return nil
+}
+
+// audit checks the hash chain of audit logs written with -audit-log
+func (c *cli) audit(_ context.Context, args []string) int {
+	fs := c.flagSet("audit", "FILE...")
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return c.usageError(fs, "expected an audit log file")
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return c.fail("audit", err)
+		}
+		last, err := audit.Verify(f)
+		f.Close()
+
+		var lineErr *audit.LineError
+		switch {
+		case errors.As(err, &lineErr):
+			fmt.Fprintf(c.stdout, "%s: chain broken at %v\n", path, err)
+			if code == exitOK {
+				code = exitMismatch
+			}
+		case err != nil:
+			return c.fail("audit", fmt.Errorf("%s: %w", path, err))
+		case last.Seq == 0:
+			fmt.Fprintf(c.stdout, "%s: empty\n", path)
+		default:
+			fmt.Fprintf(c.stdout, "%s: %d entries, chain intact, last hash %s\n", path, last.Seq, last.Hash)
+		}
+	}
+	return code
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// batchColumns are the header names accepted in batch files for each
+// transaction field. Columns are matched case-insensitively; others are ignored.
+var batchColumns = map[string]string{
+	"reference":     "id",
+	"id":            "id",
+	"suffix":        "suffix",
+	"amount":        "amount",
+	"currency":      "currency",
+	"provider":      "provider",
+	"receiver_name": "receiver_name",
+	"payer_name":    "payer_name",
+}
+
+// batchRow is a transaction read from a batch file
+type batchRow struct {
+	// line is the line number of the row in the file
+	line        int
+	transaction cbeverifier.Transaction
+	// err is set when the row could not be read as a transaction
+	err error
+	// duplicate explains why the row reuses a reference already seen, if it does
+	duplicate string
+}
+
+// Statuses of batch rows
+const (
+	statusVerified  = "verified"
+	statusFailed    = "failed"
+	statusError     = "error"
+	statusInvalid   = "invalid"
+	statusDuplicate = "duplicate"
+)
+
+// batchResult is the outcome of verifying one batch row
+type batchResult struct {
+	Line      int     `json:"line"`
+	Reference string  `json:"reference"`
+	Suffix    string  `json:"suffix,omitempty"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency,omitempty"`
+	// Status is one of the status constants
+	Status string `json:"status"`
+	// Error describes why the row was not verified
+	Error  string                          `json:"error,omitempty"`
+	Result *cbeverifier.VerificationResult `json:"result,omitempty"`
+
+	// code is the exit code of the row
+	code int
+}
+
+// batch verifies every transaction listed in a CSV file
+func (c *cli) batch(ctx context.Context, args []string) int {
+	fs := c.flagSet("batch", "[flags]")
+	file := fs.String("file", "", "CSV file with a header row and reference, suffix and amount columns")
+	concurrency := fs.Int("concurrency", 1, "number of transactions verified at once")
+	resultsFile := fs.String("results", "", "write the results to this CSV file, with status and mismatch columns")
+	export := fs.String("export", "", "write the results to this XLSX workbook, formatted for accountants")
+	fs.StringVar(&c.saveDir, "save-dir", "", "store each official receipt and its result in this directory")
+	progressFD := fs.Int("progress-fd", 0, "write newline-delimited JSON progress events to this open descriptor (e.g., 3)")
+	ledgerFile := fs.String("ledger", "", "flag references recorded in this CSV file as duplicates, and record the verified ones in it")
+	options := c.optionFlags(fs)
+	output := outputFlag(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if *file == "" {
+		return c.usageError(fs, "-file is required")
+	}
+	if *concurrency < 1 {
+		return c.usageError(fs, "-concurrency must be at least 1")
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if *export != "" && !strings.EqualFold(filepath.Ext(*export), ".xlsx") {
+		return c.usageError(fs, "-export writes .xlsx workbooks")
+	}
+	if *progressFD != 0 {
+		var err error
+		if c.progress, err = c.openProgress(*progressFD); err != nil {
+			return c.usageError(fs, "-progress-fd: %v", err)
+		}
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return c.fail("batch", err)
+	}
+	defer f.Close()
+
+	rows, err := readBatch(f)
+	if err != nil {
+		return c.fail("batch", fmt.Errorf("%s: %w", *file, err))
+	}
+	var verified *ledger
+	if *ledgerFile != "" {
+		if verified, err = openLedger(*ledgerFile); err != nil {
+			return c.fail("batch", err)
+		}
+	}
+	references := markDuplicates(rows, verified)
+	c.progress.started(len(rows))
+
+	results := make([]batchResult, 0, len(rows))
+	next := 0
+	c.verifyRows(ctx, func() (batchRow, bool) {
+		if next == len(rows) {
+			return batchRow{}, false
+		}
+		next++
+		return rows[next-1], true
+	}, options(), *concurrency, func(result batchResult) {
+		// Text results are printed as they complete
+		if *output == outputText {
+			printBatchResult(c.stdout, result)
+		}
+		results = append(results, result)
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+	if verified != nil {
+		// Rows verified before an interruption are recorded too
+		if err := verified.record(results, references, *file); err != nil {
+			return c.fail("batch", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return c.fail("batch", ctx.Err())
+	}
+
+	if *resultsFile != "" {
+		if err := writeResultsFile(*resultsFile, results); err != nil {
+			return c.fail("batch", err)
+		}
+	}
+	if *export != "" {
+		if err := exportResults(*export, results); err != nil {
+			return c.fail("batch", err)
+		}
+	}
+	table := func() { printBatchTable(c.stdout, results, useColor(c.stdout)) }
+	if err := write(c.stdout, *output, results, func() {}, table); err != nil {
+		return c.fail("batch", err)
+	}
+	// The summary follows text and tables; it goes to stderr rather than
+	// break JSON and YAML output
+	summary := summarize(results)
+	summaryOut := c.stdout
+	if *output == outputJSON || *output == outputYAML {
+		summaryOut = c.stderr
+	}
+	printSummary(summaryOut, summary)
+	c.progress.done(summary)
+
+	for _, result := range results {
+		if result.code != exitOK {
+			return result.code
+		}
+	}
+	return exitOK
+}
+
+// verifyRows verifies the rows returned by next until it returns false, with up
+// to concurrency verifications at once, calling emit with each result as it
+// completes. Calls to emit are serialized.
+func (c *cli) verifyRows(ctx context.Context, next func() (batchRow, bool), opts cbeverifier.Options, concurrency int, emit func(batchResult)) {
+	var (
+		rows = make(chan batchRow)
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rows {
+				result := c.verifyRow(withProgressLine(ctx, row.line), row, opts)
+				c.progress.compared(result)
+				mu.Lock()
+				emit(result)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for ctx.Err() == nil {
+		row, ok := next()
+		if !ok {
+			break
+		}
+		rows <- row
+	}
+	close(rows)
+	wg.Wait()
+}
+
+// verifyRow verifies the transaction of a batch row
+func (c *cli) verifyRow(ctx context.Context, row batchRow, opts cbeverifier.Options) batchResult {
+	result := batchResult{
+		Line:      row.line,
+		Reference: row.transaction.ID,
+		Suffix:    row.transaction.Suffix,
+		Amount:    row.transaction.Amount,
+		Currency:  row.transaction.Currency,
+	}
+	if result.Reference == "" {
+		result.Reference = row.transaction.FullReference
+	}
+	if row.err != nil {
+		result.Status, result.Error, result.code = statusInvalid, row.err.Error(), exitInput
+		return result
+	}
+	if row.duplicate != "" {
+		result.Status, result.Error, result.code = statusDuplicate, row.duplicate, exitMismatch
+		return result
+	}
+
+	verification, err := c.verifyTransaction(ctx, row.transaction, opts)
+	switch {
+	case err != nil:
+		result.Status, result.Error, result.code = statusError, err.Error(), errorCode(err)
+	case !verification.IsValid:
+		result.Status, result.Error, result.Result = statusFailed, verification.Error, verification
+		result.code = resultCode(verification)
+	default:
+		result.Status, result.Result = statusVerified, verification
+	}
+	return result
+}
+
+// printBatchResult prints the outcome of a batch row as a text line
+func printBatchResult(w io.Writer, result batchResult) {
+	status := result.Status
+	if result.Error != "" {
+		status += ": " + result.Error
+	}
+	fmt.Fprintf(w, "line %d\t%s\t%s\n", result.Line, result.Reference, status)
+}
+
+// readBatch reads the transactions of a batch file
+func readBatch(r io.Reader) ([]batchRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty file")
+		}
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		if field, ok := batchColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = i
+		}
+	}
+	for _, field := range []string{"id", "amount"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("missing %s column", field)
+		}
+	}
+
+	var rows []batchRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		value := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := batchRow{
+			line: line,
+			transaction: cbeverifier.Transaction{
+				ID:           value("id"),
+				Suffix:       value("suffix"),
+				Currency:     value("currency"),
+				Provider:     value("provider"),
+				ReceiverName: value("receiver_name"),
+				PayerName:    value("payer_name"),
+			},
+		}
+		amount := strings.ReplaceAll(value("amount"), ",", "")
+		if row.transaction.Amount, err = strconv.ParseFloat(amount, 64); err != nil {
+			row.err = fmt.Errorf("invalid amount %q", value("amount"))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// resultColumns are the header of results files
+var resultColumns = []string{"line", "reference", "suffix", "amount", "status", "official_amount", "mismatches", "error"}
+
+// writeResultsFile writes batch results to a CSV file
+func writeResultsFile(path string, results []batchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeResults(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeResults writes batch results as CSV, one row per transaction. Mismatches
+// are listed as "field (provided X, official Y)", separated by semicolons.
+func writeResults(w io.Writer, results []batchResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultColumns); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		var officialAmount, mismatches string
+		if result.Result != nil {
+			if details := result.Result.Details; details != nil {
+				officialAmount = strconv.FormatFloat(details.Amount, 'f', 2, 64)
+			}
+			mismatches = formatMismatches(result.Result.Mismatches)
+		}
+		if err := writer.Write([]string{
+			strconv.Itoa(result.Line),
+			result.Reference,
+			result.Suffix,
+			strconv.FormatFloat(result.Amount, 'f', 2, 64),
+			result.Status,
+			officialAmount,
+			mismatches,
+			result.Error,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatMismatches formats the mismatches of a result for a results file
+func formatMismatches(mismatches map[string]interface{}) string {
+	fields := make([]string, 0, len(mismatches))
+	for field := range mismatches {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for i, field := range fields {
+		if provided, official, ok := comparedValues(mismatches[field]); ok {
+			fields[i] = fmt.Sprintf("%s (provided %s, official %s)", field, provided, official)
+		}
+	}
+	return strings.Join(fields, "; ")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/telegram"
+)
+
+// bot runs the Telegram bot of the telegram package, answering the payments
+// customers send it until interrupted
+func (c *cli) bot(ctx context.Context, args []string) int {
+	fs := c.flagSet("bot", "[flags]")
+	token := fs.String("telegram-token", "", "token of the Telegram bot, as given by @BotFather")
+	apiURL := fs.String("telegram-api", "", "`URL` of a self-hosted Bot API server")
+	options := c.optionFlags(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if *token == "" {
+		return c.usageError(fs, "-telegram-token or %sTELEGRAM_TOKEN is required", envPrefix)
+	}
+
+	botOptions := []telegram.Option{
+		telegram.WithOptions(options()),
+		telegram.WithLogger(slog.New(slog.NewTextHandler(c.stderr, nil))),
+	}
+	if *apiURL != "" {
+		botOptions = append(botOptions, telegram.WithAPIURL(*apiURL))
+	}
+	fmt.Fprintln(c.stderr, "cbe-verify: bot running")
+	if err := telegram.New(*token, c.verifier, botOptions...).Run(ctx); err != nil {
+		return c.fail("bot", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// completionFlag is a flag offered by shell completion
+type completionFlag struct {
+	name  string
+	usage string
+	// takesValue is false for boolean flags
+	takesValue bool
+	// values are the accepted values, if they are a fixed set
+	values []string
+	// path is true for flags naming a file, directory or command
+	path bool
+}
+
+// completionCommand is a subcommand offered by shell completion
+type completionCommand struct {
+	name    string
+	summary string
+	flags   []completionFlag
+}
+
+// completion prints a completion script for bash, zsh or fish
+func (c *cli) completion(ctx context.Context, args []string) int {
+	fs := c.flagSet("completion", "bash|zsh|fish")
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "expected a shell: bash, zsh or fish")
+	}
+
+	var script func(io.Writer, []completionCommand)
+	switch fs.Arg(0) {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return c.usageError(fs, "unknown shell %q", fs.Arg(0))
+	}
+	script(c.stdout, completionCommands(ctx))
+	return exitOK
+}
+
+// completionCommands returns the subcommands with their flags. The flags are
+// collected by asking each subcommand for its help, so the scripts always match
+// the flags the subcommands register.
+func completionCommands(ctx context.Context) []completionCommand {
+	discover := &cli{stdin: strings.NewReader(""), stdout: io.Discard, stderr: io.Discard, config: &config{}, flagSets: make(map[string]*flag.FlagSet)}
+
+	var cmds []completionCommand
+	for _, cmd := range commands {
+		cmd.run(discover, ctx, []string{"-h"})
+		entry := completionCommand{name: cmd.name, summary: cmd.summary}
+		if fs := discover.flagSets[cmd.name]; fs != nil {
+			fs.VisitAll(func(f *flag.Flag) {
+				entry.flags = append(entry.flags, newCompletionFlag(f))
+			})
+		}
+		cmds = append(cmds, entry)
+	}
+	return cmds
+}
+
+// newCompletionFlag describes a flag for completion
+func newCompletionFlag(f *flag.Flag) completionFlag {
+	_, usage := flag.UnquoteUsage(f)
+	cf := completionFlag{name: f.Name, usage: usage, takesValue: true}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		cf.takesValue = false
+	}
+	cf.path = strings.Contains(usage, "file") || strings.Contains(usage, "directory") || strings.Contains(usage, "command")
+	switch f.Name {
+	case "output":
+		cf.values = []string{outputText, outputTable, outputJSON, outputYAML}
+	case "provider":
+		cf.values = cbeverifier.Providers()
+		sort.Strings(cf.values)
+	}
+	return cf
+}
+
+// bashCompletion writes a bash completion script
+func bashCompletion(w io.Writer, cmds []completionCommand) {
+	names := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		names[i] = cmd.name
+	}
+
+	fmt.Fprintf(w, `# bash completion for cbe-verify
+# Load with: source <(cbe-verify completion bash)
+_cbe_verify() {
+    local cur prev flags
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+        return
+    fi
+    case "$prev" in
+`, strings.Join(names, " "))
+	values := make(map[string]string)
+	for _, cmd := range cmds {
+		for _, f := range cmd.flags {
+			if len(f.values) > 0 {
+				values[f.name] = strings.Join(f.values, " ")
+			}
+		}
+	}
+	valueFlags := make([]string, 0, len(values))
+	for name := range values {
+		valueFlags = append(valueFlags, name)
+	}
+	sort.Strings(valueFlags)
+	for _, name := range valueFlags {
+		fmt.Fprintf(w, "    -%s|--%s)\n        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n        return ;;\n", name, name, values[name])
+	}
+	fmt.Fprint(w, `    esac
+    if [ "${COMP_WORDS[1]}" = completion ]; then
+        COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+        return
+    fi
+    case "${COMP_WORDS[1]}" in
+`)
+	for _, cmd := range cmds {
+		flags := make([]string, len(cmd.flags))
+		for i, f := range cmd.flags {
+			flags[i] = "-" + f.name
+		}
+		fmt.Fprintf(w, "    %s) flags=%q ;;\n", cmd.name, strings.Join(flags, " "))
+	}
+	fmt.Fprint(w, `    esac
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    fi
+}
+complete -o default -F _cbe_verify cbe-verify
+`)
+}
+
+// zshCompletion writes a zsh completion script
+func zshCompletion(w io.Writer, cmds []completionCommand) {
+	fmt.Fprint(w, `#compdef cbe-verify
+# zsh completion for cbe-verify
+# Load with: source <(cbe-verify completion zsh), or save as _cbe-verify in $fpath
+_cbe_verify() {
+    local -a commands
+    commands=(
+`)
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "        %s\n", zshQuote(cmd.name+":"+cmd.summary))
+	}
+	fmt.Fprint(w, `    )
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+    case $words[2] in
+`)
+	fmt.Fprint(w, "    completion)\n        _arguments '1:shell:(bash zsh fish)' ;;\n")
+	for _, cmd := range cmds {
+		if cmd.name == "completion" {
+			continue
+		}
+		fmt.Fprintf(w, "    %s)\n        _arguments", cmd.name)
+		for _, f := range cmd.flags {
+			spec := "-" + f.name + "[" + zshEscape(f.usage) + "]"
+			switch {
+			case len(f.values) > 0:
+				spec += ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+			case f.path:
+				spec += ":" + f.name + ":_files"
+			case f.takesValue:
+				spec += ":" + f.name + ": "
+			}
+			fmt.Fprintf(w, " \\\n            %s", zshQuote(spec))
+		}
+		fmt.Fprint(w, " \\\n            '*:file:_files' ;;\n")
+	}
+	fmt.Fprint(w, `    esac
+}
+if [ "$funcstack[1]" = "_cbe_verify" ]; then
+    _cbe_verify "$@"
+else
+    compdef _cbe_verify cbe-verify
+fi
+`)
+}
+
+// fishCompletion writes a fish completion script
+func fishCompletion(w io.Writer, cmds []completionCommand) {
+	fmt.Fprint(w, "# fish completion for cbe-verify\n# Load with: cbe-verify completion fish | source\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "complete -c cbe-verify -n __fish_use_subcommand -f -a %s -d %s\n", cmd.name, fishQuote(cmd.summary))
+	}
+	for _, cmd := range cmds {
+		for _, f := range cmd.flags {
+			fmt.Fprintf(w, "complete -c cbe-verify -n '__fish_seen_subcommand_from %s' -o %s -d %s", cmd.name, f.name, fishQuote(f.usage))
+			switch {
+			case len(f.values) > 0:
+				fmt.Fprintf(w, " -x -a %s", fishQuote(strings.Join(f.values, " ")))
+			case f.path:
+				fmt.Fprint(w, " -r -F")
+			case f.takesValue:
+				fmt.Fprint(w, " -x")
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w, "complete -c cbe-verify -n '__fish_seen_subcommand_from completion' -f -a 'bash zsh fish'")
+}
+
+// zshQuote quotes s in single quotes for zsh
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters with a meaning in _arguments descriptions
+func zshEscape(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// fishQuote quotes s in single quotes for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
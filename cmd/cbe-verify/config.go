@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configName is the name of the configuration file in the home directory
+const configName = ".cbe-verify.yaml"
+
+// envPrefix starts the names of the environment variables setting flag defaults
+const envPrefix = "CBE_VERIFY_"
+
+// config holds flag defaults read from the configuration file, keyed by flag
+// name. Commands holds the defaults of each subcommand, which take precedence.
+//
+// Example:
+//
+//	timeout: 20s
+//	receiver-suffix: "12345678"
+//	output: json
+//	proxy: http://proxy.internal:3128
+//	batch:
+//	  concurrency: 5
+type config struct {
+	Values   map[string]string
+	Commands map[string]map[string]string
+}
+
+// configPath returns the path of the configuration file: CBE_VERIFY_CONFIG, or
+// else ~/.cbe-verify.yaml
+func configPath() string {
+	if path := os.Getenv(envPrefix + "CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, configName)
+}
+
+// loadConfig reads the configuration file at path. A missing file is an empty
+// configuration, unless the path was set explicitly with CBE_VERIFY_CONFIG.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{Values: make(map[string]string), Commands: make(map[string]map[string]string)}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && os.Getenv(envPrefix+"CONFIG") == "" {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range raw {
+		if section, ok := value.(map[string]any); ok {
+			values := make(map[string]string, len(section))
+			for name, v := range section {
+				values[flagName(name)] = configValue(v)
+			}
+			cfg.Commands[key] = values
+			continue
+		}
+		cfg.Values[flagName(key)] = configValue(value)
+	}
+	return cfg, nil
+}
+
+// flagName returns the flag a configuration key sets, accepting underscores for
+// dashes (e.g., "receiver_suffix")
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "_", "-")
+}
+
+// configValue formats a configuration value as a flag value; lists are joined
+// with commas
+func configValue(value any) string {
+	if list, ok := value.([]any); ok {
+		values := make([]string, len(list))
+		for i, v := range list {
+			values[i] = fmt.Sprint(v)
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// lookup returns the default of a subcommand's flag: the CBE_VERIFY_ environment
+// variable named after the flag (e.g., CBE_VERIFY_RECEIVER_SUFFIX), or else the
+// subcommand's value in the configuration file, or else its top-level value
+func (cfg *config) lookup(command, name string) (string, bool) {
+	env := envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if value, ok := os.LookupEnv(env); ok {
+		return value, true
+	}
+	if value, ok := cfg.Commands[command][name]; ok {
+		return value, true
+	}
+	value, ok := cfg.Values[name]
+	return value, ok
+}
+
+// applyDefaults sets the flags of fs to their configured defaults, before the
+// command line is parsed so that it takes precedence
+func (cfg *config) applyDefaults(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := cfg.lookup(fs.Name(), f.Name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid default %q for -%s: %v", value, f.Name, setErr)
+		}
+	})
+	return err
+}
+
+// urlFlag is a flag holding an absolute URL
+type urlFlag struct {
+	url *url.URL
+}
+
+func (f *urlFlag) String() string {
+	if f.url == nil {
+		return ""
+	}
+	return f.url.String()
+}
+
+func (f *urlFlag) Set(value string) error {
+	if value == "" {
+		f.url = nil
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return errors.New("expected an absolute URL")
+	}
+	f.url = u
+	return nil
+}
+
+// proxyFlag registers the -proxy flag on fs, which parseFlags applies
+func proxyFlag(fs *flag.FlagSet) {
+	fs.Var(&urlFlag{}, "proxy", "proxy `URL` for receipt requests (default: from HTTPS_PROXY)")
+}
+
+// setDefaultProxy sends the requests of the other providers, whose default
+// clients use the default transport, through proxy
+func setDefaultProxy(proxy *url.URL) {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+}
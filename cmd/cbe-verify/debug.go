@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// debugFlag registers -v and its long form -debug on fs, which parseFlags applies
+func debugFlag(fs *flag.FlagSet) {
+	debug := fs.Bool("debug", false, "print the receipt requests, responses, parsed fields and timings to stderr")
+	fs.BoolVar(debug, "v", false, "shorthand for -debug")
+}
+
+// setVerifier creates the verifier used by the subcommands, sending its
+// requests through proxy (or the one set by HTTPS_PROXY when nil) and logging
+// its debug messages to stderr with -debug
+func (c *cli) setVerifier(proxy *url.URL) {
+	options := []cbeverifier.VerifierOption{cbeverifier.WithProxy(proxy)}
+	if c.debug {
+		logger := slog.New(slog.NewTextHandler(c.stderr, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			// Diagnostics are read as they are printed; timestamps are noise
+			ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+				if attr.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return attr
+			},
+		}))
+		options = append(options, cbeverifier.WithLogger(logger))
+	}
+	if c.auditLog != nil {
+		options = append(options, cbeverifier.WithRecorder(c.auditLog))
+	}
+	c.verifier = cbeverifier.New(options...)
+}
+
+// debugf prints a diagnostic to stderr with -debug
+func (c *cli) debugf(format string, args ...any) {
+	if c.debug {
+		fmt.Fprintf(c.stderr, "debug: "+format+"\n", args...)
+	}
+}
+
+// debugOptions adds hooks to opts printing each receipt request and response
+func (c *cli) debugOptions(opts cbeverifier.Options) cbeverifier.Options {
+	opts.OnRequest = func(req *http.Request) {
+		c.debugf("%s %s", req.Method, req.URL)
+	}
+	opts.OnResponse = func(resp *http.Response, err error, elapsed time.Duration) {
+		if err != nil {
+			c.debugf("request failed after %s: %v", elapsed.Round(time.Millisecond), err)
+			return
+		}
+		c.debugf("%s (%s, %d bytes) in %s", resp.Status, resp.Header.Get("Content-Type"), resp.ContentLength, elapsed.Round(time.Millisecond))
+	}
+	return opts
+}
+
+// debugReceipt prints which field was extracted from which line of a receipt
+// with -debug. When the receipt cannot be parsed, all of its text is printed,
+// to show what the parser saw.
+func (c *cli) debugReceipt(ctx context.Context, receipt []byte, provider string, opts cbeverifier.Options) {
+	if !c.debug {
+		return
+	}
+	c.debugf("receipt is %d bytes", len(receipt))
+
+	if provider != cbeverifier.ProviderCBE {
+		// Other providers do not report their matches; print what they parsed
+		_, p, err := lookupProvider(cbeverifier.Transaction{Provider: provider})
+		if err != nil {
+			return
+		}
+		details, err := p.Parse(ctx, receipt, opts)
+		if err != nil {
+			c.debugf("%s receipt could not be parsed: %v", provider, err)
+			return
+		}
+		for _, field := range detailFields(details) {
+			c.debugf("%-16s %q", field[0], field[1])
+		}
+		return
+	}
+
+	start := time.Now()
+	debug, err := cbeverifier.ParseCBEReceiptDebug(receipt)
+	if err != nil {
+		c.debugf("receipt could not be parsed: %v; extracted text:", err)
+		debug.Dump(c.stderr)
+		return
+	}
+	c.debugf("receipt format %q parsed in %s", debug.Format, time.Since(start).Round(time.Millisecond))
+	for _, template := range debug.Templates {
+		for _, m := range template.Matches {
+			c.debugf("%s: %-16s %q (page %d, line %d)", template.Name, m.Field, m.Value, m.Page, m.Line)
+		}
+		for _, field := range template.Missing {
+			c.debugf("%s: %-16s missing", template.Name, field)
+		}
+	}
+}
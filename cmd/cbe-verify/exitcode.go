@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/awash"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/boa"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/cbebirr"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/dashen"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/mpesa"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/telebirr"
+)
+
+// Exit codes, so that scripts can branch on the outcome
+const (
+	// exitOK means every transaction was verified
+	exitOK = 0
+	// exitMismatch means a receipt did not match its transaction
+	exitMismatch = 1
+	// exitNotFound means a receipt does not exist
+	exitNotFound = 2
+	// exitUpstream means the receipt service could not be reached or failed
+	exitUpstream = 3
+	// exitParse means a receipt could not be parsed
+	exitParse = 4
+	// exitInput means the command line or a transaction is invalid
+	exitInput = 5
+)
+
+// errorCodes map the errors of the library and the providers to exit codes
+var errorCodes = []struct {
+	err  error
+	code int
+}{
+	// CBE answers references it does not know with a page that is not a PDF
+	{cbeverifier.ErrInvalidPDFResponse, exitNotFound},
+	{awash.ErrReceiptNotFound, exitNotFound},
+	{boa.ErrReceiptNotFound, exitNotFound},
+	{cbebirr.ErrReceiptNotFound, exitNotFound},
+	{dashen.ErrReceiptNotFound, exitNotFound},
+	{mpesa.ErrConfirmationNotFound, exitNotFound},
+	{telebirr.ErrReceiptNotFound, exitNotFound},
+
+	{cbeverifier.ErrNetworkError, exitUpstream},
+	{cbeverifier.ErrUpstreamUnavailable, exitUpstream},
+	{awash.ErrNetworkError, exitUpstream},
+	{awash.ErrInvalidResponse, exitUpstream},
+	{boa.ErrNetworkError, exitUpstream},
+	{boa.ErrInvalidResponse, exitUpstream},
+	{cbebirr.ErrNetworkError, exitUpstream},
+	{cbebirr.ErrInvalidResponse, exitUpstream},
+	{dashen.ErrNetworkError, exitUpstream},
+	{dashen.ErrInvalidResponse, exitUpstream},
+	{telebirr.ErrNetworkError, exitUpstream},
+	{telebirr.ErrInvalidResponse, exitUpstream},
+	{context.DeadlineExceeded, exitUpstream},
+
+	{cbeverifier.ErrReceiptParseError, exitParse},
+	{cbeverifier.ErrPDFReadError, exitParse},
+	{cbeverifier.ErrParseTimeout, exitParse},
+	{cbeverifier.ErrMalformedPDF, exitParse},
+	{cbeverifier.ErrPDFEncrypted, exitParse},
+	{cbeverifier.ErrReceiptTooLarge, exitParse},
+	{cbeverifier.ErrSMSParseError, exitParse},
+	{cbeverifier.ErrQRNotFound, exitParse},
+	{awash.ErrReceiptTooLarge, exitParse},
+	{boa.ErrReceiptTooLarge, exitParse},
+	{cbebirr.ErrReceiptTooLarge, exitParse},
+	{dashen.ErrReceiptTooLarge, exitParse},
+	{telebirr.ErrReceiptTooLarge, exitParse},
+
+	{cbeverifier.ErrInvalidTransactionID, exitInput},
+	{cbeverifier.ErrInvalidSuffix, exitInput},
+	{cbeverifier.ErrInvalidAmount, exitInput},
+	{cbeverifier.ErrInvalidReference, exitInput},
+	{cbeverifier.ErrUnknownProvider, exitInput},
+	{cbeverifier.ErrAmbiguousReference, exitInput},
+	{cbeverifier.ErrFetchNotSupported, exitInput},
+	{awash.ErrInvalidReference, exitInput},
+	{boa.ErrInvalidReference, exitInput},
+	{boa.ErrInvalidSuffix, exitInput},
+	{cbebirr.ErrInvalidTransactionID, exitInput},
+	{dashen.ErrInvalidReference, exitInput},
+	{dashen.ErrAmoleNotFetchable, exitInput},
+	{mpesa.ErrInvalidTransactionCode, exitInput},
+	{telebirr.ErrInvalidTransactionNumber, exitInput},
+}
+
+// errorCode returns the exit code of a command that failed with err. Errors not
+// raised by a verification, such as unreadable files, are input errors.
+func errorCode(err error) int {
+	if code, ok := causeCode(err); ok {
+		return code
+	}
+	return exitInput
+}
+
+// resultCode returns the exit code of a verification result, from the error
+// it reports; failures without a known cause, such as a reused receipt, are
+// mismatches.
+func resultCode(result *cbeverifier.VerificationResult) int {
+	if result.IsValid {
+		return exitOK
+	}
+	if len(result.Mismatches) > 0 {
+		return exitMismatch
+	}
+	if code, ok := causeCode(result.Err()); ok {
+		return code
+	}
+	return exitMismatch
+}
+
+// causeCode returns the exit code of a known error of the library or the
+// providers
+func causeCode(err error) (int, bool) {
+	if err == nil {
+		return 0, false
+	}
+	var missing *cbeverifier.MissingFieldsError
+	if errors.As(err, &missing) {
+		return exitParse, true
+	}
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code, true
+		}
+	}
+	return 0, false
+}
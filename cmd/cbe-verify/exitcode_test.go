@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/telebirr"
+)
+
+func TestResultCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *cbeverifier.VerificationResult
+		want   int
+	}{
+		{"valid", &cbeverifier.VerificationResult{IsValid: true}, exitOK},
+		{"mismatch", &cbeverifier.VerificationResult{Mismatches: map[string]interface{}{"amount": 100.0}}, exitMismatch},
+		{"wrapped network error", cbeverifier.NewErrorResult(fmt.Errorf("fetch FT25123ABCDE: %w", cbeverifier.ErrNetworkError)), exitUpstream},
+		{"provider error", cbeverifier.NewErrorResult(telebirr.ErrReceiptNotFound), exitNotFound},
+		{"missing fields", cbeverifier.NewErrorResult(fmt.Errorf("parse: %w", &cbeverifier.MissingFieldsError{Fields: []string{"amount"}})), exitParse},
+		{"reused receipt", cbeverifier.NewErrorResult(cbeverifier.ErrReceiptAlreadyUsed), exitMismatch},
+		// Only the message is known, which is not matched
+		{"message only", &cbeverifier.VerificationResult{Error: cbeverifier.ErrNetworkError.Error()}, exitMismatch},
+		// An error whose message starts with a known one is not that error
+		{"similar message", cbeverifier.NewErrorResult(errors.New(cbeverifier.ErrInvalidAmount.Error() + ": elsewhere")), exitMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resultCode(tt.result); got != tt.want {
+				t.Errorf("resultCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	if got := errorCode(fmt.Errorf("line 3: %w", cbeverifier.ErrInvalidAmount)); got != exitInput {
+		t.Errorf("errorCode = %d, want %d", got, exitInput)
+	}
+	if got := errorCode(fmt.Errorf("verify: %w", cbeverifier.ErrUpstreamUnavailable)); got != exitUpstream {
+		t.Errorf("errorCode = %d, want %d", got, exitUpstream)
+	}
+	if got := errorCode(errors.New("open receipts.csv: no such file")); got != exitInput {
+		t.Errorf("errorCode = %d, want %d", got, exitInput)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// fetch fetches an official receipt and prints its details
+func (c *cli) fetch(ctx context.Context, args []string) int {
+	fs := c.flagSet("fetch", "[flags]")
+	reference := referenceFlags(fs)
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of the receipt request")
+	output := outputFlag(fs)
+	proxyFlag(fs)
+	debugFlag(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+
+	transaction := reference()
+	if err := checkReference(transaction); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+
+	opts := cbeverifier.DefaultOptions()
+	opts.FetchTimeout = *timeout
+	if c.debug {
+		opts = c.debugOptions(opts)
+	}
+	details, receipt, err := c.fetchReceipt(ctx, transaction, opts)
+	if err != nil {
+		return c.fail("fetch", err)
+	}
+	if name, _, err := lookupProvider(transaction); err == nil {
+		c.debugReceipt(ctx, receipt, name, opts)
+	}
+
+	text := func() { printDetails(c.stdout, details) }
+	table := func() { printDetailsTable(c.stdout, details, useColor(c.stdout)) }
+	if err := write(c.stdout, *output, details, text, table); err != nil {
+		return c.fail("fetch", err)
+	}
+	return exitOK
+}
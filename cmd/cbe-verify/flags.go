@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// optionFlags registers the verification option flags on fs and returns a
+// function building the Options once fs is parsed
+func (c *cli) optionFlags(fs *flag.FlagSet) func() cbeverifier.Options {
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout of the receipt request")
+	receiverSuffix := fs.String("receiver-suffix", "", "your own account suffix, which the receipt's receiver account must match")
+	tolerance := fs.Float64("tolerance", 0, "accepted difference between the provided and receipt amounts")
+	reason := fs.String("reason", "", "comma-separated tokens the payment reason must contain (e.g., an order ID)")
+	details := fs.Bool("details", true, "include the receipt details in the result")
+	tamper := fs.Bool("tamper-check", false, "fail receipts whose PDF shows signs of editing")
+	webhook := fs.String("webhook", "", "post each result, signed, to this `URL`")
+	webhookSecret := fs.String("webhook-secret", "", "key of the HMAC-SHA256 signature of webhook posts")
+	proxyFlag(fs)
+	debugFlag(fs)
+	auditFlag(fs)
+
+	return func() cbeverifier.Options {
+		opts := cbeverifier.DefaultOptions()
+		opts.FetchTimeout = *timeout
+		opts.ExpectedReceiverSuffix = *receiverSuffix
+		opts.AmountTolerance = *tolerance
+		opts.IncludeDetails = *details
+		opts.TamperCheck = *tamper
+		opts.WebhookURL = *webhook
+		opts.WebhookSecret = *webhookSecret
+		for _, token := range strings.Split(*reason, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				opts.ExpectedReasonContains = append(opts.ExpectedReasonContains, token)
+			}
+		}
+		if c.debug {
+			opts = c.debugOptions(opts)
+		}
+		return opts
+	}
+}
+
+// referenceFlags registers the flags identifying a receipt on fs and returns a
+// function building the Transaction once fs is parsed
+func referenceFlags(fs *flag.FlagSet) func() cbeverifier.Transaction {
+	ref := fs.String("ref", "", "full reference, as in the SMS: the reference with the suffix appended (e.g., FT24123ABCDE12345678) or the receipt link")
+	id := fs.String("id", "", "transaction reference (e.g., FT24123ABCDE)")
+	suffix := fs.String("suffix", "", "transaction suffix: the last 8 digits of the payer's CBE account")
+	provider := fs.String("provider", "", "bank or payment service that issued the receipt (default: detected from the reference)")
+
+	return func() cbeverifier.Transaction {
+		return cbeverifier.Transaction{
+			ID:            strings.TrimSpace(*id),
+			Suffix:        strings.TrimSpace(*suffix),
+			FullReference: strings.TrimSpace(*ref),
+			Provider:      strings.TrimSpace(*provider),
+		}
+	}
+}
+
+// checkReference returns an error if a transaction has neither a full reference
+// nor an ID, or has both
+func checkReference(t cbeverifier.Transaction) error {
+	switch {
+	case t.FullReference == "" && t.ID == "":
+		return errors.New("-ref or -id is required")
+	case t.FullReference != "" && (t.ID != "" || t.Suffix != ""):
+		return errors.New("-ref cannot be used with -id or -suffix")
+	}
+	return nil
+}
+
+// transactionFlags registers the flags of a transaction to verify on fs and
+// returns a function building it once fs is parsed
+func transactionFlags(fs *flag.FlagSet) func() cbeverifier.Transaction {
+	reference := referenceFlags(fs)
+	amount := fs.Float64("amount", 0, "expected amount (e.g., 1500.00)")
+	currency := fs.String("currency", "", "expected ISO 4217 currency of the amount (default: ETB)")
+	receiverName := fs.String("receiver-name", "", "expected receiver name")
+	payerName := fs.String("payer-name", "", "expected payer name")
+
+	return func() cbeverifier.Transaction {
+		transaction := reference()
+		transaction.Amount = *amount
+		transaction.Currency = strings.TrimSpace(*currency)
+		transaction.ReceiverName = strings.TrimSpace(*receiverName)
+		transaction.PayerName = strings.TrimSpace(*payerName)
+		return transaction
+	}
+}
+
+// lookupProvider returns the named provider, or the one detected from the
+// transaction's reference when name is empty, defaulting to CBE
+func lookupProvider(transaction cbeverifier.Transaction) (string, cbeverifier.Provider, error) {
+	name := transaction.Provider
+	if name == "" {
+		reference := transaction.FullReference
+		if reference == "" {
+			reference = transaction.ID + transaction.Suffix
+		}
+		switch names := cbeverifier.DetectProviders(reference); len(names) {
+		case 0:
+			name = cbeverifier.ProviderCBE
+		case 1:
+			name = names[0]
+		default:
+			return "", nil, fmt.Errorf("%w: %s; set -provider", cbeverifier.ErrAmbiguousReference, strings.Join(names, ", "))
+		}
+	}
+
+	provider, ok := cbeverifier.LookupProvider(name)
+	if !ok {
+		return "", nil, fmt.Errorf("%w %q (known providers: %s)", cbeverifier.ErrUnknownProvider, name, strings.Join(cbeverifier.Providers(), ", "))
+	}
+	return name, provider, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+var (
+	// reFTNumber matches a CBE transfer reference (e.g., "FT24123ABCDE")
+	reFTNumber = regexp.MustCompile(`^FT[A-Z0-9]{10}$`)
+	// reSuffix matches the 8-digit account suffix
+	reSuffix = regexp.MustCompile(`^\d{8}$`)
+)
+
+// errQuit is returned by a prompt when the user asks to quit or input ends
+var errQuit = errors.New("quit")
+
+// prompter reads the answers to prompts from the terminal
+type prompter struct {
+	w     io.Writer
+	lines <-chan string
+	ctx   context.Context
+}
+
+// newPrompter returns a prompter reading lines from r. Lines are read in the
+// background so that an interrupt ends a prompt waiting for input.
+func newPrompter(ctx context.Context, r io.Reader, w io.Writer) *prompter {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &prompter{w: w, lines: lines, ctx: ctx}
+}
+
+// ask prints a prompt until accept takes the answer, printing the hint it
+// returns for rejected answers. "q" and the end of input return errQuit.
+func (p *prompter) ask(prompt string, accept func(answer string) (hint string)) error {
+	for {
+		fmt.Fprintf(p.w, "%s: ", prompt)
+		var answer string
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				fmt.Fprintln(p.w)
+				return errQuit
+			}
+			answer = strings.TrimSpace(line)
+		case <-p.ctx.Done():
+			fmt.Fprintln(p.w)
+			return errQuit
+		}
+
+		if strings.EqualFold(answer, "q") {
+			return errQuit
+		}
+		hint := accept(answer)
+		if hint == "" {
+			return nil
+		}
+		fmt.Fprintf(p.w, "  %s\n", hint)
+	}
+}
+
+// interactive prompts for CBE transactions step by step and verifies each one,
+// for staff checking payments at a shop terminal
+func (c *cli) interactive(ctx context.Context, args []string) int {
+	fs := c.flagSet("interactive", "[flags]")
+	options := c.optionFlags(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 0 {
+		return c.usageError(fs, "unexpected arguments %q", fs.Args())
+	}
+	opts := options()
+
+	p := newPrompter(ctx, c.stdin, c.stdout)
+	fmt.Fprintln(c.stdout, "Verify a CBE transfer. Type q to quit.")
+	for {
+		fmt.Fprintln(c.stdout)
+		t, err := askTransaction(p)
+		if err != nil {
+			return exitOK
+		}
+
+		fmt.Fprintln(c.stdout, "Checking with CBE...")
+		result, err := c.verifyTransaction(ctx, t, opts)
+		if err != nil {
+			fmt.Fprintf(c.stdout, "Could not verify the transaction: %v\n", err)
+			continue
+		}
+		printResult(c.stdout, result)
+	}
+}
+
+// askTransaction prompts for the reference, suffix and amount of a transaction.
+// The suffix is not asked for when the reference is given in full, as printed
+// in the CBE SMS.
+func askTransaction(p *prompter) (cbeverifier.Transaction, error) {
+	var t cbeverifier.Transaction
+	err := p.ask("Reference (e.g., FT24123ABCDE)", func(answer string) string {
+		id, suffix, hint := parseReference(answer)
+		t.ID, t.Suffix = id, suffix
+		return hint
+	})
+	if err != nil {
+		return t, err
+	}
+	if t.Suffix == "" {
+		err = p.ask("Last 8 digits of the payer's account", func(answer string) string {
+			suffix := strings.Join(strings.Fields(answer), "")
+			if !reSuffix.MatchString(suffix) {
+				return "Enter exactly 8 digits, e.g., 12345678."
+			}
+			t.Suffix = suffix
+			return ""
+		})
+		if err != nil {
+			return t, err
+		}
+	}
+	err = p.ask("Amount in ETB", func(answer string) string {
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(answer, ",", ""), 64)
+		if err != nil || amount <= 0 {
+			return "Enter the amount as a number, e.g., 1500.00."
+		}
+		t.Amount = amount
+		return ""
+	})
+	return t, err
+}
+
+// parseReference validates a CBE reference, returning the suffix too if the
+// reference was given in full or as the receipt link, or else a hint on the
+// expected format
+func parseReference(answer string) (id, suffix, hint string) {
+	if id, suffix, err := cbeverifier.SplitReference(answer); err == nil && reFTNumber.MatchString(id) {
+		return id, suffix, ""
+	}
+
+	ref := strings.ToUpper(strings.Join(strings.Fields(answer), ""))
+	switch {
+	case ref == "":
+		return "", "", "Enter the reference printed on the receipt or SMS."
+	case !strings.HasPrefix(ref, "FT"):
+		return "", "", "A CBE reference starts with FT, e.g., FT24123ABCDE."
+	case !reFTNumber.MatchString(ref):
+		return "", "", fmt.Sprintf("A CBE reference is FT followed by 10 letters or digits; %s has %d.", ref, len(ref)-2)
+	}
+	return ref, "", ""
+}
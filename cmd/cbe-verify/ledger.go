@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// ledgerColumns are the columns of a ledger file
+var ledgerColumns = []string{"reference", "amount", "verified_at", "source"}
+
+// ledgerEntry is a reference recorded in a ledger file
+type ledgerEntry struct {
+	amount     float64
+	verifiedAt string
+	source     string
+}
+
+// ledger is a CSV file recording the references verified by earlier batch runs,
+// so that a receipt reused in a later file is caught
+type ledger struct {
+	path    string
+	entries map[string]ledgerEntry
+}
+
+// openLedger reads the ledger at path. A missing file is an empty ledger,
+// created when the first reference is recorded.
+func openLedger(path string) (*ledger, error) {
+	l := &ledger{path: path, entries: make(map[string]ledgerEntry)}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	if _, err := reader.Read(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return l, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(record) < len(ledgerColumns) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: expected %d columns", path, line, len(ledgerColumns))
+		}
+		amount, _ := strconv.ParseFloat(record[1], 64)
+		l.entries[record[0]] = ledgerEntry{amount: amount, verifiedAt: record[2], source: record[3]}
+	}
+}
+
+// lookup returns the entry recording reference, if any
+func (l *ledger) lookup(reference string) (ledgerEntry, bool) {
+	entry, ok := l.entries[reference]
+	return entry, ok
+}
+
+// record appends the verified results to the ledger file, noting file as
+// their source
+func (l *ledger) record(results []batchResult, rows map[int]string, file string) error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(ledgerColumns)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, result := range results {
+		reference := rows[result.Line]
+		if result.Status != statusVerified || reference == "" {
+			continue
+		}
+		if _, ok := l.entries[reference]; ok {
+			continue
+		}
+		entry := ledgerEntry{amount: result.Amount, verifiedAt: now, source: fmt.Sprintf("%s:%d", file, result.Line)}
+		l.entries[reference] = entry
+		w.Write([]string{reference, strconv.FormatFloat(entry.amount, 'f', 2, 64), entry.verifiedAt, entry.source})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// referenceKey returns the transaction reference identifying the receipt of t,
+// upper-cased so that the same reference typed differently is still caught. A
+// full reference is reduced to its transaction ID, as the ID alone names the
+// transfer.
+func referenceKey(t cbeverifier.Transaction) string {
+	if t.ID != "" {
+		return strings.ToUpper(strings.TrimSpace(t.ID))
+	}
+	if id, _, err := cbeverifier.SplitReference(t.FullReference); err == nil {
+		return strings.ToUpper(id)
+	}
+	return strings.ToUpper(strings.TrimSpace(t.FullReference))
+}
+
+// markDuplicates flags the rows whose reference appears on an earlier row or
+// is recorded in the ledger, if any, so that they are not verified again. It
+// returns the reference of each row by line.
+func markDuplicates(rows []batchRow, l *ledger) map[int]string {
+	references := make(map[int]string, len(rows))
+	first := make(map[string]int)
+	for i := range rows {
+		row := &rows[i]
+		if row.err != nil {
+			continue
+		}
+		reference := referenceKey(row.transaction)
+		if reference == "" {
+			continue
+		}
+		references[row.line] = reference
+
+		if line, ok := first[reference]; ok {
+			row.duplicate = fmt.Sprintf("reference %s already on line %d", reference, line)
+			continue
+		}
+		first[reference] = row.line
+		if l == nil {
+			continue
+		}
+		if entry, ok := l.lookup(reference); ok {
+			row.duplicate = fmt.Sprintf("reference %s already verified at %s (%s)", reference, entry.verifiedAt, entry.source)
+		}
+	}
+	return references
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/mailbox"
+)
+
+// mail runs the poller of the mailbox package, verifying the receipts emailed
+// to an IMAP mailbox until interrupted
+func (c *cli) mail(ctx context.Context, args []string) int {
+	fs := c.flagSet("mail", "[flags]")
+	server := fs.String("imap-server", "", "`host:port` of the IMAP server (e.g., imap.gmail.com:993)")
+	username := fs.String("imap-user", "", "user name of the mailbox")
+	password := fs.String("imap-password", "", "password of the mailbox")
+	name := fs.String("imap-mailbox", "INBOX", "mailbox to watch")
+	insecure := fs.Bool("imap-insecure", false, "connect without TLS, to a server on localhost")
+	interval := fs.Duration("interval", 0, "delay between polls (default 1m)")
+	verifiedFolder := fs.String("verified-folder", "", "folder to move verified emails to (default: leave them in the mailbox)")
+	failedFolder := fs.String("failed-folder", "", "folder to move failed emails to")
+	reviewFolder := fs.String("review-folder", "", "folder to move emails to check manually to")
+	options := c.optionFlags(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	switch {
+	case *server == "":
+		return c.usageError(fs, "-imap-server is required")
+	case *username == "" || *password == "":
+		return c.usageError(fs, "-imap-user and -imap-password or %sIMAP_PASSWORD are required", envPrefix)
+	}
+
+	poller := mailbox.New(c.verifier, mailbox.Account{
+		Server:   *server,
+		Username: *username,
+		Password: *password,
+		Mailbox:  *name,
+		Insecure: *insecure,
+	},
+		mailbox.WithOptions(options()),
+		mailbox.WithInterval(*interval),
+		mailbox.WithFolders(*verifiedFolder, *failedFolder, *reviewFolder),
+		mailbox.WithLogger(slog.New(slog.NewTextHandler(c.stderr, nil))),
+	)
+	fmt.Fprintln(c.stderr, "cbe-verify: watching", *name)
+	if err := poller.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return c.fail("mail", err)
+	}
+	return exitOK
+}
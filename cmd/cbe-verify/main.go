@@ -0,0 +1,251 @@
+// Command cbe-verify verifies Ethiopian bank transfers from the command line.
+// It checks transactions against their official receipts, parses and fetches
+// receipts, finds them from a photo of their QR code, verifies spreadsheets of
+// payments and the receipts dropped in a directory, and serves verifications
+// over HTTP.
+//
+// Usage:
+//
+//	cbe-verify <command> [flags] [arguments]
+//
+// Commands:
+//
+//	verify      verify a transaction against its official receipt
+//	parse       parse a receipt file and print its details
+//	fetch       fetch an official receipt and print its details
+//	qr          verify the receipt whose QR code is in a photo
+//	batch       verify every transaction listed in a CSV file
+//	watch       verify the receipts dropped in a directory
+//	interactive prompt for transactions and verify them one by one
+//	serve       serve verifications over HTTP
+//	bot         answer payments sent to a Telegram bot
+//	audit       check the hash chain of audit logs
+//	completion  print a shell completion script
+//	version     print the version
+//
+// Run "cbe-verify <command> -h" for the flags of a command.
+//
+// The exit code tells scripts the outcome:
+//
+//	0  verified
+//	1  the receipt does not match the transaction
+//	2  the receipt was not found
+//	3  the receipt service could not be reached or failed
+//	4  the receipt could not be parsed
+//	5  the command line or a transaction is invalid
+//
+// Commands verifying several transactions exit with the code of the first one
+// that was not verified.
+//
+// Flag defaults are read from ~/.cbe-verify.yaml (or the file named by
+// CBE_VERIFY_CONFIG), whose keys are flag names, optionally nested under a
+// command, and from CBE_VERIFY_ environment variables such as
+// CBE_VERIFY_RECEIVER_SUFFIX, which take precedence over the file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/audit"
+
+	// Register the providers of the other supported banks
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/awash"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/boa"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/cbebirr"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/dashen"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/hibret"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/mpesa"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/telebirr"
+	_ "github.com/Zahir-Seid/cbe-verifier/cbeverifier/zemen"
+)
+
+// version is the release version, set when building a release with
+// -ldflags "-X main.version=v1.2.0"
+var version string
+
+// command is a cbe-verify subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(c *cli, ctx context.Context, args []string) int
+}
+
+// commands are the subcommands, in the order they are listed in the usage. They
+// are set by init, since completion lists them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"verify", "verify a transaction against its official receipt", (*cli).verify},
+		{"parse", "parse a receipt file and print its details", (*cli).parse},
+		{"fetch", "fetch an official receipt and print its details", (*cli).fetch},
+		{"qr", "verify the receipt whose QR code is in a photo", (*cli).qr},
+		{"batch", "verify every transaction listed in a CSV file", (*cli).batch},
+		{"watch", "verify the receipts dropped in a directory", (*cli).watch},
+		{"interactive", "prompt for transactions and verify them one by one", (*cli).interactive},
+		{"serve", "serve verifications over HTTP", (*cli).serve},
+		{"bot", "answer payments sent to a Telegram bot", (*cli).bot},
+		{"mail", "verify the receipts emailed to an IMAP mailbox", (*cli).mail},
+		{"audit", "check the hash chain of audit logs", (*cli).audit},
+		{"completion", "print a shell completion script", (*cli).completion},
+		{"version", "print the version", (*cli).version},
+	}
+}
+
+// cli holds the state shared by the subcommands
+type cli struct {
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	verifier *cbeverifier.Verifier
+	config   *config
+	// saveDir is where verifications store the official receipts, if set
+	saveDir string
+	// debug prints diagnostics of each verification to stderr
+	debug bool
+	// progress receives the progress events of batch runs, if set
+	progress *progress
+	// flagSets records the flag set of each subcommand run, if not nil
+	flagSets map[string]*flag.FlagSet
+	// auditLog records every verification with -audit-log, if set
+	auditLog *audit.Log
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the subcommand named by args[0] and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr, verifier: cbeverifier.New(cbeverifier.WithProxy(nil))}
+	if len(args) == 0 {
+		c.usage(stderr)
+		return exitInput
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		c.usage(stdout)
+		return exitOK
+	case "-version", "--version":
+		return c.version(ctx, nil)
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			cfg, err := loadConfig(configPath())
+			if err != nil {
+				fmt.Fprintf(stderr, "cbe-verify: reading configuration: %v\n", err)
+				return exitInput
+			}
+			c.config = cfg
+			code := cmd.run(c, ctx, args[1:])
+			// Results posted to -webhook are sent in the background
+			if err := c.verifier.FlushWebhooks(ctx); err != nil {
+				fmt.Fprintf(stderr, "cbe-verify: webhooks not delivered: %v\n", err)
+			}
+			if c.auditLog != nil {
+				c.auditLog.Close()
+			}
+			return code
+		}
+	}
+
+	fmt.Fprintf(stderr, "cbe-verify: unknown command %q\n\n", args[0])
+	c.usage(stderr)
+	return exitInput
+}
+
+// usage prints the list of subcommands to w
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: cbe-verify <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun \"cbe-verify <command> -h\" for the flags of a command.\n")
+}
+
+// flagSet returns the flag set of a subcommand, whose usage line shows arguments
+func (c *cli) flagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: cbe-verify %s %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	if c.flagSets != nil {
+		c.flagSets[name] = fs
+	}
+	return fs
+}
+
+// parseFlags parses the arguments of a subcommand, over the defaults of the
+// configuration file and environment. When it returns false, the subcommand must
+// exit with code.
+func (c *cli) parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	if err := c.config.applyDefaults(fs); err != nil {
+		return c.usageError(fs, "%v", err), false
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitInput, false
+	}
+
+	var proxy *url.URL
+	if f := fs.Lookup("proxy"); f != nil {
+		if proxy = f.Value.(*urlFlag).url; proxy != nil {
+			setDefaultProxy(proxy)
+		}
+	}
+	if f := fs.Lookup("debug"); f != nil {
+		c.debug = f.Value.(flag.Getter).Get().(bool)
+	}
+	if f := fs.Lookup("audit-log"); f != nil && f.Value.String() != "" {
+		if err := c.openAudit(f.Value.String()); err != nil {
+			return c.fail(fs.Name(), err), false
+		}
+	}
+	c.setVerifier(proxy)
+	return exitOK, true
+}
+
+// usageError reports an invalid command line and returns exitInput
+func (c *cli) usageError(fs *flag.FlagSet, format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "cbe-verify %s: %s\n\n", fs.Name(), fmt.Sprintf(format, args...))
+	fs.Usage()
+	return exitInput
+}
+
+// fail reports an error of a subcommand and returns its exit code
+func (c *cli) fail(name string, err error) int {
+	fmt.Fprintf(c.stderr, "cbe-verify %s: %v\n", name, err)
+	return errorCode(err)
+}
+
+// version prints the version: the one set at build time, or else the module
+// version of builds installed with go install
+func (c *cli) version(_ context.Context, _ []string) int {
+	v := version
+	if v == "" {
+		v = "dev"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+	fmt.Fprintln(c.stdout, "cbe-verify", v)
+	return exitOK
+}
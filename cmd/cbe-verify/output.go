@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"gopkg.in/yaml.v3"
+)
+
+// printResult prints a verification result as text
+func printResult(w io.Writer, result *cbeverifier.VerificationResult) {
+	if result.IsValid {
+		fmt.Fprintln(w, "Transaction verified successfully.")
+	} else {
+		fmt.Fprintf(w, "Verification failed: %s\n", result.Error)
+	}
+	printFields(w, "Mismatches", result.Mismatches)
+	printFields(w, "Warnings", result.Warnings)
+	if result.NeedsReview {
+		fmt.Fprintln(w, "The receipt was parsed with low confidence and needs manual review.")
+	}
+	if len(result.TamperIndicators) > 0 {
+		fmt.Fprintf(w, "Tamper indicators: %s\n", strings.Join(result.TamperIndicators, ", "))
+	}
+	if result.Details != nil {
+		fmt.Fprintln(w)
+		printDetails(w, result.Details)
+	}
+}
+
+// printFields prints the mismatches or warnings of a result, sorted by field
+func printFields(w io.Writer, title string, fields map[string]interface{}) {
+	if len(fields) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, name := range names {
+		if provided, official, ok := comparedValues(fields[name]); ok {
+			fmt.Fprintf(w, "  - %s: provided %s, official %s\n", name, provided, official)
+			continue
+		}
+		fmt.Fprintf(w, "  - %s: %s\n", name, formatValue(fields[name]))
+	}
+}
+
+// comparedValues returns the provided and official values of a mismatch or
+// warning entry
+func comparedValues(entry interface{}) (provided, official string, ok bool) {
+	values, ok := entry.(map[string]interface{})
+	if !ok {
+		return "", "", false
+	}
+	return formatValue(values["provided"]), formatValue(values["official"]), true
+}
+
+// formatValue formats a compared value, with amounts to two decimals
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// printDetails prints the fields printed on a receipt, one per line
+func printDetails(w io.Writer, d *cbeverifier.TransactionDetails) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, field := range detailFields(d) {
+		fmt.Fprintf(tw, "%s:\t%s\n", field[0], field[1])
+	}
+	tw.Flush()
+}
+
+// detailFields returns the labels and values of the fields printed on a
+// receipt, skipping empty ones
+func detailFields(d *cbeverifier.TransactionDetails) [][2]string {
+	var fields [][2]string
+	line := func(label, value string) {
+		if value != "" {
+			fields = append(fields, [2]string{label, value})
+		}
+	}
+	money := func(value float64) string {
+		if value == 0 {
+			return ""
+		}
+		return strings.TrimSpace(fmt.Sprintf("%.2f %s", value, d.Currency))
+	}
+
+	line("Reference", d.TransactionID)
+	line("Payer", d.Payer)
+	line("Payer account", d.PayerAccount)
+	line("Receiver", d.Receiver)
+	line("Receiver account", d.ReceiverAccount)
+	line("Receiver bank", d.ReceiverBank)
+	line("Amount", money(d.Amount))
+	line("Service charge", money(d.ServiceCharge))
+	line("VAT", money(d.VAT))
+	line("Total debited", money(d.TotalDebited))
+	line("Date", d.DateRaw)
+	line("Date (EC)", d.DateEC)
+	line("Reason", d.Reason)
+	line("Branch", d.Branch)
+	line("Channel", d.Channel)
+	line("Type", d.TransactionType)
+	line("Format", d.FormatVersion)
+	if len(d.Transfers) > 1 {
+		line("Transfers", fmt.Sprint(len(d.Transfers)))
+	}
+	return fields
+}
+
+// Output formats selected with -output
+const (
+	outputText  = "text"
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFlag registers the -output flag on fs
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", outputText, "output format: text, table, json or yaml")
+}
+
+// checkOutput returns an error if format is not a known output format
+func checkOutput(format string) error {
+	switch format {
+	case outputText, outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// write writes v to w in format, calling text or table to print those formats
+func write(w io.Writer, format string, v any, text, table func()) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case outputYAML:
+		return writeYAML(w, v)
+	case outputTable:
+		table()
+		return nil
+	default:
+		text()
+		return nil
+	}
+}
+
+// writeYAML writes v to w as YAML. Values are converted through their JSON
+// encoding so that the keys are the JSON field names, in struct order.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON is valid YAML; parsing it into a node keeps the key order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow and quoting styles parsed from JSON, so the node is
+// written as block YAML with strings quoted only where needed
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// parse parses a receipt file, or the receipt on stdin, and prints its details
+func (c *cli) parse(ctx context.Context, args []string) int {
+	fs := c.flagSet("parse", "[flags] FILE|-")
+	provider := fs.String("provider", cbeverifier.ProviderCBE, "bank or payment service that issued the receipt")
+	output := outputFlag(fs)
+	debugFlag(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "expected one receipt file")
+	}
+
+	receipt, err := readInput(c.stdin, fs.Arg(0))
+	if err != nil {
+		return c.fail("parse", err)
+	}
+
+	name, p, err := lookupProvider(cbeverifier.Transaction{Provider: strings.TrimSpace(*provider)})
+	if err != nil {
+		return c.fail("parse", err)
+	}
+	c.debugReceipt(ctx, receipt, name, cbeverifier.DefaultOptions())
+	details, err := p.Parse(ctx, receipt, cbeverifier.DefaultOptions())
+	if err != nil {
+		return c.fail("parse", err)
+	}
+
+	text := func() { printDetails(c.stdout, details) }
+	table := func() { printDetailsTable(c.stdout, details, useColor(c.stdout)) }
+	if err := write(c.stdout, *output, details, text, table); err != nil {
+		return c.fail("parse", err)
+	}
+	return exitOK
+}
+
+// readInput reads the file at path, or r when path is "-"
+func readInput(r io.Reader, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(r)
+	}
+	return os.ReadFile(path)
+}
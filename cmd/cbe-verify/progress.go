@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Progress events. started and done bracket a run; in between, each row gets
+// fetched and parsed once its receipt is read, and compared once it has a status.
+const (
+	eventStarted  = "started"
+	eventFetched  = "fetched"
+	eventParsed   = "parsed"
+	eventCompared = "compared"
+	eventDone     = "done"
+)
+
+// progressEvent is a line of -progress-fd output. Fields that do not apply to
+// an event are omitted.
+type progressEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// Rows is the number of rows of the run, on started and done
+	Rows int `json:"rows,omitempty"`
+	// Line is the line of the row in the batch file
+	Line      int    `json:"line,omitempty"`
+	Reference string `json:"reference,omitempty"`
+	// Bytes is the size of the fetched receipt
+	Bytes int `json:"bytes,omitempty"`
+	// ElapsedMS is the time taken by the fetch, or by the whole run on done
+	ElapsedMS int64   `json:"elapsed_ms,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+	Status    string  `json:"status,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	// Counts of the run's outcomes, on done
+	Verified   *int `json:"verified,omitempty"`
+	Mismatched *int `json:"mismatched,omitempty"`
+	Failed     *int `json:"failed,omitempty"`
+	Duplicate  *int `json:"duplicate,omitempty"`
+}
+
+// progress writes newline-delimited JSON progress events, for programs
+// wrapping the CLI. A nil progress writes nothing.
+type progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+// openProgress returns a progress writing to the file descriptor fd. The
+// descriptors 1 and 2 are the CLI's stdout and stderr.
+func (c *cli) openProgress(fd int) (*progress, error) {
+	var w io.Writer
+	switch fd {
+	case 1:
+		w = c.stdout
+	case 2:
+		w = c.stderr
+	default:
+		if fd < 0 {
+			return nil, fmt.Errorf("invalid file descriptor %d", fd)
+		}
+		f := os.NewFile(uintptr(fd), "progress")
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("file descriptor %d is not open", fd)
+		}
+		w = f
+	}
+	return &progress{w: w, start: time.Now()}, nil
+}
+
+// emit writes an event, stamped with the current time. Write errors are
+// ignored, so that a reader going away does not stop the run.
+func (p *progress) emit(event progressEvent) {
+	if p == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.w.Write(append(data, '\n'))
+}
+
+// started reports the start of a run of rows
+func (p *progress) started(rows int) {
+	p.emit(progressEvent{Event: eventStarted, Rows: rows})
+}
+
+// compared reports the status of a row. Rows that were not fetched, such as
+// invalid rows and duplicates, get only this event.
+func (p *progress) compared(result batchResult) {
+	if p == nil {
+		return
+	}
+	p.emit(progressEvent{
+		Event:     eventCompared,
+		Line:      result.Line,
+		Reference: result.Reference + result.Suffix,
+		Amount:    result.Amount,
+		Status:    result.Status,
+		Error:     result.Error,
+	})
+}
+
+// done reports the outcomes of a run
+func (p *progress) done(summary batchSummary) {
+	if p == nil {
+		return
+	}
+	p.emit(progressEvent{
+		Event:      eventDone,
+		Rows:       summary.Rows,
+		ElapsedMS:  time.Since(p.start).Milliseconds(),
+		Verified:   &summary.Verified,
+		Mismatched: &summary.Mismatched,
+		Failed:     &summary.Failed,
+		Duplicate:  &summary.Duplicate,
+	})
+}
+
+// progressLineKey is the context key of the batch line whose transaction is
+// being verified
+type progressLineKey struct{}
+
+// withProgressLine returns ctx carrying the line of the row being verified, so
+// that the events of its fetch report it
+func withProgressLine(ctx context.Context, line int) context.Context {
+	return context.WithValue(ctx, progressLineKey{}, line)
+}
+
+// rowEvent returns an event about the row being verified with ctx
+func rowEvent(ctx context.Context, event, reference string) progressEvent {
+	line, _ := ctx.Value(progressLineKey{}).(int)
+	return progressEvent{Event: event, Line: line, Reference: reference}
+}
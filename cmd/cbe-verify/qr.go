@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	// Register the formats of photographed and scanned receipts
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/qr/zxing"
+)
+
+// qr decodes the QR code in a photo of a CBE receipt and fetches the official
+// receipt it points to, verifying it against -amount when given
+func (c *cli) qr(ctx context.Context, args []string) int {
+	fs := c.flagSet("qr", "[flags] IMAGE|-")
+	suffix := fs.String("suffix", "", "transaction suffix, if the QR code carries only the reference")
+	amount := fs.Float64("amount", 0, "expected amount (default: print the official receipt without verifying)")
+	receiverName := fs.String("receiver-name", "", "expected receiver name")
+	payerName := fs.String("payer-name", "", "expected payer name")
+	options := c.optionFlags(fs)
+	output := outputFlag(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "expected one image file")
+	}
+	if *amount < 0 {
+		return c.usageError(fs, "-amount must be positive")
+	}
+
+	photo, err := readInput(c.stdin, fs.Arg(0))
+	if err != nil {
+		return c.fail("qr", err)
+	}
+	t, err := decodeQRTransaction(photo)
+	if err != nil {
+		return c.fail("qr", err)
+	}
+	if t.Suffix == "" {
+		t.Suffix = strings.TrimSpace(*suffix)
+	}
+	if t.Suffix == "" {
+		return c.usageError(fs, "the QR code has no account suffix (reference %s); pass -suffix", t.ID)
+	}
+	fmt.Fprintf(c.stderr, "cbe-verify qr: found reference %s\n", t.ID+t.Suffix)
+
+	opts := options()
+	if *amount == 0 {
+		details, receipt, err := c.fetchReceipt(ctx, t, opts)
+		if err != nil {
+			return c.fail("qr", err)
+		}
+		c.debugReceipt(ctx, receipt, cbeverifier.ProviderCBE, opts)
+		text := func() { printDetails(c.stdout, details) }
+		table := func() { printDetailsTable(c.stdout, details, useColor(c.stdout)) }
+		if err := write(c.stdout, *output, details, text, table); err != nil {
+			return c.fail("qr", err)
+		}
+		return exitOK
+	}
+
+	t.Amount = *amount
+	t.ReceiverName = strings.TrimSpace(*receiverName)
+	t.PayerName = strings.TrimSpace(*payerName)
+	result, err := c.verifyTransaction(ctx, t, opts)
+	if err != nil {
+		return c.fail("qr", err)
+	}
+	text := func() { printResult(c.stdout, result) }
+	table := func() { printResultTable(c.stdout, result, useColor(c.stdout)) }
+	if err := write(c.stdout, *output, result, text, table); err != nil {
+		return c.fail("qr", err)
+	}
+	return resultCode(result)
+}
+
+// decodeQRTransaction decodes the QR code in an image and returns the
+// transaction it references. The suffix is empty when the QR code carries only
+// the reference.
+func decodeQRTransaction(photo []byte) (cbeverifier.Transaction, error) {
+	img, _, err := image.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return cbeverifier.Transaction{}, fmt.Errorf("%w: %v", cbeverifier.ErrQRNotFound, err)
+	}
+	payload, err := zxing.New().Decode(img)
+	if err != nil || payload == "" {
+		return cbeverifier.Transaction{}, cbeverifier.ErrQRNotFound
+	}
+
+	if id, suffix, err := cbeverifier.SplitReference(payload); err == nil {
+		return cbeverifier.Transaction{ID: id, Suffix: suffix}, nil
+	}
+	id := cbeverifier.QRReference(payload)
+	if id == "" {
+		return cbeverifier.Transaction{}, fmt.Errorf("%w: the QR code holds no CBE reference", cbeverifier.ErrQRNotFound)
+	}
+	return cbeverifier.Transaction{ID: id}, nil
+}
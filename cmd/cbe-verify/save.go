@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// fetchReceipt fetches the official receipt of a transaction, returning its
+// parsed details and raw bytes
+func (c *cli) fetchReceipt(ctx context.Context, t cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.TransactionDetails, []byte, error) {
+	name, provider, err := lookupProvider(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	if name == cbeverifier.ProviderCBE {
+		if t.ID == "" && t.FullReference != "" {
+			if t.ID, t.Suffix, err = cbeverifier.SplitReference(t.FullReference); err != nil {
+				return nil, nil, err
+			}
+		}
+		// The CLI's verifier carries the -proxy setting
+		return c.verifier.FetchReceipt(ctx, t.ID, t.Suffix, opts)
+	}
+
+	receipt, err := provider.Fetch(ctx, t, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	details, err := provider.Parse(ctx, receipt, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return details, receipt, nil
+}
+
+// verifyTransaction verifies a transaction against its official receipt. With
+// -save-dir, the receipt and the result are stored in c.saveDir.
+func (c *cli) verifyTransaction(ctx context.Context, t cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, error) {
+	if c.saveDir == "" && !c.debug && c.progress == nil {
+		return c.verifier.Verify(ctx, t, opts)
+	}
+
+	start := time.Now()
+	result, receipt, err := c.verifyFetched(ctx, t, opts)
+	c.debugf("verification took %s", time.Since(start).Round(time.Millisecond))
+	if err != nil || receipt == nil || c.saveDir == "" {
+		return result, err
+	}
+	if err := saveEvidence(c.saveDir, t, receipt, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// verifyFetched fetches the official receipt of a transaction and verifies the
+// transaction against it, returning the receipt so that it can be kept. The
+// receipt is nil when it could not be fetched, which the result reports.
+func (c *cli) verifyFetched(ctx context.Context, t cbeverifier.Transaction, opts cbeverifier.Options) (*cbeverifier.VerificationResult, []byte, error) {
+	name, _, err := lookupProvider(t)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil, nil
+	}
+	t.Provider = name
+
+	start := time.Now()
+	details, receipt, err := c.fetchReceipt(ctx, t, opts)
+	if err != nil {
+		return cbeverifier.NewErrorResult(err), nil, nil
+	}
+	if c.progress != nil {
+		reference := t.ID + t.Suffix
+		if reference == "" {
+			reference = t.FullReference
+		}
+		fetched := rowEvent(ctx, eventFetched, reference)
+		fetched.Bytes, fetched.ElapsedMS = len(receipt), time.Since(start).Milliseconds()
+		c.progress.emit(fetched)
+		parsed := rowEvent(ctx, eventParsed, reference)
+		parsed.Amount = details.Amount
+		c.progress.emit(parsed)
+	}
+	c.debugReceipt(ctx, receipt, name, opts)
+	result, err := c.verifier.VerifyPDF(ctx, receipt, t, opts)
+	return result, receipt, err
+}
+
+// saveEvidence stores a receipt in dir with its verification result, as
+// FT24123ABCDE12345678.pdf and FT24123ABCDE12345678.result.json. Receipts that
+// are not PDFs, such as the pages of other providers, get the extension of
+// their content.
+func saveEvidence(dir string, t cbeverifier.Transaction, receipt []byte, result *cbeverifier.VerificationResult) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	base := filepath.Join(dir, evidenceName(t))
+	if err := os.WriteFile(base+receiptExt(receipt), receipt, 0o644); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(base+".result.json", append(data, '\n'), 0o644)
+}
+
+// evidenceName returns the file name of a transaction's evidence: its full
+// reference, with characters not allowed in file names replaced
+func evidenceName(t cbeverifier.Transaction) string {
+	ref := t.ID + t.Suffix
+	if ref == "" {
+		ref = t.FullReference
+		if id, suffix, err := cbeverifier.SplitReference(ref); err == nil {
+			ref = id + suffix
+		}
+	}
+	name := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(ref))
+	if name == "" {
+		return "receipt"
+	}
+	return name
+}
+
+// receiptExt returns the file extension of a receipt's content
+func receiptExt(receipt []byte) string {
+	switch contentType := http.DetectContentType(receipt); {
+	case strings.HasPrefix(contentType, "application/pdf"):
+		return ".pdf"
+	case strings.HasPrefix(contentType, "text/html"):
+		return ".html"
+	case strings.HasPrefix(contentType, "image/png"):
+		return ".png"
+	case strings.HasPrefix(contentType, "image/jpeg"):
+		return ".jpg"
+	case json.Valid(receipt):
+		return ".json"
+	case strings.HasPrefix(contentType, "text/"):
+		return ".txt"
+	}
+	return ".bin"
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/apikey"
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier/server"
+)
+
+// serve serves verifications over HTTP with the endpoints of the server
+// package: POST /v1/verify, POST /v1/parse, GET /healthz and GET /readyz. The
+// gRPC service is served by cbe-verify-grpc, of the cbeverifier/grpc module.
+func (c *cli) serve(ctx context.Context, args []string) int {
+	fs := c.flagSet("serve", "[flags]")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	keysFile := fs.String("api-keys", "", "YAML file of the API keys clients must send, with their request rates")
+	merchantsFile := fs.String("merchants", "", "YAML file of the merchants served, with their receiver suffix, policy, webhook and API keys")
+	admins := fs.String("merchant-admins", "", "comma-separated `names` of the -api-keys that may act for any merchant with the X-Merchant-ID header")
+	trustHeader := fs.Bool("trust-merchant-header", false, "select the merchant of requests without an API key by the X-Merchant-ID header, behind a gateway that authenticates merchants")
+	probe := fs.Duration("readiness-probe", 0, "make GET /readyz probe CBE at most once per `interval`, failing while it is unreachable")
+	options := c.optionFlags(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+
+	opts := options()
+	logger := slog.New(slog.NewTextHandler(c.stderr, nil))
+	serverOptions := []server.Option{server.WithOptions(opts), server.WithLogger(logger)}
+	var keys *apikey.Keys
+	if *keysFile != "" {
+		var err error
+		if keys, err = apikey.Load(*keysFile); err != nil {
+			return c.fail("serve", err)
+		}
+		serverOptions = append(serverOptions, server.WithAPIKeys(keys))
+	}
+	if *merchantsFile != "" {
+		merchants, err := server.LoadMerchants(*merchantsFile)
+		if err != nil {
+			return c.fail("serve", err)
+		}
+		if keys != nil {
+			if err := merchants.CheckKeys(keys); err != nil {
+				return c.fail("serve", err)
+			}
+		}
+		serverOptions = append(serverOptions, server.WithMerchants(merchants))
+	}
+	if *admins != "" {
+		var names []string
+		for _, name := range strings.Split(*admins, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		serverOptions = append(serverOptions, server.WithMerchantAdmins(names...))
+	}
+	if *trustHeader {
+		serverOptions = append(serverOptions, server.WithTrustedMerchantHeader())
+	}
+	if *probe > 0 {
+		serverOptions = append(serverOptions, server.WithReadinessProbe(*probe))
+	}
+	srv := server.New(c.verifier, serverOptions...)
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	// POST /verify predates the versioned endpoints
+	mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.URL.Path = "/v1/verify"
+		srv.ServeHTTP(w, r)
+	})
+
+	servers := []*http.Server{{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}}
+
+	errs := make(chan error, len(servers))
+	for _, httpServer := range servers {
+		fmt.Fprintf(c.stderr, "cbe-verify: serving on %s\n", httpServer.Addr)
+		go func() {
+			errs <- httpServer.ListenAndServe()
+		}()
+	}
+
+	// Serve until interrupted, or until a server fails
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, httpServer := range servers {
+		httpServer.Shutdown(shutdown)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return c.fail("serve", err)
+	}
+	return exitOK
+}
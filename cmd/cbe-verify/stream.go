@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// maxLineBytes limits the size of a transaction line read from a stream
+const maxLineBytes = 1 << 20
+
+// verifyStream verifies the newline-delimited JSON transactions read from r,
+// writing each result as a JSON line as soon as its verification completes.
+// Results have the shape of batch results, with the input line number, since
+// concurrent verifications may complete out of order.
+func (c *cli) verifyStream(ctx context.Context, r io.Reader, opts cbeverifier.Options, concurrency int) int {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+
+	line := 0
+	next := func() (batchRow, bool) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+
+			row := batchRow{line: line}
+			if err := json.Unmarshal(text, &row.transaction); err != nil {
+				row.err = fmt.Errorf("invalid transaction: %v", err)
+			}
+			return row, true
+		}
+		return batchRow{}, false
+	}
+
+	var (
+		enc       = json.NewEncoder(c.stdout)
+		code      = exitOK
+		firstLine int
+		encoded   error
+	)
+	enc.SetEscapeHTML(false)
+	c.verifyRows(ctx, next, opts, concurrency, func(result batchResult) {
+		// Exit with the code of the first line that was not verified
+		if result.code != exitOK && (firstLine == 0 || result.Line < firstLine) {
+			code, firstLine = result.code, result.Line
+		}
+		if err := enc.Encode(result); err != nil && encoded == nil {
+			encoded = err
+		}
+	})
+
+	for _, err := range []error{scanner.Err(), encoded, ctx.Err()} {
+		if err != nil {
+			return c.fail("verify", err)
+		}
+	}
+	return code
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// batchTotals are the amounts of a batch in one currency
+type batchTotals struct {
+	// Expected is the sum of the amounts listed in the batch file
+	Expected float64
+	// Verified is the sum of the official amounts of the verified rows
+	Verified float64
+	// Delta is Verified minus Expected; it is negative when money is missing
+	Delta float64
+}
+
+// batchSummary counts the outcomes of a batch run and totals its amounts
+type batchSummary struct {
+	Rows       int
+	Verified   int
+	Mismatched int
+	// Failed counts the rows that could not be verified, because they were
+	// invalid or their receipt could not be fetched or parsed
+	Failed    int
+	Duplicate int
+	// Totals holds the amounts by currency
+	Totals map[string]*batchTotals
+}
+
+// summarize counts the outcomes of results and totals their amounts by
+// currency, ETB unless the row says otherwise
+func summarize(results []batchResult) batchSummary {
+	summary := batchSummary{Rows: len(results), Totals: make(map[string]*batchTotals)}
+	for _, result := range results {
+		currency := result.Currency
+		if currency == "" {
+			currency = "ETB"
+		}
+		totals := summary.Totals[currency]
+		if totals == nil {
+			totals = &batchTotals{}
+			summary.Totals[currency] = totals
+		}
+		totals.Expected += result.Amount
+
+		switch result.Status {
+		case statusVerified:
+			summary.Verified++
+			amount, ok := officialAmount(result)
+			if !ok {
+				amount = result.Amount
+			}
+			totals.Verified += amount
+		case statusFailed:
+			// A receipt that could not be fetched fails without a mismatch
+			if result.code == exitMismatch {
+				summary.Mismatched++
+			} else {
+				summary.Failed++
+			}
+		case statusDuplicate:
+			summary.Duplicate++
+		default:
+			summary.Failed++
+		}
+	}
+	for _, totals := range summary.Totals {
+		totals.Delta = totals.Verified - totals.Expected
+	}
+	return summary
+}
+
+// printSummary prints the counts and totals of a batch run
+func printSummary(w io.Writer, summary batchSummary) {
+	fmt.Fprintf(w, "\n%d rows: %d verified, %d mismatched, %d failed, %d duplicate\n",
+		summary.Rows, summary.Verified, summary.Mismatched, summary.Failed, summary.Duplicate)
+
+	currencies := make([]string, 0, len(summary.Totals))
+	for currency := range summary.Totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		totals := summary.Totals[currency]
+		fmt.Fprintf(w, "Verified:  %12.2f %s\n", totals.Verified, currency)
+		fmt.Fprintf(w, "Expected:  %12.2f %s\n", totals.Expected, currency)
+		fmt.Fprintf(w, "Delta:     %+12.2f %s\n", totals.Delta, currency)
+	}
+}
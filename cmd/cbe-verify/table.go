@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// ANSI escape sequences coloring table output
+const (
+	colorReset  = "\x1b[0m"
+	colorBold   = "\x1b[1m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// useColor reports whether output to w should be colored: w must be a terminal,
+// and NO_COLOR must not be set (see https://no-color.org)
+func useColor(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// table is a table of text cells, rendered with aligned columns
+type table struct {
+	header []string
+	rows   [][]string
+	// colors holds the color of each row, or "" for none
+	colors []string
+}
+
+// add appends a row, colored with color when colors are enabled
+func (t *table) add(color string, cells ...string) {
+	t.rows = append(t.rows, cells)
+	t.colors = append(t.colors, color)
+}
+
+// render writes the table to w, with a bold header underlined with dashes.
+// Cells are padded before being colored, since escape sequences take no space.
+func (t *table) render(w io.Writer, color bool) {
+	widths := make([]int, len(t.header))
+	for i, cell := range t.header {
+		widths[i] = utf8.RuneCountInString(cell)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	line := func(cells []string, style string) {
+		var b strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			if i < len(cells)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			}
+		}
+		text := strings.TrimRight(b.String(), " ")
+		if color && style != "" {
+			text = style + text + colorReset
+		}
+		fmt.Fprintln(w, text)
+	}
+
+	line(t.header, colorBold)
+	dashes := make([]string, len(widths))
+	for i, width := range widths {
+		dashes[i] = strings.Repeat("-", width)
+	}
+	line(dashes, "")
+	for i, row := range t.rows {
+		line(row, t.colors[i])
+	}
+}
+
+// paint wraps text in style when colors are enabled
+func paint(text, style string, color bool) string {
+	if !color {
+		return text
+	}
+	return style + text + colorReset
+}
+
+// printResultTable prints a verification result with its mismatches and
+// warnings side by side with the official values, and the receipt details
+func printResultTable(w io.Writer, result *cbeverifier.VerificationResult, color bool) {
+	if result.IsValid {
+		fmt.Fprintln(w, paint("VERIFIED", colorBold+colorGreen, color))
+	} else {
+		fmt.Fprintf(w, "%s  %s\n", paint("FAILED", colorBold+colorRed, color), result.Error)
+	}
+
+	if len(result.Mismatches)+len(result.Warnings) > 0 {
+		fmt.Fprintln(w)
+		t := &table{header: []string{"FIELD", "PROVIDED", "OFFICIAL", "CHECK"}}
+		addComparisons(t, result.Mismatches, "mismatch", colorRed)
+		addComparisons(t, result.Warnings, "warning", colorYellow)
+		t.render(w, color)
+	}
+	if result.NeedsReview {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, paint("The receipt was parsed with low confidence and needs manual review.", colorYellow, color))
+	}
+	if len(result.TamperIndicators) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, paint("Tamper indicators: "+strings.Join(result.TamperIndicators, ", "), colorRed, color))
+	}
+	if result.Details != nil {
+		fmt.Fprintln(w)
+		printDetailsTable(w, result.Details, color)
+	}
+}
+
+// addComparisons adds a row per mismatch or warning, sorted by field
+func addComparisons(t *table, fields map[string]interface{}, check, color string) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		provided, official, ok := comparedValues(fields[name])
+		if !ok {
+			provided, official = formatValue(fields[name]), "-"
+		}
+		t.add(color, name, provided, official, check)
+	}
+}
+
+// printDetailsTable prints the fields printed on a receipt as a table
+func printDetailsTable(w io.Writer, d *cbeverifier.TransactionDetails, color bool) {
+	t := &table{header: []string{"RECEIPT", "VALUE"}}
+	for _, field := range detailFields(d) {
+		t.add("", field[0], field[1])
+	}
+	t.render(w, color)
+}
+
+// printBatchTable prints a table of batch results, in green when verified and
+// red otherwise
+func printBatchTable(w io.Writer, results []batchResult, color bool) {
+	t := &table{header: []string{"LINE", "REFERENCE", "AMOUNT", "STATUS", "DETAIL"}}
+	for _, result := range results {
+		detail := result.Error
+		if result.Result != nil && len(result.Result.Mismatches) > 0 {
+			detail = formatMismatches(result.Result.Mismatches)
+		}
+		style := colorRed
+		if result.Status == statusVerified {
+			style = colorGreen
+		}
+		t.add(style, fmt.Sprint(result.Line), result.Reference+result.Suffix, fmt.Sprintf("%.2f", result.Amount), result.Status, detail)
+	}
+	t.render(w, color)
+}
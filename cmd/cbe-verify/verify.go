@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// verify verifies a transaction against its official receipt, a receipt file or
+// the transactions streamed on stdin
+func (c *cli) verify(ctx context.Context, args []string) int {
+	fs := c.flagSet("verify", "[flags] [-]")
+	transaction := transactionFlags(fs)
+	pdf := fs.String("pdf", "", "verify against this receipt file instead of fetching the official receipt")
+	options := c.optionFlags(fs)
+	output := outputFlag(fs)
+	concurrency := fs.Int("concurrency", 1, "number of transactions read from stdin verified at once")
+	saveReceipt := fs.String("save-receipt", "", "store the official receipt in this file")
+	fs.StringVar(&c.saveDir, "save-dir", "", "store the official receipt and the result in this directory")
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+
+	// "-" streams newline-delimited JSON transactions from stdin
+	if fs.NArg() > 0 {
+		if fs.NArg() != 1 || fs.Arg(0) != "-" {
+			return c.usageError(fs, "unexpected arguments %q", fs.Args())
+		}
+		if *pdf != "" || *saveReceipt != "" {
+			return c.usageError(fs, "-pdf and -save-receipt cannot be used with -")
+		}
+		if *concurrency < 1 {
+			return c.usageError(fs, "-concurrency must be at least 1")
+		}
+		return c.verifyStream(ctx, c.stdin, options(), *concurrency)
+	}
+
+	t := transaction()
+	if err := checkReference(t); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if t.Amount <= 0 {
+		return c.usageError(fs, "-amount must be positive")
+	}
+	if *pdf != "" && (*saveReceipt != "" || c.saveDir != "") {
+		return c.usageError(fs, "-save-receipt and -save-dir store fetched receipts and cannot be used with -pdf")
+	}
+
+	var (
+		result *cbeverifier.VerificationResult
+		err    error
+	)
+	switch {
+	case *pdf != "":
+		// Supplied receipts are verified offline; the suffix is not needed
+		receipt, readErr := os.ReadFile(*pdf)
+		if readErr != nil {
+			return c.fail("verify", readErr)
+		}
+		if name, _, err := lookupProvider(t); err == nil {
+			c.debugReceipt(ctx, receipt, name, options())
+		}
+		result, err = c.verifier.VerifyPDF(ctx, receipt, t, options())
+	case *saveReceipt != "":
+		var receipt []byte
+		result, receipt, err = c.verifyFetched(ctx, t, options())
+		if receipt != nil {
+			if writeErr := os.WriteFile(*saveReceipt, receipt, 0o644); writeErr != nil {
+				return c.fail("verify", writeErr)
+			}
+		}
+		if err == nil && c.saveDir != "" && receipt != nil {
+			err = saveEvidence(c.saveDir, t, receipt, result)
+		}
+	default:
+		result, err = c.verifyTransaction(ctx, t, options())
+	}
+	if err != nil {
+		return c.fail("verify", err)
+	}
+
+	text := func() { printResult(c.stdout, result) }
+	table := func() { printResultTable(c.stdout, result, useColor(c.stdout)) }
+	if err := write(c.stdout, *output, result, text, table); err != nil {
+		return c.fail("verify", err)
+	}
+	return resultCode(result)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zahir-Seid/cbe-verifier/cbeverifier"
+)
+
+// watchResult is the outcome of verifying a receipt dropped in a watched directory
+type watchResult struct {
+	// File is the path the receipt was moved to
+	File      string  `json:"file"`
+	Reference string  `json:"reference,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+	// Status is statusVerified or statusFailed
+	Status string                          `json:"status"`
+	Error  string                          `json:"error,omitempty"`
+	Result *cbeverifier.VerificationResult `json:"result,omitempty"`
+
+	// code is the exit code of the receipt
+	code int
+}
+
+// fileState is the size and modification time of a file when it was last seen
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher verifies the receipts dropped in a directory
+type watcher struct {
+	c           *cli
+	dir         string
+	verifiedDir string
+	failedDir   string
+	provider    string
+	onVerified  string
+	onFailed    string
+	output      string
+	opts        cbeverifier.Options
+
+	// seen holds the files found by the previous scan
+	seen map[string]fileState
+}
+
+// watch monitors a directory, verifying every receipt PDF dropped in it and
+// moving it to the verified or failed directory
+func (c *cli) watch(ctx context.Context, args []string) int {
+	fs := c.flagSet("watch", "[flags] DIR")
+	verifiedDir := fs.String("verified-dir", "", "directory verified receipts are moved to (default: DIR/verified)")
+	failedDir := fs.String("failed-dir", "", "directory failed receipts are moved to (default: DIR/failed)")
+	onVerified := fs.String("on-verified", "", "command run with the path of each verified receipt")
+	onFailed := fs.String("on-failed", "", "command run with the path of each failed receipt")
+	provider := fs.String("provider", cbeverifier.ProviderCBE, "bank or payment service that issued the receipts")
+	interval := fs.Duration("interval", 2*time.Second, "how often the directory is scanned")
+	once := fs.Bool("once", false, "verify the receipts in the directory and exit instead of watching")
+	options := c.optionFlags(fs)
+	output := outputFlag(fs)
+	if code, ok := c.parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return c.usageError(fs, "expected one directory")
+	}
+	if err := checkOutput(*output); err != nil {
+		return c.usageError(fs, "%v", err)
+	}
+	if *output == outputTable {
+		// Receipts are printed one by one as they are dropped
+		return c.usageError(fs, "-output table is not supported by watch")
+	}
+	if *interval <= 0 {
+		return c.usageError(fs, "-interval must be positive")
+	}
+
+	w := &watcher{
+		c:           c,
+		dir:         fs.Arg(0),
+		verifiedDir: *verifiedDir,
+		failedDir:   *failedDir,
+		provider:    strings.TrimSpace(*provider),
+		onVerified:  *onVerified,
+		onFailed:    *onFailed,
+		output:      *output,
+		opts:        options(),
+		seen:        make(map[string]fileState),
+	}
+	if w.verifiedDir == "" {
+		w.verifiedDir = filepath.Join(w.dir, "verified")
+	}
+	if w.failedDir == "" {
+		w.failedDir = filepath.Join(w.dir, "failed")
+	}
+	for _, dir := range []string{w.verifiedDir, w.failedDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return c.fail("watch", err)
+		}
+	}
+
+	if *once {
+		return w.scan(ctx, true)
+	}
+
+	fmt.Fprintf(c.stderr, "cbe-verify: watching %s\n", w.dir)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		w.scan(ctx, false)
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan verifies the receipts in the directory and returns the exit code of the
+// first that failed. Files still being written, whose size or modification time
+// changed since the previous scan, are left for the next one unless all is set.
+func (w *watcher) scan(ctx context.Context, all bool) int {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return w.c.fail("watch", err)
+	}
+
+	code := exitOK
+	found := make(map[string]fileState)
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(entry.Name()), ".pdf") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				w.c.fail("watch", err)
+			}
+			continue
+		}
+
+		path := filepath.Join(w.dir, entry.Name())
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if prev, ok := w.seen[path]; !all && (!ok || prev != state) {
+			found[path] = state
+			continue
+		}
+
+		result := w.process(ctx, path)
+		if result.code != exitOK && code == exitOK {
+			code = result.code
+		}
+	}
+	w.seen = found
+	return code
+}
+
+// process verifies a receipt, moves it and runs the hook for its outcome
+func (w *watcher) process(ctx context.Context, path string) watchResult {
+	result := w.verify(ctx, path)
+
+	dir, hook := w.verifiedDir, w.onVerified
+	if result.Status != statusVerified {
+		dir, hook = w.failedDir, w.onFailed
+	}
+	moved, err := moveFile(path, dir)
+	if err != nil {
+		w.c.fail("watch", err)
+		moved = path
+	}
+	result.File = moved
+
+	w.print(result)
+	if hook != "" {
+		w.runHook(ctx, hook, result)
+	}
+	return result
+}
+
+// verify parses a receipt and checks it with the verification options. The
+// receipt is compared with its own reference and amount, so it fails only the
+// checks that do not depend on an expected transaction, such as the receiver
+// account, the payment reason or tampering.
+func (w *watcher) verify(ctx context.Context, path string) watchResult {
+	result := watchResult{File: path, Status: statusFailed}
+	fail := func(err error) watchResult {
+		result.Error, result.code = err.Error(), errorCode(err)
+		return result
+	}
+
+	receipt, err := os.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	name, provider, err := lookupProvider(cbeverifier.Transaction{Provider: w.provider})
+	if err != nil {
+		return fail(err)
+	}
+	w.c.debugReceipt(ctx, receipt, name, w.opts)
+	details, err := provider.Parse(ctx, receipt, w.opts)
+	if err != nil {
+		return fail(err)
+	}
+	result.Reference, result.Amount = details.TransactionID, details.Amount
+
+	verification, err := w.c.verifier.VerifyPDF(ctx, receipt, cbeverifier.Transaction{
+		ID:       details.TransactionID,
+		Amount:   details.Amount,
+		Currency: details.Currency,
+		Provider: w.provider,
+	}, w.opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Result, result.code = verification, resultCode(verification)
+	if verification.IsValid {
+		result.Status = statusVerified
+	} else {
+		result.Error = verification.Error
+	}
+	return result
+}
+
+// print prints the outcome of a receipt, as a text line or a JSON line
+func (w *watcher) print(result watchResult) {
+	if w.output == outputText {
+		status := result.Status
+		if result.Error != "" {
+			status += ": " + result.Error
+		}
+		fmt.Fprintf(w.c.stdout, "%s\t%s\t%s\n", result.File, result.Reference, status)
+		return
+	}
+
+	// Every receipt is printed as it is processed, so structured output is one
+	// JSON document per line
+	enc := json.NewEncoder(w.c.stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		w.c.fail("watch", err)
+	}
+}
+
+// runHook runs a hook command with the receipt's path as its argument. The
+// outcome is passed in CBE_STATUS, CBE_REFERENCE, CBE_AMOUNT, CBE_ERROR and
+// CBE_RESULT (the JSON verification result); the command's output goes to stderr.
+func (w *watcher) runHook(ctx context.Context, hook string, result watchResult) {
+	resultJSON, _ := json.Marshal(result.Result)
+
+	cmd := exec.CommandContext(ctx, hook, result.File)
+	cmd.Env = append(os.Environ(),
+		"CBE_STATUS="+result.Status,
+		"CBE_REFERENCE="+result.Reference,
+		"CBE_AMOUNT="+strconv.FormatFloat(result.Amount, 'f', 2, 64),
+		"CBE_ERROR="+result.Error,
+		"CBE_RESULT="+string(resultJSON),
+	)
+	cmd.Stdout, cmd.Stderr = w.c.stderr, w.c.stderr
+	if err := cmd.Run(); err != nil {
+		w.c.fail("watch", fmt.Errorf("hook %s: %w", hook, err))
+	}
+}
+
+// moveFile moves a file into dir, numbering its name if a file of the same
+// name is already there, and returns its new path
+func moveFile(path, dir string) (string, error) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	target := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+	}
+	return target, os.Rename(path, target)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cell styles of the exported workbook, indexes into the cellXfs of xlsxStyles
+const (
+	styleDefault = iota
+	styleHeader
+	styleAmount
+	styleVerified
+	styleFailed
+	styleError
+	styleMismatch
+	styleTotal
+	styleTotalAmount
+)
+
+// xlsxStyles defines the fonts, fills and cell formats of the exported workbook:
+// a bold gray header, amounts with two decimals, statuses filled green, red or
+// yellow, and mismatched values in red
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00"/></numFmts>
+<fonts count="3">
+<font><sz val="11"/><name val="Calibri"/></font>
+<font><b/><sz val="11"/><name val="Calibri"/></font>
+<font><sz val="11"/><color rgb="FF9C0006"/><name val="Calibri"/></font>
+</fonts>
+<fills count="6">
+<fill><patternFill patternType="none"/></fill>
+<fill><patternFill patternType="gray125"/></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFD9D9D9"/></patternFill></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFC6EFCE"/></patternFill></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFFFC7CE"/></patternFill></fill>
+<fill><patternFill patternType="solid"><fgColor rgb="FFFFEB9C"/></patternFill></fill>
+</fills>
+<borders count="1"><border/></borders>
+<cellStyleXfs count="1"><xf/></cellStyleXfs>
+<cellXfs count="9">
+<xf/>
+<xf fontId="1" fillId="2" applyFont="1" applyFill="1"/>
+<xf numFmtId="164" applyNumberFormat="1"/>
+<xf fillId="3" applyFill="1"/>
+<xf fillId="4" applyFill="1"/>
+<xf fillId="5" applyFill="1"/>
+<xf fontId="2" applyFont="1"/>
+<xf fontId="1" applyFont="1"/>
+<xf fontId="1" numFmtId="164" applyFont="1" applyNumberFormat="1"/>
+</cellXfs>
+</styleSheet>
+`
+
+// xlsxParts are the fixed parts of a workbook with a single "Results" sheet,
+// whose worksheet is xl/worksheets/sheet1.xml
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>
+`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>
+`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Results" sheetId="1" r:id="rId1"/></sheets>
+</workbook>
+`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>
+`},
+	{"xl/styles.xml", xlsxStyles},
+}
+
+// xlsxCell is a cell of the exported sheet: a string, a number or a formula
+// with its computed value
+type xlsxCell struct {
+	text    string
+	number  float64
+	formula string
+	numeric bool
+	style   int
+}
+
+// textCell returns a string cell
+func textCell(text string, style int) xlsxCell {
+	return xlsxCell{text: text, style: style}
+}
+
+// numberCell returns a numeric cell
+func numberCell(number float64, style int) xlsxCell {
+	return xlsxCell{number: number, numeric: true, style: style}
+}
+
+// exportResults writes batch results to an XLSX workbook at path
+func exportResults(path string, results []batchResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeWorkbook(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeWorkbook writes batch results as an XLSX workbook for accountants: one
+// row per transaction with its status filled in color, the provided and
+// official values of each mismatched field side by side, and a totals row
+func writeWorkbook(w io.Writer, results []batchResult) error {
+	// Every field that mismatched in some row gets a pair of columns
+	fieldSet := make(map[string]bool)
+	for _, result := range results {
+		if result.Result != nil {
+			for field := range result.Result.Mismatches {
+				fieldSet[field] = true
+			}
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	header := []xlsxCell{
+		textCell("Line", styleHeader),
+		textCell("Reference", styleHeader),
+		textCell("Suffix", styleHeader),
+		textCell("Amount", styleHeader),
+		textCell("Official amount", styleHeader),
+		textCell("Status", styleHeader),
+	}
+	for _, field := range fields {
+		header = append(header, textCell(field+" provided", styleHeader), textCell(field+" official", styleHeader))
+	}
+	header = append(header, textCell("Error", styleHeader))
+	rows := [][]xlsxCell{header}
+
+	var amount, official float64
+	verified := 0
+	for _, result := range results {
+		row := []xlsxCell{
+			numberCell(float64(result.Line), styleDefault),
+			textCell(result.Reference, styleDefault),
+			textCell(result.Suffix, styleDefault),
+			numberCell(result.Amount, styleAmount),
+			textCell("", styleDefault),
+			textCell(result.Status, statusStyle(result.Status)),
+		}
+		amount += result.Amount
+		if result.Status == statusVerified {
+			verified++
+		}
+		if value, ok := officialAmount(result); ok {
+			row[4] = numberCell(value, styleAmount)
+			official += value
+		}
+		for _, field := range fields {
+			provided, officialValue := "", ""
+			if result.Result != nil {
+				provided, officialValue, _ = comparedValues(result.Result.Mismatches[field])
+			}
+			row = append(row, textCell(provided, styleMismatch), textCell(officialValue, styleMismatch))
+		}
+		row = append(row, textCell(result.Error, styleDefault))
+		rows = append(rows, row)
+	}
+
+	last := len(results) + 1
+	total := []xlsxCell{
+		textCell("Total", styleTotal),
+		textCell(fmt.Sprintf("%d transactions", len(results)), styleTotal),
+		textCell("", styleTotal),
+		{formula: fmt.Sprintf("SUM(D2:D%d)", last), number: amount, numeric: true, style: styleTotalAmount},
+		{formula: fmt.Sprintf("SUM(E2:E%d)", last), number: official, numeric: true, style: styleTotalAmount},
+		{formula: fmt.Sprintf(`COUNTIF(F2:F%d,"%s")&" %s"`, last, statusVerified, statusVerified), text: fmt.Sprintf("%d %s", verified, statusVerified), style: styleTotal},
+	}
+	if len(results) == 0 {
+		total[3].formula, total[4].formula, total[5].formula = "", "", ""
+	}
+	rows = append(rows, total)
+
+	zw := zip.NewWriter(w)
+	parts := append(xlsxParts, struct{ name, content string }{"xl/worksheets/sheet1.xml", worksheetXML(rows, len(header), last)})
+	for _, p := range parts {
+		part, err := zw.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(part, p.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// officialAmount returns the amount of a row's official receipt, from its
+// details or else from its amount mismatch
+func officialAmount(result batchResult) (float64, bool) {
+	if result.Result == nil {
+		return 0, false
+	}
+	if details := result.Result.Details; details != nil {
+		return details.Amount, true
+	}
+	if entry, ok := result.Result.Mismatches["amount"].(map[string]interface{}); ok {
+		amount, ok := entry["official"].(float64)
+		return amount, ok
+	}
+	return 0, false
+}
+
+// statusStyle returns the style of a status cell
+func statusStyle(status string) int {
+	switch status {
+	case statusVerified:
+		return styleVerified
+	case statusFailed:
+		return styleFailed
+	}
+	return styleError
+}
+
+// worksheetXML renders the rows of a sheet whose header is frozen and filtered
+// down to row last
+func worksheetXML(rows [][]xlsxCell, columns, last int) string {
+	widths := make([]int, columns)
+	for _, row := range rows {
+		for i, cell := range row {
+			width := utf8.RuneCountInString(cell.text)
+			if cell.numeric {
+				width = len(strconv.FormatFloat(cell.number, 'f', 2, 64)) + 2
+			}
+			widths[i] = min(max(widths[i], width+2), 60)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+<cols>`)
+	for i, width := range widths {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width)
+	}
+	b.WriteString("</cols>\n<sheetData>\n")
+	for r, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for i, cell := range row {
+			ref := columnName(i) + strconv.Itoa(r+1)
+			switch {
+			case cell.formula != "" && cell.numeric:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><f>%s</f><v>%s</v></c>`, ref, cell.style, escapeXML(cell.formula), formatNumber(cell.number))
+			case cell.formula != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="str"><f>%s</f><v>%s</v></c>`, ref, cell.style, escapeXML(cell.formula), escapeXML(cell.text))
+			case cell.numeric:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, formatNumber(cell.number))
+			case cell.text != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, escapeXML(cell.text))
+			case cell.style != styleDefault:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, cell.style)
+			}
+		}
+		b.WriteString("</row>\n")
+	}
+	fmt.Fprintf(&b, "</sheetData>\n<autoFilter ref=\"A1:%s%d\"/>\n</worksheet>\n", columnName(columns-1), last)
+	return b.String()
+}
+
+// columnName returns the letters of the column at index i (A, B, ..., AA, ...)
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// formatNumber formats a cell value
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// escapeXML escapes text for a cell
+func escapeXML(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}